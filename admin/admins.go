@@ -0,0 +1,461 @@
+package admin
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"Tahlilchi.uz/authPackage"
+	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/toolkit"
+	"github.com/lib/pq"
+)
+
+// minPasswordLength is the minimum length of an admin password
+const minPasswordLength = 8
+
+// AdminUser is a struct to map an admin account without its password hash
+type AdminUser struct {
+	ID                 int    `json:"id"`
+	Name               string `json:"name"`
+	Email              string `json:"email"`
+	Role               string `json:"role"`
+	Disabled           bool   `json:"disabled"`
+	MustChangePassword bool   `json:"must_change_password"`
+	CreatedAt          string `json:"created_at"`
+	UpdatedAt          string `json:"updated_at"`
+}
+
+// validatePassword returns a message for the client if the password is not acceptable, empty otherwise
+func validatePassword(password string) string {
+	if len(password) < minPasswordLength {
+		return "password must be at least " + strconv.Itoa(minPasswordLength) + " characters long"
+	}
+	return ""
+}
+
+// getAdminUser is a function to get an admin account by id
+func getAdminUser(id int) (AdminUser, error) {
+	database, err := db.DB()
+	if err != nil {
+		return AdminUser{}, err
+	}
+
+	var a AdminUser
+	err = database.QueryRow("SELECT id, name, email, role, disabled, must_change_password, created_at, updated_at FROM admins WHERE id = $1", id).Scan(&a.ID, &a.Name, &a.Email, &a.Role, &a.Disabled, &a.MustChangePassword, &a.CreatedAt, &a.UpdatedAt)
+	return a, err
+}
+
+// isLastSuperAdmin reports whether the admin with the given id is the only enabled superadmin left
+func isLastSuperAdmin(id int) (bool, error) {
+	database, err := db.DB()
+	if err != nil {
+		return false, err
+	}
+
+	var others int
+	err = database.QueryRow("SELECT COUNT(*) FROM admins WHERE role = $1 AND NOT disabled AND id <> $2", authPackage.RoleSuperAdmin, id).Scan(&others)
+	if err != nil {
+		return false, err
+	}
+	return others == 0, nil
+}
+
+// targetAdmin reads the {id} route variable and loads the admin account.
+// It writes the error response itself and returns false if the handler must stop.
+// Superadmins may not use the management routes on their own account, so they cannot lock themselves out.
+func targetAdmin(w http.ResponseWriter, r *http.Request) (AdminUser, bool) {
+	id, err := toolkit.GetID(r)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusBadRequest, "invalid id")
+		return AdminUser{}, false
+	}
+
+	a, err := getAdminUser(id)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "admin not found")
+		return AdminUser{}, false
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return AdminUser{}, false
+	}
+
+	if a.Email == authPackage.AdminEmail(r) {
+		response.Res(w, "error", http.StatusBadRequest, "cannot change your own account here")
+		return AdminUser{}, false
+	}
+
+	return a, true
+}
+
+// listAdmins is a route handler function to get the list of all admin accounts
+func listAdmins(w http.ResponseWriter, r *http.Request) {
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	rows, err := database.Query("SELECT id, name, email, role, disabled, must_change_password, created_at, updated_at FROM admins ORDER BY id")
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	defer rows.Close()
+
+	admins := []AdminUser{}
+	for rows.Next() {
+		var a AdminUser
+		if err := rows.Scan(&a.ID, &a.Name, &a.Email, &a.Role, &a.Disabled, &a.MustChangePassword, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+		admins = append(admins, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, admins)
+}
+
+// createAdmin is a route handler function to create a new admin account.
+// The given password is temporary: the new admin has to change it after the first login.
+func createAdmin(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	email := strings.TrimSpace(r.FormValue("email"))
+	role := r.FormValue("role")
+	password := r.FormValue("password")
+
+	if name == "" || email == "" || role == "" || password == "" {
+		response.Res(w, "error", http.StatusBadRequest, "name, email, role and password are required")
+		return
+	}
+
+	if !authPackage.ValidRole(role) {
+		response.Res(w, "error", http.StatusBadRequest, "invalid role, it must be one of: "+strings.Join(authPackage.Roles(), ", "))
+		return
+	}
+
+	if msg := validatePassword(password); msg != "" {
+		response.Res(w, "error", http.StatusBadRequest, msg)
+		return
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	var id int
+	err = database.QueryRow("INSERT INTO admins (name, email, role, password, must_change_password) VALUES ($1, $2, $3, $4, true) RETURNING id", name, email, role, hash).Scan(&id)
+	if err != nil {
+		// unique_violation on admins.email
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			response.Res(w, "error", http.StatusConflict, "an admin with this email already exists")
+			return
+		}
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	a, err := getAdminUser(id)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusCreated, a)
+}
+
+// setAdminDisabled returns a route handler function which disables or re-enables an admin account
+func setAdminDisabled(disabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		a, ok := targetAdmin(w, r)
+		if !ok {
+			return
+		}
+
+		if disabled && a.Role == authPackage.RoleSuperAdmin {
+			last, err := isLastSuperAdmin(a.ID)
+			if err != nil {
+				toolkit.LogError(r, err)
+				response.Res(w, "error", http.StatusInternalServerError, "server error")
+				return
+			}
+			if last {
+				response.Res(w, "error", http.StatusBadRequest, "cannot disable the last superadmin")
+				return
+			}
+		}
+
+		database, err := db.DB()
+		if err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+
+		_, err = database.Exec("UPDATE admins SET disabled = $1, updated_at = NOW() WHERE id = $2", disabled, a.ID)
+		if err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+
+		if disabled {
+			// log the admin out everywhere
+			if _, err := authPackage.RevokeAdminSessions(a.Email, ""); err != nil {
+				toolkit.LogError(r, err)
+				response.Res(w, "error", http.StatusInternalServerError, "server error")
+				return
+			}
+			response.Res(w, "success", http.StatusOK, "admin disabled")
+		} else {
+			response.Res(w, "success", http.StatusOK, "admin enabled")
+		}
+	}
+}
+
+// deleteAdmin is a route handler function to delete an admin account
+func deleteAdmin(w http.ResponseWriter, r *http.Request) {
+	a, ok := targetAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	if a.Role == authPackage.RoleSuperAdmin {
+		last, err := isLastSuperAdmin(a.ID)
+		if err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+		if last {
+			response.Res(w, "error", http.StatusBadRequest, "cannot delete the last superadmin")
+			return
+		}
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	_, err = database.Exec("DELETE FROM admins WHERE id = $1", a.ID)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// log the admin out everywhere
+	if _, err := authPackage.RevokeAdminSessions(a.Email, ""); err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, "admin deleted")
+}
+
+// updateAdminRole is a route handler function to change the role of an admin account
+func updateAdminRole(w http.ResponseWriter, r *http.Request) {
+	a, ok := targetAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	role := r.FormValue("role")
+	if !authPackage.ValidRole(role) {
+		response.Res(w, "error", http.StatusBadRequest, "invalid role, it must be one of: "+strings.Join(authPackage.Roles(), ", "))
+		return
+	}
+
+	if a.Role == authPackage.RoleSuperAdmin && role != authPackage.RoleSuperAdmin {
+		last, err := isLastSuperAdmin(a.ID)
+		if err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+		if last {
+			response.Res(w, "error", http.StatusBadRequest, "cannot change the role of the last superadmin")
+			return
+		}
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	_, err = database.Exec("UPDATE admins SET role = $1, updated_at = NOW() WHERE id = $2", role, a.ID)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, "admin role updated")
+}
+
+// forceAdminPasswordReset is a route handler function to set a temporary password for an admin account.
+// The admin cannot use any other admin route until they change it.
+func forceAdminPasswordReset(w http.ResponseWriter, r *http.Request) {
+	a, ok := targetAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	password := r.FormValue("password")
+	if msg := validatePassword(password); msg != "" {
+		response.Res(w, "error", http.StatusBadRequest, msg)
+		return
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	_, err = database.Exec("UPDATE admins SET password = $1, must_change_password = true, updated_at = NOW() WHERE id = $2", hash, a.ID)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// log the admin out everywhere
+	if _, err := authPackage.RevokeAdminSessions(a.Email, ""); err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, "password reset forced")
+}
+
+// changeOwnPassword is a route handler function for the logged in admin to change their own password
+func changeOwnPassword(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	currentPassword := r.FormValue("current_password")
+	newPassword := r.FormValue("new_password")
+	if currentPassword == "" || newPassword == "" {
+		response.Res(w, "error", http.StatusBadRequest, "current_password and new_password are required")
+		return
+	}
+
+	if msg := validatePassword(newPassword); msg != "" {
+		response.Res(w, "error", http.StatusBadRequest, msg)
+		return
+	}
+
+	if currentPassword == newPassword {
+		response.Res(w, "error", http.StatusBadRequest, "new password must be different from the current one")
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	var id int
+	var hash string
+	var disabled bool
+	err = database.QueryRow("SELECT id, password, disabled FROM admins WHERE email = $1", authPackage.AdminEmail(r)).Scan(&id, &hash, &disabled)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusForbidden, "Forbidden")
+		return
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	if disabled {
+		response.Res(w, "error", http.StatusForbidden, "account disabled")
+		return
+	}
+
+	if !checkPasswordHash(currentPassword, hash) {
+		response.Res(w, "error", http.StatusUnauthorized, "current password is wrong")
+		return
+	}
+
+	newHash, err := hashPassword(newPassword)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	_, err = database.Exec("UPDATE admins SET password = $1, must_change_password = false, updated_at = NOW() WHERE id = $2", newHash, id)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// log the admin out of their other sessions, keeping the current one
+	session, _ := authPackage.Store.Get(r, "Tahlilchi.uz-admin")
+	if _, err := authPackage.RevokeAdminSessions(authPackage.AdminEmail(r), session.ID); err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, "password changed")
+}
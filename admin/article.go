@@ -2,14 +2,19 @@ package admin
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
 	"strconv"
+	"time"
 
+	"Tahlilchi.uz/authPackage"
 	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/media"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/slug"
+	"Tahlilchi.uz/toolkit"
+	"Tahlilchi.uz/upload"
 	"github.com/gorilla/mux"
 	"github.com/lib/pq"
 )
@@ -27,15 +32,14 @@ type ArticleCategory struct {
 func getArticleCategory(w http.ResponseWriter, r *http.Request) {
 	database, err := db.DB()
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	rows, err := database.Query("select * from article_category order by id")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -45,7 +49,7 @@ func getArticleCategory(w http.ResponseWriter, r *http.Request) {
 		var a ArticleCategory
 		err := rows.Scan(&a.ID, &a.TitleLatin, &a.DescriptionLatin, &a.TitleCyrillic, &a.DescriptionCyrillic)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -64,16 +68,15 @@ func updateArticleCategory(w http.ResponseWriter, r *http.Request) {
 	// check if the category exists
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	var exists bool
 	err = database.QueryRow("SELECT EXISTS(SELECT 1 FROM article_category WHERE id=$1)", id).Scan(&exists)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -86,7 +89,7 @@ func updateArticleCategory(w http.ResponseWriter, r *http.Request) {
 	// Parse the form
 	err = r.ParseForm()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusBadRequest, "form parse error")
 		return
 	}
@@ -101,7 +104,7 @@ func updateArticleCategory(w http.ResponseWriter, r *http.Request) {
 		`
 		_, err = database.Exec(sqlStatement, titleLatin, id)
 		if err != nil {
-			log.Printf("%v: writing title_latin into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing title_latin into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -117,7 +120,7 @@ func updateArticleCategory(w http.ResponseWriter, r *http.Request) {
 		`
 		_, err = database.Exec(sqlStatement, descriptionLatin, id)
 		if err != nil {
-			log.Printf("%v: writing description_latin into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing description_latin into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -133,7 +136,7 @@ func updateArticleCategory(w http.ResponseWriter, r *http.Request) {
 		`
 		_, err = database.Exec(sqlStatement, titleCyrillic, id)
 		if err != nil {
-			log.Printf("%v: writing title_cyrillic into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing title_cyrillic into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -149,7 +152,7 @@ func updateArticleCategory(w http.ResponseWriter, r *http.Request) {
 		`
 		_, err = database.Exec(sqlStatement, descriptionCyrillic, id)
 		if err != nil {
-			log.Printf("%v: writing description_cyrillic into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing description_cyrillic into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -167,16 +170,15 @@ func deleteArticleCategory(w http.ResponseWriter, r *http.Request) {
 	// check if the category exists
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	var exists bool
 	err = database.QueryRow("SELECT EXISTS(SELECT 1 FROM article_category WHERE id=$1)", id).Scan(&exists)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -189,7 +191,7 @@ func deleteArticleCategory(w http.ResponseWriter, r *http.Request) {
 	// Prepare the SQL statement: delete from article_category where id = $1
 	stmt, err := database.Prepare("DELETE FROM article_category WHERE id = $1")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -198,7 +200,7 @@ func deleteArticleCategory(w http.ResponseWriter, r *http.Request) {
 	// Execute the SQL statement
 	_, err = stmt.Exec(id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -210,11 +212,10 @@ func deleteArticleCategory(w http.ResponseWriter, r *http.Request) {
 func addArticleCategory(w http.ResponseWriter, r *http.Request) {
 	db, err := db.DB()
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
 	var c Category
 	c.TitleLatin = r.FormValue("title_latin")
@@ -234,7 +235,7 @@ func addArticleCategory(w http.ResponseWriter, r *http.Request) {
 
 	_, err = db.Exec("INSERT INTO article_category(title_latin, description_latin, title_cyrillic, description_cyrillic) VALUES($1, $2, $3, $4)", c.TitleLatin, c.DescriptionLatin, c.TitleCyrillic, c.DescriptionCyrillic)
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -251,16 +252,36 @@ type ArticlePhoto struct {
 	CreatedAt string `json:"created_at"`
 }
 
+// maxArticleRequest limits the request of an article with its photos
+const maxArticleRequest = 200 << 20
+
+var (
+	// articlePhoto is the upload kind of the photos of an article
+	articlePhoto = upload.Image.Max(10 << 20)
+	// articleCoverImage is the upload kind of the cover image of an article
+	articleCoverImage = upload.Image.Max(15 << 20)
+)
+
 // addArticle is a handler function to add a new article to the database
 func addArticle(w http.ResponseWriter, r *http.Request) {
 	// Parse the multipart form
-	err := r.ParseMultipartForm(200 << 20)
+	err := upload.ParseForm(w, r, maxArticleRequest)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusBadRequest, "file too large")
+		upload.Fail(w, r, err)
 		return
 	}
 
+	// publishing window
+	window, err := parsePublishWindow(r)
+	if err != nil {
+		toolkit.LogInfo(r, err.Error())
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// fill a missing alphabet from the other one on request
+	autofill(r, titleFields, descriptionFields)
+
 	// title_latin
 	titleLatin := r.FormValue("title_latin")
 	if titleLatin == "" {
@@ -283,46 +304,17 @@ func addArticle(w http.ResponseWriter, r *http.Request) {
 
 	// photos
 	photoFiles := r.MultipartForm.File["photo"]
-	var photos []ArticlePhoto
+	var photos []*upload.File
 	if len(photoFiles) > 0 {
 		for _, fh := range photoFiles {
-			// check whether the file is an image by checking the content type
-			if fh.Header.Get("Content-Type")[:5] != "image" {
-				log.Printf("%v: photo is not an image: %v", r.URL, fh.Header.Get("Content-Type"))
-				response.Res(w, "error", http.StatusBadRequest, "photo is not an image")
-				return
-			}
-			// Check if the file size is greater than 10MB
-			if fh.Size > 10<<20 {
-				log.Printf("%v: photo size exceeds 10MB limit: %v", r.URL, fh.Size)
-				response.Res(w, "error", http.StatusBadRequest, "photo size exceeds 10MB limit")
-				return
-			}
-			// Read the file
-			file, err := fh.Open()
-			if err != nil {
-				log.Printf("%v: error: %v", r.URL, err)
-				response.Res(w, "error", http.StatusInternalServerError, "server error")
-				return
-			}
-			// create a variable of type ArticlePhoto
-			var photo ArticlePhoto
-			photo.FileName = fh.Filename
-			// Read the file into a byte slice
-			photo.File, err = io.ReadAll(file)
+			// Check the format and size of the file
+			photo, err := upload.Check(fh, "photo", articlePhoto)
 			if err != nil {
-				log.Printf("%v: error: %v", r.URL, err)
-				response.Res(w, "error", http.StatusInternalServerError, "server error")
+				upload.Fail(w, r, err)
 				return
 			}
-			// Append the byte slice to the photos slice
+			// Append the photo to the photos slice
 			photos = append(photos, photo)
-			err = file.Close()
-			if err != nil {
-				log.Printf("%v: error: %v", r.URL, err)
-				response.Res(w, "error", http.StatusInternalServerError, "server error")
-				return
-			}
 		}
 	}
 
@@ -330,42 +322,14 @@ func addArticle(w http.ResponseWriter, r *http.Request) {
 	videos := r.Form["video"]
 
 	// cover_image
-	coverImageFile, coverImageHeader, err := r.FormFile("cover_image")
-	// cover_image is []byte
-	var coverImage []byte
-	if err != nil && err != http.ErrMissingFile {
-		log.Printf("%v: error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusBadRequest, "cover_image error")
-		return
-	} else if err == http.ErrMissingFile {
-		coverImage = nil
-	} else {
-		// check whether the file is an image by checking the content type
-		if coverImageHeader.Header.Get("Content-Type")[:5] != "image" {
-			log.Printf("%v: cover_image is not an image: %v", r.URL, coverImageHeader.Header.Get("Content-Type"))
-			response.Res(w, "error", http.StatusBadRequest, "cover_image is not an image")
-			return
-		}
-		// Check if the file size is greater than 15MB
-		if coverImageHeader.Size > 15<<20 {
-			log.Printf("%v: cover_image size exceeds 15MB limit: %v", r.URL, coverImageHeader.Size)
-			response.Res(w, "error", http.StatusBadRequest, "cover_image size exceeds 15MB limit")
-			return
-		}
-		// Read the file
-		coverImage, err = io.ReadAll(coverImageFile)
-		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
-			response.Res(w, "error", http.StatusInternalServerError, "server error")
-			return
-		}
-		// Close the file
-		err = coverImageFile.Close()
-		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
-			response.Res(w, "error", http.StatusInternalServerError, "server error")
-			return
-		}
+	coverImage, err := upload.FormFile(r, "cover_image", articleCoverImage)
+	if err != nil {
+		upload.Fail(w, r, err)
+		return
+	}
+	var coverImageName string
+	if coverImage != nil {
+		coverImageName = coverImage.Name()
 	}
 
 	// tags
@@ -378,7 +342,7 @@ func addArticle(w http.ResponseWriter, r *http.Request) {
 	if categoryStr != "" {
 		categoryInt, err := strconv.Atoi(categoryStr)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -392,7 +356,7 @@ func addArticle(w http.ResponseWriter, r *http.Request) {
 	if relatedStr != "" {
 		relatedInt, err := strconv.Atoi(relatedStr)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -402,16 +366,32 @@ func addArticle(w http.ResponseWriter, r *http.Request) {
 	// Open a connection to the database
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// Store the cover image in the media storage
+	coverImageID, err := media.SaveFileNullable(r.Context(), coverImage, coverImageName)
+	if err != nil {
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	// Prepare the SQL statement: insert title_latin, description_latin, title_cyrillic, description_cyrillic, videos, cover_image, tags, category, related into articles return id
-	stmt, err := database.Prepare("INSERT INTO articles(title_latin, description_latin, title_cyrillic, description_cyrillic, videos, cover_image, tags, category, related) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id")
+	// Start a new transaction, the article, its photos and its first revision are written together
+	tx, err := database.Begin()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	defer tx.Rollback()
+
+	// Prepare the SQL statement: insert title_latin, description_latin, title_cyrillic, description_cyrillic, videos, cover_image_id, tags, category, related into articles return id
+	stmt, err := tx.Prepare("INSERT INTO articles(title_latin, description_latin, title_cyrillic, description_cyrillic, videos, cover_image_id, tags, category, related, publish_at, unpublish_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id")
+	if err != nil {
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -420,24 +400,19 @@ func addArticle(w http.ResponseWriter, r *http.Request) {
 	// Execute the SQL statement
 	// id is bigint
 	var id int64
-	err = stmt.QueryRow(titleLatin, descriptionLatin, titleCyrillic, descriptionCyrillic, pq.Array(videos), coverImage, pq.Array(tags), category, related).Scan(&id)
+	err = stmt.QueryRow(titleLatin, descriptionLatin, titleCyrillic, descriptionCyrillic, pq.Array(videos), coverImageID, pq.Array(tags), category, related, window.PublishAt, window.UnpublishAt).Scan(&id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	// check if len(photos) > 0
 	if len(photos) > 0 {
-		// Prepare the SQL statement: insert article, file_name, file into article_photos
-		stmt, err = database.Prepare("INSERT INTO article_photos(article, file_name, file) VALUES($1, $2, $3)")
+		// Prepare the SQL statement: insert article, file_name, media_id into article_photos
+		stmt, err = tx.Prepare("INSERT INTO article_photos(article, file_name, media_id) VALUES($1, $2, $3)")
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
-			// delete the article from the articles table
-			_, err = database.Exec("DELETE FROM articles WHERE id = $1", id)
-			if err != nil {
-				log.Printf("%v: error: %v", r.URL, err)
-			}
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -445,20 +420,42 @@ func addArticle(w http.ResponseWriter, r *http.Request) {
 
 		// Execute the SQL statement
 		for _, photo := range photos {
-			_, err = stmt.Exec(id, photo.FileName, photo.File)
+			var mediaID int64
+			mediaID, err = media.SaveFile(r.Context(), photo, photo.Name())
+			if err == nil {
+				_, err = stmt.Exec(id, photo.Name(), mediaID)
+			}
 			if err != nil {
-				log.Printf("%v: error: %v", r.URL, err)
-				// delete the article from the articles table
-				_, err = database.Exec("DELETE FROM articles WHERE id = $1", id)
-				if err != nil {
-					log.Printf("%v: error: %v", r.URL, err)
-				}
+				toolkit.LogError(r, err)
 				response.Res(w, "error", http.StatusInternalServerError, "server error")
 				return
 			}
 		}
 	}
 
+	// the first revision
+	err = articleRevisions.save(tx, id, authPackage.AdminEmail(r))
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// Commit the transaction
+	err = tx.Commit()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	err = slug.Article.Assign(database, id)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
 	response.Res(w, "success", http.StatusCreated, "Article added")
 }
 
@@ -468,7 +465,6 @@ func articleExists(id string) (*bool, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
 	var exists bool
 	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM articles WHERE id=$1)", id).Scan(&exists)
@@ -485,7 +481,6 @@ func articleIsArchived(id string) (*bool, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
 	// Prepare the SQL statement
 	stmt, err := db.Prepare("SELECT archived FROM articles WHERE id = $1")
@@ -510,45 +505,80 @@ func editArticle(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := articleExists(id)
 	if err != nil {
-		log.Printf("%v: edit article articleExists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "edit article articleExists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: edit article articleExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "edit article articleExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot edit non existent article")
 		return
 	}
 
 	archived, err := articleIsArchived(id)
 	if err != nil {
-		log.Printf("%v: edit article articleIsArchived(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "edit article articleIsArchived(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if *archived {
-		log.Printf("%v: edit article articleIsArchived(id): %v", r.URL, *archived)
+		toolkit.LogInfof(r, "edit article articleIsArchived(id): %v", *archived)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot edit archived article")
 		return
 	}
 
 	// Parse multipart form
-	err = r.ParseMultipartForm(200 << 20)
+	err = upload.ParseForm(w, r, maxArticleRequest)
+	if err != nil {
+		upload.Fail(w, r, err)
+		return
+	}
+
+	// publishing window
+	window, err := parsePublishWindow(r)
 	if err != nil {
-		log.Printf("%v: edit article: %v", r.URL, err)
+		toolkit.LogInfo(r, err.Error())
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
 	db, err := db.DB()
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	if !articleWorkflow.editable(w, r, db, id) {
+		return
+	}
+
+	// the window with the stored times in place of the fields which were not sent
+	err = window.check(db, "articles", id)
+	if errors.Is(err, errPublishWindow) {
+		toolkit.LogInfo(r, err.Error())
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// fill a missing alphabet from the other one on request
+	autofill(r, titleFields, descriptionFields)
+
+	// Start a new transaction, the fields and their revision are written together and a failed edit changes nothing
+	tx, err := db.Begin()
+	if err != nil {
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
+	defer tx.Rollback()
 
 	title_latin := r.FormValue("title_latin")
 	if title_latin != "" {
@@ -557,9 +587,9 @@ func editArticle(w http.ResponseWriter, r *http.Request) {
 			SET title_latin = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, title_latin, id)
+		_, err = tx.Exec(sqlStatement, title_latin, id)
 		if err != nil {
-			log.Printf("%v: writing title_latin into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing title_latin into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -572,9 +602,9 @@ func editArticle(w http.ResponseWriter, r *http.Request) {
 			SET description_latin = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, description_latin, id)
+		_, err = tx.Exec(sqlStatement, description_latin, id)
 		if err != nil {
-			log.Printf("%v: writing description_latin into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing description_latin into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -587,9 +617,9 @@ func editArticle(w http.ResponseWriter, r *http.Request) {
 			SET title_cyrillic = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, title_cyrillic, id)
+		_, err = tx.Exec(sqlStatement, title_cyrillic, id)
 		if err != nil {
-			log.Printf("%v: writing title_cyrillic into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing title_cyrillic into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -602,28 +632,23 @@ func editArticle(w http.ResponseWriter, r *http.Request) {
 			SET description_cyrillic = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, description_cyrillic, id)
+		_, err = tx.Exec(sqlStatement, description_cyrillic, id)
 		if err != nil {
-			log.Printf("%v: writing description_cyrillic into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing description_cyrillic into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 	}
 
 	// photos
-	photos := r.MultipartForm.File["photo"]
-	if len(photos) > 0 {
-		for _, fh := range photos {
-			// check whether the file is an image by checking the content type
-			if fh.Header.Get("Content-Type")[:5] != "image" {
-				log.Printf("%v: photo is not an image: %v", r.URL, fh.Header.Get("Content-Type"))
-				response.Res(w, "error", http.StatusBadRequest, "photo is not an image")
-				return
-			}
-			// Check if the file size is greater than 10MB
-			if fh.Size > 10<<20 {
-				log.Printf("%v: photo size exceeds 10MB limit: %v", r.URL, fh.Size)
-				response.Res(w, "error", http.StatusBadRequest, "photo size exceeds 10MB limit")
+	photoFiles := r.MultipartForm.File["photo"]
+	if len(photoFiles) > 0 {
+		// Check the format and size of all the files before the photos are replaced
+		photos := make([]*upload.File, len(photoFiles))
+		for i, fh := range photoFiles {
+			photos[i], err = upload.Check(fh, "photo", articlePhoto)
+			if err != nil {
+				upload.Fail(w, r, err)
 				return
 			}
 		}
@@ -632,39 +657,30 @@ func editArticle(w http.ResponseWriter, r *http.Request) {
 			DELETE FROM article_photos
 			WHERE article = $1;
 		`
-		_, err = db.Exec(sqlStatement, id)
+		_, err = tx.Exec(sqlStatement, id)
 		if err != nil {
-			log.Printf("%v: deleting photos from db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "deleting photos from db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 		// insert new photos
-		for _, fh := range photos {
-			// Read the file
-			file, err := fh.Open()
+		for i, fh := range photoFiles {
+			// Store the file in the media storage
+			mediaID, err := media.SaveFile(r.Context(), photos[i], fh.Filename)
 			if err != nil {
-				log.Printf("%v: error: %v", r.URL, err)
-				response.Res(w, "error", http.StatusInternalServerError, "server error")
-				return
-			}
-			// defer the closing of the file
-			defer file.Close()
-			// Read the file into a byte slice
-			photo, err := io.ReadAll(file)
-			if err != nil {
-				log.Printf("%v: error: %v", r.URL, err)
+				toolkit.LogError(r, err)
 				response.Res(w, "error", http.StatusInternalServerError, "server error")
 				return
 			}
 			// Prepare the SQL statement
 			sqlStatement := `
-				INSERT INTO article_photos(article, file_name, file)
+				INSERT INTO article_photos(article, file_name, media_id)
 				VALUES($1, $2, $3);
 			`
 			// Execute the SQL statement
-			_, err = db.Exec(sqlStatement, id, fh.Filename, photo)
+			_, err = tx.Exec(sqlStatement, id, fh.Filename, mediaID)
 			if err != nil {
-				log.Printf("%v: writing photos into db: %v", r.URL, err)
+				toolkit.LogErrorf(r, "writing photos into db: %v", err)
 				response.Res(w, "error", http.StatusInternalServerError, "server error")
 				return
 			}
@@ -677,59 +693,38 @@ func editArticle(w http.ResponseWriter, r *http.Request) {
 			SET videos = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, pq.Array(videos), id)
+		_, err = tx.Exec(sqlStatement, pq.Array(videos), id)
 		if err != nil {
-			log.Printf("%v: writing videos into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing videos into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 	}
 
-	coverImageFile, coverImageHeader, err := r.FormFile("cover_image")
+	coverImage, err := upload.FormFile(r, "cover_image", articleCoverImage)
 	if err != nil {
-		if err == http.ErrMissingFile {
-			coverImageFile = nil
-		} else {
-			log.Printf("%v: cover_image error: %v", r.URL, err)
-			response.Res(w, "error", http.StatusBadRequest, "cover_image error")
-			return
-		}
+		upload.Fail(w, r, err)
+		return
 	}
 
-	var coverImage []byte = nil
-
-	if coverImageFile != nil {
-		// check whether the file is an image by checking the content type
-		if coverImageHeader.Header.Get("Content-Type")[:5] != "image" {
-			log.Printf("%v: cover_image is not an image: %v", r.URL, coverImageHeader.Header.Get("Content-Type"))
-			response.Res(w, "error", http.StatusBadRequest, "cover_image is not an image")
-			return
-		}
-		// Check if the file size is greater than 15MB
-		if coverImageHeader.Size > 15<<20 {
-			log.Printf("%v: cover_image size exceeds 15MB limit: %v", r.URL, coverImageHeader.Size)
-			response.Res(w, "error", http.StatusBadRequest, "cover_image size exceeds 15MB limit")
-			return
-		}
-		// Read the file
-		coverImage, err = io.ReadAll(coverImageFile)
+	if coverImage != nil {
+		// Store the file in the media storage
+		coverImageID, err := media.SaveFile(r.Context(), coverImage, coverImage.Name())
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
-		// Close the file
-		coverImageFile.Close()
 		// Prepare the SQL statement
 		sqlStatement := `
 			UPDATE articles
-			SET cover_image = $1, updated_at = NOW()
+			SET cover_image_id = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
 		// Execute the SQL statement
-		_, err = db.Exec(sqlStatement, coverImage, id)
+		_, err = tx.Exec(sqlStatement, coverImageID, id)
 		if err != nil {
-			log.Printf("%v: writing cover_image into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing cover_image into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -741,9 +736,9 @@ func editArticle(w http.ResponseWriter, r *http.Request) {
 			SET tags = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, pq.Array(tags), id)
+		_, err = tx.Exec(sqlStatement, pq.Array(tags), id)
 		if err != nil {
-			log.Printf("%v: writing tags into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing tags into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -752,7 +747,7 @@ func editArticle(w http.ResponseWriter, r *http.Request) {
 	if category := r.FormValue("category"); category != "" {
 		categoryInt, err := strconv.ParseInt(category, 10, 64)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -761,9 +756,9 @@ func editArticle(w http.ResponseWriter, r *http.Request) {
 			SET category = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, categoryInt, id)
+		_, err = tx.Exec(sqlStatement, categoryInt, id)
 		if err != nil {
-			log.Printf("%v: writing category into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing category into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -772,7 +767,7 @@ func editArticle(w http.ResponseWriter, r *http.Request) {
 	if related := r.FormValue("related"); related != "" {
 		relatedInt, err := strconv.ParseInt(related, 10, 64)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -781,24 +776,52 @@ func editArticle(w http.ResponseWriter, r *http.Request) {
 			SET related = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, relatedInt, id)
+		_, err = tx.Exec(sqlStatement, relatedInt, id)
 		if err != nil {
-			log.Printf("%v: writing related into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing related into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 	}
 
+	err = window.update(tx, "articles", id)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	err = articleRevisions.save(tx, id, authPackage.AdminEmail(r))
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// Commit the transaction
+	err = tx.Commit()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	err = slug.Article.Assign(db, id)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
 	response.Res(w, "success", http.StatusOK, "Article edited")
 }
 
 // addArticlePhotos is a handler function to add photos to an article
 func addArticlePhotos(w http.ResponseWriter, r *http.Request) {
 	// Parse the multipart form
-	err := r.ParseMultipartForm(200 << 20)
+	err := upload.ParseForm(w, r, maxArticleRequest)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusBadRequest, "file too large")
+		upload.Fail(w, r, err)
 		return
 	}
 
@@ -809,95 +832,76 @@ func addArticlePhotos(w http.ResponseWriter, r *http.Request) {
 	// Check if the article exists
 	exists, err := articleExists(id)
 	if err != nil {
-		log.Printf("%v: add article photos articleExists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "add article photos articleExists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: add article photos articleExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "add article photos articleExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot add photos to non existent article")
 		return
 	}
 
 	archived, err := articleIsArchived(id)
 	if err != nil {
-		log.Printf("%v: add article photos articleIsArchived(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "add article photos articleIsArchived(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if *archived {
-		log.Printf("%v: add article photos articleIsArchived(id): %v", r.URL, *archived)
+		toolkit.LogInfof(r, "add article photos articleIsArchived(id): %v", *archived)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot add photos to archived article")
 		return
 	}
 
+	// Open a connection to the database
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// the photos are content of the article, reviewed with it
+	if !articleWorkflow.editable(w, r, database, id) {
+		return
+	}
+
 	// Get the photos from the multipart form
 	photoFiles := r.MultipartForm.File["photo"]
-	var photos []ArticlePhoto
+	var photos []*upload.File
 	if len(photoFiles) > 0 {
 		for _, fh := range photoFiles {
-			// check whether the file is an image by checking the content type
-			if fh.Header.Get("Content-Type")[:5] != "image" {
-				log.Printf("%v: photo is not an image: %v", r.URL, fh.Header.Get("Content-Type"))
-				response.Res(w, "error", http.StatusBadRequest, "photo is not an image")
-				return
-			}
-			// Check if the file size is greater than 10MB
-			if fh.Size > 10<<20 {
-				log.Printf("%v: photo size exceeds 10MB limit: %v", r.URL, fh.Size)
-				response.Res(w, "error", http.StatusBadRequest, "photo size exceeds 10MB limit")
-				return
-			}
-			// Read the file
-			file, err := fh.Open()
+			// Check the format and size of the file
+			photo, err := upload.Check(fh, "photo", articlePhoto)
 			if err != nil {
-				log.Printf("%v: error: %v", r.URL, err)
-				response.Res(w, "error", http.StatusInternalServerError, "server error")
+				upload.Fail(w, r, err)
 				return
 			}
-			// create a variable of type ArticlePhoto
-			var photo ArticlePhoto
-			photo.FileName = fh.Filename
-			// Read the file into a byte slice
-			photo.File, err = io.ReadAll(file)
-			if err != nil {
-				log.Printf("%v: error: %v", r.URL, err)
-				response.Res(w, "error", http.StatusInternalServerError, "server error")
-				return
-			}
-			// close the file
-			err = file.Close()
-			if err != nil {
-				log.Printf("%v: error: %v", r.URL, err)
-				response.Res(w, "error", http.StatusInternalServerError, "server error")
-				return
-			}
-			// Append the byte slice to the photos slice
+			// Append the photo to the photos slice
 			photos = append(photos, photo)
 		}
-		// Open a connection to the database
-		database, err := db.DB()
-		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
-			response.Res(w, "error", http.StatusInternalServerError, "server error")
-			return
-		}
-		defer database.Close()
-		// Prepare the SQL statement: insert article, file_name, file into article_photos
-		stmt, err := database.Prepare("INSERT INTO article_photos(article, file_name, file) VALUES($1, $2, $3)")
+		// Prepare the SQL statement: insert article, file_name, media_id into article_photos
+		stmt, err := database.Prepare("INSERT INTO article_photos(article, file_name, media_id) VALUES($1, $2, $3)")
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 		defer stmt.Close()
 		// Execute the SQL statement
 		for _, photo := range photos {
-			_, err = stmt.Exec(id, photo.FileName, photo.File)
+			mediaID, err := media.SaveFile(r.Context(), photo, photo.Name())
 			if err != nil {
-				log.Printf("%v: error: %v", r.URL, err)
+				toolkit.LogError(r, err)
+				response.Res(w, "error", http.StatusInternalServerError, "server error")
+				return
+			}
+			_, err = stmt.Exec(id, photo.Name(), mediaID)
+			if err != nil {
+				toolkit.LogError(r, err)
 				response.Res(w, "error", http.StatusInternalServerError, "server error")
 				return
 			}
@@ -915,13 +919,13 @@ func getArticlePhotos(w http.ResponseWriter, r *http.Request) {
 	// Check if the article exists
 	exists, err := articleExists(id)
 	if err != nil {
-		log.Printf("%v: get article photos articleExists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "get article photos articleExists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: get article photos articleExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "get article photos articleExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot get photos of non existent article")
 		return
 	}
@@ -929,16 +933,15 @@ func getArticlePhotos(w http.ResponseWriter, r *http.Request) {
 	// Open a connection to the database
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// Prepare the SQL statement: select id, article, file_name, created_at from article_photos where article = $1
 	rows, err := database.Query("SELECT id, article, file_name, created_at FROM article_photos WHERE article = $1", id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -953,7 +956,7 @@ func getArticlePhotos(w http.ResponseWriter, r *http.Request) {
 		// Scan the rows into the variable
 		err := rows.Scan(&p.ID, &p.Article, &p.FileName, &p.CreatedAt)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -973,13 +976,13 @@ func getArticlePhoto(w http.ResponseWriter, r *http.Request) {
 	// Check if the article exists
 	exists, err := articleExists(id)
 	if err != nil {
-		log.Printf("%v: get article photo articleExists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "get article photo articleExists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: get article photo articleExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "get article photo articleExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot get photo of non existent article")
 		return
 	}
@@ -990,40 +993,36 @@ func getArticlePhoto(w http.ResponseWriter, r *http.Request) {
 	// Open a connection to the database
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	// Prepare the SQL statement: select file from article_photos where article = $1 and id = $2
-	stmt, err := database.Prepare("SELECT file FROM article_photos WHERE article = $1 AND id = $2")
+	// Prepare the SQL statement: select media_id from article_photos where article = $1 and id = $2
+	stmt, err := database.Prepare("SELECT media_id FROM article_photos WHERE article = $1 AND id = $2")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 	defer stmt.Close()
 
 	// Execute the SQL statement
-	var photo []byte
-	err = stmt.QueryRow(id, photoID).Scan(&photo)
+	var mediaID sql.NullInt64
+	err = stmt.QueryRow(id, photoID).Scan(&mediaID)
 	if err != nil {
 		// check if the error is no rows in result set
 		if err == sql.ErrNoRows {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusNotFound, "photo not found")
 			return
 		}
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 	// Send the response as photo
-	contentType := http.DetectContentType(photo)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(photo)))
-	w.Write(photo)
+	media.ServePrivate(w, r, mediaID, "")
 }
 
 // deleteArticlePhoto is a handler function to delete a photo of an article
@@ -1035,13 +1034,13 @@ func deleteArticlePhoto(w http.ResponseWriter, r *http.Request) {
 	// Check if the article exists
 	exists, err := articleExists(id)
 	if err != nil {
-		log.Printf("%v: delete article photo articleExists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "delete article photo articleExists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: delete article photo articleExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "delete article photo articleExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot delete photo of non existent article")
 		return
 	}
@@ -1052,16 +1051,20 @@ func deleteArticlePhoto(w http.ResponseWriter, r *http.Request) {
 	// Open a connection to the database
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
+
+	// the photos are content of the article, reviewed with it
+	if !articleWorkflow.editable(w, r, database, id) {
+		return
+	}
 
 	// Prepare the SQL statement: delete from article_photos where id = $1
 	stmt, err := database.Prepare("DELETE FROM article_photos WHERE id = $1 AND article = $2")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -1070,7 +1073,7 @@ func deleteArticlePhoto(w http.ResponseWriter, r *http.Request) {
 	// Execute the SQL statement
 	_, err = stmt.Exec(photoID, id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -1078,7 +1081,7 @@ func deleteArticlePhoto(w http.ResponseWriter, r *http.Request) {
 	// update the updated_at column of the articles table
 	_, err = database.Exec("UPDATE articles SET updated_at = NOW() WHERE id = $1", id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		// no response is sent to the client
 		// do not return
 	}
@@ -1096,13 +1099,13 @@ func getArticleCoverImage(w http.ResponseWriter, r *http.Request) {
 	// Check if the article exists
 	exists, err := articleExists(id)
 	if err != nil {
-		log.Printf("%v: get article cover image articleExists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "get article cover image articleExists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: get article cover image articleExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "get article cover image articleExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot get cover image of non existent article")
 		return
 	}
@@ -1110,40 +1113,36 @@ func getArticleCoverImage(w http.ResponseWriter, r *http.Request) {
 	// Open a connection to the database
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	// Prepare the SQL statement: select cover_image from articles where id = $1
-	stmt, err := database.Prepare("SELECT cover_image FROM articles WHERE id = $1")
+	// Prepare the SQL statement: select cover_image_id from articles where id = $1
+	stmt, err := database.Prepare("SELECT cover_image_id FROM articles WHERE id = $1")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 	defer stmt.Close()
 
 	// Execute the SQL statement
-	var coverImage []byte
-	err = stmt.QueryRow(id).Scan(&coverImage)
+	var coverImageID sql.NullInt64
+	err = stmt.QueryRow(id).Scan(&coverImageID)
 	if err != nil {
 		// check if the error is no rows in result set
 		if err == sql.ErrNoRows {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusNotFound, "cover image not found")
 			return
 		}
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 	// Send the response as cover image
-	contentType := http.DetectContentType(coverImage)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(coverImage)))
-	w.Write(coverImage)
+	media.ServePrivate(w, r, coverImageID, "article-"+id+"-cover")
 }
 
 // deleteArticle is a handler function to delete an article
@@ -1153,42 +1152,41 @@ func deleteArticle(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := articleExists(id)
 	if err != nil {
-		log.Printf("%v: delete article articleExists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "delete article articleExists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: delete article articleExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "delete article articleExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot delete non existent article")
 		return
 	}
 
 	archived, err := articleIsArchived(id)
 	if err != nil {
-		log.Printf("%v: delete article articleIsArchived(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "delete article articleIsArchived(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if *archived {
-		log.Printf("%v: delete article articleIsArchived(id): %v", r.URL, *archived)
+		toolkit.LogInfof(r, "delete article articleIsArchived(id): %v", *archived)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot delete archived article")
 		return
 	}
 
 	db, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
 	// First delete the photos of the article
 	_, err = db.Exec("DELETE FROM article_photos WHERE article = $1", id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -1196,124 +1194,44 @@ func deleteArticle(w http.ResponseWriter, r *http.Request) {
 	// set null to the related column of the articles where related = id
 	_, err = db.Exec("UPDATE articles SET related = NULL, updated_at = NOW() WHERE related = $1", id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	// delete the article from the articles table
-	// Prepare the SQL statement
-	stmt, err := db.Prepare("DELETE FROM articles WHERE id=$1")
-	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	_, err = stmt.Exec(id)
-	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	response.Res(w, "success", http.StatusOK, "deleted")
-}
-
-func archiveArticle(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	exists, err := articleExists(id)
-	if err != nil {
-		log.Printf("%v: archive article articleExists(id): %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	if !*exists {
-		log.Printf("%v: archive article articleExists(id): %v", r.URL, *exists)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot archive non existent article")
-		return
-	}
-
-	archived, err := articleIsArchived(id)
-	if err != nil {
-		log.Printf("%v: archive article articleIsArchived(id): %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	if *archived {
-		log.Printf("%v: archive article articleIsArchived(id): %v", r.URL, *archived)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot archive already archived article")
-		return
-	}
-
-	db, err := db.DB()
+	// delete the revisions of the article
+	err = articleRevisions.deleteAll(db, id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
-	_, err = db.Exec("UPDATE articles SET archived = true WHERE id = $1", id)
+	// and its old slugs
+	err = slug.Article.DeleteRedirects(db, id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	response.Res(w, "success", http.StatusOK, "archived")
-}
-
-func unArchiveArticle(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	exists, err := articleExists(id)
-	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	if !*exists {
-		log.Printf("%v: unarchive article articleExists(id): %v", r.URL, *exists)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot unarchive non existent article")
-		return
-	}
-
-	archived, err := articleIsArchived(id)
-	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	if !*archived {
-		log.Printf("%v: unarchive article articleIsArchived(id): %v", r.URL, *archived)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot unarchive not archived article")
-		return
-	}
-
-	db, err := db.DB()
+	// delete the article from the articles table
+	// Prepare the SQL statement
+	stmt, err := db.Prepare("DELETE FROM articles WHERE id=$1")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
-	_, err = db.Exec("UPDATE articles SET archived = false WHERE id = $1", id)
+	_, err = stmt.Exec(id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	response.Res(w, "success", http.StatusOK, "unarchive done")
+	response.Res(w, "success", http.StatusOK, "deleted")
 }
 
 type ArticleCount struct {
@@ -1324,16 +1242,15 @@ type ArticleCount struct {
 func getArticleCountAll(w http.ResponseWriter, r *http.Request) {
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	var count int
 	err = database.QueryRow("SELECT COUNT(*) FROM articles").Scan(&count)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -1352,17 +1269,16 @@ func getArticleCount(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	var count int
 	query := fmt.Sprintf("SELECT COUNT(*) FROM articles WHERE created_at > current_date - interval '1 %s'", period)
 	err = database.QueryRow(query).Scan(&count)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -1385,13 +1301,17 @@ type Article struct {
 	Category            *int           `json:"category"`
 	Related             *int           `json:"related"`
 	Completed           bool           `json:"completed"`
+	PublishAt           *time.Time     `json:"publish_at"`
+	UnpublishAt         *time.Time     `json:"unpublish_at"`
+	Status              string         `json:"status"`
+	Slug                *string        `json:"slug"`
 }
 
 func getArticles(w http.ResponseWriter, r *http.Request) {
 	// Parse the page number from the query parameters
 	pageStr, ok := r.URL.Query()["page"]
 	if !ok || len(pageStr[0]) < 1 {
-		log.Printf("%v: Url Param 'page' is missing. Setting default value to 1.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'page' is missing. Setting default value to 1.")
 		pageStr = []string{"1"}
 	}
 	page, _ := strconv.Atoi(pageStr[0])
@@ -1399,7 +1319,7 @@ func getArticles(w http.ResponseWriter, r *http.Request) {
 	// Parse the limit from the query parameters
 	limitStr, ok := r.URL.Query()["limit"]
 	if !ok || len(limitStr[0]) < 1 {
-		log.Printf("%v: Url Param 'limit' is missing. Setting default value to 10.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'limit' is missing. Setting default value to 10.")
 		limitStr = []string{"10"}
 	}
 	limit, _ := strconv.Atoi(limitStr[0])
@@ -1409,15 +1329,14 @@ func getArticles(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, tags, archived, created_at, updated_at, category, related, completed FROM articles ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
+	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, tags, archived, created_at, updated_at, category, related, completed, publish_at, unpublish_at, status, slug FROM articles ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -1426,9 +1345,9 @@ func getArticles(w http.ResponseWriter, r *http.Request) {
 	var articles []Article
 	for rows.Next() {
 		var a Article
-		err := rows.Scan(&a.ID, &a.TitleLatin, &a.DescriptionLatin, &a.TitleCyrillic, &a.DescriptionCyrillic, pq.Array(&a.Videos), pq.Array(&a.Tags), &a.Archived, &a.CreatedAt, &a.UpdatedAt, &a.Category, &a.Related, &a.Completed)
+		err := rows.Scan(&a.ID, &a.TitleLatin, &a.DescriptionLatin, &a.TitleCyrillic, &a.DescriptionCyrillic, pq.Array(&a.Videos), pq.Array(&a.Tags), &a.Archived, &a.CreatedAt, &a.UpdatedAt, &a.Category, &a.Related, &a.Completed, &a.PublishAt, &a.UnpublishAt, &a.Status, &a.Slug)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -1440,7 +1359,7 @@ func getArticles(w http.ResponseWriter, r *http.Request) {
 		// Prepare the SQL statement: select id, article, file_name, created_at from article_photos where article = $1
 		rows, err := database.Query("SELECT id, article, file_name, created_at FROM article_photos WHERE article = $1", a.ID)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -1455,7 +1374,7 @@ func getArticles(w http.ResponseWriter, r *http.Request) {
 			// Scan the rows into the variable
 			err := rows.Scan(&p.ID, &p.Article, &p.FileName, &p.CreatedAt)
 			if err != nil {
-				log.Printf("%v: error: %v", r.URL, err)
+				toolkit.LogError(r, err)
 				response.Res(w, "error", http.StatusInternalServerError, "server error")
 				return
 			}
@@ -1468,52 +1387,3 @@ func getArticles(w http.ResponseWriter, r *http.Request) {
 
 	response.Res(w, "success", http.StatusOK, articles)
 }
-
-// articleCompleted is a handler to update the completed field of an article
-func articleCompleted(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	exists, err := articleExists(id)
-	if err != nil {
-		log.Printf("%v: articleCompleted articleExists(id): %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	if !*exists {
-		log.Printf("%v: articleCompleted articleExists(id): %v", r.URL, *exists)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot update completed field of non existent article")
-		return
-	}
-
-	archived, err := articleIsArchived(id)
-	if err != nil {
-		log.Printf("%v: articleCompleted articleIsArchived(id): %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	if *archived {
-		log.Printf("%v: articleCompleted articleIsArchived(id): %v", r.URL, *archived)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot update completed field of archived article")
-		return
-	}
-
-	db, err := db.DB()
-	if err != nil {
-		log.Printf("%v: articleCompleted db.DB(): %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-	defer db.Close()
-
-	_, err = db.Exec("UPDATE articles SET completed = NOT completed WHERE id = $1", id)
-	if err != nil {
-		log.Printf("%v: articleCompleted db.Exec(): %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	response.Res(w, "success", http.StatusOK, "completed field updated")
-}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"Tahlilchi.uz/metrics"
 	"Tahlilchi.uz/model"
 	"Tahlilchi.uz/response"
 	"Tahlilchi.uz/toolkit"
@@ -94,6 +95,8 @@ func approveArticleComment(w http.ResponseWriter, r *http.Request) {
 		// return
 		return
 	}
+	metrics.CommentApprovals.Inc("article")
+
 	// respond with the success message
 	response.Res(w, "success", http.StatusOK, "article comment approved successfully")
 }
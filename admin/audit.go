@@ -0,0 +1,352 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"Tahlilchi.uz/authPackage"
+	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/toolkit"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// auditEntityTables maps the entity types of the admin routes to their tables,
+// for the before/after diff of PATCH requests
+var auditEntityTables = map[string]string{
+	"admins":                    "admins",
+	"news/category":             "news_category",
+	"news/category/subcategory": "news_subcategory",
+	"news/regions":              "news_regions",
+	"news/post":                 "news_posts",
+	"news/post/comment":         "news_post_comments",
+	"article/category":          "article_category",
+	"article":                   "articles",
+	"article/comment":           "article_comments",
+	"business-promotional/post": "business_promotional_posts",
+	"e-newspaper/category":      "e_newspaper_category",
+	"e-newspaper":               "e_newspapers",
+	"e-newspaper/comment":       "e_newspaper_comments",
+	"photo-gallery":             "photo_gallery",
+	"contact":                   "admin_contact",
+	"video-news":                "video_news",
+	"video-news/comment":        "video_news_comments",
+}
+
+// auditVerbs are the path segments of the admin routes naming the action rather than the entity
+var auditVerbs = map[string]bool{
+	"add": true, "edit": true, "update": true, "delete": true,
+	"archive": true, "unarchive": true, "completed": true, "approve": true,
+	"disable": true, "enable": true, "role": true, "force-password-reset": true, "reset-2fa": true,
+	"enroll": true, "confirm": true, "recovery-codes": true, "restore": true, "status": true,
+}
+
+// auditSkipped are the admin routes which change nothing though they are not GET
+var auditSkipped = map[string]bool{
+	"/admin/transliterate": true,
+}
+
+// auditHiddenColumns are never written to the audit log
+var auditHiddenColumns = []string{"password", "totp_secret", "totp_last_step", "search_vector"}
+
+// auditRoute returns the action, entity type and the route variable holding the entity id of a route template,
+// e.g. "archive", "news/post" and "id" for /admin/news/post/archive/{id}
+func auditRoute(method, template string) (action, entity, idVar string) {
+	var statics []string
+	for _, seg := range strings.Split(strings.TrimPrefix(template, "/admin"), "/") {
+		switch {
+		case seg == "":
+		case strings.HasPrefix(seg, "{"):
+			idVar = strings.Trim(seg, "{}")
+		case auditVerbs[seg]:
+			action = seg
+		default:
+			statics = append(statics, seg)
+		}
+	}
+
+	if action == "" {
+		switch method {
+		case http.MethodPost:
+			action = "create"
+		case http.MethodDelete:
+			action = "delete"
+		default:
+			action = "update"
+		}
+	}
+	return action, strings.Join(statics, "/"), idVar
+}
+
+// statusRecorder is a http.ResponseWriter which remembers the status code
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for http.ResponseController
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// auditLog is a router middleware which writes every POST, PATCH, PUT and DELETE request of the admin routes to the audit_log table.
+// For PATCH requests on a known entity the changed columns are stored with their values before and after the request.
+func auditLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPatch, http.MethodPut, http.MethodDelete:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		template := r.URL.Path
+		if route := mux.CurrentRoute(r); route != nil {
+			if t, err := route.GetPathTemplate(); err == nil {
+				template = t
+			}
+		}
+		if auditSkipped[template] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		action, entity, idVar := auditRoute(r.Method, template)
+		entityID := mux.Vars(r)[idVar]
+
+		// the row before the request
+		var before map[string]any
+		table, known := auditEntityTables[entity]
+		id, idErr := strconv.Atoi(entityID)
+		diff := r.Method == http.MethodPatch && known && idErr == nil
+		if diff {
+			var err error
+			before, err = auditSnapshot(table, id)
+			if err != nil {
+				toolkit.LogError(r, err)
+				diff = false
+			}
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		var changes sql.NullString
+		if diff && rec.status < 400 {
+			after, err := auditSnapshot(table, id)
+			if err != nil {
+				toolkit.LogError(r, err)
+			} else if c := auditDiff(before, after); len(c) > 0 {
+				b, _ := json.Marshal(c)
+				changes = sql.NullString{String: string(b), Valid: true}
+			}
+		}
+
+		database, err := db.DB()
+		if err != nil {
+			toolkit.LogError(r, err)
+			return
+		}
+
+		// the email is read after the handler, so a login is recorded with the admin who logged in
+		_, err = database.Exec("INSERT INTO audit_log (admin_email, action, route, entity_type, entity_id, changes, status, ip, created_at) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9)",
+			authPackage.AdminEmail(r), action, r.Method+" "+template, entity, entityID, changes, rec.status, toolkit.ClientIP(r), time.Now().UTC())
+		if err != nil {
+			toolkit.LogError(r, err)
+		}
+	})
+}
+
+// auditQueries caches the snapshot query of every table
+var auditQueries sync.Map
+
+// auditSnapshot returns the row of table with the given id as a map, nil if there is no such row.
+// Binary columns are replaced by their md5 sum, so a changed file shows up without its content.
+func auditSnapshot(table string, id int) (map[string]any, error) {
+	database, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	query, ok := auditQueries.Load(table)
+	if !ok {
+		rows, err := database.Query("SELECT column_name FROM information_schema.columns WHERE table_schema = 'public' AND table_name = $1 AND data_type = 'bytea'", table)
+		if err != nil {
+			return nil, err
+		}
+		var binary []string
+		for rows.Next() {
+			var c string
+			if err := rows.Scan(&c); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			binary = append(binary, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		sums := "'{}'::jsonb"
+		if len(binary) > 0 {
+			var pairs []string
+			for _, c := range binary {
+				pairs = append(pairs, pq.QuoteLiteral(c)+", md5(t."+pq.QuoteIdentifier(c)+")")
+			}
+			sums = "jsonb_build_object(" + strings.Join(pairs, ", ") + ")"
+		}
+
+		hidden := append(append([]string{}, auditHiddenColumns...), binary...)
+		for i, c := range hidden {
+			hidden[i] = pq.QuoteLiteral(c)
+		}
+
+		query = "SELECT (to_jsonb(t) - ARRAY[" + strings.Join(hidden, ", ") + "]::text[]) || " + sums + " FROM " + pq.QuoteIdentifier(table) + " t WHERE t.id = $1"
+		auditQueries.Store(table, query)
+	}
+
+	var data []byte
+	err = database.QueryRow(query.(string), id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var row map[string]any
+	err = json.Unmarshal(data, &row)
+	return row, err
+}
+
+// auditChange is the value of a column before and after a request
+type auditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// auditDiff returns the columns whose values differ between before and after
+func auditDiff(before, after map[string]any) map[string]auditChange {
+	changes := map[string]auditChange{}
+	for k, b := range before {
+		if a := after[k]; !reflect.DeepEqual(a, b) {
+			changes[k] = auditChange{Before: b, After: a}
+		}
+	}
+	for k, a := range after {
+		if _, ok := before[k]; !ok {
+			changes[k] = auditChange{Before: nil, After: a}
+		}
+	}
+	return changes
+}
+
+// AuditEntry is a struct to map a row of the audit log
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	AdminEmail string          `json:"admin_email"`
+	Action     string          `json:"action"`
+	Route      string          `json:"route"`
+	EntityType string          `json:"entity_type"`
+	EntityID   *string         `json:"entity_id"`
+	Changes    json.RawMessage `json:"changes"`
+	Status     int             `json:"status"`
+	IP         string          `json:"ip"`
+	CreatedAt  string          `json:"created_at"`
+}
+
+// getAuditLog is a route handler function to query the audit log.
+// Query parameters: admin (email), entity (type), entity_id, from and to (dates as 2006-01-02, both inclusive), page and limit.
+func getAuditLog(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := toolkit.GetPageLimit(r)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusBadRequest, "invalid page or limit")
+		return
+	}
+
+	q := r.URL.Query()
+	var where []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, strings.Replace(cond, "?", "$"+strconv.Itoa(len(args)), 1))
+	}
+
+	if v := q.Get("admin"); v != "" {
+		add("admin_email = ?", v)
+	}
+	if v := q.Get("entity"); v != "" {
+		add("entity_type = ?", v)
+	}
+	if v := q.Get("entity_id"); v != "" {
+		add("entity_id = ?", v)
+	}
+	if v := q.Get("from"); v != "" {
+		from, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			response.Res(w, "error", http.StatusBadRequest, "from must be a date like 2006-01-02")
+			return
+		}
+		add("created_at >= ?", from)
+	}
+	if v := q.Get("to"); v != "" {
+		to, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			response.Res(w, "error", http.StatusBadRequest, "to must be a date like 2006-01-02")
+			return
+		}
+		add("created_at < ?", to.AddDate(0, 0, 1))
+	}
+
+	query := "SELECT id, admin_email, action, route, entity_type, entity_id, changes, status, ip, created_at FROM audit_log"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, limit, (page-1)*limit)
+	query += " ORDER BY id DESC LIMIT $" + strconv.Itoa(len(args)-1) + " OFFSET $" + strconv.Itoa(len(args))
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	rows, err := database.Query(query, args...)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	defer rows.Close()
+
+	list := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		var changes []byte
+		if err := rows.Scan(&e.ID, &e.AdminEmail, &e.Action, &e.Route, &e.EntityType, &e.EntityID, &changes, &e.Status, &e.IP, &e.CreatedAt); err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+		if changes != nil {
+			e.Changes = changes
+		}
+		list = append(list, e)
+	}
+
+	response.Res(w, "success", http.StatusOK, list)
+}
@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"fmt"
+	"log"
+
+	"Tahlilchi.uz/authPackage"
+	"Tahlilchi.uz/config"
+	"Tahlilchi.uz/db"
+)
+
+// BootstrapSuperAdmin creates the first superadmin from the configuration
+// when the admins table is empty, so a fresh deployment can be managed through the API only.
+// The password is temporary and has to be changed after the first login.
+func BootstrapSuperAdmin(cfg config.SuperAdmin) error {
+	name, email, password := cfg.Name, cfg.Email, cfg.Password
+	if email == "" || password == "" {
+		return nil
+	}
+	if name == "" {
+		name = "Superadmin"
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	var count int
+	err = database.QueryRow("SELECT COUNT(*) FROM admins").Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if msg := validatePassword(password); msg != "" {
+		return fmt.Errorf("SUPERADMIN_PASSWORD: %s", msg)
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.Exec("INSERT INTO admins (name, email, role, password, must_change_password) VALUES ($1, $2, $3, $4, true)", name, email, authPackage.RoleSuperAdmin, hash)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("bootstrap: superadmin %s created, the password has to be changed after the first login", email)
+	return nil
+}
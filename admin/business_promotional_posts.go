@@ -1,28 +1,43 @@
 package admin
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
 	"regexp"
-	"strconv"
 	"time"
 
 	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/media"
+	"Tahlilchi.uz/metrics"
 	"Tahlilchi.uz/model"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/slug"
 	"Tahlilchi.uz/toolkit"
+	"Tahlilchi.uz/upload"
 	"github.com/gorilla/mux"
 	"github.com/lib/pq"
 )
 
+// maxBPPostRequest limits the request of a business promotional post with its photos
+const maxBPPostRequest = 100 << 20
+
+// bpPostImage is the upload kind of the photos and the cover image of a business promotional post
+var bpPostImage = upload.Image.Max(10 << 20)
+
 func addBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 	// parse multipart form
-	// maxMemory is 100MB
-	err := r.ParseMultipartForm(100 << 20)
+	err := upload.ParseForm(w, r, maxBPPostRequest)
 	if err != nil {
-		toolkit.LogError(r, err)
+		upload.Fail(w, r, err)
+		return
+	}
+
+	// publishing window
+	window, err := parsePublishWindow(r)
+	if err != nil {
+		toolkit.LogInfo(r, err.Error())
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
@@ -30,6 +45,9 @@ func addBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 	// declare a new businessPromotionalPost
 	var businessPromotionalPost model.BusinessPromotionalPost
 
+	// fill a missing alphabet from the other one on request
+	autofill(r, titleFields, descriptionFields)
+
 	// title_latin
 	titleLatin := r.FormValue("title_latin")
 	if titleLatin == "" {
@@ -62,37 +80,15 @@ func addBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 
 	// photos
 	photoArray := r.MultipartForm.File["photo"]
+	var photos []*upload.File
 	for _, fh := range photoArray {
-		if fh.Size > 10<<20 {
-			err := fmt.Errorf("photo %v size exceeds 10MB limit: %v", fh.Filename, fh.Size)
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
-			return
-		}
-		// check if file is image using http.DetectContentType
-		contentType := fh.Header.Get("Content-Type")
-		if contentType[:5] != "image" {
-			err := fmt.Errorf("photo %v is not an image: %v", fh.Filename, contentType)
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
-			return
-		}
-		file, err := fh.Open()
-		if err != nil {
-			err := fmt.Errorf("error opening photo %v: %v", fh.Filename, err)
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
-			return
-		}
-		defer file.Close()
-		photo, err := io.ReadAll(file)
+		// check the format and the size of the photo by its content
+		photo, err := upload.Check(fh, "photo", bpPostImage)
 		if err != nil {
-			err := fmt.Errorf("error reading photo %v: %v", fh.Filename, err)
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
+			upload.Fail(w, r, err)
 			return
 		}
-		businessPromotionalPost.Photos = append(businessPromotionalPost.Photos, model.BusinessPromotionalPostPhoto{FileName: fh.Filename, File: photo})
+		photos = append(photos, photo)
 	}
 
 	// videoArray
@@ -101,42 +97,10 @@ func addBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 	businessPromotionalPost.Videos = videos
 
 	// cover_image
-	_, coverImageHeader, err := r.FormFile("cover_image")
-	if err != nil && err != http.ErrMissingFile {
-		toolkit.LogError(r, err)
-		response.Res(w, "error", http.StatusBadRequest, err.Error())
+	coverImage, err := upload.FormFile(r, "cover_image", bpPostImage)
+	if err != nil {
+		upload.Fail(w, r, err)
 		return
-	} else if err == http.ErrMissingFile {
-	} else {
-		if coverImageHeader.Size > 10<<20 {
-			err := fmt.Errorf("cover_image %v size exceeds 10MB limit: %v", coverImageHeader.Filename, coverImageHeader.Size)
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
-			return
-		}
-		contentType := coverImageHeader.Header.Get("Content-Type")
-		if contentType[:5] != "image" {
-			err := fmt.Errorf("cover_image %v is not an image: %v", coverImageHeader.Filename, contentType)
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
-			return
-		}
-		file, err := coverImageHeader.Open()
-		if err != nil {
-			err := fmt.Errorf("error opening cover_image %v: %v", coverImageHeader.Filename, err)
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
-			return
-		}
-		defer file.Close()
-		coverImage, err := io.ReadAll(file)
-		if err != nil {
-			err := fmt.Errorf("error reading cover_image %v: %v", coverImageHeader.Filename, err)
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
-			return
-		}
-		businessPromotionalPost.CoverImage = coverImage
 	}
 
 	// expiration
@@ -174,7 +138,14 @@ func addBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
+
+	// Store the cover image in the media storage
+	coverImageID, err := media.SaveFileNullable(r.Context(), coverImage, "cover_image")
+	if err != nil {
+		toolkit.LogError(r, fmt.Errorf("saving cover_image: %v", err))
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
 
 	// Start a new transaction
 	tx, err := database.Begin()
@@ -184,8 +155,8 @@ func addBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Prepare the SQL statement: add into business_promotional_posts: title_latin, description_latin, title_cyrillic, description_cyrillic, videos, cover_image, expiration, partner returning id
-	stmt, err := tx.Prepare("INSERT INTO business_promotional_posts (title_latin, description_latin, title_cyrillic, description_cyrillic, videos, cover_image, expiration, partner) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id")
+	// Prepare the SQL statement: add into business_promotional_posts: title_latin, description_latin, title_cyrillic, description_cyrillic, videos, cover_image_id, expiration, partner returning id
+	stmt, err := tx.Prepare("INSERT INTO business_promotional_posts (title_latin, description_latin, title_cyrillic, description_cyrillic, videos, cover_image_id, expiration, partner, publish_at, unpublish_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id")
 	if err != nil {
 		toolkit.LogError(r, fmt.Errorf("prepare the SQL statement: %v", err))
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
@@ -193,7 +164,7 @@ func addBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Execute the SQL statement
-	err = stmt.QueryRow(businessPromotionalPost.TitleLatin, businessPromotionalPost.DescriptionLatin, businessPromotionalPost.TitleCyrillic, businessPromotionalPost.DescriptionCyrillic, pq.Array(businessPromotionalPost.Videos), businessPromotionalPost.CoverImage, businessPromotionalPost.Expiration, businessPromotionalPost.Partner).Scan(&businessPromotionalPost.ID)
+	err = stmt.QueryRow(businessPromotionalPost.TitleLatin, businessPromotionalPost.DescriptionLatin, businessPromotionalPost.TitleCyrillic, businessPromotionalPost.DescriptionCyrillic, pq.Array(businessPromotionalPost.Videos), coverImageID, businessPromotionalPost.Expiration, businessPromotionalPost.Partner, window.PublishAt, window.UnpublishAt).Scan(&businessPromotionalPost.ID)
 	if err != nil {
 		toolkit.LogError(r, fmt.Errorf("execute the SQL statement: %v", err))
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
@@ -209,7 +180,7 @@ func addBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// add business promotional post photos into bpp_photos
-	for _, photo := range businessPromotionalPost.Photos {
+	for _, photo := range photos {
 		// Start a new transaction
 		tx, err := database.Begin()
 		if err != nil {
@@ -223,8 +194,8 @@ func addBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Prepare the SQL statement: add into bpp_photos: bpp, file_name, file
-		stmt, err := tx.Prepare("INSERT INTO bpp_photos (bpp, file_name, file) VALUES ($1, $2, $3)")
+		// Prepare the SQL statement: add into bpp_photos: bpp, file_name, media_id
+		stmt, err := tx.Prepare("INSERT INTO bpp_photos (bpp, file_name, media_id) VALUES ($1, $2, $3)")
 		if err != nil {
 			toolkit.LogError(r, fmt.Errorf("prepare the SQL statement: %v", err))
 			// delete business promotional post from business_promotional_posts
@@ -236,8 +207,12 @@ func addBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Execute the SQL statement
-		_, err = stmt.Exec(businessPromotionalPost.ID, photo.FileName, photo.File)
+		// Store the photo in the media storage and execute the SQL statement
+		var mediaID int64
+		mediaID, err = media.SaveFile(r.Context(), photo, photo.Name())
+		if err == nil {
+			_, err = stmt.Exec(businessPromotionalPost.ID, photo.Name(), mediaID)
+		}
 		if err != nil {
 			toolkit.LogError(r, fmt.Errorf("execute the SQL statement: %v", err))
 			// delete business promotional post from business_promotional_posts
@@ -263,6 +238,13 @@ func addBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	err = slug.BPPost.Assign(database, businessPromotionalPost.ID)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
 	response.Res(w, "success", http.StatusCreated, "business promotional post added")
 }
 
@@ -277,7 +259,6 @@ func bpPostExists(id string) (*bool, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
 	var exists bool
 	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM business_promotional_posts WHERE id=$1)", id).Scan(&exists)
@@ -294,7 +275,6 @@ func bpPostIsArchived(id string) (*bool, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
 	// Prepare the SQL statement
 	stmt, err := db.Prepare("SELECT archived FROM business_promotional_posts WHERE id = $1")
@@ -319,45 +299,67 @@ func editBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := bpPostExists(id)
 	if err != nil {
-		log.Printf("%v: edit business promotional post bpPostExists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "edit business promotional post bpPostExists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: edit business promotional post bpPostExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "edit business promotional post bpPostExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot edit non existent business promotional post")
 		return
 	}
 
 	archived, err := bpPostIsArchived(id)
 	if err != nil {
-		log.Printf("%v: edit business promotional post bpPostIsArchived(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "edit business promotional post bpPostIsArchived(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if *archived {
-		log.Printf("%v: edit business promotional post bpPostIsArchived(id): %v", r.URL, *archived)
+		toolkit.LogInfof(r, "edit business promotional post bpPostIsArchived(id): %v", *archived)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot edit archived business promotional post")
 		return
 	}
 
 	// Parse multipart form
-	err = r.ParseMultipartForm(100 << 20) // maxMemory is 100MB
+	err = upload.ParseForm(w, r, maxBPPostRequest)
 	if err != nil {
-		log.Printf("%v: edit business promotional post: %v", r.URL, err)
+		upload.Fail(w, r, err)
+		return
+	}
+
+	// publishing window
+	window, err := parsePublishWindow(r)
+	if err != nil {
+		toolkit.LogInfo(r, err.Error())
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
 	db, err := db.DB()
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// the window with the stored times in place of the fields which were not sent
+	err = window.check(db, "business_promotional_posts", id)
+	if errors.Is(err, errPublishWindow) {
+		toolkit.LogInfo(r, err.Error())
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
+
+	// fill a missing alphabet from the other one on request
+	autofill(r, titleFields, descriptionFields)
 
 	title_latin := r.FormValue("title_latin")
 	if title_latin != "" {
@@ -368,7 +370,7 @@ func editBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 		`
 		_, err = db.Exec(sqlStatement, title_latin, id)
 		if err != nil {
-			log.Printf("%v: writing title_latin into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing title_latin into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -383,7 +385,7 @@ func editBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 		`
 		_, err = db.Exec(sqlStatement, description_latin, id)
 		if err != nil {
-			log.Printf("%v: writing description_latin into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing description_latin into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -398,7 +400,7 @@ func editBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 		`
 		_, err = db.Exec(sqlStatement, title_cyrillic, id)
 		if err != nil {
-			log.Printf("%v: writing title_cyrillic into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing title_cyrillic into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -413,7 +415,7 @@ func editBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 		`
 		_, err = db.Exec(sqlStatement, description_cyrillic, id)
 		if err != nil {
-			log.Printf("%v: writing description_cyrillic into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing description_cyrillic into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -429,55 +431,32 @@ func editBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 		`
 		_, err = db.Exec(sqlStatement, pq.Array(videos), id)
 		if err != nil {
-			log.Printf("%v: writing videos into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing videos into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 	}
 
-	_, coverImageHeader, err := r.FormFile("cover_image")
-	if err != nil && err != http.ErrMissingFile {
-		log.Printf("%v: FormFile(\"cover_image\"): %v", r.URL, err)
-		response.Res(w, "error", http.StatusBadRequest, err.Error())
+	coverImage, err := upload.FormFile(r, "cover_image", bpPostImage)
+	if err != nil {
+		upload.Fail(w, r, err)
 		return
-	} else if err == http.ErrMissingFile {
-	} else {
-		if coverImageHeader.Size > 10<<20 {
-			err := fmt.Errorf("cover_image %v size exceeds 10MB limit: %v", coverImageHeader.Filename, coverImageHeader.Size)
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
-			return
-		}
-		contentType := coverImageHeader.Header.Get("Content-Type")
-		if contentType[:5] != "image" {
-			err := fmt.Errorf("cover_image %v is not an image: %v", coverImageHeader.Filename, contentType)
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
-			return
-		}
-		file, err := coverImageHeader.Open()
-		if err != nil {
-			err := fmt.Errorf("error opening cover_image %v: %v", coverImageHeader.Filename, err)
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
-			return
-		}
-		defer file.Close()
-		coverImage, err := io.ReadAll(file)
+	}
+	if coverImage != nil {
+		coverImageID, err := media.SaveFile(r.Context(), coverImage, coverImage.Name())
 		if err != nil {
-			err := fmt.Errorf("error reading cover_image %v: %v", coverImageHeader.Filename, err)
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
+			toolkit.LogErrorf(r, "saving cover_image: %v", err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 		sqlStatement := `
 			UPDATE business_promotional_posts
-			SET cover_image = $1, updated_at = NOW()
+			SET cover_image_id = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, coverImage, id)
+		_, err = db.Exec(sqlStatement, coverImageID, id)
 		if err != nil {
-			log.Printf("%v: writing cover_image into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing cover_image into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -487,7 +466,7 @@ func editBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 	if expiration != "" {
 		expirationValid := checkExpiration(expiration)
 		if !expirationValid {
-			log.Printf("%v: FormValue(\"expiration\") valid: %v", r.URL, expirationValid)
+			toolkit.LogInfof(r, "FormValue(\"expiration\") valid: %v", expirationValid)
 			response.Res(w, "error", http.StatusBadRequest, "expiration value is invalid")
 			return
 		}
@@ -507,7 +486,7 @@ func editBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 		`
 		_, err = db.Exec(sqlStatement, expirationForDB, id)
 		if err != nil {
-			log.Printf("%v: writing expiration into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing expiration into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -522,12 +501,26 @@ func editBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
 		`
 		_, err = db.Exec(sqlStatement, partner, id)
 		if err != nil {
-			log.Printf("%v: writing partner into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing partner into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 	}
 
+	err = window.update(db, "business_promotional_posts", id)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	err = slug.BPPost.Assign(db, id)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
 	response.Res(w, "success", http.StatusOK, "business promotional post updated")
 }
 
@@ -537,42 +530,49 @@ func deleteBPPost(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := bpPostExists(id)
 	if err != nil {
-		log.Printf("%v: delete business promotional post bpPostExists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "delete business promotional post bpPostExists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: delete business promotional post bpPostExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "delete business promotional post bpPostExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot delete non existent business promotional post")
 		return
 	}
 
 	archived, err := bpPostIsArchived(id)
 	if err != nil {
-		log.Printf("%v: delete business promotional post bpPostIsArchived(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "delete business promotional post bpPostIsArchived(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if *archived {
-		log.Printf("%v: delete business promotional post bpPostIsArchived(id): %v", r.URL, *archived)
+		toolkit.LogInfof(r, "delete business promotional post bpPostIsArchived(id): %v", *archived)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot delete archived business promotional post")
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// first delete photos from bpp_photos
 	_, err = database.Exec("DELETE FROM bpp_photos WHERE bpp = $1", id)
 	if err != nil {
-		log.Printf("%v: delete business promotional post photos: %v", r.URL, err)
+		toolkit.LogErrorf(r, "delete business promotional post photos: %v", err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// and its old slugs
+	err = slug.BPPost.DeleteRedirects(database, id)
+	if err != nil {
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -580,7 +580,7 @@ func deleteBPPost(w http.ResponseWriter, r *http.Request) {
 	// Prepare the SQL statement
 	stmt, err := database.Prepare("DELETE FROM business_promotional_posts WHERE id=$1")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -588,7 +588,7 @@ func deleteBPPost(w http.ResponseWriter, r *http.Request) {
 
 	_, err = stmt.Exec(id)
 	if err != nil {
-		log.Printf("%v: delete business promotional post: %v", r.URL, err)
+		toolkit.LogErrorf(r, "delete business promotional post: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -602,41 +602,40 @@ func archiveBPPost(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := bpPostExists(id)
 	if err != nil {
-		log.Printf("%v: archive business promotional post bpPostExists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "archive business promotional post bpPostExists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: archive business promotional post bpPostExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "archive business promotional post bpPostExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot archive non existent business promotional post")
 		return
 	}
 
 	archived, err := bpPostIsArchived(id)
 	if err != nil {
-		log.Printf("%v: archive business promotional post bpPostIsArchived(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "archive business promotional post bpPostIsArchived(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if *archived {
-		log.Printf("%v: archive business promotional post bpPostIsArchived(id): %v", r.URL, *archived)
+		toolkit.LogInfof(r, "archive business promotional post bpPostIsArchived(id): %v", *archived)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot archive already archived business promotional post")
 		return
 	}
 
 	db, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
 	_, err = db.Exec("UPDATE business_promotional_posts SET archived = true WHERE id = $1", id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -650,41 +649,40 @@ func unArchiveBPPost(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := bpPostExists(id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: archive business promotional post bpPostExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "archive business promotional post bpPostExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot unarchive non existent business promotional post")
 		return
 	}
 
 	archived, err := bpPostIsArchived(id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*archived {
-		log.Printf("%v: unarchive business promotional post bpPostIsArchived(id): %v", r.URL, *archived)
+		toolkit.LogInfof(r, "unarchive business promotional post bpPostIsArchived(id): %v", *archived)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot unarchive not archived business promotional post")
 		return
 	}
 
 	db, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
 	_, err = db.Exec("UPDATE business_promotional_posts SET archived = false WHERE id = $1", id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -692,41 +690,44 @@ func unArchiveBPPost(w http.ResponseWriter, r *http.Request) {
 	response.Res(w, "success", http.StatusOK, "unarchive done")
 }
 
-func CheckAndArchiveExpiredBPPosts() {
+// CheckAndArchiveExpiredBPPosts archives the business promotional posts whose expiration has passed.
+// It is run by the scheduler.
+func CheckAndArchiveExpiredBPPosts() error {
 	db, err := db.DB()
 	if err != nil {
-		log.Printf("checkAndArchiveExpiredBPPosts(): error: %v", err)
-		return
+		return err
 	}
-	defer db.Close()
 
 	// Start a new transaction
 	tx, err := db.Begin()
 	if err != nil {
-		log.Printf("checkAndArchiveExpiredBPPosts(): Start a new transaction: error: %v", err)
-		return
+		return fmt.Errorf("start a new transaction: %v", err)
 	}
+	defer tx.Rollback()
 
 	// Prepare the SQL statement
-	stmt, err := tx.Prepare("UPDATE business_promotional_posts SET archived = true WHERE expiration <= $1")
+	stmt, err := tx.Prepare("UPDATE business_promotional_posts SET archived = true WHERE expiration <= $1 AND NOT archived")
 	if err != nil {
-		log.Printf("checkAndArchiveExpiredBPPosts(): Prepare the SQL statement: error: %v", err)
-		return
+		return fmt.Errorf("prepare the SQL statement: %v", err)
 	}
+	defer stmt.Close()
 
 	// Execute the SQL statement
-	_, err = stmt.Exec(time.Now().UTC())
+	res, err := stmt.Exec(time.Now().UTC())
 	if err != nil {
-		log.Printf("checkAndArchiveExpiredBPPosts(): Execute the SQL statement: error: %v", err)
-		return
+		return fmt.Errorf("execute the SQL statement: %v", err)
 	}
 
 	// Commit the transaction
 	err = tx.Commit()
 	if err != nil {
-		log.Printf("checkAndArchiveExpiredBPPosts(): Commit the transaction: error: %v", err)
-		return
+		return fmt.Errorf("commit the transaction: %v", err)
 	}
+
+	if n, err := res.RowsAffected(); err == nil {
+		metrics.ArchivedBPPosts.Add(float64(n))
+	}
+	return nil
 }
 
 type businessPromotionalPostCount struct {
@@ -745,17 +746,16 @@ func getBusinessPromotionalPostCount(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	var count int
 	query := fmt.Sprintf("SELECT COUNT(id) FROM business_promotional_posts WHERE created_at > current_date - interval '1 %s'", period)
 	err = database.QueryRow(query).Scan(&count)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -779,10 +779,9 @@ func getBusinessPromotionalPosts(w http.ResponseWriter, r *http.Request) {
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	// Prepare the SQL statement: select id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, expiration, created_at, updated_at, archived, partner, completed from business_promotional_posts
-	stmt, err := database.Prepare("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, expiration, created_at, updated_at, archived, partner, completed FROM business_promotional_posts ORDER BY created_at DESC LIMIT $1 OFFSET $2")
+	// Prepare the SQL statement: select id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, expiration, created_at, updated_at, archived, partner, completed, publish_at, unpublish_at, slug from business_promotional_posts
+	stmt, err := database.Prepare("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, expiration, created_at, updated_at, archived, partner, completed, publish_at, unpublish_at, slug FROM business_promotional_posts ORDER BY created_at DESC LIMIT $1 OFFSET $2")
 	if err != nil {
 		toolkit.LogError(r, fmt.Errorf("getBusinessPromotionalPosts(): %v", err))
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
@@ -803,7 +802,7 @@ func getBusinessPromotionalPosts(w http.ResponseWriter, r *http.Request) {
 	var bppListResponse model.BusinessPromotionalPostListResponse
 	for rows.Next() {
 		var bpp model.BusinessPromotionalPost
-		err = rows.Scan(&bpp.ID, &bpp.TitleLatin, &bpp.DescriptionLatin, &bpp.TitleCyrillic, &bpp.DescriptionCyrillic, pq.Array(&bpp.Videos), &bpp.Expiration, &bpp.CreatedAt, &bpp.UpdatedAt, &bpp.Archived, &bpp.Partner, &bpp.Completed)
+		err = rows.Scan(&bpp.ID, &bpp.TitleLatin, &bpp.DescriptionLatin, &bpp.TitleCyrillic, &bpp.DescriptionCyrillic, pq.Array(&bpp.Videos), &bpp.Expiration, &bpp.CreatedAt, &bpp.UpdatedAt, &bpp.Archived, &bpp.Partner, &bpp.Completed, &bpp.PublishAt, &bpp.UnpublishAt, &bpp.Slug)
 		if err != nil {
 			toolkit.LogError(r, fmt.Errorf("getBusinessPromotionalPosts(): %v", err))
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
@@ -833,48 +832,58 @@ func getBusinessPromotionalPosts(w http.ResponseWriter, r *http.Request) {
 	response.Res(w, "success", http.StatusOK, bppListResponse)
 }
 
-// businessPromotionalPostCompleted is a handler to make business promotional post completed field true/false
+// businessPromotionalPostCompleted is a handler to make business promotional post completed field true/false.
+// The form value completed, true or false, sets it, so a repeated request changes nothing; without it the field is flipped.
 func businessPromotionalPostCompleted(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	completed, set, err := formCompleted(r)
+	if err != nil {
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
+		return
+	}
+
 	exists, err := bpPostExists(id)
 	if err != nil {
-		log.Printf("%v: businessPromotionalPostCompleted bpPostExists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "businessPromotionalPostCompleted bpPostExists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: businessPromotionalPostCompleted bpPostExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "businessPromotionalPostCompleted bpPostExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot update completed field of non existent business promotional post")
 		return
 	}
 
 	archived, err := bpPostIsArchived(id)
 	if err != nil {
-		log.Printf("%v: businessPromotionalPostCompleted bpPostIsArchived(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "businessPromotionalPostCompleted bpPostIsArchived(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if *archived {
-		log.Printf("%v: businessPromotionalPostCompleted bpPostIsArchived(id): %v", r.URL, *archived)
+		toolkit.LogInfof(r, "businessPromotionalPostCompleted bpPostIsArchived(id): %v", *archived)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot update completed field of archived business promotional post")
 		return
 	}
 
 	db, err := db.DB()
 	if err != nil {
-		log.Printf("%v: businessPromotionalPostCompleted db connection error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "businessPromotionalPostCompleted db connection error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
-	_, err = db.Exec("UPDATE business_promotional_posts SET completed = NOT completed WHERE id = $1", id)
+	// publishing clears an expired unpublish_at, the post would be unpublished again right away
+	_, err = db.Exec(`UPDATE business_promotional_posts SET completed = v.completed,
+			unpublish_at = CASE WHEN v.completed AND unpublish_at <= NOW() THEN NULL ELSE unpublish_at END, updated_at = NOW()
+		FROM (SELECT CASE WHEN $2 THEN $3 ELSE NOT completed END AS completed FROM business_promotional_posts WHERE id = $1) v
+		WHERE id = $1`, id, set, completed)
 	if err != nil {
-		log.Printf("%v: businessPromotionalPostCompleted db execution error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "businessPromotionalPostCompleted db execution error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -889,76 +898,59 @@ func addBusinessPromotionalPostPhoto(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := bpPostExists(id)
 	if err != nil {
-		log.Printf("%v: addBusinessPromotionalPostPhoto bpPostExists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "addBusinessPromotionalPostPhoto bpPostExists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: addBusinessPromotionalPostPhoto bpPostExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "addBusinessPromotionalPostPhoto bpPostExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot add photo to non existent business promotional post")
 		return
 	}
 
 	archived, err := bpPostIsArchived(id)
 	if err != nil {
-		log.Printf("%v: addBusinessPromotionalPostPhoto bpPostIsArchived(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "addBusinessPromotionalPostPhoto bpPostIsArchived(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if *archived {
-		log.Printf("%v: addBusinessPromotionalPostPhoto bpPostIsArchived(id): %v", r.URL, *archived)
+		toolkit.LogInfof(r, "addBusinessPromotionalPostPhoto bpPostIsArchived(id): %v", *archived)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot add photo to archived business promotional post")
 		return
 	}
 
 	// Parse multipart form
-	err = r.ParseMultipartForm(100 << 20) // maxMemory is 100MB
+	err = upload.ParseForm(w, r, maxBPPostRequest)
 	if err != nil {
-		log.Printf("%v: addBusinessPromotionalPostPhoto: %v", r.URL, err)
-		response.Res(w, "error", http.StatusBadRequest, err.Error())
+		upload.Fail(w, r, err)
 		return
 	}
 
 	// Open a connection to the database
 	db, err := db.DB()
 	if err != nil {
-		log.Printf("%v: addBusinessPromotionalPostPhoto: %v", r.URL, err)
+		toolkit.LogErrorf(r, "addBusinessPromotionalPostPhoto: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
 	photoArray := r.MultipartForm.File["photo"]
 	for _, fh := range photoArray {
-		if fh.Size > 10<<20 {
-			err := fmt.Errorf("photo %v size exceeds 10MB limit: %v", fh.Filename, fh.Size)
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
-			return
-		}
-		// check if file is image using http.DetectContentType
-		contentType := fh.Header.Get("Content-Type")
-		if contentType[:5] != "image" {
-			err := fmt.Errorf("photo %v is not an image: %v", fh.Filename, contentType)
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
-			return
-		}
-		file, err := fh.Open()
+		// check the format and the size of the photo by its content
+		photo, err := upload.Check(fh, "photo", bpPostImage)
 		if err != nil {
-			err := fmt.Errorf("error opening photo %v: %v", fh.Filename, err)
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
+			upload.Fail(w, r, err)
 			return
 		}
-		defer file.Close()
-		photo, err := io.ReadAll(file)
+
+		// Store the photo in the media storage
+		mediaID, err := media.SaveFile(r.Context(), photo, fh.Filename)
 		if err != nil {
-			err := fmt.Errorf("error reading photo %v: %v", fh.Filename, err)
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
+			toolkit.LogError(r, fmt.Errorf("saving photo %v: %v", fh.Filename, err))
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 
@@ -970,8 +962,8 @@ func addBusinessPromotionalPostPhoto(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Prepare the SQL statement: add into bpp_photos: bpp, file_name, file
-		stmt, err := tx.Prepare("INSERT INTO bpp_photos (bpp, file_name, file) VALUES ($1, $2, $3)")
+		// Prepare the SQL statement: add into bpp_photos: bpp, file_name, media_id
+		stmt, err := tx.Prepare("INSERT INTO bpp_photos (bpp, file_name, media_id) VALUES ($1, $2, $3)")
 		if err != nil {
 			toolkit.LogError(r, fmt.Errorf("prepare the SQL statement: %v", err))
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
@@ -979,7 +971,7 @@ func addBusinessPromotionalPostPhoto(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Execute the SQL statement
-		_, err = stmt.Exec(id, fh.Filename, photo)
+		_, err = stmt.Exec(id, fh.Filename, mediaID)
 		if err != nil {
 			toolkit.LogError(r, fmt.Errorf("execute the SQL statement: %v", err))
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
@@ -1013,26 +1005,26 @@ func getBusinessPromotionalPostPhotoList(w http.ResponseWriter, r *http.Request)
 
 	exists, err := bpPostExists(id)
 	if err != nil {
-		log.Printf("%v: getBusinessPromotionalPostPhotoList bpPostExists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "getBusinessPromotionalPostPhotoList bpPostExists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: getBusinessPromotionalPostPhotoList bpPostExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "getBusinessPromotionalPostPhotoList bpPostExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot get photo list of non existent business promotional post")
 		return
 	}
 
 	archived, err := bpPostIsArchived(id)
 	if err != nil {
-		log.Printf("%v: getBusinessPromotionalPostPhotoList bpPostIsArchived(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "getBusinessPromotionalPostPhotoList bpPostIsArchived(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if *archived {
-		log.Printf("%v: getBusinessPromotionalPostPhotoList bpPostIsArchived(id): %v", r.URL, *archived)
+		toolkit.LogInfof(r, "getBusinessPromotionalPostPhotoList bpPostIsArchived(id): %v", *archived)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot get photo list of archived business promotional post")
 		return
 	}
@@ -1040,16 +1032,15 @@ func getBusinessPromotionalPostPhotoList(w http.ResponseWriter, r *http.Request)
 	// Open a connection to the database
 	db, err := db.DB()
 	if err != nil {
-		log.Printf("%v: getBusinessPromotionalPostPhotoList: %v", r.URL, err)
+		toolkit.LogErrorf(r, "getBusinessPromotionalPostPhotoList: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
 	// select id, bpp, file_name, created_at order by id
 	rows, err := db.Query("SELECT id, bpp, file_name, created_at FROM bpp_photos WHERE bpp = $1 ORDER BY id", id)
 	if err != nil {
-		log.Printf("%v: getBusinessPromotionalPostPhotoList: %v", r.URL, err)
+		toolkit.LogErrorf(r, "getBusinessPromotionalPostPhotoList: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -1060,7 +1051,7 @@ func getBusinessPromotionalPostPhotoList(w http.ResponseWriter, r *http.Request)
 		var photo model.BusinessPromotionalPostPhoto
 		err := rows.Scan(&photo.ID, &photo.BPP, &photo.FileName, &photo.CreatedAt)
 		if err != nil {
-			log.Printf("%v: getBusinessPromotionalPostPhotoList: %v", r.URL, err)
+			toolkit.LogErrorf(r, "getBusinessPromotionalPostPhotoList: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -1077,26 +1068,26 @@ func getBusinessPromotionalPostPhoto(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := bpPostExists(id)
 	if err != nil {
-		log.Printf("%v: getBusinessPromotionalPostPhoto bpPostExists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "getBusinessPromotionalPostPhoto bpPostExists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: getBusinessPromotionalPostPhoto bpPostExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "getBusinessPromotionalPostPhoto bpPostExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot get photo of non existent business promotional post")
 		return
 	}
 
 	archived, err := bpPostIsArchived(id)
 	if err != nil {
-		log.Printf("%v: getBusinessPromotionalPostPhoto bpPostIsArchived(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "getBusinessPromotionalPostPhoto bpPostIsArchived(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if *archived {
-		log.Printf("%v: getBusinessPromotionalPostPhoto bpPostIsArchived(id): %v", r.URL, *archived)
+		toolkit.LogInfof(r, "getBusinessPromotionalPostPhoto bpPostIsArchived(id): %v", *archived)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot get photo of archived business promotional post")
 		return
 	}
@@ -1104,25 +1095,25 @@ func getBusinessPromotionalPostPhoto(w http.ResponseWriter, r *http.Request) {
 	// Open a connection to the database
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: getBusinessPromotionalPostPhoto: %v", r.URL, err)
+		toolkit.LogErrorf(r, "getBusinessPromotionalPostPhoto: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	photo_id := vars["photo_id"]
 	var photo model.BusinessPromotionalPostPhoto
-	err = database.QueryRow("SELECT id, bpp, file_name, file, created_at FROM bpp_photos WHERE id = $1 AND bpp = $2", photo_id, id).Scan(&photo.ID, &photo.BPP, &photo.FileName, &photo.File, &photo.CreatedAt)
+	err = database.QueryRow("SELECT id, bpp, file_name, media_id, created_at FROM bpp_photos WHERE id = $1 AND bpp = $2", photo_id, id).Scan(&photo.ID, &photo.BPP, &photo.FileName, &photo.MediaID, &photo.CreatedAt)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "photo not found")
+		return
+	}
 	if err != nil {
-		log.Printf("%v: getBusinessPromotionalPostPhoto: %v", r.URL, err)
+		toolkit.LogErrorf(r, "getBusinessPromotionalPostPhoto: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	contentType := http.DetectContentType(photo.File)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(photo.File)))
-	w.Write(photo.File)
+	media.ServePrivate(w, r, photo.MediaID, "")
 }
 
 // deleteBusinessPromotionalPostPhoto is a handler to delete photo of business promotional post
@@ -1132,26 +1123,26 @@ func deleteBusinessPromotionalPostPhoto(w http.ResponseWriter, r *http.Request)
 
 	exists, err := bpPostExists(id)
 	if err != nil {
-		log.Printf("%v: deleteBusinessPromotionalPostPhoto bpPostExists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "deleteBusinessPromotionalPostPhoto bpPostExists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: deleteBusinessPromotionalPostPhoto bpPostExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "deleteBusinessPromotionalPostPhoto bpPostExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot delete photo of non existent business promotional post")
 		return
 	}
 
 	archived, err := bpPostIsArchived(id)
 	if err != nil {
-		log.Printf("%v: deleteBusinessPromotionalPostPhoto bpPostIsArchived(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "deleteBusinessPromotionalPostPhoto bpPostIsArchived(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if *archived {
-		log.Printf("%v: deleteBusinessPromotionalPostPhoto bpPostIsArchived(id): %v", r.URL, *archived)
+		toolkit.LogInfof(r, "deleteBusinessPromotionalPostPhoto bpPostIsArchived(id): %v", *archived)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot delete photo of archived business promotional post")
 		return
 	}
@@ -1161,22 +1152,21 @@ func deleteBusinessPromotionalPostPhoto(w http.ResponseWriter, r *http.Request)
 	// Open a connection to the database
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: deleteBusinessPromotionalPostPhoto: %v", r.URL, err)
+		toolkit.LogErrorf(r, "deleteBusinessPromotionalPostPhoto: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	stmt, err := database.Prepare("DELETE FROM bpp_photos WHERE id=$1 AND bpp=$2")
 	if err != nil {
-		log.Printf("%v: deleteBusinessPromotionalPostPhoto: %v", r.URL, err)
+		toolkit.LogErrorf(r, "deleteBusinessPromotionalPostPhoto: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	_, err = stmt.Exec(photo_id, id)
 	if err != nil {
-		log.Printf("%v: deleteBusinessPromotionalPostPhoto: %v", r.URL, err)
+		toolkit.LogErrorf(r, "deleteBusinessPromotionalPostPhoto: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -1191,26 +1181,26 @@ func getBusinessPromotionalPostCoverImage(w http.ResponseWriter, r *http.Request
 
 	exists, err := bpPostExists(id)
 	if err != nil {
-		log.Printf("%v: getBusinessPromotionalPostCoverImage bpPostExists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "getBusinessPromotionalPostCoverImage bpPostExists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: getBusinessPromotionalPostCoverImage bpPostExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "getBusinessPromotionalPostCoverImage bpPostExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot get cover image of non existent business promotional post")
 		return
 	}
 
 	archived, err := bpPostIsArchived(id)
 	if err != nil {
-		log.Printf("%v: getBusinessPromotionalPostCoverImage bpPostIsArchived(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "getBusinessPromotionalPostCoverImage bpPostIsArchived(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if *archived {
-		log.Printf("%v: getBusinessPromotionalPostCoverImage bpPostIsArchived(id): %v", r.URL, *archived)
+		toolkit.LogInfof(r, "getBusinessPromotionalPostCoverImage bpPostIsArchived(id): %v", *archived)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot get cover image of archived business promotional post")
 		return
 	}
@@ -1218,22 +1208,18 @@ func getBusinessPromotionalPostCoverImage(w http.ResponseWriter, r *http.Request
 	// Open a connection to the database
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: getBusinessPromotionalPostCoverImage: %v", r.URL, err)
+		toolkit.LogErrorf(r, "getBusinessPromotionalPostCoverImage: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	var coverImage []byte
-	err = database.QueryRow("SELECT cover_image FROM business_promotional_posts WHERE id = $1", id).Scan(&coverImage)
+	var coverImageID sql.NullInt64
+	err = database.QueryRow("SELECT cover_image_id FROM business_promotional_posts WHERE id = $1", id).Scan(&coverImageID)
 	if err != nil {
-		log.Printf("%v: getBusinessPromotionalPostCoverImage: %v", r.URL, err)
+		toolkit.LogErrorf(r, "getBusinessPromotionalPostCoverImage: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	contentType := http.DetectContentType(coverImage)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(coverImage)))
-	w.Write(coverImage)
+	media.ServePrivate(w, r, coverImageID, "business-promotional-post-"+id+"-cover")
 }
@@ -1,14 +1,15 @@
 package admin
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/media"
 	"Tahlilchi.uz/response"
 	"Tahlilchi.uz/toolkit"
 	"github.com/gorilla/mux"
@@ -23,21 +24,21 @@ type AppealListResponse struct {
 }
 
 type Appeal struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Surname     string `json:"surname"`
-	PhoneNumber string `json:"phone_number"`
-	Message     string `json:"message"`
-	CreatedAt   string `json:"created_at"`
-	Picture     []byte `json:"picture"`
-	Video       []byte `json:"video"`
+	ID          int           `json:"id"`
+	Name        string        `json:"name"`
+	Surname     string        `json:"surname"`
+	PhoneNumber string        `json:"phone_number"`
+	Message     string        `json:"message"`
+	CreatedAt   string        `json:"created_at"`
+	Picture     sql.NullInt64 `json:"-"`
+	Video       sql.NullInt64 `json:"-"`
 }
 
 func appealList(w http.ResponseWriter, r *http.Request) {
 	// get page and limit from the request url
 	page, limit, err := toolkit.GetPageLimit(r)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
@@ -48,15 +49,14 @@ func appealList(w http.ResponseWriter, r *http.Request) {
 	// Open a connection to the database
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	rows, err := database.Query("SELECT id, name, surname, phone_number, message, created_at FROM appeals ORDER BY id DESC LIMIT $1 OFFSET $2", limit, offset)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -66,7 +66,7 @@ func appealList(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var a Appeal
 		if err := rows.Scan(&a.ID, &a.Name, &a.Surname, &a.PhoneNumber, &a.Message, &a.CreatedAt); err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -74,7 +74,7 @@ func appealList(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -84,7 +84,7 @@ func appealList(w http.ResponseWriter, r *http.Request) {
 	var count int
 	err = database.QueryRow("SELECT COUNT(*) FROM appeals").Scan(&count)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -108,7 +108,6 @@ func appealExists(id string) (*bool, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
 	var exists bool
 	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM appeals WHERE id=$1)", id).Scan(&exists)
@@ -125,49 +124,40 @@ func appealPicture(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusBadRequest, "invalid id")
 		return
 	}
 
 	exists, err := appealExists(idStr)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: appealExists(idStr): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "appealExists(idStr): %v", *exists)
 		response.Res(w, "error", http.StatusNotFound, "appeal not found")
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	var appeal Appeal
-	err = database.QueryRow("SELECT picture FROM appeals WHERE id = $1", id).Scan(&appeal.Picture)
+	err = database.QueryRow("SELECT picture_id FROM appeals WHERE id = $1", id).Scan(&appeal.Picture)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	contentType := http.DetectContentType(appeal.Picture)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(appeal.Picture)))
-	_, err = w.Write(appeal.Picture)
-	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
+	media.ServePrivate(w, r, appeal.Picture, "appeal-"+idStr+"-picture")
 }
 
 func appealVideo(w http.ResponseWriter, r *http.Request) {
@@ -176,49 +166,40 @@ func appealVideo(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusBadRequest, "invalid id")
 		return
 	}
 
 	exists, err := appealExists(idStr)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: appealExists(idStr): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "appealExists(idStr): %v", *exists)
 		response.Res(w, "error", http.StatusNotFound, "appeal not found")
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	var appeal Appeal
-	err = database.QueryRow("SELECT video FROM appeals WHERE id = $1", id).Scan(&appeal.Video)
+	err = database.QueryRow("SELECT video_id FROM appeals WHERE id = $1", id).Scan(&appeal.Video)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	contentType := http.DetectContentType(appeal.Video)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(appeal.Video)))
-	_, err = w.Write(appeal.Video)
-	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
+	media.ServePrivate(w, r, appeal.Video, "appeal-"+idStr+"-video")
 }
 
 func adminContactExists() (*bool, error) {
@@ -226,7 +207,6 @@ func adminContactExists() (*bool, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer database.Close()
 
 	var exists bool
 	err = database.QueryRow("SELECT EXISTS (SELECT 1 FROM admin_contact LIMIT 1)").Scan(&exists)
@@ -247,7 +227,7 @@ type AdminContact struct {
 func createAdminContact(w http.ResponseWriter, r *http.Request) {
 	exists, err := adminContactExists()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -260,7 +240,7 @@ func createAdminContact(w http.ResponseWriter, r *http.Request) {
 	var adminContact AdminContact
 	err = json.NewDecoder(r.Body).Decode(&adminContact)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
@@ -280,16 +260,15 @@ func createAdminContact(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	_, err = database.Exec("INSERT INTO admin_contact (address, soc_med_acs, phone_number, email) VALUES ($1, $2, $3, $4)",
 		adminContact.Address, pq.Array(adminContact.SocMedAcs), adminContact.PhoneNumber, adminContact.Email)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -310,15 +289,14 @@ type AdminContactGET struct {
 func getAdminContact(w http.ResponseWriter, r *http.Request) {
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	result, err := database.Query("SELECT * FROM admin_contact LIMIT 1")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -329,7 +307,7 @@ func getAdminContact(w http.ResponseWriter, r *http.Request) {
 		var socMedAcs []uint8
 		err := result.Scan(&contact.ID, &contact.Address, &socMedAcs, &contact.PhoneNumber, &contact.Email, &contact.CreatedAt, &contact.UpdatedAt)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -347,16 +325,15 @@ func updateAdminContact(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	if contact.Address != "" {
 		_, err := database.Exec("UPDATE admin_contact SET address = $1, updated_at = NOW() WHERE id = $2", contact.Address, params["id"])
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -372,7 +349,7 @@ func updateAdminContact(w http.ResponseWriter, r *http.Request) {
 
 		_, err := database.Exec("UPDATE admin_contact SET soc_med_acs = $1, updated_at = NOW() WHERE id = $2", pq.Array(socMedAcs), params["id"])
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -381,7 +358,7 @@ func updateAdminContact(w http.ResponseWriter, r *http.Request) {
 	if contact.PhoneNumber != "" {
 		_, err := database.Exec("UPDATE admin_contact SET phone_number = $1, updated_at = NOW() WHERE id = $2", contact.PhoneNumber, params["id"])
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -390,7 +367,7 @@ func updateAdminContact(w http.ResponseWriter, r *http.Request) {
 	if contact.Email != "" {
 		_, err := database.Exec("UPDATE admin_contact SET email = $1, updated_at = NOW() WHERE id = $2", contact.Email, params["id"])
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -408,16 +385,15 @@ type AppealCount struct {
 func getAppealCountAll(w http.ResponseWriter, r *http.Request) {
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	var count int
 	err = database.QueryRow("SELECT COUNT(*) FROM appeals").Scan(&count)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -432,7 +408,7 @@ func deleteAppeal(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := appealExists(id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -444,15 +420,14 @@ func deleteAppeal(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	_, err = database.Exec("DELETE FROM appeals WHERE id = $1", id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -471,17 +446,16 @@ func getAppealCount(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	var count int
 	query := fmt.Sprintf("SELECT COUNT(*) FROM appeals WHERE created_at > current_date - interval '1 %s'", period)
 	err = database.QueryRow(query).Scan(&count)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -0,0 +1,46 @@
+package admin
+
+import (
+	"net/http"
+
+	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/scheduler"
+)
+
+// DBStats is a struct to map the statistics of the shared database connection pool
+type DBStats struct {
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDuration       string `json:"wait_duration"`
+	MaxIdleClosed      int64  `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64  `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64  `json:"max_lifetime_closed"`
+}
+
+// getDBStats is a route handler function to get the statistics of the shared database connection pool
+func getDBStats(w http.ResponseWriter, r *http.Request) {
+	s := db.Stats()
+
+	response.Res(w, "success", http.StatusOK, DBStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDuration:       s.WaitDuration.String(),
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	})
+}
+
+// getSchedulerStatus returns a route handler function to get the status of the scheduled jobs
+func getSchedulerStatus(jobs *scheduler.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.Res(w, "success", http.StatusOK, jobs.Status())
+	}
+}
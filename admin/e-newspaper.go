@@ -1,14 +1,19 @@
 package admin
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/media"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/slug"
+	"Tahlilchi.uz/toolkit"
+	"Tahlilchi.uz/upload"
 	"github.com/gorilla/mux"
 )
 
@@ -24,7 +29,7 @@ func addENewspaperCategory(w http.ResponseWriter, r *http.Request) {
 	var e ENewspaperCategory
 	err := r.ParseForm()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
@@ -33,22 +38,21 @@ func addENewspaperCategory(w http.ResponseWriter, r *http.Request) {
 	e.TitleCyrillic = r.FormValue("title_cyrillic")
 
 	if e.TitleLatin == "" || e.TitleCyrillic == "" {
-		log.Printf("%v: title_latin: %v; title_cyrillic: %v", r.URL, e.TitleLatin, e.TitleCyrillic)
+		toolkit.LogInfof(r, "title_latin: %v; title_cyrillic: %v", e.TitleLatin, e.TitleCyrillic)
 		response.Res(w, "error", http.StatusBadRequest, "Both title_latin and title_cyrillic are required")
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: db connection error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db connection error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	_, err = database.Exec("INSERT INTO e_newspaper_category (title_latin, title_cyrillic) VALUES ($1, $2)", e.TitleLatin, e.TitleCyrillic)
 	if err != nil {
-		log.Printf("%v: db execution error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db execution error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -60,15 +64,14 @@ func addENewspaperCategory(w http.ResponseWriter, r *http.Request) {
 func getENewspaperCategoryList(w http.ResponseWriter, r *http.Request) {
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: db connection error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db connection error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	rows, err := database.Query("SELECT id, title_latin, title_cyrillic FROM e_newspaper_category")
 	if err != nil {
-		log.Printf("%v: db execution error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db execution error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -79,7 +82,7 @@ func getENewspaperCategoryList(w http.ResponseWriter, r *http.Request) {
 		var e ENewspaperCategory
 		err := rows.Scan(&e.ID, &e.TitleLatin, &e.TitleCyrillic)
 		if err != nil {
-			log.Printf("%v: db execution error: %v", r.URL, err)
+			toolkit.LogErrorf(r, "db execution error: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -98,15 +101,14 @@ func updateENewspaperCategory(w http.ResponseWriter, r *http.Request) {
 	var exists bool
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: db connection error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db connection error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	err = database.QueryRow("SELECT EXISTS(SELECT 1 FROM e_newspaper_category WHERE id = $1)", id).Scan(&exists)
 	if err != nil {
-		log.Printf("%v: db execution error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db execution error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -119,7 +121,7 @@ func updateENewspaperCategory(w http.ResponseWriter, r *http.Request) {
 	var e ENewspaperCategory
 	err = r.ParseForm()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
@@ -131,7 +133,7 @@ func updateENewspaperCategory(w http.ResponseWriter, r *http.Request) {
 		// update title_latin column in database
 		_, err = database.Exec("UPDATE e_newspaper_category SET title_latin = $1 WHERE id = $2", e.TitleLatin, id)
 		if err != nil {
-			log.Printf("%v: db execution error: %v", r.URL, err)
+			toolkit.LogErrorf(r, "db execution error: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -141,7 +143,7 @@ func updateENewspaperCategory(w http.ResponseWriter, r *http.Request) {
 		// update title_cyrillic column in database
 		_, err = database.Exec("UPDATE e_newspaper_category SET title_cyrillic = $1 WHERE id = $2", e.TitleCyrillic, id)
 		if err != nil {
-			log.Printf("%v: db execution error: %v", r.URL, err)
+			toolkit.LogErrorf(r, "db execution error: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -157,17 +159,16 @@ func deleteENewspaperCategory(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: db connection error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db connection error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// check e-newspaper category exists
 	var exists bool
 	err = database.QueryRow("SELECT EXISTS(SELECT 1 FROM e_newspaper_category WHERE id = $1)", id).Scan(&exists)
 	if err != nil {
-		log.Printf("%v: db execution error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db execution error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -179,7 +180,7 @@ func deleteENewspaperCategory(w http.ResponseWriter, r *http.Request) {
 
 	_, err = database.Exec("DELETE FROM e_newspaper_category WHERE id = $1", id)
 	if err != nil {
-		log.Printf("%v: db execution error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db execution error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -187,82 +188,53 @@ func deleteENewspaperCategory(w http.ResponseWriter, r *http.Request) {
 	response.Res(w, "success", http.StatusOK, "e-newspaper category deleted")
 }
 
+// maxENewspaperRequest limits the request of an e-newspaper with its two files and cover image
+const maxENewspaperRequest = 80 << 20
+
+var (
+	// eNewspaperFile is the upload kind of the files of an e-newspaper
+	eNewspaperFile = upload.PDF.Max(30 << 20)
+	// eNewspaperCoverImage is the upload kind of the cover image of an e-newspaper
+	eNewspaperCoverImage = upload.Image.Max(15 << 20)
+)
+
 func addENewspaper(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(80 << 20) // Max memory 80MB
+	err := upload.ParseForm(w, r, maxENewspaperRequest)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		upload.Fail(w, r, err)
 		return
 	}
 
+	// publishing window
+	window, err := parsePublishWindow(r)
+	if err != nil {
+		toolkit.LogInfo(r, err.Error())
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// fill a missing alphabet from the other one on request
+	autofill(r, titleFields)
+
 	title_latin := r.FormValue("title_latin")
 	title_cyrillic := r.FormValue("title_cyrillic")
 
-	file_latin, file_latin_header, err := r.FormFile("file_latin")
-	var fileLatinForDB []byte
-	if err != nil && err != http.ErrMissingFile {
-		log.Printf("%v: file_latin error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
+	fileLatinForDB, err := upload.FormFile(r, "file_latin", eNewspaperFile)
+	if err != nil {
+		upload.Fail(w, r, err)
 		return
-	} else if err == http.ErrMissingFile {
-		fileLatinForDB = nil
-	} else {
-		// check whether file is pdf
-		if file_latin_header.Header.Get("Content-Type") != "application/pdf" {
-			response.Res(w, "error", http.StatusBadRequest, "file_latin is not a pdf")
-			return
-		}
-		// Check size limits
-		if file_latin_header.Size > int64(30<<20) {
-			response.Res(w, "error", http.StatusBadRequest, "file_latin exceeds 30MB limit")
-			return
-		}
-		fileLatinForDB, _ = io.ReadAll(file_latin)
-		file_latin.Close()
 	}
 
-	file_cyrillic, file_cyrillic_header, err := r.FormFile("file_cyrillic")
-	var fileCyrillicForDB []byte
-	if err != nil && err != http.ErrMissingFile {
-		log.Printf("%v: file_cyrillic error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
+	fileCyrillicForDB, err := upload.FormFile(r, "file_cyrillic", eNewspaperFile)
+	if err != nil {
+		upload.Fail(w, r, err)
 		return
-	} else if err == http.ErrMissingFile {
-		fileCyrillicForDB = nil
-	} else {
-		// check whether file is pdf
-		if file_cyrillic_header.Header.Get("Content-Type") != "application/pdf" {
-			response.Res(w, "error", http.StatusBadRequest, "file_cyrillic is not a pdf")
-			return
-		}
-		if file_cyrillic_header.Size > int64(30<<20) {
-			response.Res(w, "error", http.StatusBadRequest, "file_cyrillic exceeds 30MB limit")
-			return
-		}
-		fileCyrillicForDB, _ = io.ReadAll(file_cyrillic)
-		file_cyrillic.Close()
 	}
 
-	cover_image, cover_image_header, err := r.FormFile("cover_image")
-	var coverImageForDB []byte
-	if err != nil && err != http.ErrMissingFile {
-		log.Printf("%v: cover_image error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
+	coverImageForDB, err := upload.FormFile(r, "cover_image", eNewspaperCoverImage)
+	if err != nil {
+		upload.Fail(w, r, err)
 		return
-	} else if err == http.ErrMissingFile {
-		coverImageForDB = nil
-	} else {
-		// check whether file is image
-		if cover_image_header.Header.Get("Content-Type")[:5] != "image" {
-			response.Res(w, "error", http.StatusBadRequest, "cover_image is not an image")
-			return
-		}
-		if cover_image_header.Size > int64(15<<20) {
-			response.Res(w, "error", http.StatusBadRequest, "Cover image exceeds 15MB limit")
-			return
-		}
-		coverImageForDB, _ = io.ReadAll(cover_image)
-		cover_image.Close()
 	}
 
 	// category
@@ -270,23 +242,51 @@ func addENewspaper(w http.ResponseWriter, r *http.Request) {
 	// convert category to int
 	categoryInt, err := strconv.Atoi(category)
 	if err != nil {
-		log.Printf("%v: category conversion error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "category conversion error: %v", err)
 		response.Res(w, "error", http.StatusBadRequest, "category conversion error")
 		return
 	}
 
 	db, err := db.DB()
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	fileLatinID, err := media.SaveFileNullable(r.Context(), fileLatinForDB, "file_latin.pdf")
+	if err != nil {
+		toolkit.LogErrorf(r, "saving file_latin: %v", err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	fileCyrillicID, err := media.SaveFileNullable(r.Context(), fileCyrillicForDB, "file_cyrillic.pdf")
+	if err != nil {
+		toolkit.LogErrorf(r, "saving file_cyrillic: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
-	_, err = db.Exec(`INSERT INTO e_newspapers (title_latin, title_cyrillic, file_latin, file_cyrillic, cover_image, category) VALUES ($1, $2, $3, $4, $5, $6)`,
-		title_latin, title_cyrillic, fileLatinForDB, fileCyrillicForDB, coverImageForDB, categoryInt)
+	coverImageID, err := media.SaveFileNullable(r.Context(), coverImageForDB, "cover_image")
 	if err != nil {
-		log.Printf("%v: %v", r.URL, err)
+		toolkit.LogErrorf(r, "saving cover_image: %v", err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	var id int64
+	err = db.QueryRow(`INSERT INTO e_newspapers (title_latin, title_cyrillic, file_latin_id, file_cyrillic_id, cover_image_id, category, publish_at, unpublish_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		title_latin, title_cyrillic, fileLatinID, fileCyrillicID, coverImageID, categoryInt, window.PublishAt, window.UnpublishAt).Scan(&id)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	err = slug.ENewspaper.Assign(db, id)
+	if err != nil {
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -300,7 +300,6 @@ func eNewspaperExists(id string) (*bool, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
 	var exists bool
 	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM e_newspapers WHERE id=$1)", id).Scan(&exists)
@@ -317,7 +316,6 @@ func eNewspaperIsArchived(id string) (*bool, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
 	// Prepare the SQL statement
 	stmt, err := db.Prepare("SELECT archived FROM e_newspapers WHERE id = $1")
@@ -342,45 +340,71 @@ func editENewspaper(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := eNewspaperExists(id)
 	if err != nil {
-		log.Printf("%v: edit e-newspaper eNewspaperExists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "edit e-newspaper eNewspaperExists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: edit e-newspaper eNewspaperExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "edit e-newspaper eNewspaperExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot edit non existent e-newspaper")
 		return
 	}
 
 	archived, err := eNewspaperIsArchived(id)
 	if err != nil {
-		log.Printf("%v: edit e-newspaper eNewspaperIsArchived(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "edit e-newspaper eNewspaperIsArchived(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if *archived {
-		log.Printf("%v: edit e-newspaper eNewspaperIsArchived(id): %v", r.URL, *archived)
+		toolkit.LogInfof(r, "edit e-newspaper eNewspaperIsArchived(id): %v", *archived)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot edit archived e-newspaper")
 		return
 	}
 
 	// Parse multipart form
-	err = r.ParseMultipartForm(80 << 20)
+	err = upload.ParseForm(w, r, maxENewspaperRequest)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		upload.Fail(w, r, err)
+		return
+	}
+
+	// publishing window
+	window, err := parsePublishWindow(r)
+	if err != nil {
+		toolkit.LogInfo(r, err.Error())
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
 	db, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error while connecting to db: %v", r.URL, err)
+		toolkit.LogErrorf(r, "error while connecting to db: %v", err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	if !eNewspaperWorkflow.editable(w, r, db, id) {
+		return
+	}
+
+	// the window with the stored times in place of the fields which were not sent
+	err = window.check(db, "e_newspapers", id)
+	if errors.Is(err, errPublishWindow) {
+		toolkit.LogInfo(r, err.Error())
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
+
+	// fill a missing alphabet from the other one on request
+	autofill(r, titleFields)
 
 	title_latin := r.FormValue("title_latin")
 	if title_latin != "" {
@@ -391,7 +415,7 @@ func editENewspaper(w http.ResponseWriter, r *http.Request) {
 		`
 		_, err = db.Exec(sqlStatement, title_latin, id)
 		if err != nil {
-			log.Printf("%v: writing title_latin into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing title_latin into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -406,103 +430,82 @@ func editENewspaper(w http.ResponseWriter, r *http.Request) {
 		`
 		_, err = db.Exec(sqlStatement, title_cyrillic, id)
 		if err != nil {
-			log.Printf("%v: writing title_cyrillic into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing title_cyrillic into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 	}
 
-	file_latin, file_latin_header, err := r.FormFile("file_latin")
-	if err != nil && err != http.ErrMissingFile {
-		log.Printf("%v: file_latin error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
+	fileLatinForDB, err := upload.FormFile(r, "file_latin", eNewspaperFile)
+	if err != nil {
+		upload.Fail(w, r, err)
 		return
-	} else if err == http.ErrMissingFile {
-	} else {
-		// check whether file is pdf
-		if file_latin_header.Header.Get("Content-Type") != "application/pdf" {
-			response.Res(w, "error", http.StatusBadRequest, "file_latin is not a pdf")
-			return
-		}
-		// Check size limits
-		if file_latin_header.Size > int64(30<<20) {
-			response.Res(w, "error", http.StatusBadRequest, "file_latin exceeds 30MB limit")
+	}
+	if fileLatinForDB != nil {
+		fileLatinID, err := media.SaveFile(r.Context(), fileLatinForDB, fileLatinForDB.Name())
+		if err != nil {
+			toolkit.LogErrorf(r, "saving file_latin: %v", err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
-		fileLatinForDB, _ := io.ReadAll(file_latin)
-		file_latin.Close()
 		sqlStatement := `
 			UPDATE e_newspapers
-			SET file_latin = $1, updated_at = NOW()
+			SET file_latin_id = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, fileLatinForDB, id)
+		_, err = db.Exec(sqlStatement, fileLatinID, id)
 		if err != nil {
-			log.Printf("%v: writing file_latin into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing file_latin into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 	}
 
-	file_cyrillic, file_cyrillic_header, err := r.FormFile("file_cyrillic")
-	if err != nil && err != http.ErrMissingFile {
-		log.Printf("%v: file_cyrillic error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
+	fileCyrillicForDB, err := upload.FormFile(r, "file_cyrillic", eNewspaperFile)
+	if err != nil {
+		upload.Fail(w, r, err)
 		return
-	} else if err == http.ErrMissingFile {
-	} else {
-		// check whether file is pdf
-		if file_cyrillic_header.Header.Get("Content-Type") != "application/pdf" {
-			response.Res(w, "error", http.StatusBadRequest, "file_cyrillic is not a pdf")
-			return
-		}
-		// Check size limits
-		if file_cyrillic_header.Size > int64(30<<20) {
-			response.Res(w, "error", http.StatusBadRequest, "file_cyrillic exceeds 30MB limit")
+	}
+	if fileCyrillicForDB != nil {
+		fileCyrillicID, err := media.SaveFile(r.Context(), fileCyrillicForDB, fileCyrillicForDB.Name())
+		if err != nil {
+			toolkit.LogErrorf(r, "saving file_cyrillic: %v", err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
-		fileCyrillicForDB, _ := io.ReadAll(file_cyrillic)
-		file_cyrillic.Close()
 		sqlStatement := `
 			UPDATE e_newspapers
-			SET file_cyrillic = $1, updated_at = NOW()
+			SET file_cyrillic_id = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, fileCyrillicForDB, id)
+		_, err = db.Exec(sqlStatement, fileCyrillicID, id)
 		if err != nil {
-			log.Printf("%v: writing file_cyrillic into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing file_cyrillic into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 	}
 
-	cover_image, cover_image_header, err := r.FormFile("cover_image")
-	if err != nil && err != http.ErrMissingFile {
-		log.Printf("%v: cover_image error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
+	coverImageForDB, err := upload.FormFile(r, "cover_image", eNewspaperCoverImage)
+	if err != nil {
+		upload.Fail(w, r, err)
 		return
-	} else if err == http.ErrMissingFile {
-	} else {
-		// check whether file is image
-		if cover_image_header.Header.Get("Content-Type")[:5] != "image" {
-			response.Res(w, "error", http.StatusBadRequest, "cover_image is not an image")
-			return
-		}
-		// Check size limits
-		if cover_image_header.Size > int64(15<<20) {
-			response.Res(w, "error", http.StatusBadRequest, "Cover image exceeds 15MB limit")
+	}
+	if coverImageForDB != nil {
+		coverImageID, err := media.SaveFile(r.Context(), coverImageForDB, coverImageForDB.Name())
+		if err != nil {
+			toolkit.LogErrorf(r, "saving cover_image: %v", err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
-		coverImageForDB, _ := io.ReadAll(cover_image)
-		cover_image.Close()
 		sqlStatement := `
 			UPDATE e_newspapers
-			SET cover_image = $1, updated_at = NOW()
+			SET cover_image_id = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, coverImageForDB, id)
+		_, err = db.Exec(sqlStatement, coverImageID, id)
 		if err != nil {
-			log.Printf("%v: writing cover_image into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing cover_image into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -514,7 +517,7 @@ func editENewspaper(w http.ResponseWriter, r *http.Request) {
 		// convert category to int
 		categoryInt, err := strconv.Atoi(category)
 		if err != nil {
-			log.Printf("%v: category conversion error: %v", r.URL, err)
+			toolkit.LogErrorf(r, "category conversion error: %v", err)
 			response.Res(w, "error", http.StatusBadRequest, "category conversion error")
 			return
 		}
@@ -525,164 +528,88 @@ func editENewspaper(w http.ResponseWriter, r *http.Request) {
 		`
 		_, err = db.Exec(sqlStatement, categoryInt, id)
 		if err != nil {
-			log.Printf("%v: writing category into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing category into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 	}
 
-	response.Res(w, "success", http.StatusOK, "E-newspaper edited")
-}
-
-func deleteENewspaper(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	id := params["id"]
-
-	exists, err := eNewspaperExists(id)
-	if err != nil {
-		log.Printf("%v: delete e-newspaper eNewspaperExists(id) error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	if !*exists {
-		log.Printf("%v: delete e-newspaper eNewspaperExists(id): %v", r.URL, *exists)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot delete non existent e-newspaper")
-		return
-	}
-
-	archived, err := eNewspaperIsArchived(id)
+	err = window.update(db, "e_newspapers", id)
 	if err != nil {
-		log.Printf("%v: delete e-newspaper eNewspaperIsArchived(id) error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	if *archived {
-		log.Printf("%v: delete e-newspaper eNewspaperIsArchived(id): %v", r.URL, *archived)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot delete archived e-newspaper")
-		return
-	}
-
-	db, err := db.DB()
+	err = slug.ENewspaper.Assign(db, id)
 	if err != nil {
-		log.Printf("%v: db error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
-	stmt, err := db.Prepare("DELETE FROM e_newspapers WHERE id=$1")
-	if err != nil {
-		log.Printf("%v: db error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	_, err = stmt.Exec(id)
-	if err != nil {
-		log.Printf("%v: db statement execution error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	response.Res(w, "success", http.StatusOK, "deleted")
+	response.Res(w, "success", http.StatusOK, "E-newspaper edited")
 }
 
-func archiveENewspaper(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+func deleteENewspaper(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id := params["id"]
 
 	exists, err := eNewspaperExists(id)
 	if err != nil {
-		log.Printf("%v: archive e-newspaper eNewspaperExists(id) error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "delete e-newspaper eNewspaperExists(id) error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: archive e-newspaper eNewspaperExists(id): %v", r.URL, *exists)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot archive non existent e-newspaper")
+		toolkit.LogInfof(r, "delete e-newspaper eNewspaperExists(id): %v", *exists)
+		response.Res(w, "error", http.StatusBadRequest, "Cannot delete non existent e-newspaper")
 		return
 	}
 
 	archived, err := eNewspaperIsArchived(id)
 	if err != nil {
-		log.Printf("%v: archive e-newspaper eNewspaperIsArchived(id) error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "delete e-newspaper eNewspaperIsArchived(id) error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if *archived {
-		log.Printf("%v: archive e-newspaper eNewspaperIsArchived(id): %v", r.URL, *archived)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot archive already archived e-newspaper")
+		toolkit.LogInfof(r, "delete e-newspaper eNewspaperIsArchived(id): %v", *archived)
+		response.Res(w, "error", http.StatusBadRequest, "Cannot delete archived e-newspaper")
 		return
 	}
 
 	db, err := db.DB()
 	if err != nil {
-		log.Printf("%v: db error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
-	_, err = db.Exec("UPDATE e_newspapers SET archived = true WHERE id = $1", id)
+	err = slug.ENewspaper.DeleteRedirects(db, id)
 	if err != nil {
-		log.Printf("%v: db error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	response.Res(w, "success", http.StatusOK, "archived")
-}
-
-func unArchiveENewspaper(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	exists, err := eNewspaperExists(id)
-	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	if !*exists {
-		log.Printf("%v: unarchive e-newspaper eNewspaperExists(id): %v", r.URL, *exists)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot unarchive non existent e-newspaper")
-		return
-	}
-
-	archived, err := eNewspaperIsArchived(id)
-	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	if !*archived {
-		log.Printf("%v: unarchive e-newspaper eNewspaperIsArchived(id): %v", r.URL, *archived)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot unarchive not archived e-newspaper")
-		return
-	}
-
-	db, err := db.DB()
+	stmt, err := db.Prepare("DELETE FROM e_newspapers WHERE id=$1")
 	if err != nil {
-		log.Printf("%v: db error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
-	_, err = db.Exec("UPDATE e_newspapers SET archived = false WHERE id = $1", id)
+	_, err = stmt.Exec(id)
 	if err != nil {
-		log.Printf("%v: db error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db statement execution error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	response.Res(w, "success", http.StatusOK, "unarchive done")
+	response.Res(w, "success", http.StatusOK, "deleted")
 }
 
 type ENewspaperCount struct {
@@ -693,16 +620,15 @@ type ENewspaperCount struct {
 func getENewspaperCountAll(w http.ResponseWriter, r *http.Request) {
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	var count int
 	err = database.QueryRow("SELECT COUNT(*) FROM e_newspapers").Scan(&count)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -721,17 +647,16 @@ func getENewspaperCount(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	var count int
 	query := fmt.Sprintf("SELECT COUNT(*) FROM e_newspapers WHERE created_at > current_date - interval '1 %s'", period)
 	err = database.QueryRow(query).Scan(&count)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -743,7 +668,7 @@ func getENewspaperList(w http.ResponseWriter, r *http.Request) {
 	// Parse the page number from the query parameters
 	pageStr, ok := r.URL.Query()["page"]
 	if !ok || len(pageStr[0]) < 1 {
-		log.Printf("%v: Url Param 'page' is missing. Setting default value to 1.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'page' is missing. Setting default value to 1.")
 		pageStr = []string{"1"}
 	}
 	page, _ := strconv.Atoi(pageStr[0])
@@ -751,7 +676,7 @@ func getENewspaperList(w http.ResponseWriter, r *http.Request) {
 	// Parse the limit from the query parameters
 	limitStr, ok := r.URL.Query()["limit"]
 	if !ok || len(limitStr[0]) < 1 {
-		log.Printf("%v: Url Param 'limit' is missing. Setting default value to 10.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'limit' is missing. Setting default value to 10.")
 		limitStr = []string{"10"}
 	}
 	limit, _ := strconv.Atoi(limitStr[0])
@@ -761,15 +686,14 @@ func getENewspaperList(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, title_cyrillic, created_at, updated_at, archived, completed, category FROM e_newspapers ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
+	rows, err := database.Query("SELECT id, title_latin, title_cyrillic, created_at, updated_at, archived, completed, category, publish_at, unpublish_at, status, slug FROM e_newspapers ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -778,9 +702,9 @@ func getENewspaperList(w http.ResponseWriter, r *http.Request) {
 	var eNewspapers []ENewspaper
 	for rows.Next() {
 		var eNewspaper ENewspaper
-		err := rows.Scan(&eNewspaper.ID, &eNewspaper.TitleLatin, &eNewspaper.TitleCyrillic, &eNewspaper.CreatedAt, &eNewspaper.UpdatedAt, &eNewspaper.Archived, &eNewspaper.Completed, &eNewspaper.Category)
+		err := rows.Scan(&eNewspaper.ID, &eNewspaper.TitleLatin, &eNewspaper.TitleCyrillic, &eNewspaper.CreatedAt, &eNewspaper.UpdatedAt, &eNewspaper.Archived, &eNewspaper.Completed, &eNewspaper.Category, &eNewspaper.PublishAt, &eNewspaper.UnpublishAt, &eNewspaper.Status, &eNewspaper.Slug)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -791,63 +715,18 @@ func getENewspaperList(w http.ResponseWriter, r *http.Request) {
 }
 
 type ENewspaper struct {
-	ID            int    `json:"id"`
-	TitleLatin    string `json:"title_latin"`
-	TitleCyrillic string `json:"title_cyrillic"`
-	CreatedAt     string `json:"created_at"`
-	UpdatedAt     string `json:"updated_at"`
-	Archived      bool   `json:"archived"`
-	Completed     bool   `json:"completed"`
-	Category      int    `json:"category"`
-}
-
-// eNewspaperCompleted is a handler to make e-newspaper completed field true/false
-func eNewspaperCompleted(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	exists, err := eNewspaperExists(id)
-	if err != nil {
-		log.Printf("%v: eNewspaperExists(id) error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	if !*exists {
-		log.Printf("%v: eNewspaperExists(id): %v", r.URL, *exists)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot update completed field of non existent e-newspaper")
-		return
-	}
-
-	archived, err := eNewspaperIsArchived(id)
-	if err != nil {
-		log.Printf("%v: eNewspaperIsArchived(id) error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	if *archived {
-		log.Printf("%v: eNewspaperIsArchived(id): %v", r.URL, *archived)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot update completed field of archived e-newspaper")
-		return
-	}
-
-	db, err := db.DB()
-	if err != nil {
-		log.Printf("%v: db error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-	defer db.Close()
-
-	_, err = db.Exec("UPDATE e_newspapers SET completed = NOT completed WHERE id = $1", id)
-	if err != nil {
-		log.Printf("%v: db error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	response.Res(w, "success", http.StatusOK, "completed field updated")
+	ID            int        `json:"id"`
+	TitleLatin    string     `json:"title_latin"`
+	TitleCyrillic string     `json:"title_cyrillic"`
+	CreatedAt     string     `json:"created_at"`
+	UpdatedAt     string     `json:"updated_at"`
+	Archived      bool       `json:"archived"`
+	Completed     bool       `json:"completed"`
+	Category      int        `json:"category"`
+	PublishAt     *time.Time `json:"publish_at"`
+	UnpublishAt   *time.Time `json:"unpublish_at"`
+	Status        string     `json:"status"`
+	Slug          *string    `json:"slug"`
 }
 
 // getENewspaperFile is a handler to get e-newspaper pdf latin or cyrillic file by id
@@ -863,48 +742,45 @@ func getENewspaperFile(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := eNewspaperExists(id)
 	if err != nil {
-		log.Printf("%v: eNewspaperExists(id) error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "eNewspaperExists(id) error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: eNewspaperExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "eNewspaperExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusNotFound, "e-newspaper not found")
 		return
 	}
 
 	db, err := db.DB()
 	if err != nil {
-		log.Printf("%v: db error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
-	var file []byte
+	var fileID sql.NullInt64
+	var title string
 	if alphabet == "latin" {
-		err = db.QueryRow("SELECT file_latin FROM e_newspapers WHERE id = $1", id).Scan(&file)
+		err = db.QueryRow("SELECT file_latin_id, title_latin FROM e_newspapers WHERE id = $1", id).Scan(&fileID, &title)
 		if err != nil {
-			log.Printf("%v: db error: %v", r.URL, err)
+			toolkit.LogErrorf(r, "db error: %v", err)
 			response.Res(w, "error", http.StatusBadRequest, err.Error())
 			return
 		}
 	}
 
 	if alphabet == "cyrillic" {
-		err = db.QueryRow("SELECT file_cyrillic FROM e_newspapers WHERE id = $1", id).Scan(&file)
+		err = db.QueryRow("SELECT file_cyrillic_id, title_cyrillic FROM e_newspapers WHERE id = $1", id).Scan(&fileID, &title)
 		if err != nil {
-			log.Printf("%v: db error: %v", r.URL, err)
+			toolkit.LogErrorf(r, "db error: %v", err)
 			response.Res(w, "error", http.StatusBadRequest, err.Error())
 			return
 		}
 	}
 
-	contentType := http.DetectContentType(file)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(file)))
-	w.Write(file)
+	media.ServePrivate(w, r, fileID, title)
 }
 
 // getENewspaperCoverImage is a handler to get e-newspaper cover image by id
@@ -914,35 +790,31 @@ func getENewspaperCoverImage(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := eNewspaperExists(id)
 	if err != nil {
-		log.Printf("%v: eNewspaperExists(id) error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "eNewspaperExists(id) error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: eNewspaperExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "eNewspaperExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusNotFound, "e-newspaper not found")
 		return
 	}
 
 	db, err := db.DB()
 	if err != nil {
-		log.Printf("%v: db error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
-	var coverImage []byte
-	err = db.QueryRow("SELECT cover_image FROM e_newspapers WHERE id = $1", id).Scan(&coverImage)
+	var coverImageID sql.NullInt64
+	err = db.QueryRow("SELECT cover_image_id FROM e_newspapers WHERE id = $1", id).Scan(&coverImageID)
 	if err != nil {
-		log.Printf("%v: db error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db error: %v", err)
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
-	contentType := http.DetectContentType(coverImage)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(coverImage)))
-	w.Write(coverImage)
+	media.ServePrivate(w, r, coverImageID, "e-newspaper-"+id+"-cover")
 }
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"Tahlilchi.uz/metrics"
 	"Tahlilchi.uz/model"
 	"Tahlilchi.uz/response"
 	"Tahlilchi.uz/toolkit"
@@ -93,6 +94,8 @@ func approveENewspaperComment(w http.ResponseWriter, r *http.Request) {
 		// return
 		return
 	}
+	metrics.CommentApprovals.Inc("e_newspaper")
+
 	// respond with the e-newspaper comment
 	response.Res(w, "success", http.StatusOK, "e-newspaper comment approved successfully")
 }
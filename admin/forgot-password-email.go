@@ -1,20 +1,30 @@
 package admin
 
 import (
+	"crypto/rand"
 	"database/sql"
-	"log"
-	"math/rand"
+	"math/big"
 	"net/http"
-	"net/smtp"
-	"os"
 	"strconv"
+	"time"
 
 	"Tahlilchi.uz/authPackage"
 	"Tahlilchi.uz/db"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/toolkit"
 	"github.com/gorilla/sessions"
+	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	// resetCodeTTL is how long an identification code can be used
+	resetCodeTTL = 15 * time.Minute
+	// resetCodeMaxAttempts is the number of wrong codes after which the code is invalidated
+	resetCodeMaxAttempts = 5
+)
+
+// forgotPasswordEmail sends an identification code to the email of the admin.
+// The response is the same whether the email is registered or not, so it cannot be used to find admin emails.
 func forgotPasswordEmail(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
@@ -28,38 +38,100 @@ func forgotPasswordEmail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the requests are counted for unknown emails as well, a 429 tells nothing about the email
+	if throttleResetCode(w, r, email) {
+		return
+	}
+
 	// Connect to the database
 	db, err := db.DB()
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
-	var exists bool
-	err = db.QueryRow("SELECT exists (SELECT 1 FROM public.admins WHERE email=$1)", email).Scan(&exists)
+	var adminID int
+	err = db.QueryRow("SELECT id FROM public.admins WHERE email = $1 AND NOT disabled", email).Scan(&adminID)
 	if err != nil && err != sql.ErrNoRows {
-		log.Println(err)
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	iCode, err := generate6drn()
+	if err != nil {
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	if exists {
-		iCode := generate6drn()
-		eSent := sendEmail("forgot-password", email, iCode)
-		if !eSent.Status {
-			response.Res(w, "error", http.StatusInternalServerError, eSent.Message)
+	// hash the code for unknown emails as well, so both cases take about the same time
+	codeHash, err := bcrypt.GenerateFromPassword([]byte(strconv.Itoa(iCode)), bcrypt.DefaultCost)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// adminID stays 0 if the email is not registered
+	if adminID != 0 {
+		tx, err := db.Begin()
+		if err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+		defer tx.Rollback()
+
+		// only the last requested code is valid
+		_, err = tx.Exec("DELETE FROM admin_password_resets WHERE admin_id = $1", adminID)
+		if err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+
+		_, err = tx.Exec("INSERT INTO admin_password_resets (admin_id, code_hash, expires_at) VALUES ($1, $2, $3)", adminID, string(codeHash), time.Now().Add(resetCodeTTL).UTC())
+		if err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 
-		session := saveIdentificationCode(r, email, iCode)
-		session.Save(r, w)
+		// send the email in the background, waiting for the smtp server would tell the email is registered
+		go func() {
+			if eSent := sendEmail("forgot-password", email, iCode); !eSent.Status {
+				toolkit.LogErrorf(r, "sending the code: %v", eSent.Message)
+			}
+		}()
+	}
 
-		response.Res(w, "success", http.StatusOK, "Code sent to the email")
+	session, err := saveIdentificationCode(r, email)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	if adminID != 0 {
+		err = session.Save(r, w)
 	} else {
-		response.Res(w, "error", http.StatusNotFound, "Email not found")
+		// nothing is stored for an unknown email, the client gets a cookie looking the same
+		err = authPackage.Store.SaveUnstored(w, session)
 	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, "If the email is registered, a code has been sent to it")
 }
 
 type emailStatus struct {
@@ -68,41 +140,48 @@ type emailStatus struct {
 }
 
 func sendEmail(event string, to string, code int) emailStatus {
-	if event == "forgot-password" {
-		// Set up authentication information.
-		auth := emailAuth()
-
-		// Connect to the server, authenticate, set the sender and recipient,
-		// and send the email all in one step.
-		msg := []byte("To: " + to + "\r\n" +
-			"Subject: Tahlilchi.uz administratori parolini unutgan vaqt uchun administrator identifikatsiya kodi | Tahlilchi.uz администратори паролини унутган вақт учун администратор идентификация коди\r\n" +
-			"\r\n" +
-			"Kod | Код: " + strconv.Itoa(code) + ". Iltimos, bu kodni hech kimga bermang. Aks holda, profilingiz xavfsizligi xavf ostida qoladi. | Илтимос, бу кодни ҳеч кимга берманг. Акс ҳолда, профилингиз хавфсизлиги хавф остида қолади." + "\r\n")
-		err := smtp.SendMail(os.Getenv("SMTPSERVER")+":"+os.Getenv("SMTPPORT"), auth, os.Getenv("EMAILFROM"), []string{to}, msg)
-		if err != nil {
-			log.Println(err)
-			return emailStatus{Status: false, Message: err.Error()}
-		}
+	var subject, body string
+	switch event {
+	case "forgot-password":
+		subject = "Tahlilchi.uz administratori parolini unutgan vaqt uchun administrator identifikatsiya kodi | Tahlilchi.uz администратори паролини унутган вақт учун администратор идентификация коди"
+		body = "Kod | Код: " + strconv.Itoa(code) + ". Iltimos, bu kodni hech kimga bermang. Aks holda, profilingiz xavfsizligi xavf ostida qoladi. | Илтимос, бу кодни ҳеч кимга берманг. Акс ҳолда, профилингиз хавфсизлиги хавф остида қолади."
+	case "password-changed":
+		subject = "Tahlilchi.uz administratori paroli o'zgartirildi | Tahlilchi.uz администратори пароли ўзгартирилди"
+		body = "Profilingiz paroli o'zgartirildi va barcha seanslar yopildi. Agar buni siz qilmagan bo'lsangiz, darhol bosh administratorga xabar bering. | Профилингиз пароли ўзгартирилди ва барча сеанслар ёпилди. Агар буни сиз қилмаган бўлсангиз, дарҳол бош администраторга хабар беринг."
+	default:
+		return emailStatus{Status: false, Message: "unknown event"}
+	}
 
-		return emailStatus{Status: true, Message: ""}
+	// the mailer is set up by AdminRouter
+	err := mailer.Send(to, subject, body)
+	if err != nil {
+		return emailStatus{Status: false, Message: err.Error()}
 	}
-	return emailStatus{Status: false, Message: "unknown event"}
-}
 
-func generate6drn() int {
-	return rand.Intn(900000) + 100000
+	return emailStatus{Status: true, Message: ""}
 }
 
-func emailAuth() smtp.Auth {
-	return smtp.PlainAuth("", os.Getenv("EMAILFROM"), os.Getenv("EMAILFROMPASSWORD"), os.Getenv("SMTPSERVER"))
+// generate6drn returns a random 6 digit number from crypto/rand
+func generate6drn() (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(900000))
+	if err != nil {
+		return 0, err
+	}
+	return int(n.Int64()) + 100000, nil
 }
 
-func saveIdentificationCode(r *http.Request, email string, iCode int) *sessions.Session {
+// saveIdentificationCode starts a new forgot-password session for email.
+// The code itself is kept hashed in the database only.
+func saveIdentificationCode(r *http.Request, email string) (*sessions.Session, error) {
 	session, _ := authPackage.Store.Get(r, "admin-forgot-password")
 
+	// a new session lives as long as the new code
+	if err := authPackage.Store.Renew(session); err != nil {
+		return nil, err
+	}
+
 	session.Options.HttpOnly = true
-	session.Options.MaxAge = 3600
-	session.Values["email"] = email
-	session.Values["i-code"] = iCode
-	return session
+	session.Options.MaxAge = int(resetCodeTTL.Seconds())
+	session.Values = map[interface{}]interface{}{"email": email}
+	return session, nil
 }
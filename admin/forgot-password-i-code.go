@@ -1,12 +1,15 @@
 package admin
 
 import (
-	"log"
+	"database/sql"
 	"net/http"
-	"strconv"
+	"time"
 
 	"Tahlilchi.uz/authPackage"
+	"Tahlilchi.uz/db"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/toolkit"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func forgotPasswordICode(w http.ResponseWriter, r *http.Request) {
@@ -25,7 +28,7 @@ func forgotPasswordICode(w http.ResponseWriter, r *http.Request) {
 	authentication := iCodeAuth(r, iCode)
 	if !authentication.status && authentication.message != "" {
 		if authentication.message == "Forbidden" {
-			response.Res(w, "error", http.StatusForbidden, authentication.message)
+			response.Res(w, "error", http.StatusForbidden, "invalid or expired code")
 			return
 		} else {
 			response.Res(w, "error", http.StatusInternalServerError, authentication.message)
@@ -35,25 +38,63 @@ func forgotPasswordICode(w http.ResponseWriter, r *http.Request) {
 
 	session, _ := authPackage.Store.Get(r, "admin-forgot-password")
 	session.Values["#i#-$code$-?authenticated?"] = true
-	session.Save(r, w)
+	err = session.Save(r, w)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
 
 	response.Res(w, "success", http.StatusOK, "i-code authenticated")
 }
 
+// iCodeAuth checks iCode against the active reset code of the session's email.
+// Every check counts as an attempt; the code is invalidated after resetCodeMaxAttempts of them.
 func iCodeAuth(r *http.Request, iCode string) iCodeAuthRT {
 	session, _ := authPackage.Store.Get(r, "admin-forgot-password")
 
-	iCodeI, err := strconv.Atoi(iCode)
+	email, ok := session.Values["email"].(string)
+	if !ok || email == "" {
+		return iCodeAuthRT{status: false, message: "Forbidden"}
+	}
+
+	database, err := db.DB()
 	if err != nil {
-		log.Println(err.Error())
-		return iCodeAuthRT{status: false, message: err.Error()}
+		toolkit.LogError(r, err)
+		return iCodeAuthRT{status: false, message: "server error"}
 	}
 
-	if siCode, ok := session.Values["i-code"].(int); siCode != iCodeI || !ok {
-		return iCodeAuthRT{
-			status:  false,
-			message: "Forbidden",
-		}
+	var id int64
+	var codeHash string
+	err = database.QueryRow("SELECT pr.id, pr.code_hash FROM admin_password_resets pr JOIN admins a ON a.id = pr.admin_id WHERE a.email = $1 AND pr.used_at IS NULL AND NOT pr.verified AND pr.expires_at > $2 ORDER BY pr.id DESC LIMIT 1",
+		email, time.Now().UTC()).Scan(&id, &codeHash)
+	if err == sql.ErrNoRows {
+		return iCodeAuthRT{status: false, message: "Forbidden"}
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		return iCodeAuthRT{status: false, message: "server error"}
+	}
+
+	// count the attempt before checking the code, so parallel guesses cannot go over the limit
+	res, err := database.Exec("UPDATE admin_password_resets SET attempts = attempts + 1 WHERE id = $1 AND attempts < $2", id, resetCodeMaxAttempts)
+	if err != nil {
+		toolkit.LogError(r, err)
+		return iCodeAuthRT{status: false, message: "server error"}
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return iCodeAuthRT{status: false, message: "Forbidden"}
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(codeHash), []byte(iCode)) != nil {
+		return iCodeAuthRT{status: false, message: "Forbidden"}
+	}
+
+	// the code cannot be checked again, the new-password step looks for the verified reset
+	_, err = database.Exec("UPDATE admin_password_resets SET verified = true WHERE id = $1", id)
+	if err != nil {
+		toolkit.LogError(r, err)
+		return iCodeAuthRT{status: false, message: "server error"}
 	}
 
 	return iCodeAuthRT{status: true, message: ""}
@@ -1,12 +1,13 @@
 package admin
 
 import (
-	"log"
 	"net/http"
+	"time"
 
 	"Tahlilchi.uz/authPackage"
 	"Tahlilchi.uz/db"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/toolkit"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -29,35 +30,75 @@ func forgotPasswordNewPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hash, _ := hashPassword(newPassword)
+	if msg := validatePassword(newPassword); msg != "" {
+		response.Res(w, "error", http.StatusBadRequest, msg)
+		return
+	}
+
+	hash, err := hashPassword(newPassword)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
 
 	db, err := db.DB()
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
-	// Update statement
-	stmt, err := db.Prepare("UPDATE public.admins SET password = $1 WHERE email = $2")
+	tx, err := db.Begin()
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
+	defer tx.Rollback()
 
-	session, _ := authPackage.Store.Get(r, "admin-forgot-password")
-	email := session.Values["email"].(string)
-	_, err = stmt.Exec(hash, email)
+	// Use up the verified code, a code sets a password only once
+	res, err := tx.Exec("UPDATE admin_password_resets pr SET used_at = $1 FROM admins a WHERE a.id = pr.admin_id AND a.email = $2 AND pr.verified AND pr.used_at IS NULL AND pr.expires_at > $1", time.Now().UTC(), authentication.email)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		response.Res(w, "error", http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	_, err = tx.Exec("UPDATE public.admins SET password = $1, must_change_password = false, updated_at = NOW() WHERE email = $2", hash, authentication.email)
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
+	err = tx.Commit()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// Log the admin out everywhere, whoever asked for the code may have been logged in as them
+	_, err = authPackage.RevokeAdminSessions(authentication.email, "")
+	if err != nil {
+		toolkit.LogError(r, err)
+	}
+
+	session, _ := authPackage.Store.Get(r, "admin-forgot-password")
 	session.Options.MaxAge = -1
 	session.Save(r, w)
+
+	go func() {
+		if eSent := sendEmail("password-changed", authentication.email, 0); !eSent.Status {
+			toolkit.LogErrorf(r, "sending the confirmation: %v", eSent.Message)
+		}
+	}()
+
 	response.Res(w, "success", http.StatusOK, "New password has been set")
 }
 
@@ -73,13 +114,20 @@ func auth(r *http.Request) authRT {
 		return authRT{status: false, message: "Forbidden"}
 	}
 
+	email, ok := session.Values["email"].(string)
+	if !ok || email == "" {
+		return authRT{status: false, message: "Forbidden"}
+	}
+
 	return authRT{
 		status:  true,
 		message: "",
+		email:   email,
 	}
 }
 
 type authRT struct {
 	status  bool
 	message string
+	email   string
 }
@@ -0,0 +1,258 @@
+package admin
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"Tahlilchi.uz/authPackage"
+	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/toolkit"
+	"github.com/lib/pq"
+)
+
+// Results of a login attempt as stored in admin_login_attempts.result
+const (
+	loginSuccess      = "success"
+	loginBadPassword  = "bad_password"
+	loginUnknownEmail = "unknown_email"
+	loginDisabled     = "disabled"
+	loginBad2FA       = "bad_2fa"
+	login2FAPending   = "2fa_pending"
+	loginThrottled    = "throttled"
+	// the forgot-password codes requested for the email
+	resetRequested = "reset_requested"
+	resetThrottled = "reset_throttled"
+)
+
+// loginThrottle are the limits of failed login attempts of one account or one IP address.
+// After free failures every further one doubles the wait before the next attempt, starting at one second;
+// after lockout failures no attempt is accepted for lockoutFor.
+type loginThrottle struct {
+	free       int
+	lockout    int
+	lockoutFor time.Duration
+}
+
+var (
+	accountThrottle = loginThrottle{free: 3, lockout: 10, lockoutFor: 15 * time.Minute}
+	// many admins may share the IP address of an office, so it has higher limits
+	ipThrottle = loginThrottle{free: 10, lockout: 50, lockoutFor: 15 * time.Minute}
+
+	// every new forgot-password code gets resetCodeMaxAttempts guesses,
+	// so the codes are limited tighter than the logins
+	resetAccountThrottle = loginThrottle{free: 2, lockout: 5, lockoutFor: time.Hour}
+	resetIPThrottle      = loginThrottle{free: 5, lockout: 20, lockoutFor: time.Hour}
+)
+
+// loginFailureWindow is how long a failed attempt counts
+const loginFailureWindow = time.Hour
+
+// maxLoginBackoff caps the exponential backoff below the lockout
+const maxLoginBackoff = 5 * time.Minute
+
+// wait returns how long to wait after failures failed attempts, the last one at last
+func (t loginThrottle) wait(failures int, last time.Time) time.Duration {
+	var d time.Duration
+	switch {
+	case failures >= t.lockout:
+		d = t.lockoutFor
+	case failures >= t.free:
+		d = time.Duration(math.Pow(2, float64(failures-t.free))) * time.Second
+		if d > maxLoginBackoff {
+			d = maxLoginBackoff
+		}
+	default:
+		return 0
+	}
+	return time.Until(last.Add(d))
+}
+
+// loginRetryAfter returns how long the client has to wait before it may try to log in as email again, 0 if it may try now.
+// Failures are counted per account since its last successful login and per IP address, within loginFailureWindow.
+func loginRetryAfter(r *http.Request, email string) (time.Duration, error) {
+	database, err := db.DB()
+	if err != nil {
+		return 0, err
+	}
+
+	since := time.Now().Add(-loginFailureWindow).UTC()
+	failed := []string{loginBadPassword, loginUnknownEmail, loginBad2FA}
+
+	var accountFailures int
+	var accountLast time.Time
+	err = database.QueryRow(`SELECT COUNT(*), COALESCE(MAX(created_at), $3) FROM admin_login_attempts
+		WHERE email = $1 AND result = ANY($2) AND created_at > GREATEST($3, (SELECT COALESCE(MAX(created_at), $3) FROM admin_login_attempts WHERE email = $1 AND result = $4))`,
+		email, pq.Array(failed), since, loginSuccess).Scan(&accountFailures, &accountLast)
+	if err != nil {
+		return 0, err
+	}
+
+	var ipFailures int
+	var ipLast time.Time
+	err = database.QueryRow("SELECT COUNT(*), COALESCE(MAX(created_at), $3) FROM admin_login_attempts WHERE ip = $1 AND result = ANY($2) AND created_at > $3",
+		toolkit.ClientIP(r), pq.Array(failed), since).Scan(&ipFailures, &ipLast)
+	if err != nil {
+		return 0, err
+	}
+
+	wait := accountThrottle.wait(accountFailures, accountLast)
+	if w := ipThrottle.wait(ipFailures, ipLast); w > wait {
+		wait = w
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, nil
+}
+
+// recordLoginAttempt is a function to store a login attempt with its result.
+// Errors are only logged, they must not change the response of the login.
+func recordLoginAttempt(r *http.Request, email, result string) {
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		return
+	}
+
+	_, err = database.Exec("INSERT INTO admin_login_attempts (email, ip, user_agent, result, created_at) VALUES ($1, $2, $3, $4, $5)",
+		email, toolkit.ClientIP(r), r.UserAgent(), result, time.Now().UTC())
+	if err != nil {
+		toolkit.LogError(r, err)
+	}
+}
+
+// throttleLogin writes a 429 response and returns true if the client has to wait before trying to log in as email
+func throttleLogin(w http.ResponseWriter, r *http.Request, email string) bool {
+	wait, err := loginRetryAfter(r, email)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return true
+	}
+	if wait <= 0 {
+		return false
+	}
+
+	recordLoginAttempt(r, email, loginThrottled)
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	response.Res(w, "error", http.StatusTooManyRequests, "too many failed login attempts, try again later")
+	return true
+}
+
+// resetRetryAfter returns how long the client has to wait before it may request a forgot-password code for email again,
+// 0 if it may request one now. The requests are counted per email and per IP address within loginFailureWindow.
+func resetRetryAfter(r *http.Request, email string) (time.Duration, error) {
+	database, err := db.DB()
+	if err != nil {
+		return 0, err
+	}
+
+	since := time.Now().Add(-loginFailureWindow).UTC()
+
+	var accountRequests int
+	var accountLast time.Time
+	err = database.QueryRow("SELECT COUNT(*), COALESCE(MAX(created_at), $3) FROM admin_login_attempts WHERE email = $1 AND result = $2 AND created_at > $3",
+		email, resetRequested, since).Scan(&accountRequests, &accountLast)
+	if err != nil {
+		return 0, err
+	}
+
+	var ipRequests int
+	var ipLast time.Time
+	err = database.QueryRow("SELECT COUNT(*), COALESCE(MAX(created_at), $3) FROM admin_login_attempts WHERE ip = $1 AND result = $2 AND created_at > $3",
+		toolkit.ClientIP(r), resetRequested, since).Scan(&ipRequests, &ipLast)
+	if err != nil {
+		return 0, err
+	}
+
+	wait := resetAccountThrottle.wait(accountRequests, accountLast)
+	if w := resetIPThrottle.wait(ipRequests, ipLast); w > wait {
+		wait = w
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, nil
+}
+
+// throttleResetCode writes a 429 response and returns true if the client has to wait before requesting a forgot-password code for email.
+// Otherwise the request is recorded and counts for the next ones.
+func throttleResetCode(w http.ResponseWriter, r *http.Request, email string) bool {
+	wait, err := resetRetryAfter(r, email)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return true
+	}
+	if wait > 0 {
+		recordLoginAttempt(r, email, resetThrottled)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		response.Res(w, "error", http.StatusTooManyRequests, "too many codes requested, try again later")
+		return true
+	}
+
+	recordLoginAttempt(r, email, resetRequested)
+	return false
+}
+
+// LoginAttempt is a struct to map a login attempt
+type LoginAttempt struct {
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	Result    string `json:"result"`
+	CreatedAt string `json:"created_at"`
+}
+
+// getLoginActivity is a route handler function to get the recent login attempts of the logged in admin's account
+func getLoginActivity(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := toolkit.GetPageLimit(r)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusBadRequest, "invalid page or limit")
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	rows, err := database.Query("SELECT ip, user_agent, result, created_at FROM admin_login_attempts WHERE email = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3",
+		authPackage.AdminEmail(r), limit, (page-1)*limit)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	defer rows.Close()
+
+	list := []LoginAttempt{}
+	for rows.Next() {
+		var a LoginAttempt
+		if err := rows.Scan(&a.IP, &a.UserAgent, &a.Result, &a.CreatedAt); err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+		list = append(list, a)
+	}
+
+	response.Res(w, "success", http.StatusOK, list)
+}
+
+// DeleteOldLoginAttempts removes the login attempts older than 90 days.
+// It is run by the scheduler.
+func DeleteOldLoginAttempts() error {
+	database, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	_, err = database.Exec("DELETE FROM admin_login_attempts WHERE created_at < $1", time.Now().AddDate(0, 0, -90).UTC())
+	return err
+}
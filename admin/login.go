@@ -1,18 +1,26 @@
 package admin
 
 import (
-	"fmt"
-	"log"
+	"database/sql"
 	"net/http"
+	"time"
 
 	"Tahlilchi.uz/authPackage"
 	"Tahlilchi.uz/db"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/toolkit"
 	"golang.org/x/crypto/bcrypt"
 
 	_ "github.com/lib/pq"
 )
 
+// dummyPasswordHash is a bcrypt hash of a random password with the cost of hashPassword,
+// checked for unknown emails
+const dummyPasswordHash = "$2a$14$AICUQ4GCgNzneJ6Gi91g2eqrqWi.tOf.akddyenznp485dEsjseOu"
+
+// twoFactorLoginTTL is how long the second login step can be completed after the password was checked
+const twoFactorLoginTTL = 5 * time.Minute
+
 func login(w http.ResponseWriter, r *http.Request) {
 	session, _ := authPackage.Store.Get(r, "Tahlilchi.uz-admin")
 
@@ -25,38 +33,80 @@ func login(w http.ResponseWriter, r *http.Request) {
 	email := r.FormValue("email")
 	password := r.FormValue("password")
 
-	// Connect to the database
-	db, err := db.DB()
-	if err != nil {
-		log.Println(err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
+	// Slow down password guessing: location: admin/login-attempts.go
+	if throttleLogin(w, r, email) {
 		return
 	}
-	defer db.Close()
 
 	// Query the database
-	var dbName, dbEmail, dbRole, dbPassword string
-	err = db.QueryRow("SELECT name, email, role, password FROM public.admins WHERE email = $1", email).Scan(&dbName, &dbEmail, &dbRole, &dbPassword)
+	a, err := getLoginAdmin(email)
+	if err == sql.ErrNoRows {
+		// check the password anyway, so an unknown email takes as long as a wrong password
+		checkPasswordHash(password, dummyPasswordHash)
+		recordLoginAttempt(r, email, loginUnknownEmail)
+		response.Res(w, "error", http.StatusUnauthorized, "Invalid login credentials")
+		return
+	}
 	if err != nil {
-		fmt.Println(err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	// Check password
-	authenticated := checkPasswordHash(password, dbPassword)
+	authenticated := checkPasswordHash(password, a.password)
 	if !authenticated {
+		recordLoginAttempt(r, email, loginBadPassword)
 		response.Res(w, "error", http.StatusUnauthorized, "Invalid login credentials")
 		return
 	}
 
-	// Set admin as authenticated
+	if a.Disabled {
+		recordLoginAttempt(r, email, loginDisabled)
+		response.Res(w, "error", http.StatusForbidden, "account disabled")
+		return
+	}
+
+	// Give the session a new id, so an id known before the login cannot be used after it
+	err = authPackage.Store.Renew(session)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
 	session.Options.HttpOnly = true
 	session.Options.MaxAge = 3600 * 24
+
+	// With two-factor authentication the session is authenticated by the second step: location: admin/two-factor.go
+	if a.TwoFactorEnabled {
+		session.Values = map[interface{}]interface{}{
+			"2fa-email":    a.Email,
+			"2fa-until":    time.Now().Add(twoFactorLoginTTL).Unix(),
+			"2fa-attempts": 0,
+		}
+		err = session.Save(r, w)
+		if err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+		recordLoginAttempt(r, email, login2FAPending)
+		response.Res(w, "success", http.StatusOK, twoFactorChallenge{TwoFactorRequired: true})
+		return
+	}
+
+	// Set admin as authenticated
 	session.Values["#Tahlilchi.uz#-$admin$-?authenticated?"] = true
 	session.Values["email"] = email
-	session.Save(r, w)
-	response.Res(w, "success", http.StatusOK, admin{Name: dbName, Email: dbEmail, Role: dbRole})
+	err = session.Save(r, w)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	recordLoginAttempt(r, email, loginSuccess)
+	response.Res(w, "success", http.StatusOK, a.admin)
 }
 
 func checkPasswordHash(password, hash string) bool {
@@ -65,9 +115,45 @@ func checkPasswordHash(password, hash string) bool {
 }
 
 type admin struct {
-	Name  string `json:"name"`
-	Email string `json:"email"`
-	Role  string `json:"role"`
+	Name               string   `json:"name"`
+	Email              string   `json:"email"`
+	Role               string   `json:"role"`
+	Permissions        []string `json:"permissions"`
+	MustChangePassword bool     `json:"must_change_password"`
+	TwoFactorEnabled   bool     `json:"two_factor_enabled"`
+	TwoFactorRequired  bool     `json:"two_factor_required"`
+}
+
+// twoFactorChallenge is the login response when the code of the second step is needed
+type twoFactorChallenge struct {
+	TwoFactorRequired bool `json:"two_factor_required"`
+}
+
+// loginAdmin is an admin account as read on login
+type loginAdmin struct {
+	admin
+	id         int
+	password   string
+	totpSecret string
+	Disabled   bool
+}
+
+// getLoginAdmin is a function to read the admin account with the given email for the login
+func getLoginAdmin(email string) (loginAdmin, error) {
+	// Connect to the database
+	db, err := db.DB()
+	if err != nil {
+		return loginAdmin{}, err
+	}
+
+	var a loginAdmin
+	err = db.QueryRow("SELECT a.id, a.name, a.email, a.role, a.password, a.disabled, a.must_change_password, a.totp_enabled, COALESCE(a.totp_secret, ''), COALESCE(rs.require_2fa, false) FROM public.admins a LEFT JOIN role_settings rs ON rs.role = a.role WHERE a.email = $1", email).
+		Scan(&a.id, &a.Name, &a.Email, &a.Role, &a.password, &a.Disabled, &a.MustChangePassword, &a.TwoFactorEnabled, &a.totpSecret, &a.TwoFactorRequired)
+	if err != nil {
+		return loginAdmin{}, err
+	}
+	a.Permissions = authPackage.Permissions(a.Role)
+	return a, nil
 }
 
 // logout logs the admin out
@@ -75,8 +161,13 @@ func logout(w http.ResponseWriter, r *http.Request) {
 	// Get a session
 	session, _ := authPackage.Store.Get(r, "Tahlilchi.uz-admin")
 	// Revoke users authentication
-	// Delete the session
+	// Delete the session, the stored one as well, so a copy of the cookie is no longer valid
 	session.Options.MaxAge = -1
-	session.Save(r, w)
+	err := session.Save(r, w)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
 	response.Res(w, "success", http.StatusOK, "Logged out")
 }
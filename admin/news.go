@@ -3,18 +3,21 @@ package admin
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"Tahlilchi.uz/authPackage"
 	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/media"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/slug"
 	"Tahlilchi.uz/toolkit"
+	"Tahlilchi.uz/upload"
 	"github.com/gorilla/mux"
 	"github.com/lib/pq"
 )
@@ -38,15 +41,14 @@ type CategoryForGet struct {
 func getCategoryList(w http.ResponseWriter, r *http.Request) {
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	rows, err := database.Query("SELECT * FROM news_category ORDER BY id")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -57,14 +59,14 @@ func getCategoryList(w http.ResponseWriter, r *http.Request) {
 		var c CategoryForGet
 		err := rows.Scan(&c.ID, &c.TitleLatin, &c.DescriptionLatin, &c.TitleCyrillic, &c.DescriptionCyrillic)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 
 		subRows, err := database.Query("SELECT * FROM news_subcategory WHERE category_id = $1", c.ID)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -74,7 +76,7 @@ func getCategoryList(w http.ResponseWriter, r *http.Request) {
 			var s SubcategoryForGet
 			err := subRows.Scan(&s.ID, &s.CategoryID, &s.TitleLatin, &s.DescriptionLatin, &s.TitleCyrillic, &s.DescriptionCyrillic)
 			if err != nil {
-				log.Printf("%v: error: %v", r.URL, err)
+				toolkit.LogError(r, err)
 				response.Res(w, "error", http.StatusInternalServerError, "server error")
 				return
 			}
@@ -90,15 +92,14 @@ func getCategoryList(w http.ResponseWriter, r *http.Request) {
 func getSubCategoryList(w http.ResponseWriter, r *http.Request) {
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	rows, err := database.Query("SELECT * FROM news_subcategory ORDER BY id")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -109,7 +110,7 @@ func getSubCategoryList(w http.ResponseWriter, r *http.Request) {
 		var c SubcategoryForGet
 		err := rows.Scan(&c.ID, &c.CategoryID, &c.TitleLatin, &c.DescriptionLatin, &c.TitleCyrillic, &c.DescriptionCyrillic)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -160,8 +161,6 @@ func updateSubCategory(w http.ResponseWriter, r *http.Request) {
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	// defer the close of the database connection
-	defer database.Close()
 
 	// title_latin
 	if subcategory.TitleLatin != "" {
@@ -245,8 +244,6 @@ func deleteSubCategory(w http.ResponseWriter, r *http.Request) {
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	// defer the close of the database connection
-	defer database.Close()
 
 	// delete the subcategory
 	_, err = database.Exec("DELETE FROM news_subcategory WHERE id = $1 AND category_id = $2", id, category_id)
@@ -269,15 +266,14 @@ func getSubCategoryListByCategory(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	rows, err := database.Query("SELECT * FROM news_subcategory WHERE category_id = $1 ORDER BY id", categoryID)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -288,7 +284,7 @@ func getSubCategoryListByCategory(w http.ResponseWriter, r *http.Request) {
 		var c SubcategoryForGet
 		err := rows.Scan(&c.ID, &c.CategoryID, &c.TitleLatin, &c.DescriptionLatin, &c.TitleCyrillic, &c.DescriptionCyrillic)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -310,11 +306,10 @@ type SubcategoryForGet struct {
 func addCategory(w http.ResponseWriter, r *http.Request) {
 	db, err := db.DB()
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
 	var c Category
 	c.TitleLatin = r.FormValue("title_latin")
@@ -334,7 +329,7 @@ func addCategory(w http.ResponseWriter, r *http.Request) {
 
 	_, err = db.Exec("INSERT INTO news_category(title_latin, description_latin, title_cyrillic, description_cyrillic) VALUES($1, $2, $3, $4)", c.TitleLatin, c.DescriptionLatin, c.TitleCyrillic, c.DescriptionCyrillic)
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -355,7 +350,7 @@ func addSubcategory(w http.ResponseWriter, r *http.Request) {
 	var s Subcategory
 	err := json.NewDecoder(r.Body).Decode(&s)
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
@@ -368,15 +363,14 @@ func addSubcategory(w http.ResponseWriter, r *http.Request) {
 
 	db, err := db.DB()
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
 	_, err = db.Exec("INSERT INTO news_subcategory (category_id, title_latin, description_latin, title_cyrillic, description_cyrillic) VALUES ($1, $2, $3, $4, $5)", s.CategoryID, s.TitleLatin, s.DescriptionLatin, s.TitleCyrillic, s.DescriptionCyrillic)
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -397,18 +391,17 @@ func addRegions(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&regions)
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
 	db, err := db.DB()
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
 	for _, region := range regions {
 		if region.NameLatin == "" || region.NameCyrillic == "" {
@@ -418,7 +411,7 @@ func addRegions(w http.ResponseWriter, r *http.Request) {
 
 		_, err = db.Exec("INSERT INTO news_regions (name_latin, description_latin, name_cyrillic, description_cyrillic) VALUES ($1, $2, $3, $4)", region.NameLatin, region.DescriptionLatin, region.NameCyrillic, region.DescriptionCyrillic)
 		if err != nil {
-			log.Println(err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -429,14 +422,27 @@ func addRegions(w http.ResponseWriter, r *http.Request) {
 
 var re = regexp.MustCompile(`^(true|false)$`)
 
+// maxNewsPostRequest limits the request of a news post with a photo, an audio and a cover image
+const maxNewsPostRequest = 150 << 20
+
 func addNewsPost(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(1 << 30) // Max memory 1GB
+	err := upload.ParseForm(w, r, maxNewsPostRequest)
 	if err != nil {
-		log.Println(err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		upload.Fail(w, r, err)
+		return
+	}
+
+	// publishing window
+	window, err := parsePublishWindow(r)
+	if err != nil {
+		toolkit.LogInfo(r, err.Error())
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
+	// fill a missing alphabet from the other one on request
+	autofill(r, titleFields, descriptionFields)
+
 	title_latin := r.FormValue("title_latin")
 	description_latin := r.FormValue("description_latin")
 	title_cyrillic := r.FormValue("title_cyrillic")
@@ -448,31 +454,17 @@ func addNewsPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	photo, _, err := r.FormFile("photo")
-	var photoForDB []byte
-	if err != nil && err != http.ErrMissingFile {
-		log.Printf("photo: %v", err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
+	photoForDB, err := upload.FormFile(r, "photo", upload.Image)
+	if err != nil {
+		upload.Fail(w, r, err)
 		return
-	} else if err == http.ErrMissingFile {
-		photoForDB = nil
-	} else {
-		photoForDB, _ = io.ReadAll(photo)
-		photo.Close()
-		// check file type
-		filetype := http.DetectContentType(photoForDB)
-		if !strings.HasPrefix(filetype, "image/") {
-			log.Printf("%v: photo is not an image file", r.URL)
-			response.Res(w, "error", http.StatusBadRequest, "photo is not an image file")
-			return
-		}
 	}
 
 	video := r.FormValue("video")
 	/*
 		var videoForDB []byte
 		if err != nil && err != http.ErrMissingFile {
-			log.Printf("video: %v", err)
+			toolkit.LogErrorf(r, "video: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 			} else if err == http.ErrMissingFile {
@@ -487,45 +479,16 @@ func addNewsPost(w http.ResponseWriter, r *http.Request) {
 				}
 	*/
 
-	audio, _, err := r.FormFile("audio")
-	var audioForDB []byte
-	if err != nil && err != http.ErrMissingFile {
-		log.Printf("audio: %v", err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	} else if err == http.ErrMissingFile {
-		audioForDB = nil
-	} else {
-		audioForDB, _ = io.ReadAll(audio)
-		audio.Close()
-		// Check file type
-		filetype := http.DetectContentType(audioForDB)
-		if !strings.HasPrefix(filetype, "audio/") {
-			log.Printf("%v: audio is not an audio file", r.URL)
-			response.Res(w, "error", http.StatusBadRequest, "audio is not an audio file")
-			return
-		}
+	audioForDB, err := upload.FormFile(r, "audio", upload.Audio)
+	if err != nil {
+		upload.Fail(w, r, err)
+		return
 	}
 
-	cover_image, _, err := r.FormFile("cover_image")
-	var coverImageForDB []byte
-	if err != nil && err != http.ErrMissingFile {
-		log.Printf("cover_image: %v", err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
+	coverImageForDB, err := upload.FormFile(r, "cover_image", upload.Image)
+	if err != nil {
+		upload.Fail(w, r, err)
 		return
-	} else if err == http.ErrMissingFile {
-		coverImageForDB = nil
-	} else {
-		coverImageForDB, _ = io.ReadAll(cover_image)
-		cover_image.Close()
-
-		// check file type
-		filetype := http.DetectContentType(coverImageForDB)
-		if !strings.HasPrefix(filetype, "image/") {
-			log.Printf("%v: cover_image is not an image file", r.URL)
-			response.Res(w, "error", http.StatusBadRequest, "cover_image is not an image file")
-			return
-		}
 	}
 
 	// Get tags if they exist
@@ -539,7 +502,7 @@ func addNewsPost(w http.ResponseWriter, r *http.Request) {
 	if category := r.FormValue("category"); category != "" {
 		categoryInt.Int64, err = strconv.ParseInt(category, 10, 64)
 		if err != nil {
-			log.Println(err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -550,7 +513,7 @@ func addNewsPost(w http.ResponseWriter, r *http.Request) {
 	if subcategory := r.FormValue("subcategory"); subcategory != "" {
 		subcategoryInt.Int64, err = strconv.ParseInt(subcategory, 10, 64)
 		if err != nil {
-			log.Println(err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -561,7 +524,7 @@ func addNewsPost(w http.ResponseWriter, r *http.Request) {
 	if region := r.FormValue("region"); region != "" {
 		regionInt.Int64, err = strconv.ParseInt(region, 10, 64)
 		if err != nil {
-			log.Println(err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -577,7 +540,7 @@ func addNewsPost(w http.ResponseWriter, r *http.Request) {
 
 		topBool.Bool, err = strconv.ParseBool(top)
 		if err != nil {
-			log.Println(err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -593,7 +556,7 @@ func addNewsPost(w http.ResponseWriter, r *http.Request) {
 
 		latestBool.Bool, err = strconv.ParseBool(latest)
 		if err != nil {
-			log.Println(err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -604,7 +567,7 @@ func addNewsPost(w http.ResponseWriter, r *http.Request) {
 	if related := r.FormValue("related"); related != "" {
 		relatedInt.Int64, err = strconv.ParseInt(related, 10, 64)
 		if err != nil {
-			log.Println(err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -613,16 +576,69 @@ func addNewsPost(w http.ResponseWriter, r *http.Request) {
 
 	db, err := db.DB()
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	photoID, err := media.SaveFileNullable(r.Context(), photoForDB, "photo")
+	if err != nil {
+		toolkit.LogErrorf(r, "saving photo: %v", err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	audioID, err := media.SaveFileNullable(r.Context(), audioForDB, "audio")
+	if err != nil {
+		toolkit.LogErrorf(r, "saving audio: %v", err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	coverImageID, err := media.SaveFileNullable(r.Context(), coverImageForDB, "cover_image")
+	if err != nil {
+		toolkit.LogErrorf(r, "saving cover_image: %v", err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// Start a new transaction, the post and its first revision are written together
+	tx, err := db.Begin()
+	if err != nil {
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
+	defer tx.Rollback()
 
-	_, err = db.Exec(`INSERT INTO news_posts (title_latin, description_latin, title_cyrillic, description_cyrillic, photo, video, audio, cover_image, tags, category, subcategory, region, top, latest, related) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14 , $15)`,
-		title_latin, description_latin, title_cyrillic, description_cyrillic, photoForDB, video, audioForDB, coverImageForDB, pq.Array(tags), categoryInt, subcategoryInt, regionInt, topBool, latestBool, relatedInt)
+	var id int64
+	err = tx.QueryRow(`INSERT INTO news_posts (title_latin, description_latin, title_cyrillic, description_cyrillic, photo_id, video, audio_id, cover_image_id, tags, category, subcategory, region, top, latest, related, publish_at, unpublish_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14 , $15, $16, $17) RETURNING id`,
+		title_latin, description_latin, title_cyrillic, description_cyrillic, photoID, video, audioID, coverImageID, pq.Array(tags), categoryInt, subcategoryInt, regionInt, topBool, latestBool, relatedInt, window.PublishAt, window.UnpublishAt).Scan(&id)
 	if err != nil {
-		log.Println(err, categoryInt)
+		toolkit.LogErrorf(r, "%v (category %v)", err, categoryInt)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// the first revision
+	err = newsPostRevisions.save(tx, id, authPackage.AdminEmail(r))
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// Commit the transaction
+	err = tx.Commit()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	err = slug.NewsPost.Assign(db, id)
+	if err != nil {
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -636,7 +652,6 @@ func exists(id string) (*bool, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
 	var exists bool
 	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM news_posts WHERE id=$1)", id).Scan(&exists)
@@ -653,7 +668,6 @@ func isArchived(id string) (*bool, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
 	// Prepare the SQL statement
 	stmt, err := db.Prepare("SELECT archived FROM news_posts WHERE id = $1")
@@ -678,45 +692,80 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := exists(id)
 	if err != nil {
-		log.Printf("%v: edit news post exists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "edit news post exists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: edit news post exists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "edit news post exists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot edit non existent news post")
 		return
 	}
 
 	archived, err := isArchived(id)
 	if err != nil {
-		log.Printf("%v: edit news post isArchived(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "edit news post isArchived(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if *archived {
-		log.Printf("%v: edit news post isArchived(id): %v", r.URL, *archived)
+		toolkit.LogInfof(r, "edit news post isArchived(id): %v", *archived)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot edit archived news post")
 		return
 	}
 
 	// Parse multipart form
-	err = r.ParseMultipartForm(1 << 30) // 1GB = 1 << 30 bytes
+	err = upload.ParseForm(w, r, maxNewsPostRequest)
+	if err != nil {
+		upload.Fail(w, r, err)
+		return
+	}
+
+	// publishing window
+	window, err := parsePublishWindow(r)
 	if err != nil {
-		log.Printf("%v: edit news post: %v", r.URL, err)
+		toolkit.LogInfo(r, err.Error())
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
 	db, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error while connecting to db: %v", r.URL, err)
+		toolkit.LogErrorf(r, "error while connecting to db: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
+
+	if !newsPostWorkflow.editable(w, r, db, id) {
+		return
+	}
+
+	// the window with the stored times in place of the fields which were not sent
+	err = window.check(db, "news_posts", id)
+	if errors.Is(err, errPublishWindow) {
+		toolkit.LogInfo(r, err.Error())
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// fill a missing alphabet from the other one on request
+	autofill(r, titleFields, descriptionFields)
+
+	// Start a new transaction, the fields and their revision are written together and a failed edit changes nothing
+	tx, err := db.Begin()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	defer tx.Rollback()
 
 	title_latin := r.FormValue("title_latin")
 	if title_latin != "" {
@@ -725,9 +774,9 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 			SET title_latin = $1, updated_at = NOW() 
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, title_latin, id)
+		_, err = tx.Exec(sqlStatement, title_latin, id)
 		if err != nil {
-			log.Printf("%v: writing title_latin into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing title_latin into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -740,9 +789,9 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 			SET description_latin = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, description_latin, id)
+		_, err = tx.Exec(sqlStatement, description_latin, id)
 		if err != nil {
-			log.Printf("%v: writing description_latin into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing description_latin into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -755,9 +804,9 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 			SET title_cyrillic = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, title_cyrillic, id)
+		_, err = tx.Exec(sqlStatement, title_cyrillic, id)
 		if err != nil {
-			log.Printf("%v: writing title_cyrillic into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing title_cyrillic into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -770,40 +819,36 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 			SET description_cyrillic = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, description_cyrillic, id)
+		_, err = tx.Exec(sqlStatement, description_cyrillic, id)
 		if err != nil {
-			log.Printf("%v: writing description_cyrillic into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing description_cyrillic into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 	}
 
-	photo, _, err := r.FormFile("photo")
-	if err != nil && err != http.ErrMissingFile {
-		log.Printf("photo: %v", err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
+	photoForDB, err := upload.FormFile(r, "photo", upload.Image)
+	if err != nil {
+		upload.Fail(w, r, err)
 		return
-	} else if err == http.ErrMissingFile {
-	} else {
-		photoForDB, _ := io.ReadAll(photo)
-		photo.Close()
+	}
+	if photoForDB != nil {
 
-		// check file type
-		filetype := http.DetectContentType(photoForDB)
-		if !strings.HasPrefix(filetype, "image/") {
-			log.Printf("%v: photo is not an image file", r.URL)
-			response.Res(w, "error", http.StatusBadRequest, "photo is not an image file")
+		photoID, err := media.SaveFile(r.Context(), photoForDB, "photo")
+		if err != nil {
+			toolkit.LogErrorf(r, "saving photo: %v", err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 
 		sqlStatement := `
 			UPDATE news_posts
-			SET photo = $1, updated_at = NOW()
+			SET photo_id = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, photoForDB, id)
+		_, err = tx.Exec(sqlStatement, photoID, id)
 		if err != nil {
-			log.Printf("%v: writing photo into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing photo into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -816,16 +861,16 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 			SET video = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, video, id)
+		_, err = tx.Exec(sqlStatement, video, id)
 		if err != nil {
-			log.Printf("%v: writing video into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing video into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 	}
 	/*
 		if err != nil && err != http.ErrMissingFile {
-			log.Printf("video: %v", err)
+			toolkit.LogErrorf(r, "video: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		} else if err == http.ErrMissingFile {
@@ -841,72 +886,64 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 				SET video = $1, updated_at = NOW()
 				WHERE id = $2;
 			`
-			_, err = db.Exec(sqlStatement, videoForDB, id)
+			_, err = tx.Exec(sqlStatement, videoForDB, id)
 			if err != nil {
-				log.Printf("%v: writing video into db: %v", r.URL, err)
+				toolkit.LogErrorf(r, "writing video into db: %v", err)
 				response.Res(w, "error", http.StatusInternalServerError, "server error")
 				return
 			}
 		}
 	*/
 
-	audio, _, err := r.FormFile("audio")
-	if err != nil && err != http.ErrMissingFile {
-		log.Printf("audio: %v", err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
+	audioForDB, err := upload.FormFile(r, "audio", upload.Audio)
+	if err != nil {
+		upload.Fail(w, r, err)
 		return
-	} else if err == http.ErrMissingFile {
-	} else {
-		audioForDB, _ := io.ReadAll(audio)
-		audio.Close()
+	}
+	if audioForDB != nil {
 
-		// Check file type
-		filetype := http.DetectContentType(audioForDB)
-		if !strings.HasPrefix(filetype, "audio/") {
-			log.Printf("%v: audio is not an audio file", r.URL)
-			response.Res(w, "error", http.StatusBadRequest, "audio is not an audio file")
+		audioID, err := media.SaveFile(r.Context(), audioForDB, "audio")
+		if err != nil {
+			toolkit.LogErrorf(r, "saving audio: %v", err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 
 		sqlStatement := `
 			UPDATE news_posts
-			SET audio = $1, updated_at = NOW()
+			SET audio_id = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, audioForDB, id)
+		_, err = tx.Exec(sqlStatement, audioID, id)
 		if err != nil {
-			log.Printf("%v: writing audio into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing audio into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 	}
 
-	cover_image, _, err := r.FormFile("cover_image")
-	if err != nil && err != http.ErrMissingFile {
-		log.Printf("cover_image: %v", err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
+	coverImageForDB, err := upload.FormFile(r, "cover_image", upload.Image)
+	if err != nil {
+		upload.Fail(w, r, err)
 		return
-	} else if err == http.ErrMissingFile {
-	} else {
-		coverImageForDB, _ := io.ReadAll(cover_image)
-		cover_image.Close()
+	}
+	if coverImageForDB != nil {
 
-		// check file type
-		filetype := http.DetectContentType(coverImageForDB)
-		if !strings.HasPrefix(filetype, "image/") {
-			log.Printf("%v: cover_image is not an image file", r.URL)
-			response.Res(w, "error", http.StatusBadRequest, "cover_image is not an image file")
+		coverImageID, err := media.SaveFile(r.Context(), coverImageForDB, "cover_image")
+		if err != nil {
+			toolkit.LogErrorf(r, "saving cover_image: %v", err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 
 		sqlStatement := `
 			UPDATE news_posts
-			SET cover_image = $1, updated_at = NOW()
+			SET cover_image_id = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, coverImageForDB, id)
+		_, err = tx.Exec(sqlStatement, coverImageID, id)
 		if err != nil {
-			log.Printf("%v: writing cover_image into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing cover_image into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -918,9 +955,9 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 			SET tags = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, pq.Array(tags), id)
+		_, err = tx.Exec(sqlStatement, pq.Array(tags), id)
 		if err != nil {
-			log.Printf("%v: writing tags into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing tags into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -929,7 +966,7 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 	if category := r.FormValue("category"); category != "" {
 		categoryInt, err := strconv.ParseInt(category, 10, 64)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -938,9 +975,9 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 			SET category = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, categoryInt, id)
+		_, err = tx.Exec(sqlStatement, categoryInt, id)
 		if err != nil {
-			log.Printf("%v: writing category into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing category into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -949,7 +986,7 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 	if subcategory := r.FormValue("subcategory"); subcategory != "" {
 		subcategoryInt, err := strconv.ParseInt(subcategory, 10, 64)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -958,9 +995,9 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 			SET subcategory = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, subcategoryInt, id)
+		_, err = tx.Exec(sqlStatement, subcategoryInt, id)
 		if err != nil {
-			log.Printf("%v: writing subcategory into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing subcategory into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -969,7 +1006,7 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 	if region := r.FormValue("region"); region != "" {
 		regionInt, err := strconv.ParseInt(region, 10, 64)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -978,9 +1015,9 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 			SET region = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, regionInt, id)
+		_, err = tx.Exec(sqlStatement, regionInt, id)
 		if err != nil {
-			log.Printf("%v: writing region into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing region into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -994,7 +1031,7 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 
 		topBool, err := strconv.ParseBool(top)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -1003,9 +1040,9 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 			SET top = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, topBool, id)
+		_, err = tx.Exec(sqlStatement, topBool, id)
 		if err != nil {
-			log.Printf("%v: writing top into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing top into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -1019,7 +1056,7 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 
 		latestBool, err := strconv.ParseBool(latest)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -1028,9 +1065,9 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 			SET latest = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, latestBool, id)
+		_, err = tx.Exec(sqlStatement, latestBool, id)
 		if err != nil {
-			log.Printf("%v: writing latest into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing latest into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -1039,7 +1076,7 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 	if related := r.FormValue("related"); related != "" {
 		relatedInt, err := strconv.ParseInt(related, 10, 64)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -1048,175 +1085,123 @@ func editNewsPost(w http.ResponseWriter, r *http.Request) {
 			SET related = $1, updated_at = NOW()
 			WHERE id = $2;
 		`
-		_, err = db.Exec(sqlStatement, relatedInt, id)
+		_, err = tx.Exec(sqlStatement, relatedInt, id)
 		if err != nil {
-			log.Printf("%v: writing related into db: %v", r.URL, err)
+			toolkit.LogErrorf(r, "writing related into db: %v", err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 	}
 
-	response.Res(w, "success", http.StatusOK, "News post edited")
-}
-
-func deleteNewsPost(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	id := params["id"]
-
-	exists, err := exists(id)
+	err = window.update(tx, "news_posts", id)
 	if err != nil {
-		log.Printf("%v: delete news post exists(id): %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	if !*exists {
-		log.Printf("%v: delete news post exists(id): %v", r.URL, *exists)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot delete non existent news post")
-		return
-	}
-
-	archived, err := isArchived(id)
-	if err != nil {
-		log.Printf("%v: delete news post isArchived(id): %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	if *archived {
-		log.Printf("%v: delete news post isArchived(id): %v", r.URL, *archived)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot delete archived news post")
-		return
-	}
-
-	db, err := db.DB()
+	err = newsPostRevisions.save(tx, id, authPackage.AdminEmail(r))
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
-	// first delete news post comments: table news_post_comments, fk news_post
-	_, err = db.Exec("DELETE FROM news_post_comments WHERE news_post = $1", id)
+	// Commit the transaction
+	err = tx.Commit()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	// then delete news post
-	stmt, err := db.Prepare("DELETE FROM news_posts WHERE id=$1")
-	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	_, err = stmt.Exec(id)
+	err = slug.NewsPost.Assign(db, id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	response.Res(w, "success", http.StatusOK, "deleted")
+	response.Res(w, "success", http.StatusOK, "News post edited")
 }
 
-func archiveNewsPost(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+func deleteNewsPost(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id := params["id"]
 
 	exists, err := exists(id)
 	if err != nil {
-		log.Printf("%v: archive news post exists(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "delete news post exists(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: archive news post exists(id): %v", r.URL, *exists)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot archive non existent news post")
+		toolkit.LogInfof(r, "delete news post exists(id): %v", *exists)
+		response.Res(w, "error", http.StatusBadRequest, "Cannot delete non existent news post")
 		return
 	}
 
 	archived, err := isArchived(id)
 	if err != nil {
-		log.Printf("%v: archive news post isArchived(id): %v", r.URL, err)
+		toolkit.LogErrorf(r, "delete news post isArchived(id): %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if *archived {
-		log.Printf("%v: archive news post isArchived(id): %v", r.URL, *archived)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot archive already archived news post")
+		toolkit.LogInfof(r, "delete news post isArchived(id): %v", *archived)
+		response.Res(w, "error", http.StatusBadRequest, "Cannot delete archived news post")
 		return
 	}
 
 	db, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
-	_, err = db.Exec("UPDATE news_posts SET archived = true WHERE id = $1", id)
+	// first delete news post comments: table news_post_comments, fk news_post
+	_, err = db.Exec("DELETE FROM news_post_comments WHERE news_post = $1", id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	response.Res(w, "success", http.StatusOK, "archived")
-}
-
-func unArchiveNewsPost(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	exists, err := exists(id)
+	// and the revisions of the news post
+	err = newsPostRevisions.deleteAll(db, id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	if !*exists {
-		log.Printf("%v: unarchive news post exists(id): %v", r.URL, *exists)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot unarchive non existent news post")
-		return
-	}
-
-	archived, err := isArchived(id)
+	// and its old slugs
+	err = slug.NewsPost.DeleteRedirects(db, id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	if !*archived {
-		log.Printf("%v: unarchive news post isArchived(id): %v", r.URL, *archived)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot unarchive not archived news post")
-		return
-	}
-
-	db, err := db.DB()
+	// then delete news post
+	stmt, err := db.Prepare("DELETE FROM news_posts WHERE id=$1")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
-	_, err = db.Exec("UPDATE news_posts SET archived = false WHERE id = $1", id)
+	_, err = stmt.Exec(id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	response.Res(w, "success", http.StatusOK, "unarchive done")
+	response.Res(w, "success", http.StatusOK, "deleted")
 }
 
 type NewsPostCount struct {
@@ -1228,16 +1213,15 @@ type NewsPostCount struct {
 func getNewsPostCountAll(w http.ResponseWriter, r *http.Request) {
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	var count int
 	err = database.QueryRow("SELECT COUNT(*) FROM news_posts").Scan(&count)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -1256,17 +1240,16 @@ func getNewsPostCount(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	var count int
 	query := fmt.Sprintf("SELECT COUNT(*) FROM news_posts WHERE created_at > current_date - interval '1 %s'", period)
 	err = database.QueryRow(query).Scan(&count)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -1278,7 +1261,7 @@ func getNewsPosts(w http.ResponseWriter, r *http.Request) {
 	// Parse the page number from the query parameters
 	pageStr, ok := r.URL.Query()["page"]
 	if !ok || len(pageStr[0]) < 1 {
-		log.Printf("%v: Url Param 'page' is missing. Setting default value to 1.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'page' is missing. Setting default value to 1.")
 		pageStr = []string{"1"}
 	}
 	page, _ := strconv.Atoi(pageStr[0])
@@ -1286,7 +1269,7 @@ func getNewsPosts(w http.ResponseWriter, r *http.Request) {
 	// Parse the limit from the query parameters
 	limitStr, ok := r.URL.Query()["limit"]
 	if !ok || len(limitStr[0]) < 1 {
-		log.Printf("%v: Url Param 'limit' is missing. Setting default value to 10.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'limit' is missing. Setting default value to 10.")
 		limitStr = []string{"10"}
 	}
 	limit, _ := strconv.Atoi(limitStr[0])
@@ -1296,16 +1279,15 @@ func getNewsPosts(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// Query the database
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags, archived, created_at, updated_at, category, subcategory, region, top, latest, related, completed FROM news_posts ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
+	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags, archived, created_at, updated_at, category, subcategory, region, top, latest, related, completed, publish_at, unpublish_at, status, slug FROM news_posts ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -1315,8 +1297,8 @@ func getNewsPosts(w http.ResponseWriter, r *http.Request) {
 	var posts []NewsPost
 	for rows.Next() {
 		var p NewsPost
-		if err := rows.Scan(&p.ID, &p.TitleLatin, &p.DescriptionLatin, &p.TitleCyrillic, &p.DescriptionCyrillic, &p.Video, pq.Array(&p.Tags), &p.Archived, &p.CreatedAt, &p.UpdatedAt, &p.Category, &p.Subcategory, &p.Region, &p.Top, &p.Latest, &p.Related, &p.Completed); err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+		if err := rows.Scan(&p.ID, &p.TitleLatin, &p.DescriptionLatin, &p.TitleCyrillic, &p.DescriptionCyrillic, &p.Video, pq.Array(&p.Tags), &p.Archived, &p.CreatedAt, &p.UpdatedAt, &p.Category, &p.Subcategory, &p.Region, &p.Top, &p.Latest, &p.Related, &p.Completed, &p.PublishAt, &p.UnpublishAt, &p.Status, &p.Slug); err != nil {
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -1328,7 +1310,7 @@ func getNewsPosts(w http.ResponseWriter, r *http.Request) {
 	var total int
 	err = database.QueryRow("SELECT COUNT(*) FROM news_posts").Scan(&total)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -1344,23 +1326,27 @@ func getNewsPosts(w http.ResponseWriter, r *http.Request) {
 }
 
 type NewsPost struct {
-	ID                  int       `json:"id"`
-	TitleLatin          string    `json:"title_latin"`
-	DescriptionLatin    string    `json:"description_latin"`
-	TitleCyrillic       string    `json:"title_cyrillic"`
-	DescriptionCyrillic string    `json:"description_cyrillic"`
-	Video               string    `json:"video"`
-	Tags                []string  `json:"tags"`
-	Archived            bool      `json:"archived"`
-	CreatedAt           time.Time `json:"created_at"`
-	UpdatedAt           time.Time `json:"updated_at"`
-	Category            *int      `json:"category"`
-	Subcategory         *int      `json:"subcategory"`
-	Region              *int      `json:"region"`
-	Top                 *bool     `json:"top"`
-	Latest              *bool     `json:"latest"`
-	Related             *int      `json:"related"`
-	Completed           bool      `json:"completed"`
+	ID                  int        `json:"id"`
+	TitleLatin          string     `json:"title_latin"`
+	DescriptionLatin    string     `json:"description_latin"`
+	TitleCyrillic       string     `json:"title_cyrillic"`
+	DescriptionCyrillic string     `json:"description_cyrillic"`
+	Video               string     `json:"video"`
+	Tags                []string   `json:"tags"`
+	Archived            bool       `json:"archived"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	Category            *int       `json:"category"`
+	Subcategory         *int       `json:"subcategory"`
+	Region              *int       `json:"region"`
+	Top                 *bool      `json:"top"`
+	Latest              *bool      `json:"latest"`
+	Related             *int       `json:"related"`
+	Completed           bool       `json:"completed"`
+	PublishAt           *time.Time `json:"publish_at"`
+	UnpublishAt         *time.Time `json:"unpublish_at"`
+	Status              string     `json:"status"`
+	Slug                *string    `json:"slug"`
 }
 
 type ResponseNewsPostsData struct {
@@ -1369,105 +1355,56 @@ type ResponseNewsPostsData struct {
 	PreviousPage bool       `json:"previous_page"`
 }
 
-// newsPostCompleted is a handler to update the completed field of a news post
-func newsPostCompleted(w http.ResponseWriter, r *http.Request) {
+func getNewsPostPhoto(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	exists, err := exists(id)
-	if err != nil {
-		log.Printf("%v: news post completed exists(id): %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-
-	if !*exists {
-		log.Printf("%v: news post completed exists(id): %v", r.URL, *exists)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot update completed field of non existent news post")
-		return
-	}
-
-	archived, err := isArchived(id)
+	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: news post completed isArchived(id): %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	if *archived {
-		log.Printf("%v: news post completed isArchived(id): %v", r.URL, *archived)
-		response.Res(w, "error", http.StatusBadRequest, "Cannot update completed field of archived news post")
-		return
-	}
-
-	db, err := db.DB()
-	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
+	var photoID sql.NullInt64
+	err = database.QueryRow("SELECT photo_id FROM news_posts WHERE id = $1", id).Scan(&photoID)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "not found")
 		return
 	}
-	defer db.Close()
-
-	_, err = db.Exec("UPDATE news_posts SET completed = NOT completed WHERE id = $1", id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	response.Res(w, "success", http.StatusOK, "completed field updated")
+	media.ServePrivate(w, r, photoID, "news-"+id+"-photo")
 }
 
-func getNewsPostPhoto(w http.ResponseWriter, r *http.Request) {
+func getNewsPostAudio(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
-		return
-	}
-	defer database.Close()
-
-	var photo []byte
-	err = database.QueryRow("SELECT photo FROM news_posts WHERE id = $1", id).Scan(&photo)
-	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	contentType := http.DetectContentType(photo)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(photo)))
-	w.Write(photo)
-}
-
-func getNewsPostAudio(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	database, err := db.DB()
-	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
-		response.Res(w, "error", http.StatusInternalServerError, "server error")
+	var audioID sql.NullInt64
+	err = database.QueryRow("SELECT audio_id FROM news_posts WHERE id = $1", id).Scan(&audioID)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "not found")
 		return
 	}
-	defer database.Close()
-
-	var audio []byte
-	err = database.QueryRow("SELECT audio FROM news_posts WHERE id = $1", id).Scan(&audio)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	contentType := http.DetectContentType(audio)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(audio)))
-	w.Write(audio)
+	media.ServePrivate(w, r, audioID, "news-"+id+"-audio")
 }
 
 func getNewsPostCoverImage(w http.ResponseWriter, r *http.Request) {
@@ -1476,35 +1413,34 @@ func getNewsPostCoverImage(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	var CoverImage []byte
-	err = database.QueryRow("SELECT cover_image FROM news_posts WHERE id = $1", id).Scan(&CoverImage)
+	var coverImageID sql.NullInt64
+	err = database.QueryRow("SELECT cover_image_id FROM news_posts WHERE id = $1", id).Scan(&coverImageID)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "not found")
+		return
+	}
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	contentType := http.DetectContentType(CoverImage)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(CoverImage)))
-	w.Write(CoverImage)
+	media.ServePrivate(w, r, coverImageID, "news-"+id+"-cover")
 }
 
 // getRegions is a route handler function to get all news regions
 func getRegions(w http.ResponseWriter, r *http.Request) {
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// create a slice to hold the regions
 	var regions []Region
@@ -1565,7 +1501,6 @@ func updateRegion(w http.ResponseWriter, r *http.Request) {
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// check news region existence
 	var exists bool
@@ -1654,7 +1589,6 @@ func deleteRegion(w http.ResponseWriter, r *http.Request) {
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// check news region existence
 	var exists bool
@@ -1716,7 +1650,6 @@ func updateCategory(w http.ResponseWriter, r *http.Request) {
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// check news category existence
 	var exists bool
@@ -1805,7 +1738,6 @@ func deleteCategory(w http.ResponseWriter, r *http.Request) {
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// check news category existence
 	var exists bool
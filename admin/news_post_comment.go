@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"Tahlilchi.uz/metrics"
 	"Tahlilchi.uz/model"
 	"Tahlilchi.uz/response"
 	"Tahlilchi.uz/toolkit"
@@ -84,5 +85,6 @@ func approveNewsPostComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	metrics.CommentApprovals.Inc("news_post")
 	response.Res(w, "success", http.StatusOK, "news post comment approved successfully")
 }
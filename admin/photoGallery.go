@@ -1,16 +1,18 @@
 package admin
 
 import (
+	"database/sql"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/media"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/slug"
 	"Tahlilchi.uz/toolkit"
+	"Tahlilchi.uz/upload"
 	"github.com/gorilla/mux"
 )
 
@@ -26,31 +28,41 @@ func addPhotoGallery(w http.ResponseWriter, r *http.Request) {
 	var p PhotoGallery
 	err := r.ParseForm()
 	if err != nil {
-		log.Printf("%v: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
+	// fill a missing alphabet from the other one on request
+	autofill(r, titleFields)
+
 	p.TitleLatin = r.FormValue("title_latin")
 	p.TitleCyrillic = r.FormValue("title_cyrillic")
 
 	if p.TitleLatin == "" || p.TitleCyrillic == "" {
-		log.Printf("%v: title_latin: %v; title_cyrillic: %v", r.URL, p.TitleLatin, p.TitleCyrillic)
+		toolkit.LogInfof(r, "title_latin: %v; title_cyrillic: %v", p.TitleLatin, p.TitleCyrillic)
 		response.Res(w, "error", http.StatusBadRequest, "Both title_latin and title_cyrillic are required")
 		return
 	}
 
 	db, err := db.DB()
 	if err != nil {
-		log.Printf("%v: db connection error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db connection error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
-	_, err = db.Exec("INSERT INTO photo_gallery (title_latin, title_cyrillic) VALUES ($1, $2)", p.TitleLatin, p.TitleCyrillic)
+	var id int64
+	err = db.QueryRow("INSERT INTO photo_gallery (title_latin, title_cyrillic) VALUES ($1, $2) RETURNING id", p.TitleLatin, p.TitleCyrillic).Scan(&id)
 	if err != nil {
-		log.Printf("%v: db execution error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db execution error: %v", err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	err = slug.PhotoGallery.Assign(db, id)
+	if err != nil {
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -64,7 +76,6 @@ func photoGalleryExists(id string) (*bool, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
 	var exists bool
 	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM photo_gallery WHERE id=$1)", id).Scan(&exists)
@@ -75,28 +86,33 @@ func photoGalleryExists(id string) (*bool, error) {
 	return &exists, nil
 }
 
+// maxPhotoGalleryRequest limits the request adding photos to a photo gallery
+const maxPhotoGalleryRequest = 500 << 20
+
+// photoGalleryPhoto is the upload kind of the photos of a photo gallery
+var photoGalleryPhoto = upload.Image.Max(10 << 20)
+
 func photoGalleryAddPhotos(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
 	exists, err := photoGalleryExists(id)
 	if err != nil {
-		log.Printf("%v: photoGalleryAddPhotos photoGalleryExists(id) error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "photoGalleryAddPhotos photoGalleryExists(id) error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: photoGalleryAddPhotos photoGalleryExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "photoGalleryAddPhotos photoGalleryExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "Cannot add photos to non existent photo gallery")
 		return
 	}
 
 	// Parse the multipart form in the request
-	err = r.ParseMultipartForm(500 << 20) // Max memory 500 MB
+	err = upload.ParseForm(w, r, maxPhotoGalleryRequest)
 	if err != nil {
-		log.Printf("%v: Could not parse multipart form: %v", r.URL, err)
-		response.Res(w, "error", http.StatusBadRequest, err.Error())
+		upload.Fail(w, r, err)
 		return
 	}
 
@@ -104,47 +120,35 @@ func photoGalleryAddPhotos(w http.ResponseWriter, r *http.Request) {
 	// They are accessible only after ParseMultipartForm is called.
 	files := r.MultipartForm.File["photo[]"] // "photo" is the key of the input form
 
-	for _, fileHeader := range files {
-		// Check if the file is an image
-		if fileHeader.Header.Get("Content-Type")[:5] != "image" {
-			err := fmt.Errorf("photo %v is not an image: %v", fileHeader.Filename, fileHeader.Header.Get("Content-Type"))
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
-			return
-		}
-
-		// Check if the file size is greater than 10MB
-		if fileHeader.Size > 10<<20 {
-			err := fmt.Errorf("photo %v size exceeds 10MB limit: %v", fileHeader.Filename, fileHeader.Size)
-			toolkit.LogError(r, err)
-			response.Res(w, "error", http.StatusBadRequest, err.Error())
+	// Check the format and size of all the files before any is saved
+	photos := make([]*upload.File, len(files))
+	for i, fileHeader := range files {
+		photos[i], err = upload.Check(fileHeader, "photo[]", photoGalleryPhoto)
+		if err != nil {
+			upload.Fail(w, r, err)
 			return
 		}
 	}
 
 	db, err := db.DB()
 	if err != nil {
-		log.Printf("%v: db error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "db error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer db.Close()
 
-	for _, fileHeader := range files {
-		file, err := fileHeader.Open()
+	for i, fileHeader := range files {
+		mediaID, err := media.SaveFile(r.Context(), photos[i], fileHeader.Filename)
 		if err != nil {
-			message := fmt.Sprintf("%v: Could not open multipart file %v: %v", r.URL, fileHeader.Filename, err)
-			log.Println(message)
-			response.Res(w, "error", http.StatusBadRequest, message)
+			toolkit.LogErrorf(r, "%v: saving %v: %v", r.URL, fileHeader.Filename, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
-		fileByteA, _ := io.ReadAll(file)
-		file.Close()
 
-		_, err = db.Exec("INSERT INTO photo_gallery_photos (photo_gallery, file_name, file) VALUES ($1, $2, $3)", id, fileHeader.Filename, fileByteA)
+		_, err = db.Exec("INSERT INTO photo_gallery_photos (photo_gallery, file_name, media_id) VALUES ($1, $2, $3)", id, fileHeader.Filename, mediaID)
 		if err != nil {
 			message := fmt.Sprintf("%v: db.Exec INSERT INTO photo_gallery_photos %v error: %v", r.URL, fileHeader.Filename, err)
-			log.Println(message)
+			toolkit.LogErrorf(r, "%s", message)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -152,7 +156,7 @@ func photoGalleryAddPhotos(w http.ResponseWriter, r *http.Request) {
 		_, err = db.Exec("UPDATE photo_gallery SET updated_at = CURRENT_TIMESTAMP WHERE id = $1", id)
 		if err != nil {
 			message := fmt.Sprintf("%v: %v: UPDATE photo_gallery SET updated_at = CURRENT_TIMESTAMP error: %v", r.URL, fileHeader.Filename, err)
-			log.Println(message)
+			toolkit.LogErrorf(r, "%s", message)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -165,7 +169,7 @@ func getPhotoGalleryList(w http.ResponseWriter, r *http.Request) {
 	// Parse the page number from the query parameters
 	pageStr, ok := r.URL.Query()["page"]
 	if !ok || len(pageStr[0]) < 1 {
-		log.Printf("%v: Url Param 'page' is missing. Setting default value to 1.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'page' is missing. Setting default value to 1.")
 		pageStr = []string{"1"}
 	}
 	page, _ := strconv.Atoi(pageStr[0])
@@ -173,7 +177,7 @@ func getPhotoGalleryList(w http.ResponseWriter, r *http.Request) {
 	// Parse the limit from the query parameters
 	limitStr, ok := r.URL.Query()["limit"]
 	if !ok || len(limitStr[0]) < 1 {
-		log.Printf("%v: Url Param 'limit' is missing. Setting default value to 10.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'limit' is missing. Setting default value to 10.")
 		limitStr = []string{"10"}
 	}
 	limit, _ := strconv.Atoi(limitStr[0])
@@ -183,15 +187,14 @@ func getPhotoGalleryList(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT * FROM photo_gallery ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
+	rows, err := database.Query("SELECT id, title_latin, created_at, updated_at, title_cyrillic, slug FROM photo_gallery ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -200,8 +203,8 @@ func getPhotoGalleryList(w http.ResponseWriter, r *http.Request) {
 	var photoGalleryList []PhotoGalleryList
 	for rows.Next() {
 		var photoGallery PhotoGalleryList
-		if err := rows.Scan(&photoGallery.ID, &photoGallery.TitleLatin, &photoGallery.CreatedAt, &photoGallery.UpdatedAt, &photoGallery.TitleCyrillic); err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+		if err := rows.Scan(&photoGallery.ID, &photoGallery.TitleLatin, &photoGallery.CreatedAt, &photoGallery.UpdatedAt, &photoGallery.TitleCyrillic, &photoGallery.Slug); err != nil {
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -209,7 +212,7 @@ func getPhotoGalleryList(w http.ResponseWriter, r *http.Request) {
 		var photoGalleryPhotos []PhotoGalleryPhoto
 		photos, err := database.Query("SELECT id, photo_gallery, file_name, created_at FROM photo_gallery_photos WHERE photo_gallery = $1 ORDER BY id", photoGallery.ID)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -218,7 +221,7 @@ func getPhotoGalleryList(w http.ResponseWriter, r *http.Request) {
 		for photos.Next() {
 			var photoGalleryPhoto PhotoGalleryPhoto
 			if err := photos.Scan(&photoGalleryPhoto.ID, &photoGalleryPhoto.PhotoGallery, &photoGalleryPhoto.FileName, &photoGalleryPhoto.CreatedAt); err != nil {
-				log.Printf("%v: error: %v", r.URL, err)
+				toolkit.LogError(r, err)
 				response.Res(w, "error", http.StatusInternalServerError, "server error")
 				return
 			}
@@ -238,6 +241,7 @@ type PhotoGalleryList struct {
 	TitleCyrillic string              `json:"title_cyrillic"`
 	CreatedAt     string              `json:"created_at"`
 	UpdatedAt     string              `json:"updated_at"`
+	Slug          *string             `json:"slug"`
 	Photos        []PhotoGalleryPhoto `json:"photos"`
 }
 
@@ -245,7 +249,7 @@ func getPhotoGalleryPhotos(w http.ResponseWriter, r *http.Request) {
 	// Parse the page number from the query parameters
 	pageStr, ok := r.URL.Query()["page"]
 	if !ok || len(pageStr[0]) < 1 {
-		log.Printf("%v: Url Param 'page' is missing. Setting default value to 1.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'page' is missing. Setting default value to 1.")
 		pageStr = []string{"1"}
 	}
 	page, _ := strconv.Atoi(pageStr[0])
@@ -253,7 +257,7 @@ func getPhotoGalleryPhotos(w http.ResponseWriter, r *http.Request) {
 	// Parse the limit from the query parameters
 	limitStr, ok := r.URL.Query()["limit"]
 	if !ok || len(limitStr[0]) < 1 {
-		log.Printf("%v: Url Param 'limit' is missing. Setting default value to 10.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'limit' is missing. Setting default value to 10.")
 		limitStr = []string{"10"}
 	}
 	limit, _ := strconv.Atoi(limitStr[0])
@@ -266,28 +270,27 @@ func getPhotoGalleryPhotos(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := photoGalleryExists(id)
 	if err != nil {
-		log.Printf("%v: getPhotoGalleryPhotos photoGalleryExists(id) error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "getPhotoGalleryPhotos photoGalleryExists(id) error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: getPhotoGalleryPhotos photoGalleryExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "getPhotoGalleryPhotos photoGalleryExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "photo gallery not found")
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	rows, err := database.Query("SELECT id, photo_gallery, file_name, created_at FROM photo_gallery_photos WHERE photo_gallery = $1 ORDER BY id DESC LIMIT $2 OFFSET $3", id, limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -297,7 +300,7 @@ func getPhotoGalleryPhotos(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var photoGalleryPhoto PhotoGalleryPhoto
 		if err := rows.Scan(&photoGalleryPhoto.ID, &photoGalleryPhoto.PhotoGallery, &photoGalleryPhoto.FileName, &photoGalleryPhoto.CreatedAt); err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -308,11 +311,11 @@ func getPhotoGalleryPhotos(w http.ResponseWriter, r *http.Request) {
 }
 
 type PhotoGalleryPhoto struct {
-	ID           int       `json:"id"`
-	PhotoGallery int       `json:"photo_gallery"`
-	FileName     string    `json:"file_name"`
-	CreatedAt    time.Time `json:"created_at"`
-	File         []byte    `json:"file"`
+	ID           int           `json:"id"`
+	PhotoGallery int           `json:"photo_gallery"`
+	FileName     string        `json:"file_name"`
+	CreatedAt    time.Time     `json:"created_at"`
+	MediaID      sql.NullInt64 `json:"-"`
 }
 
 // getPhotoGalleryPhoto is a handler to get photo gallery photo
@@ -323,24 +326,24 @@ func getPhotoGalleryPhoto(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	var photoGalleryPhoto PhotoGalleryPhoto
-	err = database.QueryRow("SELECT id, photo_gallery, file_name, created_at, file FROM photo_gallery_photos WHERE photo_gallery = $1 AND id = $2", photo_gallery, id).Scan(&photoGalleryPhoto.ID, &photoGalleryPhoto.PhotoGallery, &photoGalleryPhoto.FileName, &photoGalleryPhoto.CreatedAt, &photoGalleryPhoto.File)
+	err = database.QueryRow("SELECT id, photo_gallery, file_name, created_at, media_id FROM photo_gallery_photos WHERE photo_gallery = $1 AND id = $2", photo_gallery, id).Scan(&photoGalleryPhoto.ID, &photoGalleryPhoto.PhotoGallery, &photoGalleryPhoto.FileName, &photoGalleryPhoto.CreatedAt, &photoGalleryPhoto.MediaID)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "photo not found")
+		return
+	}
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	contentType := http.DetectContentType(photoGalleryPhoto.File)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(photoGalleryPhoto.File)))
-	w.Write(photoGalleryPhoto.File)
+	media.ServePrivate(w, r, photoGalleryPhoto.MediaID, "")
 }
 
 // deletePhotoGalleryPhoto is a handler to delete photo gallery photo
@@ -351,15 +354,14 @@ func deletePhotoGalleryPhoto(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	_, err = database.Exec("DELETE FROM photo_gallery_photos WHERE photo_gallery = $1 AND id = $2", photo_gallery, id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -374,35 +376,41 @@ func deletePhotoGallery(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := photoGalleryExists(id)
 	if err != nil {
-		log.Printf("%v: deletePhotoGallery photoGalleryExists(id) error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "deletePhotoGallery photoGalleryExists(id) error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: deletePhotoGallery photoGalleryExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "deletePhotoGallery photoGalleryExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "photo gallery not found")
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	_, err = database.Exec("DELETE FROM photo_gallery_photos WHERE photo_gallery = $1", id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	_, err = database.Exec("DELETE FROM photo_gallery WHERE id = $1", id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	err = slug.PhotoGallery.DeleteRedirects(database, id)
+	if err != nil {
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -417,13 +425,13 @@ func updatePhotoGallery(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := photoGalleryExists(id)
 	if err != nil {
-		log.Printf("%v: updatePhotoGallery photoGalleryExists(id) error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "updatePhotoGallery photoGalleryExists(id) error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: updatePhotoGallery photoGalleryExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "updatePhotoGallery photoGalleryExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "photo gallery not found")
 		return
 	}
@@ -431,24 +439,26 @@ func updatePhotoGallery(w http.ResponseWriter, r *http.Request) {
 	var p PhotoGallery
 	err = r.ParseForm()
 	if err != nil {
-		log.Printf("%v: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
+
+	// fill a missing alphabet from the other one on request
+	autofill(r, titleFields)
 
 	p.TitleLatin = r.FormValue("title_latin")
 	if p.TitleLatin != "" {
 		_, err = database.Exec("UPDATE photo_gallery SET title_latin = $1, updated_at = NOW() WHERE id = $2", p.TitleLatin, id)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -458,11 +468,18 @@ func updatePhotoGallery(w http.ResponseWriter, r *http.Request) {
 	if p.TitleCyrillic != "" {
 		_, err = database.Exec("UPDATE photo_gallery SET title_cyrillic = $1, updated_at = NOW() WHERE id = $2", p.TitleCyrillic, id)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 	}
 
+	err = slug.PhotoGallery.Assign(database, id)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
 	response.Res(w, "success", http.StatusOK, "photo gallery updated")
 }
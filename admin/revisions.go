@@ -0,0 +1,347 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"Tahlilchi.uz/authPackage"
+	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/slug"
+	"Tahlilchi.uz/toolkit"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// revisionEntity is a content type with revisions
+type revisionEntity struct {
+	// Type is the entity_type of its revisions
+	Type  string
+	Table string
+	// Columns are the content columns kept in a revision and written back by a restore
+	Columns []string
+	// MediaColumns are the Columns referencing the media table
+	MediaColumns []string
+	// Slug is reassigned after a restore, which may change title_latin
+	Slug slug.Entity
+	// Workflow limits the restores of the approved and published content to the reviewers
+	Workflow workflowEntity
+}
+
+var (
+	newsPostRevisions = revisionEntity{
+		Type:  "news_post",
+		Table: "news_posts",
+		Columns: []string{"title_latin", "description_latin", "title_cyrillic", "description_cyrillic",
+			"photo_id", "video", "audio_id", "cover_image_id", "tags", "category", "subcategory", "region", "top", "latest", "related"},
+		MediaColumns: []string{"photo_id", "audio_id", "cover_image_id"},
+		Slug:         slug.NewsPost,
+		Workflow:     newsPostWorkflow,
+	}
+	articleRevisions = revisionEntity{
+		Type:  "article",
+		Table: "articles",
+		Columns: []string{"title_latin", "description_latin", "title_cyrillic", "description_cyrillic",
+			"videos", "cover_image_id", "tags", "category", "related"},
+		MediaColumns: []string{"cover_image_id"},
+		Slug:         slug.Article,
+		Workflow:     articleWorkflow,
+	}
+)
+
+// execer is a *sql.DB or a *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// save writes the current content of the row id as its next revision, by the admin with the given email
+func (e revisionEntity) save(ex execer, id any, authorEmail string) error {
+	var pairs, media []string
+	for _, c := range e.Columns {
+		pairs = append(pairs, "'"+c+"', t."+c)
+	}
+	for _, c := range e.MediaColumns {
+		media = append(media, "t."+c)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO revisions (entity_type, entity_id, number, author_email, content, media_ids, created_at)
+		SELECT $1, t.id, COALESCE((SELECT MAX(number) FROM revisions WHERE entity_type = $1 AND entity_id = t.id), 0) + 1, $2,
+			jsonb_build_object(%s), array_remove(ARRAY[%s]::bigint[], NULL), NOW()
+		FROM %s t WHERE t.id = $3`, strings.Join(pairs, ", "), strings.Join(media, ", "), e.Table)
+	_, err := ex.Exec(query, e.Type, authorEmail, id)
+	if err != nil {
+		return fmt.Errorf("saving the revision of %s %v: %v", e.Type, id, err)
+	}
+	return nil
+}
+
+// deleteAll removes the revisions of the deleted row id
+func (e revisionEntity) deleteAll(ex execer, id any) error {
+	_, err := ex.Exec("DELETE FROM revisions WHERE entity_type = $1 AND entity_id = $2", e.Type, id)
+	return err
+}
+
+// Revision is a struct to map a row of the revisions table
+type Revision struct {
+	Number      int             `json:"number"`
+	AuthorEmail string          `json:"author_email"`
+	CreatedAt   string          `json:"created_at"`
+	Content     json.RawMessage `json:"content,omitempty"`
+}
+
+// errRevisionNotFound is returned for a revision number without a row
+var errRevisionNotFound = errors.New("revision not found")
+
+// get returns the revision number of the row id
+func (e revisionEntity) get(database *sql.DB, id string, number string) (Revision, error) {
+	var rev Revision
+	var content []byte
+	err := database.QueryRow("SELECT number, author_email, created_at, content FROM revisions WHERE entity_type = $1 AND entity_id = $2 AND number = $3",
+		e.Type, id, number).Scan(&rev.Number, &rev.AuthorEmail, &rev.CreatedAt, &content)
+	if err == sql.ErrNoRows {
+		return Revision{}, errRevisionNotFound
+	}
+	if err != nil {
+		return Revision{}, err
+	}
+	rev.Content = content
+	return rev, nil
+}
+
+// listHandler is a route handler function listing the revisions of the row {id}, newest first, without their content
+func (e revisionEntity) listHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := strconv.Atoi(id); err != nil {
+		response.Res(w, "error", http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	rows, err := database.Query("SELECT number, author_email, created_at FROM revisions WHERE entity_type = $1 AND entity_id = $2 ORDER BY number DESC", e.Type, id)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	defer rows.Close()
+
+	list := []Revision{}
+	for rows.Next() {
+		var rev Revision
+		if err := rows.Scan(&rev.Number, &rev.AuthorEmail, &rev.CreatedAt); err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+		list = append(list, rev)
+	}
+	if err := rows.Err(); err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, list)
+}
+
+// getHandler is a route handler function returning the revision {number} of the row {id} with its content
+func (e revisionEntity) getHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if _, err := strconv.Atoi(vars["id"]); err != nil {
+		response.Res(w, "error", http.StatusBadRequest, "invalid id")
+		return
+	}
+	if _, err := strconv.Atoi(vars["number"]); err != nil {
+		response.Res(w, "error", http.StatusBadRequest, "invalid revision number")
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	rev, err := e.get(database, vars["id"], vars["number"])
+	if err == errRevisionNotFound {
+		response.Res(w, "error", http.StatusNotFound, "revision not found")
+		return
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, rev)
+}
+
+// RevisionDiff is the fields which differ between two revisions
+type RevisionDiff struct {
+	From    int                    `json:"from"`
+	To      int                    `json:"to"`
+	Changes map[string]auditChange `json:"changes"`
+}
+
+// diffHandler is a route handler function returning the field-level diff between the revisions ?from= and ?to= of the row {id}
+func (e revisionEntity) diffHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := strconv.Atoi(id); err != nil {
+		response.Res(w, "error", http.StatusBadRequest, "invalid id")
+		return
+	}
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if _, err := strconv.Atoi(from); err != nil {
+		response.Res(w, "error", http.StatusBadRequest, "from must be a revision number")
+		return
+	}
+	if _, err := strconv.Atoi(to); err != nil {
+		response.Res(w, "error", http.StatusBadRequest, "to must be a revision number")
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	var contents [2]map[string]any
+	var diff RevisionDiff
+	for i, number := range []string{from, to} {
+		rev, err := e.get(database, id, number)
+		if err == errRevisionNotFound {
+			response.Res(w, "error", http.StatusNotFound, "revision "+number+" not found")
+			return
+		}
+		if err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+		if err := json.Unmarshal(rev.Content, &contents[i]); err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+		if i == 0 {
+			diff.From = rev.Number
+		} else {
+			diff.To = rev.Number
+		}
+	}
+	diff.Changes = auditDiff(contents[0], contents[1])
+
+	response.Res(w, "success", http.StatusOK, diff)
+}
+
+// restoreHandler is a route handler function making the revision ?revision= of the row {id} its current content.
+// The restore is saved as a new revision, so it can be undone the same way.
+func (e revisionEntity) restoreHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := strconv.Atoi(id); err != nil {
+		response.Res(w, "error", http.StatusBadRequest, "invalid id")
+		return
+	}
+	number := r.URL.Query().Get("revision")
+	if _, err := strconv.Atoi(number); err != nil {
+		response.Res(w, "error", http.StatusBadRequest, "revision must be a revision number")
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	var archived bool
+	err = database.QueryRow("SELECT archived FROM "+e.Table+" WHERE id = $1", id).Scan(&archived)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	if archived {
+		response.Res(w, "error", http.StatusBadRequest, "Cannot restore a revision of an archived "+strings.ReplaceAll(e.Type, "_", " "))
+		return
+	}
+	if !e.Workflow.editable(w, r, database, id) {
+		return
+	}
+
+	rev, err := e.get(database, id, number)
+	if err == errRevisionNotFound {
+		response.Res(w, "error", http.StatusNotFound, "revision not found")
+		return
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// Start a new transaction
+	tx, err := database.Begin()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	defer tx.Rollback()
+
+	columns := strings.Join(e.Columns, ", ")
+	_, err = tx.Exec(fmt.Sprintf("UPDATE %s SET (%s) = (SELECT %s FROM jsonb_populate_record(NULL::%s, $1)), updated_at = NOW() WHERE id = $2",
+		e.Table, columns, columns, e.Table), []byte(rev.Content), id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		// foreign_key_violation, e.g. the category of the revision was deleted since
+		toolkit.LogInfo(r, pqErr.Message)
+		response.Res(w, "error", http.StatusConflict, "The revision references a deleted row: "+pqErr.Detail)
+		return
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	if err := e.save(tx, id, authPackage.AdminEmail(r)); err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// Commit the transaction
+	if err := tx.Commit(); err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	if err := e.Slug.Assign(database, id); err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, "Revision "+strconv.Itoa(rev.Number)+" restored")
+}
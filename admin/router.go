@@ -2,16 +2,82 @@ package admin
 
 import (
 	"Tahlilchi.uz/authPackage"
+	"Tahlilchi.uz/config"
+	"Tahlilchi.uz/mail"
 	"Tahlilchi.uz/middleware"
+	"Tahlilchi.uz/scheduler"
 	"github.com/gorilla/mux"
 )
 
-func AdminRouter(r *mux.Router) *mux.Router {
+// mailer sends the emails to the admins
+var mailer *mail.Mailer
+
+// totpIssuer is the name of the app shown by the authenticator apps of the admins
+var totpIssuer string
+
+func AdminRouter(r *mux.Router, cfg *config.Config, jobs *scheduler.Scheduler) *mux.Router {
+	mailer = mail.New(cfg.Mail)
+	totpIssuer = cfg.TOTPIssuer
+
 	adminRouter := r.PathPrefix("/admin").Subrouter()
+	// record every change made through the admin routes: location: admin/audit.go
+	adminRouter.Use(auditLog)
 	adminRouter.HandleFunc("/login", login).Methods("POST") // .Schemes(os.Getenv("SCHEMES"))
+	// second login step of admins with two-factor authentication: location: admin/two-factor.go
+	adminRouter.HandleFunc("/login/2fa", loginTwoFactor).Methods("POST")
 	// logout route: method: GET
 	adminRouter.HandleFunc("/logout", middleware.Chain(logout, authPackage.AdminAuth())).Methods("GET")
 
+	// route for the logged in admin to change their own password: location: admin/admins.go
+	adminRouter.HandleFunc("/password", middleware.Chain(changeOwnPassword, authPackage.AdminAuth())).Methods("PATCH")
+	// route for the logged in admin to get the recent login attempts of their account: location: admin/login-attempts.go
+	adminRouter.HandleFunc("/login-activity", middleware.Chain(getLoginActivity, authPackage.AdminAuth())).Methods("GET")
+	// route to preview the Latin to Cyrillic or Cyrillic to Latin transliteration of a text: location: admin/translit.go
+	adminRouter.HandleFunc("/transliterate", middleware.Chain(previewTransliteration, authPackage.AdminAuth())).Methods("POST")
+
+	// two-factor authentication router of the logged in admin: location: admin/two-factor.go
+	twoFactorRouter := adminRouter.PathPrefix("/2fa").Subrouter()
+	// route to create a new TOTP secret
+	twoFactorRouter.HandleFunc("/enroll", middleware.Chain(enrollTwoFactor, authPackage.AdminAuth())).Methods("POST")
+	// route to turn two-factor authentication on with a code of the new secret
+	twoFactorRouter.HandleFunc("/confirm", middleware.Chain(confirmTwoFactor, authPackage.AdminAuth())).Methods("POST")
+	// route to get new recovery codes
+	twoFactorRouter.HandleFunc("/recovery-codes", middleware.Chain(regenerateRecoveryCodes, authPackage.AdminAuth())).Methods("POST")
+	// route to turn two-factor authentication off
+	twoFactorRouter.HandleFunc("/disable", middleware.Chain(disableTwoFactor, authPackage.AdminAuth())).Methods("POST")
+
+	// admin accounts router: location: admin/admins.go
+	adminsRouter := adminRouter.PathPrefix("/admins").Subrouter()
+	// route to get the admin list
+	adminsRouter.HandleFunc("/list", middleware.Chain(listAdmins, authPackage.RequirePermission(authPackage.AdminManage), authPackage.AdminAuth())).Methods("GET")
+	// route to create an admin
+	adminsRouter.HandleFunc("", middleware.Chain(createAdmin, authPackage.RequirePermission(authPackage.AdminManage), authPackage.AdminAuth())).Methods("POST")
+	// route to disable an admin
+	adminsRouter.HandleFunc("/disable/{id}", middleware.Chain(setAdminDisabled(true), authPackage.RequirePermission(authPackage.AdminManage), authPackage.AdminAuth())).Methods("PATCH")
+	// route to re-enable an admin
+	adminsRouter.HandleFunc("/enable/{id}", middleware.Chain(setAdminDisabled(false), authPackage.RequirePermission(authPackage.AdminManage), authPackage.AdminAuth())).Methods("PATCH")
+	// route to delete an admin
+	adminsRouter.HandleFunc("/delete/{id}", middleware.Chain(deleteAdmin, authPackage.RequirePermission(authPackage.AdminManage), authPackage.AdminAuth())).Methods("DELETE")
+	// route to change the role of an admin
+	adminsRouter.HandleFunc("/role/{id}", middleware.Chain(updateAdminRole, authPackage.RequirePermission(authPackage.AdminManage), authPackage.AdminAuth())).Methods("PATCH")
+	// route to set a temporary password the admin has to change after login
+	adminsRouter.HandleFunc("/force-password-reset/{id}", middleware.Chain(forceAdminPasswordReset, authPackage.RequirePermission(authPackage.AdminManage), authPackage.AdminAuth())).Methods("PATCH")
+	// route to log an admin out of all their sessions: location: admin/sessions.go
+	adminsRouter.HandleFunc("/sessions/{id}", middleware.Chain(revokeAdminSessions, authPackage.RequirePermission(authPackage.AdminManage), authPackage.AdminAuth())).Methods("DELETE")
+	// route to turn two-factor authentication off for an admin: location: admin/two-factor.go
+	adminsRouter.HandleFunc("/reset-2fa/{id}", middleware.Chain(resetAdminTwoFactor, authPackage.RequirePermission(authPackage.AdminManage), authPackage.AdminAuth())).Methods("PATCH")
+	// route to get the roles two-factor authentication is mandatory for
+	adminsRouter.HandleFunc("/2fa-policy", middleware.Chain(getTwoFactorPolicy, authPackage.RequirePermission(authPackage.AdminManage), authPackage.AdminAuth())).Methods("GET")
+	// route to make two-factor authentication mandatory for a role
+	adminsRouter.HandleFunc("/2fa-policy", middleware.Chain(updateTwoFactorPolicy, authPackage.RequirePermission(authPackage.AdminManage), authPackage.AdminAuth())).Methods("PATCH")
+
+	// sessions router of the logged in admin: location: admin/sessions.go
+	sessionsRouter := adminRouter.PathPrefix("/sessions").Subrouter()
+	// route to get the active sessions
+	sessionsRouter.HandleFunc("/list", middleware.Chain(listSessions, authPackage.AdminAuth())).Methods("GET")
+	// route to revoke a session
+	sessionsRouter.HandleFunc("/delete/{id}", middleware.Chain(revokeSession, authPackage.AdminAuth())).Methods("DELETE")
+
 	forgotPasswordRouter := adminRouter.PathPrefix("/forgot-password").Subrouter()
 	forgotPasswordRouter.HandleFunc("/email", forgotPasswordEmail).Methods("POST")
 	forgotPasswordRouter.HandleFunc("/i-code", forgotPasswordICode).Methods("POST")
@@ -20,233 +86,264 @@ func AdminRouter(r *mux.Router) *mux.Router {
 	// news router
 	newsRouter := adminRouter.PathPrefix("/news").Subrouter()
 	// route to add news category
-	newsRouter.HandleFunc("/category", middleware.Chain(addCategory, authPackage.AdminAuth())).Methods("POST")
+	newsRouter.HandleFunc("/category", middleware.Chain(addCategory, authPackage.RequirePermission(authPackage.NewsWrite), authPackage.AdminAuth())).Methods("POST")
 	// route to get news category list
-	newsRouter.HandleFunc("/category", middleware.Chain(getCategoryList, authPackage.AdminAuth())).Methods("GET")
+	newsRouter.HandleFunc("/category", middleware.Chain(getCategoryList, authPackage.RequirePermission(authPackage.NewsRead), authPackage.AdminAuth())).Methods("GET")
 	// route to update news category
-	newsRouter.HandleFunc("/category/{id}", middleware.Chain(updateCategory, authPackage.AdminAuth())).Methods("PATCH") // Go file path: admin/news.go
+	newsRouter.HandleFunc("/category/{id}", middleware.Chain(updateCategory, authPackage.RequirePermission(authPackage.NewsWrite), authPackage.AdminAuth())).Methods("PATCH") // Go file path: admin/news.go
 	// route to delete news category
-	newsRouter.HandleFunc("/category/{id}", middleware.Chain(deleteCategory, authPackage.AdminAuth())).Methods("DELETE") // Go file path: admin/news.go
+	newsRouter.HandleFunc("/category/{id}", middleware.Chain(deleteCategory, authPackage.RequirePermission(authPackage.NewsDelete), authPackage.AdminAuth())).Methods("DELETE") // Go file path: admin/news.go
 	// route to add news subcategory
-	newsRouter.HandleFunc("/subcategory", middleware.Chain(addSubcategory, authPackage.AdminAuth())).Methods("POST")
+	newsRouter.HandleFunc("/subcategory", middleware.Chain(addSubcategory, authPackage.RequirePermission(authPackage.NewsWrite), authPackage.AdminAuth())).Methods("POST")
 	// route to get news subcategory list
-	newsRouter.HandleFunc("/subcategory", middleware.Chain(getSubCategoryList, authPackage.AdminAuth())).Methods("GET")
+	newsRouter.HandleFunc("/subcategory", middleware.Chain(getSubCategoryList, authPackage.RequirePermission(authPackage.NewsRead), authPackage.AdminAuth())).Methods("GET")
 	// route to update news subcategory
-	newsRouter.HandleFunc("/category/{id}/subcategory/{sub_id}", middleware.Chain(updateSubCategory, authPackage.AdminAuth())).Methods("PATCH")
+	newsRouter.HandleFunc("/category/{id}/subcategory/{sub_id}", middleware.Chain(updateSubCategory, authPackage.RequirePermission(authPackage.NewsWrite), authPackage.AdminAuth())).Methods("PATCH")
 	// route to delete news subcategory
-	newsRouter.HandleFunc("/category/{id}/subcategory/{sub_id}", middleware.Chain(deleteSubCategory, authPackage.AdminAuth())).Methods("DELETE")
+	newsRouter.HandleFunc("/category/{id}/subcategory/{sub_id}", middleware.Chain(deleteSubCategory, authPackage.RequirePermission(authPackage.NewsDelete), authPackage.AdminAuth())).Methods("DELETE")
 	// route news/category/{id}/subcategory/list
-	newsRouter.HandleFunc("/category/{id}/subcategory/list", middleware.Chain(getSubCategoryListByCategory, authPackage.AdminAuth())).Methods("GET")
+	newsRouter.HandleFunc("/category/{id}/subcategory/list", middleware.Chain(getSubCategoryListByCategory, authPackage.RequirePermission(authPackage.NewsRead), authPackage.AdminAuth())).Methods("GET")
 	// route to add news region
-	newsRouter.HandleFunc("/regions", middleware.Chain(addRegions, authPackage.AdminAuth())).Methods("POST")
-	newsRouter.HandleFunc("/post", middleware.Chain(addNewsPost, authPackage.AdminAuth())).Methods("POST")
+	newsRouter.HandleFunc("/regions", middleware.Chain(addRegions, authPackage.RequirePermission(authPackage.NewsWrite), authPackage.AdminAuth())).Methods("POST")
+	newsRouter.HandleFunc("/post", middleware.Chain(addNewsPost, authPackage.RequirePermission(authPackage.NewsWrite), authPackage.AdminAuth())).Methods("POST")
 
 	// news region router
 	newsRegionRouter := newsRouter.PathPrefix("/regions").Subrouter()
 	// route to get news region list
-	newsRegionRouter.HandleFunc("", middleware.Chain(getRegions, authPackage.AdminAuth())).Methods("GET") // Go file path: admin/news.go
+	newsRegionRouter.HandleFunc("", middleware.Chain(getRegions, authPackage.RequirePermission(authPackage.NewsRead), authPackage.AdminAuth())).Methods("GET") // Go file path: admin/news.go
 	// route to update news region
-	newsRegionRouter.HandleFunc("/{id}", middleware.Chain(updateRegion, authPackage.AdminAuth())).Methods("PATCH") // Go file path: admin/news.go
+	newsRegionRouter.HandleFunc("/{id}", middleware.Chain(updateRegion, authPackage.RequirePermission(authPackage.NewsWrite), authPackage.AdminAuth())).Methods("PATCH") // Go file path: admin/news.go
 	// route to delete news region
-	newsRegionRouter.HandleFunc("/{id}", middleware.Chain(deleteRegion, authPackage.AdminAuth())).Methods("DELETE") // Go file path: admin/news.go
+	newsRegionRouter.HandleFunc("/{id}", middleware.Chain(deleteRegion, authPackage.RequirePermission(authPackage.NewsDelete), authPackage.AdminAuth())).Methods("DELETE") // Go file path: admin/news.go
 
 	newsPostRouter := newsRouter.PathPrefix("/post").Subrouter()
-	newsPostRouter.HandleFunc("/edit/{id}", middleware.Chain(editNewsPost, authPackage.AdminAuth())).Methods("PATCH")
-	newsPostRouter.HandleFunc("/delete/{id}", middleware.Chain(deleteNewsPost, authPackage.AdminAuth())).Methods("DELETE")
-	newsPostRouter.HandleFunc("/archive/{id}", middleware.Chain(archiveNewsPost, authPackage.AdminAuth())).Methods("PATCH")
-	newsPostRouter.HandleFunc("/count/{period}", middleware.Chain(getNewsPostCount, authPackage.AdminAuth())).Methods("GET")
-	newsPostRouter.HandleFunc("/count", middleware.Chain(getNewsPostCountAll, authPackage.AdminAuth())).Methods("GET")
-	newsPostRouter.HandleFunc("/list", middleware.Chain(getNewsPosts, authPackage.AdminAuth())).Methods("GET")
-	newsPostRouter.HandleFunc("/unarchive/{id}", middleware.Chain(unArchiveNewsPost, authPackage.AdminAuth())).Methods("PATCH")
-	newsPostRouter.HandleFunc("/{id}/photo", middleware.Chain(getNewsPostPhoto, authPackage.AdminAuth())).Methods("GET")
-	newsPostRouter.HandleFunc("/{id}/audio", middleware.Chain(getNewsPostAudio, authPackage.AdminAuth())).Methods("GET")
-	newsPostRouter.HandleFunc("/{id}/cover_image", middleware.Chain(getNewsPostCoverImage, authPackage.AdminAuth())).Methods("GET")
-	// route to make news post completed field true/false
-	newsPostRouter.HandleFunc("/completed/{id}", middleware.Chain(newsPostCompleted, authPackage.AdminAuth())).Methods("PATCH")
+	newsPostRouter.HandleFunc("/edit/{id}", middleware.Chain(editNewsPost, authPackage.RequirePermission(authPackage.NewsWrite), authPackage.AdminAuth())).Methods("PATCH")
+	newsPostRouter.HandleFunc("/delete/{id}", middleware.Chain(deleteNewsPost, authPackage.RequirePermission(authPackage.NewsDelete), authPackage.AdminAuth())).Methods("DELETE")
+	newsPostRouter.HandleFunc("/archive/{id}", middleware.Chain(newsPostWorkflow.actionHandler("archive"), authPackage.RequirePermission(authPackage.NewsWrite), authPackage.AdminAuth())).Methods("PATCH")
+	newsPostRouter.HandleFunc("/count/{period}", middleware.Chain(getNewsPostCount, authPackage.RequirePermission(authPackage.NewsRead), authPackage.AdminAuth())).Methods("GET")
+	newsPostRouter.HandleFunc("/count", middleware.Chain(getNewsPostCountAll, authPackage.RequirePermission(authPackage.NewsRead), authPackage.AdminAuth())).Methods("GET")
+	newsPostRouter.HandleFunc("/list", middleware.Chain(getNewsPosts, authPackage.RequirePermission(authPackage.NewsRead), authPackage.AdminAuth())).Methods("GET")
+	newsPostRouter.HandleFunc("/unarchive/{id}", middleware.Chain(newsPostWorkflow.actionHandler("unarchive"), authPackage.RequirePermission(authPackage.NewsWrite), authPackage.AdminAuth())).Methods("PATCH")
+	newsPostRouter.HandleFunc("/{id}/photo", middleware.Chain(getNewsPostPhoto, authPackage.RequirePermission(authPackage.NewsRead), authPackage.AdminAuth())).Methods("GET")
+	newsPostRouter.HandleFunc("/{id}/audio", middleware.Chain(getNewsPostAudio, authPackage.RequirePermission(authPackage.NewsRead), authPackage.AdminAuth())).Methods("GET")
+	newsPostRouter.HandleFunc("/{id}/cover_image", middleware.Chain(getNewsPostCoverImage, authPackage.RequirePermission(authPackage.NewsRead), authPackage.AdminAuth())).Methods("GET")
+	// routes of the news post workflow: transition by the form values action and comment, and the transitions so far
+	// route to publish or unpublish the news post by the completed field, as the publish and unpublish actions
+	newsPostRouter.HandleFunc("/completed/{id}", middleware.Chain(newsPostWorkflow.completedHandler, authPackage.RequirePermission(authPackage.NewsWrite), authPackage.AdminAuth())).Methods("PATCH")
+	newsPostRouter.HandleFunc("/status/{id}", middleware.Chain(newsPostWorkflow.statusHandler, authPackage.RequirePermission(authPackage.NewsWrite), authPackage.AdminAuth())).Methods("PATCH")
+	newsPostRouter.HandleFunc("/{id}/status/history", middleware.Chain(newsPostWorkflow.historyHandler, authPackage.RequirePermission(authPackage.NewsRead), authPackage.AdminAuth())).Methods("GET")
+	// routes of the news post revisions: list, diff between ?from= and ?to=, one revision, and restore of ?revision=
+	newsPostRouter.HandleFunc("/{id}/revisions", middleware.Chain(newsPostRevisions.listHandler, authPackage.RequirePermission(authPackage.NewsRead), authPackage.AdminAuth())).Methods("GET")
+	newsPostRouter.HandleFunc("/{id}/revisions/diff", middleware.Chain(newsPostRevisions.diffHandler, authPackage.RequirePermission(authPackage.NewsRead), authPackage.AdminAuth())).Methods("GET")
+	newsPostRouter.HandleFunc("/{id}/revisions/{number}", middleware.Chain(newsPostRevisions.getHandler, authPackage.RequirePermission(authPackage.NewsRead), authPackage.AdminAuth())).Methods("GET")
+	newsPostRouter.HandleFunc("/restore/{id}", middleware.Chain(newsPostRevisions.restoreHandler, authPackage.RequirePermission(authPackage.NewsWrite), authPackage.AdminAuth())).Methods("PATCH")
 
 	// article router
 	articleRouter := adminRouter.PathPrefix("/article").Subrouter()
 	// route to add article category
-	articleRouter.HandleFunc("/category", middleware.Chain(addArticleCategory, authPackage.AdminAuth())).Methods("POST")
+	articleRouter.HandleFunc("/category", middleware.Chain(addArticleCategory, authPackage.RequirePermission(authPackage.ArticleWrite), authPackage.AdminAuth())).Methods("POST")
 	// route to get article category list
-	articleRouter.HandleFunc("/category", middleware.Chain(getArticleCategory, authPackage.AdminAuth())).Methods("GET")
+	articleRouter.HandleFunc("/category", middleware.Chain(getArticleCategory, authPackage.RequirePermission(authPackage.ArticleRead), authPackage.AdminAuth())).Methods("GET")
 	// route to update article category
-	articleRouter.HandleFunc("/category/{id}", middleware.Chain(updateArticleCategory, authPackage.AdminAuth())).Methods("PATCH")
+	articleRouter.HandleFunc("/category/{id}", middleware.Chain(updateArticleCategory, authPackage.RequirePermission(authPackage.ArticleWrite), authPackage.AdminAuth())).Methods("PATCH")
 	// route to delete article category
-	articleRouter.HandleFunc("/category/{id}", middleware.Chain(deleteArticleCategory, authPackage.AdminAuth())).Methods("DELETE")
+	articleRouter.HandleFunc("/category/{id}", middleware.Chain(deleteArticleCategory, authPackage.RequirePermission(authPackage.ArticleDelete), authPackage.AdminAuth())).Methods("DELETE")
 	// route to add article
-	articleRouter.HandleFunc("", middleware.Chain(addArticle, authPackage.AdminAuth())).Methods("POST")
+	articleRouter.HandleFunc("", middleware.Chain(addArticle, authPackage.RequirePermission(authPackage.ArticleWrite), authPackage.AdminAuth())).Methods("POST")
 
 	// route to edit article
-	articleRouter.HandleFunc("/edit/{id}", middleware.Chain(editArticle, authPackage.AdminAuth())).Methods("PATCH")
+	articleRouter.HandleFunc("/edit/{id}", middleware.Chain(editArticle, authPackage.RequirePermission(authPackage.ArticleWrite), authPackage.AdminAuth())).Methods("PATCH")
 	// article photos router
 	articlePhotosRouter := articleRouter.PathPrefix("/{id}/photos").Subrouter()
 	// route to add article photos
-	articlePhotosRouter.HandleFunc("/add", middleware.Chain(addArticlePhotos, authPackage.AdminAuth())).Methods("POST")
+	articlePhotosRouter.HandleFunc("/add", middleware.Chain(addArticlePhotos, authPackage.RequirePermission(authPackage.ArticleWrite), authPackage.AdminAuth())).Methods("POST")
 	// route to get article photos
-	articlePhotosRouter.HandleFunc("", middleware.Chain(getArticlePhotos, authPackage.AdminAuth())).Methods("GET")
+	articlePhotosRouter.HandleFunc("", middleware.Chain(getArticlePhotos, authPackage.RequirePermission(authPackage.ArticleRead), authPackage.AdminAuth())).Methods("GET")
 	// route to get article photo
-	articlePhotosRouter.HandleFunc("/{photo_id}", middleware.Chain(getArticlePhoto, authPackage.AdminAuth())).Methods("GET")
+	articlePhotosRouter.HandleFunc("/{photo_id}", middleware.Chain(getArticlePhoto, authPackage.RequirePermission(authPackage.ArticleRead), authPackage.AdminAuth())).Methods("GET")
 	// route to delete article photo
-	articlePhotosRouter.HandleFunc("/delete/{photo_id}", middleware.Chain(deleteArticlePhoto, authPackage.AdminAuth())).Methods("DELETE")
+	articlePhotosRouter.HandleFunc("/delete/{photo_id}", middleware.Chain(deleteArticlePhoto, authPackage.RequirePermission(authPackage.ArticleDelete), authPackage.AdminAuth())).Methods("DELETE")
 	// route to edit article
 
 	// route to get article cover_image
-	articleRouter.HandleFunc("/{id}/cover_image", middleware.Chain(getArticleCoverImage, authPackage.AdminAuth())).Methods("GET")
+	articleRouter.HandleFunc("/{id}/cover_image", middleware.Chain(getArticleCoverImage, authPackage.RequirePermission(authPackage.ArticleRead), authPackage.AdminAuth())).Methods("GET")
 	// route to delete article
-	articleRouter.HandleFunc("/delete/{id}", middleware.Chain(deleteArticle, authPackage.AdminAuth())).Methods("DELETE")
+	articleRouter.HandleFunc("/delete/{id}", middleware.Chain(deleteArticle, authPackage.RequirePermission(authPackage.ArticleDelete), authPackage.AdminAuth())).Methods("DELETE")
 	// route to archive article
-	articleRouter.HandleFunc("/archive/{id}", middleware.Chain(archiveArticle, authPackage.AdminAuth())).Methods("PATCH")
+	articleRouter.HandleFunc("/archive/{id}", middleware.Chain(articleWorkflow.actionHandler("archive"), authPackage.RequirePermission(authPackage.ArticleWrite), authPackage.AdminAuth())).Methods("PATCH")
 	// route to get article count
-	articleRouter.HandleFunc("/count/{period}", middleware.Chain(getArticleCount, authPackage.AdminAuth())).Methods("GET")
+	articleRouter.HandleFunc("/count/{period}", middleware.Chain(getArticleCount, authPackage.RequirePermission(authPackage.ArticleRead), authPackage.AdminAuth())).Methods("GET")
 	// route to get article count all
-	articleRouter.HandleFunc("/count", middleware.Chain(getArticleCountAll, authPackage.AdminAuth())).Methods("GET")
+	articleRouter.HandleFunc("/count", middleware.Chain(getArticleCountAll, authPackage.RequirePermission(authPackage.ArticleRead), authPackage.AdminAuth())).Methods("GET")
+	// routes of the article revisions: list, diff between ?from= and ?to=, one revision, and restore of ?revision=
+	articleRouter.HandleFunc("/{id}/revisions", middleware.Chain(articleRevisions.listHandler, authPackage.RequirePermission(authPackage.ArticleRead), authPackage.AdminAuth())).Methods("GET")
+	articleRouter.HandleFunc("/{id}/revisions/diff", middleware.Chain(articleRevisions.diffHandler, authPackage.RequirePermission(authPackage.ArticleRead), authPackage.AdminAuth())).Methods("GET")
+	articleRouter.HandleFunc("/{id}/revisions/{number}", middleware.Chain(articleRevisions.getHandler, authPackage.RequirePermission(authPackage.ArticleRead), authPackage.AdminAuth())).Methods("GET")
+	articleRouter.HandleFunc("/restore/{id}", middleware.Chain(articleRevisions.restoreHandler, authPackage.RequirePermission(authPackage.ArticleWrite), authPackage.AdminAuth())).Methods("PATCH")
 	// route to get article list
-	articleRouter.HandleFunc("/list", middleware.Chain(getArticles, authPackage.AdminAuth())).Methods("GET")
+	articleRouter.HandleFunc("/list", middleware.Chain(getArticles, authPackage.RequirePermission(authPackage.ArticleRead), authPackage.AdminAuth())).Methods("GET")
 	// route to unarchive article
-	articleRouter.HandleFunc("/unarchive/{id}", middleware.Chain(unArchiveArticle, authPackage.AdminAuth())).Methods("PATCH")
-	// route to make article completed field true/false
-	articleRouter.HandleFunc("/completed/{id}", middleware.Chain(articleCompleted, authPackage.AdminAuth())).Methods("PATCH")
+	articleRouter.HandleFunc("/unarchive/{id}", middleware.Chain(articleWorkflow.actionHandler("unarchive"), authPackage.RequirePermission(authPackage.ArticleWrite), authPackage.AdminAuth())).Methods("PATCH")
+	// routes of the article workflow: transition by the form values action and comment, and the transitions so far
+	// route to publish or unpublish the article by the completed field, as the publish and unpublish actions
+	articleRouter.HandleFunc("/completed/{id}", middleware.Chain(articleWorkflow.completedHandler, authPackage.RequirePermission(authPackage.ArticleWrite), authPackage.AdminAuth())).Methods("PATCH")
+	articleRouter.HandleFunc("/status/{id}", middleware.Chain(articleWorkflow.statusHandler, authPackage.RequirePermission(authPackage.ArticleWrite), authPackage.AdminAuth())).Methods("PATCH")
+	articleRouter.HandleFunc("/{id}/status/history", middleware.Chain(articleWorkflow.historyHandler, authPackage.RequirePermission(authPackage.ArticleRead), authPackage.AdminAuth())).Methods("GET")
 
 	businessPromotionalRouter := adminRouter.PathPrefix("/business-promotional").Subrouter()
-	businessPromotionalRouter.HandleFunc("/post", middleware.Chain(addBusinessPromotionalPost, authPackage.AdminAuth())).Methods("POST")
+	businessPromotionalRouter.HandleFunc("/post", middleware.Chain(addBusinessPromotionalPost, authPackage.RequirePermission(authPackage.BPPWrite), authPackage.AdminAuth())).Methods("POST")
 
 	businessPromotionalPostRouter := businessPromotionalRouter.PathPrefix("/post").Subrouter()
 	// business promotional post photo router
 	businessPromotionalPostPhotoRouter := businessPromotionalPostRouter.PathPrefix("/{id}/photo").Subrouter()
 	// route to add business promotional post photo
-	businessPromotionalPostPhotoRouter.HandleFunc("/add", middleware.Chain(addBusinessPromotionalPostPhoto, authPackage.AdminAuth())).Methods("POST")
+	businessPromotionalPostPhotoRouter.HandleFunc("/add", middleware.Chain(addBusinessPromotionalPostPhoto, authPackage.RequirePermission(authPackage.BPPWrite), authPackage.AdminAuth())).Methods("POST")
 	// route to get business promotional post photo list
-	businessPromotionalPostPhotoRouter.HandleFunc("/list", middleware.Chain(getBusinessPromotionalPostPhotoList, authPackage.AdminAuth())).Methods("GET")
+	businessPromotionalPostPhotoRouter.HandleFunc("/list", middleware.Chain(getBusinessPromotionalPostPhotoList, authPackage.RequirePermission(authPackage.BPPRead), authPackage.AdminAuth())).Methods("GET")
 	// route to get business promotional post photo
-	businessPromotionalPostPhotoRouter.HandleFunc("/{photo_id}", middleware.Chain(getBusinessPromotionalPostPhoto, authPackage.AdminAuth())).Methods("GET")
+	businessPromotionalPostPhotoRouter.HandleFunc("/{photo_id}", middleware.Chain(getBusinessPromotionalPostPhoto, authPackage.RequirePermission(authPackage.BPPRead), authPackage.AdminAuth())).Methods("GET")
 	// route to delete business promotional post photo
-	businessPromotionalPostPhotoRouter.HandleFunc("/delete/{photo_id}", middleware.Chain(deleteBusinessPromotionalPostPhoto, authPackage.AdminAuth())).Methods("DELETE")
-
-	businessPromotionalPostRouter.HandleFunc("/edit/{id}", middleware.Chain(editBusinessPromotionalPost, authPackage.AdminAuth())).Methods("PATCH")
-	businessPromotionalPostRouter.HandleFunc("/delete/{id}", middleware.Chain(deleteBPPost, authPackage.AdminAuth())).Methods("DELETE")
-	businessPromotionalPostRouter.HandleFunc("/archive/{id}", middleware.Chain(archiveBPPost, authPackage.AdminAuth())).Methods("PATCH")
-	businessPromotionalPostRouter.HandleFunc("/count/{period}", middleware.Chain(getBusinessPromotionalPostCount, authPackage.AdminAuth())).Methods("GET")
-	businessPromotionalPostRouter.HandleFunc("/list", middleware.Chain(getBusinessPromotionalPosts, authPackage.AdminAuth())).Methods("GET")
-	businessPromotionalPostRouter.HandleFunc("/unarchive/{id}", middleware.Chain(unArchiveBPPost, authPackage.AdminAuth())).Methods("PATCH")
+	businessPromotionalPostPhotoRouter.HandleFunc("/delete/{photo_id}", middleware.Chain(deleteBusinessPromotionalPostPhoto, authPackage.RequirePermission(authPackage.BPPDelete), authPackage.AdminAuth())).Methods("DELETE")
+
+	businessPromotionalPostRouter.HandleFunc("/edit/{id}", middleware.Chain(editBusinessPromotionalPost, authPackage.RequirePermission(authPackage.BPPWrite), authPackage.AdminAuth())).Methods("PATCH")
+	businessPromotionalPostRouter.HandleFunc("/delete/{id}", middleware.Chain(deleteBPPost, authPackage.RequirePermission(authPackage.BPPDelete), authPackage.AdminAuth())).Methods("DELETE")
+	businessPromotionalPostRouter.HandleFunc("/archive/{id}", middleware.Chain(archiveBPPost, authPackage.RequirePermission(authPackage.BPPWrite), authPackage.AdminAuth())).Methods("PATCH")
+	businessPromotionalPostRouter.HandleFunc("/count/{period}", middleware.Chain(getBusinessPromotionalPostCount, authPackage.RequirePermission(authPackage.BPPRead), authPackage.AdminAuth())).Methods("GET")
+	businessPromotionalPostRouter.HandleFunc("/list", middleware.Chain(getBusinessPromotionalPosts, authPackage.RequirePermission(authPackage.BPPRead), authPackage.AdminAuth())).Methods("GET")
+	businessPromotionalPostRouter.HandleFunc("/unarchive/{id}", middleware.Chain(unArchiveBPPost, authPackage.RequirePermission(authPackage.BPPWrite), authPackage.AdminAuth())).Methods("PATCH")
 	// route to make business promotional post completed field true/false
-	businessPromotionalPostRouter.HandleFunc("/completed/{id}", middleware.Chain(businessPromotionalPostCompleted, authPackage.AdminAuth())).Methods("PATCH")
+	businessPromotionalPostRouter.HandleFunc("/completed/{id}", middleware.Chain(businessPromotionalPostCompleted, authPackage.RequirePermission(authPackage.BPPWrite), authPackage.AdminAuth())).Methods("PATCH")
 	// route to get business promotional post cover image
-	businessPromotionalPostRouter.HandleFunc("/{id}/cover_image", middleware.Chain(getBusinessPromotionalPostCoverImage, authPackage.AdminAuth())).Methods("GET")
+	businessPromotionalPostRouter.HandleFunc("/{id}/cover_image", middleware.Chain(getBusinessPromotionalPostCoverImage, authPackage.RequirePermission(authPackage.BPPRead), authPackage.AdminAuth())).Methods("GET")
 
 	// e-newspaper router
 	eNewspaperRouter := adminRouter.PathPrefix("/e-newspaper").Subrouter()
 	// route to add e-newspaper category
-	eNewspaperRouter.HandleFunc("/category", middleware.Chain(addENewspaperCategory, authPackage.AdminAuth())).Methods("POST")
+	eNewspaperRouter.HandleFunc("/category", middleware.Chain(addENewspaperCategory, authPackage.RequirePermission(authPackage.ENewspaperWrite), authPackage.AdminAuth())).Methods("POST")
 	// route to get e-newspaper category list
-	eNewspaperRouter.HandleFunc("/category/list", middleware.Chain(getENewspaperCategoryList, authPackage.AdminAuth())).Methods("GET")
+	eNewspaperRouter.HandleFunc("/category/list", middleware.Chain(getENewspaperCategoryList, authPackage.RequirePermission(authPackage.ENewspaperRead), authPackage.AdminAuth())).Methods("GET")
 	// route to update e-newspaper category
-	eNewspaperRouter.HandleFunc("/category/{id}", middleware.Chain(updateENewspaperCategory, authPackage.AdminAuth())).Methods("PATCH")
+	eNewspaperRouter.HandleFunc("/category/{id}", middleware.Chain(updateENewspaperCategory, authPackage.RequirePermission(authPackage.ENewspaperWrite), authPackage.AdminAuth())).Methods("PATCH")
 	// route to delete e-newspaper category
-	eNewspaperRouter.HandleFunc("/category/{id}", middleware.Chain(deleteENewspaperCategory, authPackage.AdminAuth())).Methods("DELETE")
+	eNewspaperRouter.HandleFunc("/category/{id}", middleware.Chain(deleteENewspaperCategory, authPackage.RequirePermission(authPackage.ENewspaperDelete), authPackage.AdminAuth())).Methods("DELETE")
 	// route to add e-newspaper
-	eNewspaperRouter.HandleFunc("/add", middleware.Chain(addENewspaper, authPackage.AdminAuth())).Methods("POST")
+	eNewspaperRouter.HandleFunc("/add", middleware.Chain(addENewspaper, authPackage.RequirePermission(authPackage.ENewspaperWrite), authPackage.AdminAuth())).Methods("POST")
 	// route to edit e-newspaper
-	eNewspaperRouter.HandleFunc("/edit/{id}", middleware.Chain(editENewspaper, authPackage.AdminAuth())).Methods("PATCH")
+	eNewspaperRouter.HandleFunc("/edit/{id}", middleware.Chain(editENewspaper, authPackage.RequirePermission(authPackage.ENewspaperWrite), authPackage.AdminAuth())).Methods("PATCH")
 	// route to delete e-newspaper
-	eNewspaperRouter.HandleFunc("/delete/{id}", middleware.Chain(deleteENewspaper, authPackage.AdminAuth())).Methods("DELETE")
-	eNewspaperRouter.HandleFunc("/archive/{id}", middleware.Chain(archiveENewspaper, authPackage.AdminAuth())).Methods("PATCH")
-	eNewspaperRouter.HandleFunc("/count/{period}", middleware.Chain(getENewspaperCount, authPackage.AdminAuth())).Methods("GET")
-	eNewspaperRouter.HandleFunc("/count", middleware.Chain(getENewspaperCountAll, authPackage.AdminAuth())).Methods("GET")
-	eNewspaperRouter.HandleFunc("/list", middleware.Chain(getENewspaperList, authPackage.AdminAuth())).Methods("GET")
-	eNewspaperRouter.HandleFunc("/unarchive/{id}", middleware.Chain(unArchiveENewspaper, authPackage.AdminAuth())).Methods("PATCH")
-	// route to make e-newspaper completed field true/false
-	eNewspaperRouter.HandleFunc("/completed/{id}", middleware.Chain(eNewspaperCompleted, authPackage.AdminAuth())).Methods("PATCH")
+	eNewspaperRouter.HandleFunc("/delete/{id}", middleware.Chain(deleteENewspaper, authPackage.RequirePermission(authPackage.ENewspaperDelete), authPackage.AdminAuth())).Methods("DELETE")
+	eNewspaperRouter.HandleFunc("/archive/{id}", middleware.Chain(eNewspaperWorkflow.actionHandler("archive"), authPackage.RequirePermission(authPackage.ENewspaperWrite), authPackage.AdminAuth())).Methods("PATCH")
+	eNewspaperRouter.HandleFunc("/count/{period}", middleware.Chain(getENewspaperCount, authPackage.RequirePermission(authPackage.ENewspaperRead), authPackage.AdminAuth())).Methods("GET")
+	eNewspaperRouter.HandleFunc("/count", middleware.Chain(getENewspaperCountAll, authPackage.RequirePermission(authPackage.ENewspaperRead), authPackage.AdminAuth())).Methods("GET")
+	eNewspaperRouter.HandleFunc("/list", middleware.Chain(getENewspaperList, authPackage.RequirePermission(authPackage.ENewspaperRead), authPackage.AdminAuth())).Methods("GET")
+	eNewspaperRouter.HandleFunc("/unarchive/{id}", middleware.Chain(eNewspaperWorkflow.actionHandler("unarchive"), authPackage.RequirePermission(authPackage.ENewspaperWrite), authPackage.AdminAuth())).Methods("PATCH")
+	// routes of the e-newspaper workflow: transition by the form values action and comment, and the transitions so far
+	// route to publish or unpublish the e-newspaper by the completed field, as the publish and unpublish actions
+	eNewspaperRouter.HandleFunc("/completed/{id}", middleware.Chain(eNewspaperWorkflow.completedHandler, authPackage.RequirePermission(authPackage.ENewspaperWrite), authPackage.AdminAuth())).Methods("PATCH")
+	eNewspaperRouter.HandleFunc("/status/{id}", middleware.Chain(eNewspaperWorkflow.statusHandler, authPackage.RequirePermission(authPackage.ENewspaperWrite), authPackage.AdminAuth())).Methods("PATCH")
+	eNewspaperRouter.HandleFunc("/{id}/status/history", middleware.Chain(eNewspaperWorkflow.historyHandler, authPackage.RequirePermission(authPackage.ENewspaperRead), authPackage.AdminAuth())).Methods("GET")
 	// route to get /e-newspaper/{id}/file/{alphabet} where file is pdf, alphabet is latin or cyrillic
-	eNewspaperRouter.HandleFunc("/{id}/file/{alphabet}", middleware.Chain(getENewspaperFile, authPackage.AdminAuth())).Methods("GET")
+	eNewspaperRouter.HandleFunc("/{id}/file/{alphabet}", middleware.Chain(getENewspaperFile, authPackage.RequirePermission(authPackage.ENewspaperRead), authPackage.AdminAuth())).Methods("GET")
 	// route to get /e-newspaper/{id}/cover_image
-	eNewspaperRouter.HandleFunc("/{id}/cover_image", middleware.Chain(getENewspaperCoverImage, authPackage.AdminAuth())).Methods("GET")
+	eNewspaperRouter.HandleFunc("/{id}/cover_image", middleware.Chain(getENewspaperCoverImage, authPackage.RequirePermission(authPackage.ENewspaperRead), authPackage.AdminAuth())).Methods("GET")
 
 	photoGalleryRouter := adminRouter.PathPrefix("/photo-gallery").Subrouter()
-	photoGalleryRouter.HandleFunc("/add", middleware.Chain(addPhotoGallery, authPackage.AdminAuth())).Methods("POST")
-	photoGalleryRouter.HandleFunc("/list", middleware.Chain(getPhotoGalleryList, authPackage.AdminAuth())).Methods("GET")
+	photoGalleryRouter.HandleFunc("/add", middleware.Chain(addPhotoGallery, authPackage.RequirePermission(authPackage.PhotoGalleryWrite), authPackage.AdminAuth())).Methods("POST")
+	photoGalleryRouter.HandleFunc("/list", middleware.Chain(getPhotoGalleryList, authPackage.RequirePermission(authPackage.PhotoGalleryRead), authPackage.AdminAuth())).Methods("GET")
 	// route to update photo gallery
-	photoGalleryRouter.HandleFunc("/update/{id}", middleware.Chain(updatePhotoGallery, authPackage.AdminAuth())).Methods("PATCH")
+	photoGalleryRouter.HandleFunc("/update/{id}", middleware.Chain(updatePhotoGallery, authPackage.RequirePermission(authPackage.PhotoGalleryWrite), authPackage.AdminAuth())).Methods("PATCH")
 	// route to delete photo gallery
-	photoGalleryRouter.HandleFunc("/delete/{id}", middleware.Chain(deletePhotoGallery, authPackage.AdminAuth())).Methods("DELETE")
+	photoGalleryRouter.HandleFunc("/delete/{id}", middleware.Chain(deletePhotoGallery, authPackage.RequirePermission(authPackage.PhotoGalleryDelete), authPackage.AdminAuth())).Methods("DELETE")
 
 	photoGalleryPhotosRouter := photoGalleryRouter.PathPrefix("/{id}/photos").Subrouter()
-	photoGalleryPhotosRouter.HandleFunc("/add", middleware.Chain(photoGalleryAddPhotos, authPackage.AdminAuth())).Methods("POST")
-	photoGalleryPhotosRouter.Handle("", middleware.Chain(getPhotoGalleryPhotos, authPackage.AdminAuth())).Methods("GET")
+	photoGalleryPhotosRouter.HandleFunc("/add", middleware.Chain(photoGalleryAddPhotos, authPackage.RequirePermission(authPackage.PhotoGalleryWrite), authPackage.AdminAuth())).Methods("POST")
+	photoGalleryPhotosRouter.Handle("", middleware.Chain(getPhotoGalleryPhotos, authPackage.RequirePermission(authPackage.PhotoGalleryRead), authPackage.AdminAuth())).Methods("GET")
 	// route to get photo gallery photo
-	photoGalleryPhotosRouter.HandleFunc("/{photo_id}", middleware.Chain(getPhotoGalleryPhoto, authPackage.AdminAuth())).Methods("GET")
+	photoGalleryPhotosRouter.HandleFunc("/{photo_id}", middleware.Chain(getPhotoGalleryPhoto, authPackage.RequirePermission(authPackage.PhotoGalleryRead), authPackage.AdminAuth())).Methods("GET")
 	// route to delete photo gallery photo
-	photoGalleryPhotosRouter.HandleFunc("/delete/{photo_id}", middleware.Chain(deletePhotoGalleryPhoto, authPackage.AdminAuth())).Methods("DELETE")
+	photoGalleryPhotosRouter.HandleFunc("/delete/{photo_id}", middleware.Chain(deletePhotoGalleryPhoto, authPackage.RequirePermission(authPackage.PhotoGalleryDelete), authPackage.AdminAuth())).Methods("DELETE")
 
 	contactRouter := adminRouter.PathPrefix("/contact").Subrouter()
 
 	contactAppealRouter := contactRouter.PathPrefix("/appeal").Subrouter()
-	contactAppealRouter.HandleFunc("/list", middleware.Chain(appealList, authPackage.AdminAuth())).Methods("GET")
-	contactAppealRouter.HandleFunc("/{id}/picture", middleware.Chain(appealPicture, authPackage.AdminAuth())).Methods("GET")
-	contactAppealRouter.HandleFunc("/{id}/video", middleware.Chain(appealVideo, authPackage.AdminAuth())).Methods("GET")
-	contactAppealRouter.HandleFunc("/count/{period}", middleware.Chain(getAppealCount, authPackage.AdminAuth())).Methods("GET")
-	contactAppealRouter.HandleFunc("/count", middleware.Chain(getAppealCountAll, authPackage.AdminAuth())).Methods("GET")
+	contactAppealRouter.HandleFunc("/list", middleware.Chain(appealList, authPackage.RequirePermission(authPackage.AppealRead), authPackage.AdminAuth())).Methods("GET")
+	contactAppealRouter.HandleFunc("/{id}/picture", middleware.Chain(appealPicture, authPackage.RequirePermission(authPackage.AppealRead), authPackage.AdminAuth())).Methods("GET")
+	contactAppealRouter.HandleFunc("/{id}/video", middleware.Chain(appealVideo, authPackage.RequirePermission(authPackage.AppealRead), authPackage.AdminAuth())).Methods("GET")
+	contactAppealRouter.HandleFunc("/count/{period}", middleware.Chain(getAppealCount, authPackage.RequirePermission(authPackage.AppealRead), authPackage.AdminAuth())).Methods("GET")
+	contactAppealRouter.HandleFunc("/count", middleware.Chain(getAppealCountAll, authPackage.RequirePermission(authPackage.AppealRead), authPackage.AdminAuth())).Methods("GET")
 	// route to delete appeal
-	contactAppealRouter.HandleFunc("/delete/{id}", middleware.Chain(deleteAppeal, authPackage.AdminAuth())).Methods("DELETE")
+	contactAppealRouter.HandleFunc("/delete/{id}", middleware.Chain(deleteAppeal, authPackage.RequirePermission(authPackage.AppealDelete), authPackage.AdminAuth())).Methods("DELETE")
 
-	contactRouter.HandleFunc("", middleware.Chain(createAdminContact, authPackage.AdminAuth())).Methods("POST")
-	contactRouter.HandleFunc("", middleware.Chain(getAdminContact, authPackage.AdminAuth())).Methods("GET")
-	contactRouter.HandleFunc("/{id}", middleware.Chain(updateAdminContact, authPackage.AdminAuth())).Methods("PATCH")
+	contactRouter.HandleFunc("", middleware.Chain(createAdminContact, authPackage.RequirePermission(authPackage.ContactWrite), authPackage.AdminAuth())).Methods("POST")
+	contactRouter.HandleFunc("", middleware.Chain(getAdminContact, authPackage.RequirePermission(authPackage.ContactRead), authPackage.AdminAuth())).Methods("GET")
+	contactRouter.HandleFunc("/{id}", middleware.Chain(updateAdminContact, authPackage.RequirePermission(authPackage.ContactWrite), authPackage.AdminAuth())).Methods("PATCH")
 
 	searchRouter := adminRouter.PathPrefix("/search").Subrouter()
 	// route to search in appeals table
-	searchRouter.HandleFunc("/appeal", middleware.Chain(searchAppeal, authPackage.AdminAuth())).Methods("GET")
+	searchRouter.HandleFunc("/appeal", middleware.Chain(searchAppeal, authPackage.RequirePermission(authPackage.AppealRead), authPackage.AdminAuth())).Methods("GET")
 	// route to search in articles table
-	searchRouter.HandleFunc("/article", middleware.Chain(searchArticle, authPackage.AdminAuth())).Methods("GET")
+	searchRouter.HandleFunc("/article", middleware.Chain(searchArticle, authPackage.RequirePermission(authPackage.ArticleRead), authPackage.AdminAuth())).Methods("GET")
 	// route to search in business_promotional_posts table
-	searchRouter.HandleFunc("/business-promotional", middleware.Chain(searchBusinessPromotional, authPackage.AdminAuth())).Methods("GET")
+	searchRouter.HandleFunc("/business-promotional", middleware.Chain(searchBusinessPromotional, authPackage.RequirePermission(authPackage.BPPRead), authPackage.AdminAuth())).Methods("GET")
 	// route to search in e_newspapers table
-	searchRouter.HandleFunc("/e-newspaper", middleware.Chain(searchENewspaper, authPackage.AdminAuth())).Methods("GET")
+	searchRouter.HandleFunc("/e-newspaper", middleware.Chain(searchENewspaper, authPackage.RequirePermission(authPackage.ENewspaperRead), authPackage.AdminAuth())).Methods("GET")
 	// route to search in news_posts table
-	searchRouter.HandleFunc("/news", middleware.Chain(searchNews, authPackage.AdminAuth())).Methods("GET")
+	searchRouter.HandleFunc("/news", middleware.Chain(searchNews, authPackage.RequirePermission(authPackage.NewsRead), authPackage.AdminAuth())).Methods("GET")
 	// route to search in photo_gallery table
-	searchRouter.HandleFunc("/photo-gallery", middleware.Chain(searchPhotoGallery, authPackage.AdminAuth())).Methods("GET")
+	searchRouter.HandleFunc("/photo-gallery", middleware.Chain(searchPhotoGallery, authPackage.RequirePermission(authPackage.PhotoGalleryRead), authPackage.AdminAuth())).Methods("GET")
 	// route to search in photo_gallery_photos table
-	searchRouter.HandleFunc("/photo-gallery/{id}/photos", middleware.Chain(searchPhotoGalleryPhotos, authPackage.AdminAuth())).Methods("GET")
+	searchRouter.HandleFunc("/photo-gallery/{id}/photos", middleware.Chain(searchPhotoGalleryPhotos, authPackage.RequirePermission(authPackage.PhotoGalleryRead), authPackage.AdminAuth())).Methods("GET")
 
 	// video news router: location: admin/video-news.go
 	videoNewsRouter := adminRouter.PathPrefix("/video-news").Subrouter()
 	// route to add video news
-	videoNewsRouter.HandleFunc("/add", middleware.Chain(addVideoNews, authPackage.AdminAuth())).Methods("POST")
+	videoNewsRouter.HandleFunc("/add", middleware.Chain(addVideoNews, authPackage.RequirePermission(authPackage.VideoNewsWrite), authPackage.AdminAuth())).Methods("POST")
 	// route to update video news
-	videoNewsRouter.HandleFunc("/update/{id}", middleware.Chain(updateVideoNews, authPackage.AdminAuth())).Methods("PATCH")
+	videoNewsRouter.HandleFunc("/update/{id}", middleware.Chain(updateVideoNews, authPackage.RequirePermission(authPackage.VideoNewsWrite), authPackage.AdminAuth())).Methods("PATCH")
 	// route to delete video news
-	videoNewsRouter.HandleFunc("/delete/{id}", middleware.Chain(deleteVideoNews, authPackage.AdminAuth())).Methods("DELETE")
+	videoNewsRouter.HandleFunc("/delete/{id}", middleware.Chain(deleteVideoNews, authPackage.RequirePermission(authPackage.VideoNewsDelete), authPackage.AdminAuth())).Methods("DELETE")
 	// route to get a video news list
-	videoNewsRouter.HandleFunc("/list", middleware.Chain(getVideoNewsList, authPackage.AdminAuth())).Methods("GET")
+	videoNewsRouter.HandleFunc("/list", middleware.Chain(getVideoNewsList, authPackage.RequirePermission(authPackage.VideoNewsRead), authPackage.AdminAuth())).Methods("GET")
+	// routes of the video news workflow: transition by the form values action and comment, and the transitions so far
+	videoNewsRouter.HandleFunc("/status/{id}", middleware.Chain(videoNewsWorkflow.statusHandler, authPackage.RequirePermission(authPackage.VideoNewsWrite), authPackage.AdminAuth())).Methods("PATCH")
+	videoNewsRouter.HandleFunc("/{id}/status/history", middleware.Chain(videoNewsWorkflow.historyHandler, authPackage.RequirePermission(authPackage.VideoNewsRead), authPackage.AdminAuth())).Methods("GET")
 
 	// article comment router
 	articleCommentRouter := articleRouter.PathPrefix("/{id}/comment").Subrouter()
 	// route to get article comment list
-	articleCommentRouter.HandleFunc("/list", middleware.Chain(getArticleCommentList, authPackage.AdminAuth())).Methods("GET") // Go file path: admin/article_comment.go
+	articleCommentRouter.HandleFunc("/list", middleware.Chain(getArticleCommentList, authPackage.RequirePermission(authPackage.CommentRead), authPackage.AdminAuth())).Methods("GET") // Go file path: admin/article_comment.go
 	// route approve/disapprove article comment
-	articleCommentRouter.HandleFunc("/approve/{comment_id}", middleware.Chain(approveArticleComment, authPackage.AdminAuth())).Methods("PATCH") // Go file path: admin/article_comment.go
+	articleCommentRouter.HandleFunc("/approve/{comment_id}", middleware.Chain(approveArticleComment, authPackage.RequirePermission(authPackage.CommentModerate), authPackage.AdminAuth())).Methods("PATCH") // Go file path: admin/article_comment.go
 
 	// e_newspaper comment router
 	eNewspaperCommentRouter := eNewspaperRouter.PathPrefix("/{id}/comment").Subrouter()
 	// route to get e-newspaper comment list
-	eNewspaperCommentRouter.HandleFunc("/list", middleware.Chain(getENewspaperCommentList, authPackage.AdminAuth())).Methods("GET") // Go file path: admin/e_newspaper_comment.go
+	eNewspaperCommentRouter.HandleFunc("/list", middleware.Chain(getENewspaperCommentList, authPackage.RequirePermission(authPackage.CommentRead), authPackage.AdminAuth())).Methods("GET") // Go file path: admin/e_newspaper_comment.go
 	// route approve/disapprove e-newspaper comment
-	eNewspaperCommentRouter.HandleFunc("/approve/{comment_id}", middleware.Chain(approveENewspaperComment, authPackage.AdminAuth())).Methods("PATCH") // Go file path: admin/e_newspaper_comment.go
+	eNewspaperCommentRouter.HandleFunc("/approve/{comment_id}", middleware.Chain(approveENewspaperComment, authPackage.RequirePermission(authPackage.CommentModerate), authPackage.AdminAuth())).Methods("PATCH") // Go file path: admin/e_newspaper_comment.go
 
 	// news post comment router
 	newsPostCommentRouter := newsPostRouter.PathPrefix("/{id}/comment").Subrouter()
 	// route to get news post comment list
-	newsPostCommentRouter.HandleFunc("/list", middleware.Chain(getNewsPostCommentList, authPackage.AdminAuth())).Methods("GET") // Go file path: admin/news_post_comment.go
+	newsPostCommentRouter.HandleFunc("/list", middleware.Chain(getNewsPostCommentList, authPackage.RequirePermission(authPackage.CommentRead), authPackage.AdminAuth())).Methods("GET") // Go file path: admin/news_post_comment.go
 	// route approve/disapprove news post comment
-	newsPostCommentRouter.HandleFunc("/approve/{comment_id}", middleware.Chain(approveNewsPostComment, authPackage.AdminAuth())).Methods("PATCH") // Go file path: admin/news_post_comment.go
+	newsPostCommentRouter.HandleFunc("/approve/{comment_id}", middleware.Chain(approveNewsPostComment, authPackage.RequirePermission(authPackage.CommentModerate), authPackage.AdminAuth())).Methods("PATCH") // Go file path: admin/news_post_comment.go
 
 	// video news comment router
 	videoNewsCommentRouter := videoNewsRouter.PathPrefix("/{id}/comment").Subrouter() // Go file path: admin/video_news_comment.go
 	// route to get video news comment list
-	videoNewsCommentRouter.HandleFunc("/list", middleware.Chain(getVideoNewsCommentList, authPackage.AdminAuth())).Methods("GET") // Go file path: admin/video_news_comment.go
+	videoNewsCommentRouter.HandleFunc("/list", middleware.Chain(getVideoNewsCommentList, authPackage.RequirePermission(authPackage.CommentRead), authPackage.AdminAuth())).Methods("GET") // Go file path: admin/video_news_comment.go
 	// route approve/disapprove video news comment
-	videoNewsCommentRouter.HandleFunc("/approve/{comment_id}", middleware.Chain(approveVideoNewsComment, authPackage.AdminAuth())).Methods("PATCH") // Go file path: admin/video_news_comment.go
+	videoNewsCommentRouter.HandleFunc("/approve/{comment_id}", middleware.Chain(approveVideoNewsComment, authPackage.RequirePermission(authPackage.CommentModerate), authPackage.AdminAuth())).Methods("PATCH") // Go file path: admin/video_news_comment.go
+
+	// route to get the database connection pool statistics
+	// route to list the content waiting for review: location: admin/workflow.go
+	adminRouter.HandleFunc("/review/inbox", middleware.Chain(getReviewInbox, authPackage.RequirePermission(authPackage.ContentReview), authPackage.AdminAuth())).Methods("GET")
+	// route to query the audit log: location: admin/audit.go
+	adminRouter.HandleFunc("/audit-log", middleware.Chain(getAuditLog, authPackage.RequirePermission(authPackage.AuditRead), authPackage.AdminAuth())).Methods("GET")
+	adminRouter.HandleFunc("/db/stats", middleware.Chain(getDBStats, authPackage.RequirePermission(authPackage.SystemRead), authPackage.AdminAuth())).Methods("GET") // Go file path: admin/database.go
+	// route to get the last runs and errors of the scheduled jobs: Go file path: admin/database.go
+	adminRouter.HandleFunc("/scheduler", middleware.Chain(getSchedulerStatus(jobs), authPackage.RequirePermission(authPackage.SystemRead), authPackage.AdminAuth())).Methods("GET")
 
 	return adminRouter
 }
@@ -0,0 +1,216 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/metrics"
+)
+
+// scheduledTables are the tables of the content with a publishing window
+var scheduledTables = []string{"news_posts", "articles", "e_newspapers", "video_news", "business_promotional_posts"}
+
+// PublishScheduled publishes the content whose publish_at has come and unpublishes the content whose unpublish_at has come.
+// publish_at is cleared once the content is published, so content unpublished by hand later is not published again,
+// and both times are cleared once it is unpublished, so content published by hand again is not unpublished again.
+// The content with the editorial workflow is only published once approved, and goes back to approved when unpublished.
+// It is run by the scheduler.
+func PublishScheduled() error {
+	db, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	// Start a new transaction
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("start a new transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	published := map[string]int64{}
+	unpublished := map[string]int64{}
+	for _, table := range scheduledTables {
+		publish := "UPDATE %s SET completed = true, publish_at = NULL, updated_at = NOW() WHERE publish_at <= $1 AND NOT archived"
+		unpublish := "UPDATE %s SET completed = false, publish_at = NULL, unpublish_at = NULL, updated_at = NOW() WHERE unpublish_at <= $1 AND completed"
+		if workflowTables[table] {
+			publish = "UPDATE %s SET status = 'published', completed = true, publish_at = NULL, updated_at = NOW() WHERE publish_at <= $1 AND status = 'approved'"
+			unpublish = "UPDATE %s SET status = 'approved', completed = false, publish_at = NULL, unpublish_at = NULL, updated_at = NOW() WHERE unpublish_at <= $1 AND status = 'published'"
+		}
+
+		res, err := tx.Exec(fmt.Sprintf(publish, table), now)
+		if err != nil {
+			return fmt.Errorf("publish %s: %v", table, err)
+		}
+		published[table], _ = res.RowsAffected()
+
+		res, err = tx.Exec(fmt.Sprintf(unpublish, table), now)
+		if err != nil {
+			return fmt.Errorf("unpublish %s: %v", table, err)
+		}
+		unpublished[table], _ = res.RowsAffected()
+	}
+
+	// Commit the transaction
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("commit the transaction: %v", err)
+	}
+
+	for _, table := range scheduledTables {
+		metrics.ScheduledPublications.Add(float64(published[table]), table, "publish")
+		metrics.ScheduledPublications.Add(float64(unpublished[table]), table, "unpublish")
+	}
+	return nil
+}
+
+// publishWindow is the publish_at and unpublish_at fields of an add or edit form
+type publishWindow struct {
+	PublishAt   sql.NullTime
+	UnpublishAt sql.NullTime
+	// publishSet and unpublishSet are true for the fields sent in the form, an empty field clears the time
+	publishSet   bool
+	unpublishSet bool
+}
+
+// parsePublishWindow reads publish_at and unpublish_at from the parsed form of r, RFC 3339 times
+func parsePublishWindow(r *http.Request) (publishWindow, error) {
+	var pw publishWindow
+	var err error
+	pw.PublishAt, pw.publishSet, err = formTime(r, "publish_at")
+	if err != nil {
+		return publishWindow{}, err
+	}
+	pw.UnpublishAt, pw.unpublishSet, err = formTime(r, "unpublish_at")
+	if err != nil {
+		return publishWindow{}, err
+	}
+
+	if !pw.valid() {
+		return publishWindow{}, errPublishWindow
+	}
+	return pw, nil
+}
+
+// errPublishWindow is returned for a window which closes before it opens, its message is returned to the admins
+var errPublishWindow = errors.New("unpublish_at must be after publish_at")
+
+// valid reports whether the window closes after it opens, a window without one of the times is valid
+func (pw publishWindow) valid() bool {
+	return !pw.PublishAt.Valid || !pw.UnpublishAt.Valid || pw.UnpublishAt.Time.After(pw.PublishAt.Time)
+}
+
+// check returns errPublishWindow when the window of an edit form closes before it opens,
+// the stored times of the row id of table taking the place of the fields which were not sent
+func (pw publishWindow) check(database *sql.DB, table, id string) error {
+	if pw.publishSet == pw.unpublishSet {
+		// both were checked by parsePublishWindow or the stored window stays
+		return nil
+	}
+
+	stored := publishWindow{}
+	err := database.QueryRow(fmt.Sprintf("SELECT publish_at, unpublish_at FROM %s WHERE id = $1", table), id).Scan(&stored.PublishAt, &stored.UnpublishAt)
+	if err != nil {
+		return fmt.Errorf("reading the publishing window: %v", err)
+	}
+	if pw.publishSet {
+		stored.PublishAt = pw.PublishAt
+	} else {
+		stored.UnpublishAt = pw.UnpublishAt
+	}
+	if !stored.valid() {
+		return errPublishWindow
+	}
+	return nil
+}
+
+// formTime returns the RFC 3339 time of field in UTC, NULL for an empty field, and whether the field was sent
+func formTime(r *http.Request, field string) (sql.NullTime, bool, error) {
+	values, ok := r.Form[field]
+	if !ok || len(values) == 0 {
+		return sql.NullTime{}, false, nil
+	}
+	t, err := parseTime(field, values[0])
+	if err != nil {
+		return sql.NullTime{}, false, err
+	}
+	return t, true, nil
+}
+
+// jsonPublishWindow reads publish_at and unpublish_at of a JSON body, the raw values of the fields, nil for the fields
+// which were not sent, and null or an empty string clearing the time
+func jsonPublishWindow(publishAt, unpublishAt json.RawMessage) (publishWindow, error) {
+	var pw publishWindow
+	var err error
+	pw.PublishAt, pw.publishSet, err = jsonTime(publishAt, "publish_at")
+	if err != nil {
+		return publishWindow{}, err
+	}
+	pw.UnpublishAt, pw.unpublishSet, err = jsonTime(unpublishAt, "unpublish_at")
+	if err != nil {
+		return publishWindow{}, err
+	}
+
+	if !pw.valid() {
+		return publishWindow{}, errPublishWindow
+	}
+	return pw, nil
+}
+
+// jsonTime is formTime of the raw JSON value of field
+func jsonTime(raw json.RawMessage, field string) (sql.NullTime, bool, error) {
+	if raw == nil {
+		return sql.NullTime{}, false, nil
+	}
+	var value *string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return sql.NullTime{}, false, fmt.Errorf("%s must be an RFC 3339 time, e.g. 2006-01-02T06:00:00+05:00", field)
+	}
+	if value == nil {
+		return sql.NullTime{}, true, nil
+	}
+
+	t, err := parseTime(field, *value)
+	if err != nil {
+		return sql.NullTime{}, false, err
+	}
+	return t, true, nil
+}
+
+// parseTime returns the RFC 3339 time value of field in UTC, NULL for an empty value
+func parseTime(field, value string) (sql.NullTime, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return sql.NullTime{}, fmt.Errorf("%s must be an RFC 3339 time, e.g. 2006-01-02T06:00:00+05:00", field)
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+}
+
+// update sets the times sent in the form on the row id of table
+func (pw publishWindow) update(ex execer, table, id string) error {
+	if pw.publishSet {
+		_, err := ex.Exec(fmt.Sprintf("UPDATE %s SET publish_at = $1, updated_at = NOW() WHERE id = $2", table), pw.PublishAt, id)
+		if err != nil {
+			return fmt.Errorf("writing publish_at into db: %v", err)
+		}
+	}
+	if pw.unpublishSet {
+		_, err := ex.Exec(fmt.Sprintf("UPDATE %s SET unpublish_at = $1, updated_at = NOW() WHERE id = $2", table), pw.UnpublishAt, id)
+		if err != nil {
+			return fmt.Errorf("writing unpublish_at into db: %v", err)
+		}
+	}
+	return nil
+}
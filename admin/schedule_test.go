@@ -0,0 +1,43 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestJSONPublishWindow(t *testing.T) {
+	at := time.Date(2024, 5, 1, 1, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name                   string
+		publishAt, unpublishAt json.RawMessage
+		want                   publishWindow
+		err                    bool
+	}{
+		{"left out", nil, nil, publishWindow{}, false},
+		{"null clears", json.RawMessage(`null`), nil, publishWindow{publishSet: true}, false},
+		{"empty string clears", nil, json.RawMessage(`""`), publishWindow{unpublishSet: true}, false},
+		{"time in utc", json.RawMessage(`"2024-05-01T06:00:00+05:00"`), nil, publishWindow{publishSet: true, PublishAt: nullTime(at)}, false},
+		{"both", json.RawMessage(`"2024-05-01T01:00:00Z"`), json.RawMessage(`"2024-05-02T01:00:00Z"`),
+			publishWindow{publishSet: true, PublishAt: nullTime(at), unpublishSet: true, UnpublishAt: nullTime(at.Add(24 * time.Hour))}, false},
+		{"closes before it opens", json.RawMessage(`"2024-05-02T01:00:00Z"`), json.RawMessage(`"2024-05-01T01:00:00Z"`), publishWindow{}, true},
+		{"not rfc 3339", json.RawMessage(`"2024-05-01"`), nil, publishWindow{}, true},
+		{"not a string", nil, json.RawMessage(`1714525200`), publishWindow{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jsonPublishWindow(tt.publishAt, tt.unpublishAt)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v, want an error %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("window = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: true}
+}
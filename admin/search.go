@@ -1,12 +1,13 @@
 package admin
 
 import (
-	"log"
 	"net/http"
 
 	"Tahlilchi.uz/db"
 	"Tahlilchi.uz/model"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/search"
+	"Tahlilchi.uz/toolkit"
 	"github.com/gorilla/mux"
 	"github.com/lib/pq"
 )
@@ -23,15 +24,14 @@ func searchAppeal(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	rows, err := database.Query("SELECT id, name, surname, phone_number, message, created_at FROM appeals WHERE name ILIKE $1 OR surname ILIKE $1 OR phone_number ILIKE $1 OR message ILIKE $1", "%"+search+"%")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -42,7 +42,7 @@ func searchAppeal(w http.ResponseWriter, r *http.Request) {
 		var appeal Appeal
 		err := rows.Scan(&appeal.ID, &appeal.Name, &appeal.Surname, &appeal.PhoneNumber, &appeal.Message, &appeal.CreatedAt)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -52,41 +52,45 @@ func searchAppeal(w http.ResponseWriter, r *http.Request) {
 	response.Res(w, "success", http.StatusOK, appeals)
 }
 
+// ArticleMatch is an article found by a search
+type ArticleMatch struct {
+	Article
+	search.Match
+}
+
 // searchArticle is the handler for the /admin/search/article endpoint.
-// It searches the articles table for the given query.
-// search columns: title_latin, description_latin, title_cyrillic, description_cyrillic, tags.
-// tags is text[].
+// It searches the articles table in both alphabets: titles, tags and descriptions, the best matches first.
+// Query parameters: search, page and limit.
 func searchArticle(w http.ResponseWriter, r *http.Request) {
-	search := r.URL.Query().Get("search")
-	if search == "" {
-		response.Res(w, "error", http.StatusBadRequest, "search query is missing")
+	q, err := search.Parse(r)
+	if err != nil {
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, tags, archived, created_at, updated_at, category, related, completed FROM articles WHERE title_latin ILIKE $1 OR description_latin ILIKE $1 OR title_cyrillic ILIKE $1 OR description_cyrillic ILIKE $1 OR tags @> ARRAY[$2]", "%"+search+"%", search)
+	rows, total, err := search.Article.Search(database, q, "t.id, t.title_latin, t.description_latin, t.title_cyrillic, t.description_cyrillic, t.videos, t.tags, t.archived, t.created_at, t.updated_at, t.category, t.related, t.completed, t.slug", "")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 	defer rows.Close()
 
-	var articles []Article
+	articles := []ArticleMatch{}
 	for rows.Next() {
-		var article Article
+		var article ArticleMatch
 		var tags pq.StringArray
 		var videos pq.StringArray
-		err := rows.Scan(&article.ID, &article.TitleLatin, &article.DescriptionLatin, &article.TitleCyrillic, &article.DescriptionCyrillic, &videos, &tags, &article.Archived, &article.CreatedAt, &article.UpdatedAt, &article.Category, &article.Related, &article.Completed)
+		err := rows.Scan(append([]any{&article.ID, &article.TitleLatin, &article.DescriptionLatin, &article.TitleCyrillic, &article.DescriptionCyrillic, &videos, &tags, &article.Archived, &article.CreatedAt, &article.UpdatedAt, &article.Category, &article.Related, &article.Completed, &article.Slug}, article.Dest()...)...)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -95,43 +99,47 @@ func searchArticle(w http.ResponseWriter, r *http.Request) {
 		articles = append(articles, article)
 	}
 
-	response.Res(w, "success", http.StatusOK, articles)
+	response.Res(w, "success", http.StatusOK, search.NewPage(q, articles, total))
+}
+
+// BusinessPromotionalPostMatch is a business promotional post found by a search
+type BusinessPromotionalPostMatch struct {
+	model.BusinessPromotionalPost
+	search.Match
 }
 
 // searchBusinessPromotional is the handler for the /admin/search/business-promotional endpoint.
-// It searches the business_promotional_posts table for the given query.
-// search columns: title_latin, description_latin, title_cyrillic, description_cyrillic, videos, partner.
-// videos is text[].
+// It searches the business_promotional_posts table in both alphabets: titles, partner and descriptions, the best matches first.
+// Query parameters: search, page and limit.
 func searchBusinessPromotional(w http.ResponseWriter, r *http.Request) {
-	search := r.URL.Query().Get("search")
-	if search == "" {
-		response.Res(w, "error", http.StatusBadRequest, "search query is missing")
+	q, err := search.Parse(r)
+	if err != nil {
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, expiration, created_at, updated_at, archived, partner, completed FROM business_promotional_posts WHERE title_latin ILIKE $1 OR description_latin ILIKE $1 OR title_cyrillic ILIKE $1 OR description_cyrillic ILIKE $1 OR videos @> ARRAY[$2] OR partner ILIKE $1", "%"+search+"%", search)
+	rows, total, err := search.BPPost.Search(database, q, "t.id, t.title_latin, t.description_latin, t.title_cyrillic, t.description_cyrillic, t.videos, t.expiration, t.created_at, t.updated_at, t.archived, t.partner, t.completed, t.slug", "")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 	defer rows.Close()
 
-	var businessPromotionalPosts []model.BusinessPromotionalPost
+	businessPromotionalPosts := []BusinessPromotionalPostMatch{}
 	for rows.Next() {
-		var businessPromotionalPost model.BusinessPromotionalPost
+		var businessPromotionalPost BusinessPromotionalPostMatch
 		var videos pq.StringArray
-		err := rows.Scan(&businessPromotionalPost.ID, &businessPromotionalPost.TitleLatin, &businessPromotionalPost.DescriptionLatin, &businessPromotionalPost.TitleCyrillic, &businessPromotionalPost.DescriptionCyrillic, &videos, &businessPromotionalPost.Expiration, &businessPromotionalPost.CreatedAt, &businessPromotionalPost.UpdatedAt, &businessPromotionalPost.Archived, &businessPromotionalPost.Partner, &businessPromotionalPost.Completed)
+		err := rows.Scan(append([]any{&businessPromotionalPost.ID, &businessPromotionalPost.TitleLatin, &businessPromotionalPost.DescriptionLatin, &businessPromotionalPost.TitleCyrillic, &businessPromotionalPost.DescriptionCyrillic, &videos, &businessPromotionalPost.Expiration, &businessPromotionalPost.CreatedAt, &businessPromotionalPost.UpdatedAt, &businessPromotionalPost.Archived, &businessPromotionalPost.Partner, &businessPromotionalPost.Completed, &businessPromotionalPost.Slug}, businessPromotionalPost.Dest()...)...)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -139,84 +147,93 @@ func searchBusinessPromotional(w http.ResponseWriter, r *http.Request) {
 		businessPromotionalPosts = append(businessPromotionalPosts, businessPromotionalPost)
 	}
 
-	response.Res(w, "success", http.StatusOK, businessPromotionalPosts)
+	response.Res(w, "success", http.StatusOK, search.NewPage(q, businessPromotionalPosts, total))
+}
+
+// ENewspaperMatch is an e-newspaper found by a search
+type ENewspaperMatch struct {
+	ENewspaper
+	search.Match
 }
 
 // searchENewspaper is the handler for the /admin/search/e-newspaper endpoint.
-// It searches the e_newspapers table for the given query.
-// search columns: title_latin, title_cyrillic.
+// It searches the e_newspapers table by the titles in both alphabets, the best matches first.
+// Query parameters: search, page and limit.
 func searchENewspaper(w http.ResponseWriter, r *http.Request) {
-	search := r.URL.Query().Get("search")
-	if search == "" {
-		response.Res(w, "error", http.StatusBadRequest, "search query is missing")
+	q, err := search.Parse(r)
+	if err != nil {
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, title_cyrillic, created_at, updated_at, archived, completed FROM e_newspapers WHERE title_latin ILIKE $1 OR title_cyrillic ILIKE $1", "%"+search+"%")
+	rows, total, err := search.ENewspaper.Search(database, q, "t.id, t.title_latin, t.title_cyrillic, t.created_at, t.updated_at, t.archived, t.completed, t.slug", "")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 	defer rows.Close()
 
-	var eNewspapers []ENewspaper
+	eNewspapers := []ENewspaperMatch{}
 	for rows.Next() {
-		var eNewspaper ENewspaper
-		err := rows.Scan(&eNewspaper.ID, &eNewspaper.TitleLatin, &eNewspaper.TitleCyrillic, &eNewspaper.CreatedAt, &eNewspaper.UpdatedAt, &eNewspaper.Archived, &eNewspaper.Completed)
+		var eNewspaper ENewspaperMatch
+		err := rows.Scan(append([]any{&eNewspaper.ID, &eNewspaper.TitleLatin, &eNewspaper.TitleCyrillic, &eNewspaper.CreatedAt, &eNewspaper.UpdatedAt, &eNewspaper.Archived, &eNewspaper.Completed, &eNewspaper.Slug}, eNewspaper.Dest()...)...)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 		eNewspapers = append(eNewspapers, eNewspaper)
 	}
 
-	response.Res(w, "success", http.StatusOK, eNewspapers)
+	response.Res(w, "success", http.StatusOK, search.NewPage(q, eNewspapers, total))
+}
+
+// NewsPostMatch is a news post found by a search
+type NewsPostMatch struct {
+	NewsPost
+	search.Match
 }
 
 // searchNews is the handler for the /admin/search/news endpoint.
-// It searches the news_posts table for the given query.
-// search columns: title_latin, description_latin, title_cyrillic, description_cyrillic, tags.
-// tags is text[].
+// It searches the news_posts table in both alphabets: titles, tags and descriptions, the best matches first.
+// Query parameters: search, page and limit.
 func searchNews(w http.ResponseWriter, r *http.Request) {
-	search := r.URL.Query().Get("search")
-	if search == "" {
-		response.Res(w, "error", http.StatusBadRequest, "search query is missing")
+	q, err := search.Parse(r)
+	if err != nil {
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags, archived, created_at, updated_at, category, subcategory, region, top, latest, related, completed FROM news_posts WHERE title_latin ILIKE $1 OR description_latin ILIKE $1 OR title_cyrillic ILIKE $1 OR description_cyrillic ILIKE $1 OR tags @> ARRAY[$2]", "%"+search+"%", search)
+	rows, total, err := search.NewsPost.Search(database, q, "t.id, t.title_latin, t.description_latin, t.title_cyrillic, t.description_cyrillic, t.video, t.tags, t.archived, t.created_at, t.updated_at, t.category, t.subcategory, t.region, t.top, t.latest, t.related, t.completed, t.slug", "")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 	defer rows.Close()
 
-	var newsPosts []NewsPost
+	newsPosts := []NewsPostMatch{}
 	for rows.Next() {
-		var newsPost NewsPost
+		var newsPost NewsPostMatch
 		var tags pq.StringArray
-		err := rows.Scan(&newsPost.ID, &newsPost.TitleLatin, &newsPost.DescriptionLatin, &newsPost.TitleCyrillic, &newsPost.DescriptionCyrillic, &newsPost.Video, &tags, &newsPost.Archived, &newsPost.CreatedAt, &newsPost.UpdatedAt, &newsPost.Category, &newsPost.Subcategory, &newsPost.Region, &newsPost.Top, &newsPost.Latest, &newsPost.Related, &newsPost.Completed)
+		err := rows.Scan(append([]any{&newsPost.ID, &newsPost.TitleLatin, &newsPost.DescriptionLatin, &newsPost.TitleCyrillic, &newsPost.DescriptionCyrillic, &newsPost.Video, &tags, &newsPost.Archived, &newsPost.CreatedAt, &newsPost.UpdatedAt, &newsPost.Category, &newsPost.Subcategory, &newsPost.Region, &newsPost.Top, &newsPost.Latest, &newsPost.Related, &newsPost.Completed, &newsPost.Slug}, newsPost.Dest()...)...)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -224,48 +241,53 @@ func searchNews(w http.ResponseWriter, r *http.Request) {
 		newsPosts = append(newsPosts, newsPost)
 	}
 
-	response.Res(w, "success", http.StatusOK, newsPosts)
+	response.Res(w, "success", http.StatusOK, search.NewPage(q, newsPosts, total))
+}
+
+// PhotoGalleryMatch is a photo gallery found by a search
+type PhotoGalleryMatch struct {
+	PhotoGallery
+	search.Match
 }
 
 // searchPhotoGallery is the handler for the /admin/search/photo-gallery endpoint.
-// It searches the photo_gallery table for the given query.
-// search columns: title_latin, title_cyrillic.
+// It searches the photo_gallery table by the titles in both alphabets, the best matches first.
+// Query parameters: search, page and limit.
 func searchPhotoGallery(w http.ResponseWriter, r *http.Request) {
-	search := r.URL.Query().Get("search")
-	if search == "" {
-		response.Res(w, "error", http.StatusBadRequest, "search query is missing")
+	q, err := search.Parse(r)
+	if err != nil {
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, title_cyrillic, created_at, updated_at FROM photo_gallery WHERE title_latin ILIKE $1 OR title_cyrillic ILIKE $1", "%"+search+"%")
+	rows, total, err := search.PhotoGallery.Search(database, q, "t.id, t.title_latin, t.title_cyrillic, t.created_at, t.updated_at", "")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 	defer rows.Close()
 
-	var photoGalleries []PhotoGallery
+	photoGalleries := []PhotoGalleryMatch{}
 	for rows.Next() {
-		var photoGallery PhotoGallery
-		err := rows.Scan(&photoGallery.ID, &photoGallery.TitleLatin, &photoGallery.TitleCyrillic, &photoGallery.CreatedAt, &photoGallery.UpdatedAt)
+		var photoGallery PhotoGalleryMatch
+		err := rows.Scan(append([]any{&photoGallery.ID, &photoGallery.TitleLatin, &photoGallery.TitleCyrillic, &photoGallery.CreatedAt, &photoGallery.UpdatedAt}, photoGallery.Dest()...)...)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 		photoGalleries = append(photoGalleries, photoGallery)
 	}
 
-	response.Res(w, "success", http.StatusOK, photoGalleries)
+	response.Res(w, "success", http.StatusOK, search.NewPage(q, photoGalleries, total))
 }
 
 // searchPhotoGalleryPhotos is the handler for the /admin/search/photo-gallery/photos endpoint.
@@ -283,15 +305,14 @@ func searchPhotoGalleryPhotos(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	rows, err := database.Query("SELECT id, photo_gallery, file_name, created_at from photo_gallery_photos WHERE file_name ILIKE $1 AND photo_gallery = $2", "%"+search+"%", id)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -302,7 +323,7 @@ func searchPhotoGalleryPhotos(w http.ResponseWriter, r *http.Request) {
 		var photoGalleryPhoto PhotoGalleryPhoto
 		err := rows.Scan(&photoGalleryPhoto.ID, &photoGalleryPhoto.PhotoGallery, &photoGalleryPhoto.FileName, &photoGalleryPhoto.CreatedAt)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
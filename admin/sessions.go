@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"net/http"
+
+	"Tahlilchi.uz/authPackage"
+	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/toolkit"
+)
+
+// listSessions is a route handler function to get the active sessions of the logged in admin
+func listSessions(w http.ResponseWriter, r *http.Request) {
+	session, _ := authPackage.Store.Get(r, "Tahlilchi.uz-admin")
+
+	list, err := authPackage.AdminSessions(authPackage.AdminEmail(r), session.ID)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, list)
+}
+
+// revokeSession is a route handler function for the logged in admin to revoke one of their own sessions
+func revokeSession(w http.ResponseWriter, r *http.Request) {
+	id, err := toolkit.GetID(r)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	revoked, err := authPackage.RevokeSession(authPackage.AdminEmail(r), int64(id))
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	if !revoked {
+		response.Res(w, "error", http.StatusNotFound, "session not found")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, "session revoked")
+}
+
+// revokeAdminSessions is a route handler function to log another admin out of all their sessions
+func revokeAdminSessions(w http.ResponseWriter, r *http.Request) {
+	a, ok := targetAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	n, err := authPackage.RevokeAdminSessions(a.Email, "")
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, map[string]int64{"revoked": n})
+}
@@ -0,0 +1,78 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/translit"
+)
+
+// alphabetFields are the names of a form field in the Latin and in the Cyrillic alphabet
+type alphabetFields struct {
+	Latin    string
+	Cyrillic string
+	// HTML is true for the rich text fields, whose tags are not transliterated
+	HTML bool
+}
+
+var (
+	titleFields       = alphabetFields{Latin: "title_latin", Cyrillic: "title_cyrillic"}
+	descriptionFields = alphabetFields{Latin: "description_latin", Cyrillic: "description_cyrillic", HTML: true}
+)
+
+// wantsAutofill reports whether the form or the query of r asks for autofill=true
+func wantsAutofill(r *http.Request) bool {
+	ok, _ := strconv.ParseBool(r.FormValue("autofill"))
+	return ok
+}
+
+// autofill fills the empty alphabet of the fields of the parsed form of r by transliterating the other one,
+// when the form asks for it with autofill=true. The fields sent in both alphabets are kept as they are.
+func autofill(r *http.Request, fields ...alphabetFields) {
+	if !wantsAutofill(r) {
+		return
+	}
+	for _, f := range fields {
+		latin, cyrillic := autofillPair(r.FormValue(f.Latin), r.FormValue(f.Cyrillic), f.HTML)
+		r.Form.Set(f.Latin, latin)
+		r.Form.Set(f.Cyrillic, cyrillic)
+	}
+}
+
+// autofillPair returns the text in both alphabets, the empty one transliterated from the other
+func autofillPair(latin, cyrillic string, html bool) (string, string) {
+	switch {
+	case latin == "" && cyrillic != "":
+		latin, _ = translit.To(translit.Latin, cyrillic, html)
+	case cyrillic == "" && latin != "":
+		cyrillic, _ = translit.To(translit.Cyrillic, latin, html)
+	}
+	return latin, cyrillic
+}
+
+// Transliteration is the preview of a conversion
+type Transliteration struct {
+	To   string `json:"to"`
+	Text string `json:"text"`
+}
+
+// previewTransliteration is a route handler function converting the form value text to the alphabet to, latin or cyrillic.
+// html=true keeps the HTML tags of the text. Nothing is stored.
+func previewTransliteration(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	html, _ := strconv.ParseBool(r.FormValue("html"))
+	to := r.FormValue("to")
+	text, ok := translit.To(to, r.FormValue("text"), html)
+	if !ok {
+		response.Res(w, "error", http.StatusBadRequest, "to must be latin or cyrillic")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, Transliteration{To: to, Text: text})
+}
@@ -0,0 +1,529 @@
+package admin
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"Tahlilchi.uz/authPackage"
+	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/toolkit"
+)
+
+// twoFactorMaxAttempts is the number of wrong codes after which the second login step has to be started over
+const twoFactorMaxAttempts = 5
+
+// twoFactorAdmin is the two-factor state of an admin account
+type twoFactorAdmin struct {
+	id       int
+	role     string
+	password string
+	secret   string
+	enabled  bool
+}
+
+// getTwoFactorAdmin is a function to read the two-factor state of the admin of the current session
+func getTwoFactorAdmin(r *http.Request) (twoFactorAdmin, error) {
+	database, err := db.DB()
+	if err != nil {
+		return twoFactorAdmin{}, err
+	}
+
+	var a twoFactorAdmin
+	err = database.QueryRow("SELECT id, role, password, COALESCE(totp_secret, ''), totp_enabled FROM admins WHERE email = $1", authPackage.AdminEmail(r)).Scan(&a.id, &a.role, &a.password, &a.secret, &a.enabled)
+	return a, err
+}
+
+// checkTOTP reports whether code is a valid TOTP code of the admin which was not used before
+func checkTOTP(database *sql.DB, id int, secret, code string) (bool, error) {
+	var lastStep int64
+	if err := database.QueryRow("SELECT totp_last_step FROM admins WHERE id = $1", id).Scan(&lastStep); err != nil {
+		return false, err
+	}
+
+	step, ok := authPackage.ValidateTOTP(secret, code, time.Now(), lastStep)
+	if !ok {
+		return false, nil
+	}
+
+	// remember the time step, so the same code cannot be replayed; the condition stops parallel requests with the same code
+	res, err := database.Exec("UPDATE admins SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1", step, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// useRecoveryCode reports whether code is an unused recovery code of the admin and marks it as used
+func useRecoveryCode(database *sql.DB, id int, code string) (bool, error) {
+	res, err := database.Exec("UPDATE admin_recovery_codes SET used_at = NOW() WHERE admin_id = $1 AND code_hash = $2 AND used_at IS NULL", id, authPackage.HashRecoveryCode(code))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// replaceRecoveryCodes generates new recovery codes for the admin, replacing the old ones
+func replaceRecoveryCodes(tx *sql.Tx, id int) ([]string, error) {
+	codes, err := authPackage.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM admin_recovery_codes WHERE admin_id = $1", id); err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		if _, err := tx.Exec("INSERT INTO admin_recovery_codes (admin_id, code_hash) VALUES ($1, $2)", id, authPackage.HashRecoveryCode(code)); err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// loginTwoFactor is a route handler function for the second login step.
+// The code may be a TOTP code or one of the recovery codes.
+func loginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	session, _ := authPackage.Store.Get(r, "Tahlilchi.uz-admin")
+
+	email, ok := session.Values["2fa-email"].(string)
+	until, _ := session.Values["2fa-until"].(int64)
+	if !ok || time.Now().Unix() > until {
+		response.Res(w, "error", http.StatusForbidden, "login expired, log in again")
+		return
+	}
+
+	attempts, _ := session.Values["2fa-attempts"].(int)
+	if attempts >= twoFactorMaxAttempts {
+		session.Options.MaxAge = -1
+		session.Save(r, w)
+		response.Res(w, "error", http.StatusForbidden, "too many wrong codes, log in again")
+		return
+	}
+
+	code := r.FormValue("code")
+	if code == "" {
+		response.Res(w, "error", http.StatusBadRequest, "code is required")
+		return
+	}
+
+	if throttleLogin(w, r, email) {
+		return
+	}
+
+	a, err := getLoginAdmin(email)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	if a.Disabled {
+		response.Res(w, "error", http.StatusForbidden, "account disabled")
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	valid, err := checkTOTP(database, a.id, a.totpSecret, code)
+	if err == nil && !valid {
+		valid, err = useRecoveryCode(database, a.id, code)
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	if !valid {
+		recordLoginAttempt(r, email, loginBad2FA)
+		session.Values["2fa-attempts"] = attempts + 1
+		session.Save(r, w)
+		response.Res(w, "error", http.StatusUnauthorized, "invalid code")
+		return
+	}
+
+	// Give the session a new id for the authenticated admin
+	err = authPackage.Store.Renew(session)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// Set admin as authenticated
+	session.Values = map[interface{}]interface{}{
+		"#Tahlilchi.uz#-$admin$-?authenticated?": true,
+		"email":                                  a.Email,
+	}
+	err = session.Save(r, w)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	recordLoginAttempt(r, email, loginSuccess)
+	response.Res(w, "success", http.StatusOK, a.admin)
+}
+
+// twoFactorEnrollment is the response of enrollTwoFactor
+type twoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// enrollTwoFactor is a route handler function which creates a new TOTP secret for the logged in admin.
+// Two-factor authentication is turned on by confirmTwoFactor once the authenticator app shows the right code.
+func enrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	a, err := getTwoFactorAdmin(r)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	if a.enabled {
+		response.Res(w, "error", http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := authPackage.GenerateTOTPSecret()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	_, err = database.Exec("UPDATE admins SET totp_secret = $1, totp_last_step = 0, updated_at = NOW() WHERE id = $2", secret, a.id)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, twoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: authPackage.TOTPProvisioningURI(totpIssuer, authPackage.AdminEmail(r), secret),
+	})
+}
+
+// confirmTwoFactor is a route handler function which turns two-factor authentication on
+// after checking a code of the enrolled secret. It returns the recovery codes, which are shown only once.
+func confirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	a, err := getTwoFactorAdmin(r)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	if a.enabled {
+		response.Res(w, "error", http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+	if a.secret == "" {
+		response.Res(w, "error", http.StatusBadRequest, "enroll first")
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	valid, err := checkTOTP(database, a.id, a.secret, r.FormValue("code"))
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	if !valid {
+		response.Res(w, "error", http.StatusUnauthorized, "invalid code")
+		return
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE admins SET totp_enabled = true, updated_at = NOW() WHERE id = $1", a.id)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(tx, a.id)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, map[string][]string{"recovery_codes": codes})
+}
+
+// regenerateRecoveryCodes is a route handler function which replaces the recovery codes of the logged in admin.
+// A TOTP code is needed, so a stolen session alone cannot be used to get new codes.
+func regenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	a, err := getTwoFactorAdmin(r)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	if !a.enabled {
+		response.Res(w, "error", http.StatusBadRequest, "two-factor authentication is not enabled")
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	valid, err := checkTOTP(database, a.id, a.secret, r.FormValue("code"))
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	if !valid {
+		response.Res(w, "error", http.StatusUnauthorized, "invalid code")
+		return
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	defer tx.Rollback()
+
+	codes, err := replaceRecoveryCodes(tx, a.id)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, map[string][]string{"recovery_codes": codes})
+}
+
+// disableTwoFactor is a route handler function which turns two-factor authentication off for the logged in admin.
+// It needs the password and a TOTP or recovery code, and is refused when the role of the admin requires two-factor authentication.
+func disableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	a, err := getTwoFactorAdmin(r)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	if !a.enabled {
+		response.Res(w, "error", http.StatusBadRequest, "two-factor authentication is not enabled")
+		return
+	}
+
+	required, err := twoFactorRequired(a.role)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	if required {
+		response.Res(w, "error", http.StatusBadRequest, "two-factor authentication is mandatory for your role")
+		return
+	}
+
+	if !checkPasswordHash(r.FormValue("password"), a.password) {
+		response.Res(w, "error", http.StatusUnauthorized, "password is wrong")
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	code := r.FormValue("code")
+	valid, err := checkTOTP(database, a.id, a.secret, code)
+	if err == nil && !valid {
+		valid, err = useRecoveryCode(database, a.id, code)
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	if !valid {
+		response.Res(w, "error", http.StatusUnauthorized, "invalid code")
+		return
+	}
+
+	err = clearTwoFactor(database, a.id)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, "two-factor authentication disabled")
+}
+
+// clearTwoFactor is a function to remove the TOTP secret and the recovery codes of an admin
+func clearTwoFactor(database *sql.DB, id int) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE admins SET totp_secret = NULL, totp_enabled = false, totp_last_step = 0, updated_at = NOW() WHERE id = $1", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM admin_recovery_codes WHERE admin_id = $1", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// resetAdminTwoFactor is a route handler function to turn two-factor authentication off for another admin,
+// e.g. after they lost their phone and their recovery codes. They are logged out everywhere.
+func resetAdminTwoFactor(w http.ResponseWriter, r *http.Request) {
+	a, ok := targetAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	err = clearTwoFactor(database, a.ID)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	if _, err := authPackage.RevokeAdminSessions(a.Email, ""); err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, "two-factor authentication reset")
+}
+
+// twoFactorRequired reports whether two-factor authentication is mandatory for role
+func twoFactorRequired(role string) (bool, error) {
+	database, err := db.DB()
+	if err != nil {
+		return false, err
+	}
+
+	var required bool
+	err = database.QueryRow("SELECT require_2fa FROM role_settings WHERE role = $1", role).Scan(&required)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return required, err
+}
+
+// TwoFactorPolicy is a struct to map whether two-factor authentication is mandatory for a role
+type TwoFactorPolicy struct {
+	Role     string `json:"role"`
+	Required bool   `json:"required"`
+}
+
+// getTwoFactorPolicy is a route handler function to get for every role whether two-factor authentication is mandatory
+func getTwoFactorPolicy(w http.ResponseWriter, r *http.Request) {
+	var list []TwoFactorPolicy
+	for _, role := range authPackage.Roles() {
+		required, err := twoFactorRequired(role)
+		if err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+		list = append(list, TwoFactorPolicy{Role: role, Required: required})
+	}
+
+	response.Res(w, "success", http.StatusOK, list)
+}
+
+// updateTwoFactorPolicy is a route handler function to make two-factor authentication mandatory for a role or not.
+// Admins of the role without two-factor authentication can only enroll until they turn it on.
+func updateTwoFactorPolicy(w http.ResponseWriter, r *http.Request) {
+	role := r.FormValue("role")
+	if !authPackage.ValidRole(role) {
+		response.Res(w, "error", http.StatusBadRequest, "invalid role, it must be one of: "+strings.Join(authPackage.Roles(), ", "))
+		return
+	}
+
+	var required bool
+	switch r.FormValue("required") {
+	case "true":
+		required = true
+	case "false":
+		required = false
+	default:
+		response.Res(w, "error", http.StatusBadRequest, "required must be true or false")
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	_, err = database.Exec("INSERT INTO role_settings (role, require_2fa) VALUES ($1, $2) ON CONFLICT (role) DO UPDATE SET require_2fa = EXCLUDED.require_2fa, updated_at = NOW()", role, required)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, TwoFactorPolicy{Role: role, Required: required})
+}
@@ -2,12 +2,14 @@ package admin
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"Tahlilchi.uz/db"
 	"Tahlilchi.uz/model"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/toolkit"
 	"github.com/gorilla/mux"
 )
 
@@ -17,15 +19,24 @@ func addVideoNews(w http.ResponseWriter, r *http.Request) {
 	var videoNews model.VideoNews
 	if err := json.NewDecoder(r.Body).Decode(&videoNews); err != nil {
 		// log the error
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		// if there is an error decoding the request body, return a bad request response
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
+	// the video news must be unpublished after it is published
+	if videoNews.PublishAt != nil && videoNews.UnpublishAt != nil && !videoNews.UnpublishAt.After(*videoNews.PublishAt) {
+		response.Res(w, "error", http.StatusBadRequest, "unpublish_at must be after publish_at")
+		return
+	}
+	// fill a missing alphabet from the other one on request: ?autofill=true
+	if wantsAutofill(r) {
+		videoNews.TextLatin, videoNews.TextCyrillic = autofillPair(videoNews.TextLatin, videoNews.TextCyrillic, true)
+	}
 	// add the video news to the database
 	if err := videoNews.AddVideoNews(); err != nil {
 		// log the error
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		// if there is an error adding the video news to the database, return an internal server error response
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
@@ -43,23 +54,85 @@ func updateVideoNews(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "id is required", http.StatusBadRequest)
 		return
 	}
-	// get the video news from the request body
-	var videoNews model.VideoNews
-	if err := json.NewDecoder(r.Body).Decode(&videoNews); err != nil {
+	if _, err := strconv.Atoi(id); err != nil {
+		response.Res(w, "error", http.StatusBadRequest, "invalid id")
+		return
+	}
+	// create a new database connection
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	// approved and published video news is changed by the reviewers only
+	if !videoNewsWorkflow.editable(w, r, database, id) {
+		return
+	}
+	// get the video news from the request body, the raw publishing window tells a cleared time from one left out
+	var body struct {
+		model.VideoNews
+		PublishAt   json.RawMessage `json:"publish_at"`
+		UnpublishAt json.RawMessage `json:"unpublish_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		// log the error
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		// if there is an error decoding the request body, return a bad request response
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
+	videoNews := body.VideoNews
+	// publishing window, null or an empty string clears a time
+	window, err := jsonPublishWindow(body.PublishAt, body.UnpublishAt)
+	if err != nil {
+		toolkit.LogInfo(r, err.Error())
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
+		return
+	}
+	// the window with the stored times in place of the fields which were not sent
+	err = window.check(database, "video_news", id)
+	if errors.Is(err, errPublishWindow) {
+		toolkit.LogInfo(r, err.Error())
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	// fill a missing alphabet from the other one on request: ?autofill=true
+	if wantsAutofill(r) {
+		videoNews.TextLatin, videoNews.TextCyrillic = autofillPair(videoNews.TextLatin, videoNews.TextCyrillic, true)
+	}
+	// start a new transaction, the fields and the window are written together
+	tx, err := database.Begin()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	defer tx.Rollback()
 	// update the video news in the database
-	if err := videoNews.UpdateVideoNews(id); err != nil {
+	if err := videoNews.UpdateVideoNews(tx, id); err != nil {
 		// log the error
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		// if there is an error updating the video news in the database, return an internal server error response
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
+	if err := window.update(tx, "video_news", id); err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	// commit the transaction
+	if err := tx.Commit(); err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
 	// return a success response using response package
 	response.Res(w, "success", http.StatusOK, "Video news updated successfully")
 }
@@ -77,7 +150,7 @@ func deleteVideoNews(w http.ResponseWriter, r *http.Request) {
 	idInt, err := strconv.Atoi(id)
 	if err != nil {
 		// log the error
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		// if there is an error converting the id to int, return a bad request response
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
@@ -87,7 +160,7 @@ func deleteVideoNews(w http.ResponseWriter, r *http.Request) {
 	// delete the video news from the database
 	if err := videoNews.DeleteVideoNews(); err != nil {
 		// log the error
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		// if there is an error deleting the video news from the database, return an internal server error response
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
@@ -109,7 +182,7 @@ func getVideoNewsList(w http.ResponseWriter, r *http.Request) {
 		page, err = strconv.Atoi(pageStr)
 		if err != nil {
 			// log the error
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			// if there is an error converting the page to int, return a bad request response
 			response.Res(w, "error", http.StatusBadRequest, err.Error())
 			return
@@ -122,7 +195,7 @@ func getVideoNewsList(w http.ResponseWriter, r *http.Request) {
 		limit, err = strconv.Atoi(limitStr)
 		if err != nil {
 			// log the error
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			// if there is an error converting the limit to int, return a bad request response
 			response.Res(w, "error", http.StatusBadRequest, err.Error())
 			return
@@ -137,7 +210,7 @@ func getVideoNewsList(w http.ResponseWriter, r *http.Request) {
 	videoNewsListResponse, err := model.GetVideoNewsList(limit, offset)
 	if err != nil {
 		// log the error
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		// if there is an error getting the list of video news from the database, return an internal server error response
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"Tahlilchi.uz/metrics"
 	"Tahlilchi.uz/model"
 	"Tahlilchi.uz/response"
 	"Tahlilchi.uz/toolkit"
@@ -93,6 +94,8 @@ func approveVideoNewsComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	metrics.CommentApprovals.Inc("video_news")
+
 	// respond with the success message
 	response.Res(w, "success", http.StatusOK, "video news comment approved successfully")
 }
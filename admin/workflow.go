@@ -0,0 +1,462 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"Tahlilchi.uz/authPackage"
+	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/toolkit"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// The statuses of the editorial workflow
+const (
+	statusDraft     = "draft"
+	statusInReview  = "in_review"
+	statusApproved  = "approved"
+	statusPublished = "published"
+	statusArchived  = "archived"
+)
+
+// workflowAction is a transition of the editorial workflow
+type workflowAction struct {
+	From []string
+	To   string
+	// Review actions need authPackage.ContentReview besides the write permission of the content
+	Review bool
+	// ReviewFrom are the statuses of From the action needs authPackage.ContentReview from, e.g. to take down live content
+	ReviewFrom []string
+	// Comment actions are refused without a comment for the author
+	Comment bool
+}
+
+// workflowActions are the transitions by their names
+var workflowActions = map[string]workflowAction{
+	"submit":          {From: []string{statusDraft}, To: statusInReview},
+	"approve":         {From: []string{statusInReview}, To: statusApproved, Review: true},
+	"request_changes": {From: []string{statusInReview}, To: statusDraft, Review: true, Comment: true},
+	"reject":          {From: []string{statusInReview}, To: statusArchived, Review: true, Comment: true},
+	"publish":         {From: []string{statusApproved}, To: statusPublished, Review: true},
+	"unpublish":       {From: []string{statusPublished}, To: statusApproved, Review: true},
+	"archive":         {From: []string{statusDraft, statusInReview, statusApproved, statusPublished}, To: statusArchived, ReviewFrom: []string{statusApproved, statusPublished}},
+	"unarchive":       {From: []string{statusArchived}, To: statusDraft},
+}
+
+// from returns the statuses the action starts from for a reviewer, or for an admin who is not one
+func (a workflowAction) from(reviewer bool) []string {
+	if reviewer {
+		return a.From
+	}
+	var from []string
+	for _, status := range a.From {
+		if !slices.Contains(a.ReviewFrom, status) {
+			from = append(from, status)
+		}
+	}
+	return from
+}
+
+// workflowEntity is a content type with the editorial workflow
+type workflowEntity struct {
+	// Type is the entity_type of its workflow events
+	Type  string
+	Table string
+	// Name is the content type in the messages
+	Name string
+	// TitleLatin and TitleCyrillic are the columns shown in the review inbox
+	TitleLatin    string
+	TitleCyrillic string
+}
+
+var (
+	newsPostWorkflow   = workflowEntity{Type: "news_post", Table: "news_posts", Name: "news post", TitleLatin: "title_latin", TitleCyrillic: "title_cyrillic"}
+	articleWorkflow    = workflowEntity{Type: "article", Table: "articles", Name: "article", TitleLatin: "title_latin", TitleCyrillic: "title_cyrillic"}
+	eNewspaperWorkflow = workflowEntity{Type: "e_newspaper", Table: "e_newspapers", Name: "e-newspaper", TitleLatin: "title_latin", TitleCyrillic: "title_cyrillic"}
+	videoNewsWorkflow  = workflowEntity{Type: "video_news", Table: "video_news", Name: "video news", TitleLatin: "text_latin", TitleCyrillic: "text_cyrillic"}
+)
+
+// workflowEntities are the content types in the review inbox
+var workflowEntities = []workflowEntity{newsPostWorkflow, articleWorkflow, eNewspaperWorkflow, videoNewsWorkflow}
+
+// workflowTables are the tables of workflowEntities
+var workflowTables = map[string]bool{"news_posts": true, "articles": true, "e_newspapers": true, "video_news": true}
+
+// transitionError is a refused transition, answered with Status and Message
+type transitionError struct {
+	Status  int
+	Message string
+}
+
+func (e *transitionError) Error() string {
+	return e.Message
+}
+
+// transition applies action to the row id by the admin with the given email, a reviewer or not, and returns the new status.
+// An action whose target status the row already has changes nothing, so a repeated request succeeds.
+// The row is only updated while its status is one the action starts from, so concurrent requests cannot undo each other.
+func (e workflowEntity) transition(database *sql.DB, id, actionName, email, comment string, reviewer bool) (string, bool, error) {
+	action := workflowActions[actionName]
+
+	// Start a new transaction
+	tx, err := database.Begin()
+	if err != nil {
+		return "", false, err
+	}
+	defer tx.Rollback()
+
+	// completed and archived follow the status, publishing clears publish_at and an expired unpublish_at
+	var from string
+	err = tx.QueryRow(fmt.Sprintf(`UPDATE %[1]s t SET status = $1::text, completed = ($1::text = 'published'), archived = ($1::text = 'archived'),
+			publish_at = CASE WHEN $1::text = 'published' THEN NULL ELSE t.publish_at END,
+			unpublish_at = CASE WHEN $1::text = 'published' AND t.unpublish_at <= NOW() THEN NULL ELSE t.unpublish_at END,
+			updated_at = NOW()
+		FROM %[1]s old WHERE t.id = old.id AND t.id = $2 AND t.status = ANY($3) RETURNING old.status`, e.Table),
+		action.To, id, pq.Array(action.from(reviewer))).Scan(&from)
+	if err == sql.ErrNoRows {
+		var current string
+		err = tx.QueryRow("SELECT status FROM "+e.Table+" WHERE id = $1", id).Scan(&current)
+		if err == sql.ErrNoRows {
+			return "", false, &transitionError{Status: http.StatusNotFound, Message: e.Name + " not found"}
+		}
+		if err != nil {
+			return "", false, err
+		}
+		if current == action.To {
+			return current, false, nil
+		}
+		return "", false, e.refusal(actionName, current, reviewer)
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	_, err = tx.Exec("INSERT INTO workflow_events (entity_type, entity_id, action, from_status, to_status, comment, admin_email) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		e.Type, id, actionName, from, action.To, comment, email)
+	if err != nil {
+		return "", false, err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit(); err != nil {
+		return "", false, err
+	}
+	return action.To, true, nil
+}
+
+// refusal returns the error of action applied to a row in the status current, which it does not start from for the admin:
+// Forbidden if it does for a reviewer, Conflict otherwise
+func (e workflowEntity) refusal(actionName, current string, reviewer bool) *transitionError {
+	action := workflowActions[actionName]
+	verb, status := strings.ReplaceAll(actionName, "_", " "), strings.ReplaceAll(current, "_", " ")
+	if !reviewer && slices.Contains(action.From, current) && (action.Review || slices.Contains(action.ReviewFrom, current)) {
+		return &transitionError{Status: http.StatusForbidden, Message: fmt.Sprintf("Only a reviewer can %s a %s %s", verb, status, e.Name)}
+	}
+	return &transitionError{Status: http.StatusConflict, Message: fmt.Sprintf("Cannot %s a %s %s", verb, status, e.Name)}
+}
+
+// statusHandler is a route handler function applying the form value action, with the optional form value comment, to the row {id}.
+// The route requires the write permission of the content, the review actions and the archiving of approved or published content
+// authPackage.ContentReview too.
+func (e workflowEntity) statusHandler(w http.ResponseWriter, r *http.Request) {
+	e.apply(w, r, r.FormValue("action"))
+}
+
+// actionHandler returns a route handler function applying action to the row {id}, for the archive and unarchive routes
+func (e workflowEntity) actionHandler(action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.apply(w, r, action)
+	}
+}
+
+func (e workflowEntity) apply(w http.ResponseWriter, r *http.Request, actionName string) {
+	id := mux.Vars(r)["id"]
+	if _, err := strconv.Atoi(id); err != nil {
+		response.Res(w, "error", http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	action, ok := workflowActions[actionName]
+	if !ok {
+		var names []string
+		for name := range workflowActions {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		response.Res(w, "error", http.StatusBadRequest, "action must be one of "+strings.Join(names, ", "))
+		return
+	}
+
+	comment := strings.TrimSpace(r.FormValue("comment"))
+	if action.Comment && comment == "" {
+		response.Res(w, "error", http.StatusBadRequest, "comment is required to "+strings.ReplaceAll(actionName, "_", " "))
+		return
+	}
+
+	admin, err := authPackage.CurrentAdmin(r)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	reviewer := authPackage.HasPermission(admin.Role, authPackage.ContentReview)
+	if action.Review && !reviewer {
+		response.Res(w, "error", http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	status, changed, err := e.transition(database, id, actionName, admin.Email, comment, reviewer)
+	if te, ok := err.(*transitionError); ok {
+		toolkit.LogInfo(r, te.Message)
+		response.Res(w, "error", te.Status, te.Message)
+		return
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	if !changed {
+		response.Res(w, "success", http.StatusOK, "already "+strings.ReplaceAll(status, "_", " "))
+		return
+	}
+	response.Res(w, "success", http.StatusOK, status)
+}
+
+// completedHandler is a route handler function for the completed routes of the content from before the workflow.
+// It publishes the approved row {id} and unpublishes the published one, as the publish and unpublish actions:
+// the form value completed, true or false, names the one to apply, without it the current state is flipped.
+func (e workflowEntity) completedHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := strconv.Atoi(id); err != nil {
+		response.Res(w, "error", http.StatusBadRequest, "invalid id")
+		return
+	}
+	completed, set, err := formCompleted(r)
+	if err != nil {
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !set {
+		database, err := db.DB()
+		if err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+		var status string
+		err = database.QueryRow("SELECT status FROM "+e.Table+" WHERE id = $1", id).Scan(&status)
+		if err == sql.ErrNoRows {
+			response.Res(w, "error", http.StatusNotFound, e.Name+" not found")
+			return
+		}
+		if err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+		completed = status != statusPublished
+	}
+
+	if completed {
+		e.apply(w, r, "publish")
+	} else {
+		e.apply(w, r, "unpublish")
+	}
+}
+
+// formCompleted returns the boolean form value completed and whether it was sent
+func formCompleted(r *http.Request) (completed, set bool, err error) {
+	value := r.FormValue("completed")
+	if value == "" {
+		return false, false, nil
+	}
+	completed, err = strconv.ParseBool(value)
+	if err != nil {
+		return false, false, errors.New("completed must be true or false")
+	}
+	return completed, true, nil
+}
+
+// editable reports whether the admin of r may change the content of the row id of e, and answers the request when not.
+// Approved and published content was let through by a reviewer, so only a reviewer may change it,
+// an editor cannot change what the readers see past the review.
+func (e workflowEntity) editable(w http.ResponseWriter, r *http.Request, database *sql.DB, id string) bool {
+	var status string
+	err := database.QueryRow("SELECT status FROM "+e.Table+" WHERE id = $1", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, e.Name+" not found")
+		return false
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return false
+	}
+	if status != statusApproved && status != statusPublished {
+		return true
+	}
+
+	admin, err := authPackage.CurrentAdmin(r)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return false
+	}
+	if !authPackage.HasPermission(admin.Role, authPackage.ContentReview) {
+		toolkit.LogInfof(r, "%s %s is %s, editing it needs %s", e.Name, id, status, authPackage.ContentReview)
+		response.Res(w, "error", http.StatusForbidden, "Only a reviewer can change an approved or published "+e.Name)
+		return false
+	}
+	return true
+}
+
+// WorkflowEvent is a struct to map a row of the workflow_events table
+type WorkflowEvent struct {
+	Action     string `json:"action"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+	Comment    string `json:"comment"`
+	AdminEmail string `json:"admin_email"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// historyHandler is a route handler function listing the status transitions of the row {id}, newest first
+func (e workflowEntity) historyHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := strconv.Atoi(id); err != nil {
+		response.Res(w, "error", http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	rows, err := database.Query("SELECT action, from_status, to_status, comment, admin_email, created_at FROM workflow_events WHERE entity_type = $1 AND entity_id = $2 ORDER BY id DESC", e.Type, id)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	defer rows.Close()
+
+	list := []WorkflowEvent{}
+	for rows.Next() {
+		var ev WorkflowEvent
+		if err := rows.Scan(&ev.Action, &ev.FromStatus, &ev.ToStatus, &ev.Comment, &ev.AdminEmail, &ev.CreatedAt); err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+		list = append(list, ev)
+	}
+	if err := rows.Err(); err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, list)
+}
+
+// ReviewItem is a struct to map an item of the review inbox
+type ReviewItem struct {
+	Type          string  `json:"type"`
+	ID            int64   `json:"id"`
+	TitleLatin    *string `json:"title_latin"`
+	TitleCyrillic *string `json:"title_cyrillic"`
+	SubmittedBy   *string `json:"submitted_by"`
+	SubmittedAt   string  `json:"submitted_at"`
+}
+
+// ReviewInbox is a struct to map a page of the review inbox
+type ReviewInbox struct {
+	Items    []ReviewItem `json:"items"`
+	Previous bool         `json:"previous"`
+	Next     bool         `json:"next"`
+}
+
+// getReviewInbox is a route handler function listing the content waiting for review, the longest waiting first.
+// Query parameters: type (news_post, article, e_newspaper or video_news), page and limit.
+func getReviewInbox(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := toolkit.GetPageLimit(r)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusBadRequest, "invalid page or limit")
+		return
+	}
+
+	typ := r.URL.Query().Get("type")
+	var selects []string
+	for _, e := range workflowEntities {
+		if typ != "" && typ != e.Type {
+			continue
+		}
+		selects = append(selects, fmt.Sprintf(`SELECT '%s' AS type, t.id::bigint AS id, t.%s AS title_latin, t.%s AS title_cyrillic, ev.admin_email, COALESCE(ev.created_at, t.updated_at) AS submitted_at
+			FROM %s t LEFT JOIN LATERAL (SELECT admin_email, created_at FROM workflow_events
+				WHERE entity_type = '%s' AND entity_id = t.id AND to_status = 'in_review' ORDER BY id DESC LIMIT 1) ev ON true
+			WHERE t.status = 'in_review'`, e.Type, e.TitleLatin, e.TitleCyrillic, e.Table, e.Type))
+	}
+	if len(selects) == 0 {
+		response.Res(w, "error", http.StatusBadRequest, "invalid type")
+		return
+	}
+
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// one more row than the limit tells whether there is a next page
+	rows, err := database.Query("SELECT * FROM ("+strings.Join(selects, " UNION ALL ")+") inbox ORDER BY submitted_at, type, id LIMIT $1 OFFSET $2", limit+1, (page-1)*limit)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	defer rows.Close()
+
+	inbox := ReviewInbox{Items: []ReviewItem{}, Previous: page > 1}
+	for rows.Next() {
+		var item ReviewItem
+		if err := rows.Scan(&item.Type, &item.ID, &item.TitleLatin, &item.TitleCyrillic, &item.SubmittedBy, &item.SubmittedAt); err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+		inbox.Items = append(inbox.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	if len(inbox.Items) > limit {
+		inbox.Items = inbox.Items[:limit]
+		inbox.Next = true
+	}
+	response.Res(w, "success", http.StatusOK, inbox)
+}
@@ -0,0 +1,83 @@
+package admin
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"Tahlilchi.uz/authPackage"
+)
+
+func TestArchiveNeedsReviewFromLiveContent(t *testing.T) {
+	editor := authPackage.HasPermission(authPackage.RoleEditor, authPackage.ContentReview)
+	if editor {
+		t.Fatal("an editor has the review permission")
+	}
+	reviewer := authPackage.HasPermission(authPackage.RoleChiefEditor, authPackage.ContentReview)
+	if !reviewer {
+		t.Fatal("a chief editor has no review permission")
+	}
+
+	tests := []struct {
+		current  string
+		reviewer bool
+		// allowed is whether the transition updates the row, status the refusal otherwise
+		allowed bool
+		status  int
+	}{
+		{statusDraft, editor, true, 0},
+		{statusInReview, editor, true, 0},
+		{statusApproved, editor, false, http.StatusForbidden},
+		{statusPublished, editor, false, http.StatusForbidden},
+		{statusApproved, reviewer, true, 0},
+		{statusPublished, reviewer, true, 0},
+	}
+	for _, tt := range tests {
+		from := workflowActions["archive"].from(tt.reviewer)
+		if got := slices.Contains(from, tt.current); got != tt.allowed {
+			t.Errorf("archive from %s by reviewer %v: allowed %v, want %v", tt.current, tt.reviewer, got, tt.allowed)
+		}
+		if tt.allowed {
+			continue
+		}
+		if err := newsPostWorkflow.refusal("archive", tt.current, tt.reviewer); err.Status != tt.status {
+			t.Errorf("archive from %s by reviewer %v: status %d, want %d (%s)", tt.current, tt.reviewer, err.Status, tt.status, err.Message)
+		}
+	}
+}
+
+func TestRefusal(t *testing.T) {
+	tests := []struct {
+		action   string
+		current  string
+		reviewer bool
+		status   int
+		message  string
+	}{
+		{"archive", statusPublished, false, http.StatusForbidden, "Only a reviewer can archive a published news post"},
+		{"publish", statusApproved, false, http.StatusForbidden, "Only a reviewer can publish a approved news post"},
+		// a status the action never starts from is a conflict for anyone
+		{"publish", statusDraft, true, http.StatusConflict, "Cannot publish a draft news post"},
+		{"publish", statusDraft, false, http.StatusConflict, "Cannot publish a draft news post"},
+		{"submit", statusInReview, false, http.StatusConflict, "Cannot submit a in review news post"},
+		{"request_changes", statusApproved, true, http.StatusConflict, "Cannot request changes a approved news post"},
+	}
+	for _, tt := range tests {
+		err := newsPostWorkflow.refusal(tt.action, tt.current, tt.reviewer)
+		if err.Status != tt.status || err.Message != tt.message {
+			t.Errorf("%s from %s by reviewer %v = %d %q, want %d %q", tt.action, tt.current, tt.reviewer, err.Status, err.Message, tt.status, tt.message)
+		}
+	}
+}
+
+func TestActionsFrom(t *testing.T) {
+	// only archive has statuses for the reviewers only, the other actions start from the same statuses for everyone
+	for name, action := range workflowActions {
+		if name == "archive" {
+			continue
+		}
+		if !slices.Equal(action.from(false), action.From) {
+			t.Errorf("%s starts from %v for an editor, want %v", name, action.from(false), action.From)
+		}
+	}
+}
@@ -3,15 +3,9 @@ package authPackage
 import (
 	"net/http"
 
+	"Tahlilchi.uz/logging"
 	"Tahlilchi.uz/middleware"
 	"Tahlilchi.uz/response"
-	"github.com/gorilla/sessions"
-)
-
-var (
-	// key must be 16, 24 or 32 bytes long (AES-128, AES-192 or AES-256)
-	key   = []byte("#Tahlilchi.uz#-$admin$-?secret?-%key%")
-	Store = sessions.NewCookieStore(key)
 )
 
 func AdminAuth() middleware.Middleware {
@@ -31,6 +25,10 @@ func AdminAuth() middleware.Middleware {
 				return
 			}
 
+			// Add the admin to the log lines of the request
+			email, _ := session.Values["email"].(string)
+			logging.SetAdmin(r.Context(), email)
+
 			// Call the next middleware/handler in chain
 			f(w, r)
 		}
@@ -0,0 +1,212 @@
+package authPackage
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+
+	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/middleware"
+	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/toolkit"
+)
+
+// Permission is a named action an admin may perform
+type Permission string
+
+const (
+	NewsRead           Permission = "news:read"
+	NewsWrite          Permission = "news:write"
+	NewsDelete         Permission = "news:delete"
+	ArticleRead        Permission = "article:read"
+	ArticleWrite       Permission = "article:write"
+	ArticleDelete      Permission = "article:delete"
+	BPPRead            Permission = "business-promotional:read"
+	BPPWrite           Permission = "business-promotional:write"
+	BPPDelete          Permission = "business-promotional:delete"
+	ENewspaperRead     Permission = "e-newspaper:read"
+	ENewspaperWrite    Permission = "e-newspaper:write"
+	ENewspaperDelete   Permission = "e-newspaper:delete"
+	PhotoGalleryRead   Permission = "photo-gallery:read"
+	PhotoGalleryWrite  Permission = "photo-gallery:write"
+	PhotoGalleryDelete Permission = "photo-gallery:delete"
+	VideoNewsRead      Permission = "video-news:read"
+	VideoNewsWrite     Permission = "video-news:write"
+	VideoNewsDelete    Permission = "video-news:delete"
+	CommentRead        Permission = "comment:read"
+	CommentModerate    Permission = "comment:moderate"
+	AppealRead         Permission = "appeal:read"
+	AppealDelete       Permission = "appeal:delete"
+	ContactRead        Permission = "contact:read"
+	ContactWrite       Permission = "contact:write"
+	SystemRead         Permission = "system:read"
+	AuditRead          Permission = "audit:read"
+	AdminManage        Permission = "admin:manage"
+	ContentReview      Permission = "content:review"
+)
+
+// Role names as stored in the admins.role column
+const (
+	RoleSuperAdmin  = "superadmin"
+	RoleChiefEditor = "chief-editor"
+	RoleEditor      = "editor"
+	RoleModerator   = "moderator"
+	RoleAdsManager  = "ads-manager"
+)
+
+// contentRead is the read access shared by the roles working on the site content
+var contentRead = []Permission{NewsRead, ArticleRead, ENewspaperRead, PhotoGalleryRead, VideoNewsRead}
+
+// editorPermissions are the permissions of the editors, the chief editor reviews their content too
+var editorPermissions = append([]Permission{
+	NewsWrite, NewsDelete,
+	ArticleWrite, ArticleDelete,
+	ENewspaperWrite, ENewspaperDelete,
+	PhotoGalleryWrite, PhotoGalleryDelete,
+	VideoNewsWrite, VideoNewsDelete,
+	CommentRead, CommentModerate,
+	ContactRead,
+}, contentRead...)
+
+// roles maps every role to its set of permissions.
+// The superadmin is not listed: it is granted every permission.
+var roles = map[string][]Permission{
+	RoleChiefEditor: append([]Permission{ContentReview}, editorPermissions...),
+	RoleEditor:      editorPermissions,
+	RoleModerator: append([]Permission{
+		CommentRead, CommentModerate,
+	}, contentRead...),
+	RoleAdsManager: {
+		BPPRead, BPPWrite, BPPDelete,
+	},
+}
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	if role == RoleSuperAdmin {
+		return true
+	}
+	_, ok := roles[role]
+	return ok
+}
+
+// Roles returns the names of all known roles
+func Roles() []string {
+	names := []string{RoleSuperAdmin}
+	for role := range roles {
+		names = append(names, role)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// HasPermission reports whether role grants p
+func HasPermission(role string, p Permission) bool {
+	if role == RoleSuperAdmin {
+		return true
+	}
+	for _, rp := range roles[role] {
+		if rp == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions returns the names of the permissions granted by role, "*" for the superadmin
+func Permissions(role string) []string {
+	if role == RoleSuperAdmin {
+		return []string{"*"}
+	}
+	var list []string
+	for _, p := range roles[role] {
+		list = append(list, string(p))
+	}
+	sort.Strings(list)
+	return list
+}
+
+// AdminEmail returns the email of the admin of the current session, empty if there is no admin session
+func AdminEmail(r *http.Request) string {
+	session, _ := Store.Get(r, "Tahlilchi.uz-admin")
+	email, _ := session.Values["email"].(string)
+	return email
+}
+
+// SessionAdmin is the admin of the current session as stored in the database
+type SessionAdmin struct {
+	ID                 int
+	Email              string
+	Role               string
+	Disabled           bool
+	MustChangePassword bool
+	TwoFactorEnabled   bool
+	// TwoFactorRequired is set when a superadmin made two-factor authentication mandatory for the role
+	TwoFactorRequired bool
+}
+
+// CurrentAdmin reads the admin of the current session from the database,
+// so role changes and disabling take effect on the next request
+func CurrentAdmin(r *http.Request) (SessionAdmin, error) {
+	database, err := db.DB()
+	if err != nil {
+		return SessionAdmin{}, err
+	}
+
+	var a SessionAdmin
+	err = database.QueryRow("SELECT a.id, a.email, a.role, a.disabled, a.must_change_password, a.totp_enabled, COALESCE(rs.require_2fa, false) FROM admins a LEFT JOIN role_settings rs ON rs.role = a.role WHERE a.email = $1", AdminEmail(r)).Scan(&a.ID, &a.Email, &a.Role, &a.Disabled, &a.MustChangePassword, &a.TwoFactorEnabled, &a.TwoFactorRequired)
+	if err != nil {
+		return SessionAdmin{}, err
+	}
+	return a, nil
+}
+
+// RequirePermission is a middleware which lets the request through only when the role of the session's admin grants p.
+// Disabled admins, admins who have to change their password first and admins who have to
+// enroll in two-factor authentication first are refused as well.
+// It must run after AdminAuth, i.e. be listed before it in middleware.Chain.
+func RequirePermission(p Permission) middleware.Middleware {
+
+	// Create a new Middleware
+	return func(f http.HandlerFunc) http.HandlerFunc {
+
+		// Define the http.HandlerFunc
+		return func(w http.ResponseWriter, r *http.Request) {
+
+			a, err := CurrentAdmin(r)
+			if err == sql.ErrNoRows {
+				response.Res(w, "error", http.StatusForbidden, "Forbidden")
+				return
+			}
+			if err != nil {
+				toolkit.LogError(r, err)
+				response.Res(w, "error", http.StatusInternalServerError, "server error")
+				return
+			}
+
+			if a.Disabled {
+				response.Res(w, "error", http.StatusForbidden, "account disabled")
+				return
+			}
+
+			if a.MustChangePassword {
+				response.Res(w, "error", http.StatusForbidden, "password change required")
+				return
+			}
+
+			if a.TwoFactorRequired && !a.TwoFactorEnabled {
+				response.Res(w, "error", http.StatusForbidden, "two-factor authentication required")
+				return
+			}
+
+			if !HasPermission(a.Role, p) {
+				toolkit.LogInfo(r, "permission "+string(p)+" denied for role "+a.Role)
+				response.Res(w, "error", http.StatusForbidden, "Forbidden")
+				return
+			}
+
+			// Call the next middleware/handler in chain
+			f(w, r)
+		}
+	}
+}
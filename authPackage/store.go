@@ -0,0 +1,346 @@
+package authPackage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"Tahlilchi.uz/config"
+	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/toolkit"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// adminSession is the name of the session of a logged in admin
+const adminSession = "Tahlilchi.uz-admin"
+
+// defaultMaxAge is the max age of the session cookie, a session expires earlier by the timeouts of the store
+const defaultMaxAge = 24 * time.Hour
+
+// touchInterval limits how often last_seen_at is written for a session in use
+const touchInterval = time.Minute
+
+// PGStore is a sessions.Store which keeps the session values in the admin_sessions table.
+// The cookie only holds a random session id signed with the session keys,
+// so deleting the row revokes the session even if somebody kept a copy of the cookie.
+// The keys and the timeouts are set by Configure before the store is used.
+type PGStore struct {
+	Options *sessions.Options
+
+	codecs          []securecookie.Codec
+	idleTimeout     time.Duration
+	absoluteTimeout time.Duration
+}
+
+// NewPGStore returns a new PGStore
+func NewPGStore() *PGStore {
+	return &PGStore{
+		Options: &sessions.Options{
+			Path:     "/",
+			MaxAge:   int(defaultMaxAge.Seconds()),
+			HttpOnly: true,
+		},
+	}
+}
+
+// Store is the session store of the admin panel
+var Store = NewPGStore()
+
+// Configure sets the keys and the timeouts of the store.
+// The first key signs new cookies, all of them are accepted. The keys are required by config.Load.
+func (s *PGStore) Configure(cfg config.Session) {
+	var keys [][]byte
+	for _, k := range cfg.Keys {
+		keys = append(keys, []byte(k))
+	}
+	for _, k := range keys {
+		if len(k) < 32 {
+			slog.Warn("session keys should be at least 32 bytes long")
+			break
+		}
+	}
+	// only the hash keys are used: the cookie value is a random id, there is nothing to encrypt
+	s.codecs = nil
+	for _, k := range keys {
+		s.codecs = append(s.codecs, securecookie.New(k, nil))
+	}
+
+	s.idleTimeout = cfg.IdleTimeout
+	s.absoluteTimeout = cfg.AbsoluteTimeout
+}
+
+// Get returns a session for the given name after adding it to the registry.
+func (s *PGStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New returns the session stored for the cookie of the request,
+// or a new session if there is no cookie or the stored session is expired or revoked.
+func (s *PGStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var token string
+	if err := securecookie.DecodeMulti(name, c.Value, &token, s.codecs...); err != nil {
+		return session, err
+	}
+
+	found, err := s.load(r, session, token)
+	if err != nil {
+		return session, err
+	}
+	if found {
+		session.ID = token
+		session.IsNew = false
+	}
+	return session, nil
+}
+
+// Save writes the session to the database and sets the cookie.
+// A negative MaxAge deletes the stored session.
+func (s *PGStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := deleteSession(session.ID); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	data, err := securecookie.GobEncoder{}.Serialize(session.Values)
+	if err != nil {
+		return err
+	}
+	email, _ := session.Values["email"].(string)
+
+	database, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	if session.ID == "" {
+		token, err := newToken()
+		if err != nil {
+			return err
+		}
+
+		lifetime := s.absoluteTimeout
+		if maxAge := time.Duration(session.Options.MaxAge) * time.Second; maxAge > 0 && maxAge < lifetime {
+			lifetime = maxAge
+		}
+
+		// times are written from here in UTC, like the ones they are compared with
+		now := time.Now().UTC()
+		_, err = database.Exec("INSERT INTO admin_sessions (token_hash, name, admin_email, data, ip, user_agent, created_at, last_seen_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $7, $8)",
+			hashToken(token), session.Name(), email, data, toolkit.ClientIP(r), r.UserAgent(), now, now.Add(lifetime))
+		if err != nil {
+			return err
+		}
+		session.ID = token
+	} else {
+		res, err := database.Exec("UPDATE admin_sessions SET admin_email = $1, data = $2 WHERE token_hash = $3", email, data, hashToken(session.ID))
+		if err != nil {
+			return err
+		}
+		// the session was revoked while the request was served, do not bring it back
+		if n, _ := res.RowsAffected(); n == 0 {
+			expired := *session.Options
+			expired.MaxAge = -1
+			http.SetCookie(w, sessions.NewCookie(session.Name(), "", &expired))
+			return nil
+		}
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// SaveUnstored sets the cookie of a new random session id without storing the session,
+// it cannot be told apart from a saved one but no session is found for it on the next request.
+// It is used where storing a session for anybody asking would let the table grow without a bound.
+func (s *PGStore) SaveUnstored(w http.ResponseWriter, session *sessions.Session) error {
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), token, s.codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// Renew deletes the stored session and gives it a new id on the next Save, keeping its values.
+// It is called on login so a session id known before authentication cannot be used after it.
+func (s *PGStore) Renew(session *sessions.Session) error {
+	if session.ID != "" {
+		if err := deleteSession(session.ID); err != nil {
+			return err
+		}
+	}
+	session.ID = ""
+	session.IsNew = true
+	return nil
+}
+
+// load reads the session values stored for token and refreshes last_seen_at.
+// It reports false if there is no such session or it is expired.
+func (s *PGStore) load(r *http.Request, session *sessions.Session, token string) (bool, error) {
+	database, err := db.DB()
+	if err != nil {
+		return false, err
+	}
+
+	var id int64
+	var data []byte
+	var lastSeen time.Time
+	err = database.QueryRow("SELECT id, data, last_seen_at FROM admin_sessions WHERE token_hash = $1 AND name = $2 AND expires_at > $3 AND last_seen_at > $4",
+		hashToken(token), session.Name(), time.Now().UTC(), time.Now().Add(-s.idleTimeout).UTC()).Scan(&id, &data, &lastSeen)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := (securecookie.GobEncoder{}).Deserialize(data, &session.Values); err != nil {
+		return false, err
+	}
+
+	if time.Now().UTC().Sub(lastSeen) > touchInterval {
+		_, err = database.Exec("UPDATE admin_sessions SET last_seen_at = $1, ip = $2, user_agent = $3 WHERE id = $4", time.Now().UTC(), toolkit.ClientIP(r), r.UserAgent(), id)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// newToken returns a random session id
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the form of the session id stored in the database,
+// so the ids cannot be taken from a database dump
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func deleteSession(token string) error {
+	database, err := db.DB()
+	if err != nil {
+		return err
+	}
+	_, err = database.Exec("DELETE FROM admin_sessions WHERE token_hash = $1", hashToken(token))
+	return err
+}
+
+// SessionInfo is a struct to map an active admin session
+type SessionInfo struct {
+	ID         int64  `json:"id"`
+	IP         string `json:"ip"`
+	UserAgent  string `json:"user_agent"`
+	CreatedAt  string `json:"created_at"`
+	LastSeenAt string `json:"last_seen_at"`
+	ExpiresAt  string `json:"expires_at"`
+	Current    bool   `json:"current"`
+}
+
+// AdminSessions returns the active sessions of the admin with the given email.
+// The session with the id current is marked as the current one.
+func AdminSessions(email, current string) ([]SessionInfo, error) {
+	database, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := database.Query("SELECT id, token_hash, ip, user_agent, created_at, last_seen_at, expires_at FROM admin_sessions WHERE admin_email = $1 AND name = $2 AND expires_at > $3 AND last_seen_at > $4 ORDER BY last_seen_at DESC",
+		email, adminSession, time.Now().UTC(), time.Now().Add(-Store.idleTimeout).UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	currentHash := hashToken(current)
+	list := []SessionInfo{}
+	for rows.Next() {
+		var si SessionInfo
+		var tokenHash string
+		if err := rows.Scan(&si.ID, &tokenHash, &si.IP, &si.UserAgent, &si.CreatedAt, &si.LastSeenAt, &si.ExpiresAt); err != nil {
+			return nil, err
+		}
+		si.Current = current != "" && tokenHash == currentHash
+		list = append(list, si)
+	}
+	return list, rows.Err()
+}
+
+// RevokeSession deletes the session with the given id if it belongs to the admin with the given email.
+// It reports whether a session was deleted.
+func RevokeSession(email string, id int64) (bool, error) {
+	database, err := db.DB()
+	if err != nil {
+		return false, err
+	}
+
+	res, err := database.Exec("DELETE FROM admin_sessions WHERE id = $1 AND admin_email = $2", id, email)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RevokeAdminSessions deletes all sessions of the admin with the given email except the one with the id except,
+// which may be empty. It returns the number of deleted sessions.
+func RevokeAdminSessions(email, except string) (int64, error) {
+	database, err := db.DB()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := database.Exec("DELETE FROM admin_sessions WHERE admin_email = $1 AND token_hash <> $2", email, hashToken(except))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteExpiredSessions removes the expired sessions from the database.
+// It is run by the scheduler.
+func DeleteExpiredSessions() error {
+	database, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	_, err = database.Exec("DELETE FROM admin_sessions WHERE expires_at <= $1 OR last_seen_at <= $2", time.Now().UTC(), time.Now().Add(-Store.idleTimeout).UTC())
+	return err
+}
@@ -0,0 +1,208 @@
+package authPackage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the base32 of the SHA1 key of the test vectors of RFC 4226 and RFC 6238, "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC4226(t *testing.T) {
+	key := []byte("12345678901234567890")
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range want {
+		if got := totpCode(key, int64(counter)); got != code {
+			t.Errorf("totpCode(counter %d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestValidateTOTPRFC6238(t *testing.T) {
+	// the SHA1 vectors of RFC 6238, the last 6 of their 8 digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfcSecret, tt.code, time.Unix(tt.unix, 0), 0)
+			if !ok {
+				t.Fatalf("ValidateTOTP(%s at %d) rejected", tt.code, tt.unix)
+			}
+			if want := tt.unix / totpPeriod; step != want {
+				t.Errorf("step = %d, want %d", step, want)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	// 1234567890 is step 41152263, the code of it is 005924
+	at := func(step int64) time.Time { return time.Unix(step*totpPeriod, 0) }
+	const step = 41152263
+
+	tests := []struct {
+		name string
+		code string
+		t    time.Time
+		ok   bool
+	}{
+		{"same step", "005924", at(step), true},
+		{"one step late", "005924", at(step + 1), true},
+		{"one step early", "005924", at(step - 1), true},
+		{"two steps late", "005924", at(step + 2), false},
+		{"two steps early", "005924", at(step - 2), false},
+		{"wrong code", "005925", at(step), false},
+		{"spaces trimmed", " 005924 ", at(step), true},
+		{"too short", "05924", at(step), false},
+		{"too long", "0005924", at(step), false},
+		{"empty", "", at(step), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ValidateTOTP(rfcSecret, tt.code, tt.t, 0)
+			if ok != tt.ok {
+				t.Fatalf("ValidateTOTP(%q) ok = %v, want %v", tt.code, ok, tt.ok)
+			}
+			if ok && got != step {
+				t.Errorf("step = %d, want %d", got, step)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPReplay(t *testing.T) {
+	const step = 41152263
+	now := time.Unix(step*totpPeriod, 0)
+
+	tests := []struct {
+		name     string
+		lastStep int64
+		ok       bool
+	}{
+		{"never used", 0, true},
+		{"older step used", step - 1, true},
+		{"same step used", step, false},
+		{"later step used", step + 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(rfcSecret, "005924", now, tt.lastStep); ok != tt.ok {
+				t.Errorf("ValidateTOTP(last step %d) ok = %v, want %v", tt.lastStep, ok, tt.ok)
+			}
+		})
+	}
+
+	// the step returned for a code is the last step of the next check
+	got, ok := ValidateTOTP(rfcSecret, "005924", now, 0)
+	if !ok {
+		t.Fatal("first use rejected")
+	}
+	if _, ok := ValidateTOTP(rfcSecret, "005924", now.Add(totpPeriod*time.Second), got); ok {
+		t.Error("replayed code accepted in the next period")
+	}
+}
+
+func TestValidateTOTPSecret(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		ok     bool
+	}{
+		{"lowercase", strings.ToLower(rfcSecret), true},
+		{"spaces trimmed", " " + rfcSecret + " ", true},
+		{"not base32", "not base32!", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tt.secret, "005924", time.Unix(1234567890, 0), 0); ok != tt.ok {
+				t.Errorf("ok = %v, want %v", ok, tt.ok)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := b32.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not base32: %v", secret, err)
+	}
+	if len(key) != 20 {
+		t.Errorf("key length = %d, want 20", len(key))
+	}
+
+	now := time.Now()
+	if _, ok := ValidateTOTP(secret, totpCode(key, now.Unix()/totpPeriod), now, 0); !ok {
+		t.Error("the current code of a new secret is rejected")
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	const code = "abcde-fgh23"
+	want := HashRecoveryCode(code)
+
+	tests := []struct {
+		name  string
+		typed string
+		same  bool
+	}{
+		{"as given", "abcde-fgh23", true},
+		{"uppercase", "ABCDE-FGH23", true},
+		{"without the dash", "abcdefgh23", true},
+		{"spaces around", "  abcde-fgh23\n", true},
+		{"more dashes", "ab-cde-fgh-23", true},
+		{"other code", "abcde-fgh24", false},
+		{"prefix", "abcde", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HashRecoveryCode(tt.typed) == want; got != tt.same {
+				t.Errorf("HashRecoveryCode(%q) matches %q: %v, want %v", tt.typed, code, got, tt.same)
+			}
+		})
+	}
+
+	if len(want) != 64 || strings.Contains(want, code) {
+		t.Errorf("HashRecoveryCode(%q) = %q, want a hex sha256", code, want)
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	hashes := map[string]bool{}
+	for _, c := range codes {
+		if len(c) != 11 || c[5] != '-' {
+			t.Errorf("code %q is not in the form xxxxx-xxxxx", c)
+		}
+		if strings.ContainsAny(c, "lo01") {
+			t.Errorf("code %q has a character easy to mistake", c)
+		}
+		h := HashRecoveryCode(c)
+		if hashes[h] {
+			t.Errorf("code %q is repeated", c)
+		}
+		hashes[h] = true
+	}
+}
@@ -1,24 +1,28 @@
 package client
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
-	"strconv"
 
 	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/media"
+	"Tahlilchi.uz/metrics"
 	"Tahlilchi.uz/response"
 	"Tahlilchi.uz/telegramBot"
 	"Tahlilchi.uz/toolkit"
+	"Tahlilchi.uz/upload"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
 
+// maxAppealRequest limits the request of an appeal with a picture and a video
+const maxAppealRequest = 130 << 20
+
 func addAppeal(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(1 << 30) // Max memory 1GB
+	err := upload.ParseForm(w, r, maxAppealRequest)
 	if err != nil {
-		toolkit.LogError(r, err)
-		response.Res(w, "error", http.StatusBadRequest, err.Error())
+		upload.Fail(w, r, err)
 		return
 	}
 
@@ -50,13 +54,24 @@ func addAppeal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// check the format and size of the picture and the video before the appeal is saved
+	picture, err := upload.FormFile(r, "picture", upload.Image)
+	if err != nil {
+		upload.Fail(w, r, err)
+		return
+	}
+	video, err := upload.FormFile(r, "video", upload.Video)
+	if err != nil {
+		upload.Fail(w, r, err)
+		return
+	}
+
 	database, err := db.DB()
 	if err != nil {
 		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// Insert the appeal form into the database returning the id. it is integer
 	var id int
@@ -68,51 +83,13 @@ func addAppeal(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// insert the picture into the database
-	pictureFile, _, err := r.FormFile("picture")
-	if err != nil {
-		if err != http.ErrMissingFile {
-			toolkit.LogError(r, fmt.Errorf("appeal id: %v: error reading the picture file: %v", id, err))
-			// delete the appeal by id from the database
-			_, err = database.Exec("DELETE FROM appeals WHERE id = $1", id)
-			if err != nil {
-				toolkit.LogError(r, fmt.Errorf("appeal id: %v: error deleting the appeal from the database: %v", id, err))
-			}
-			response.Res(w, "error", http.StatusInternalServerError, "server error")
-			return
-		}
-	} else {
-		defer pictureFile.Close()
-
-		// read the picture file
-		picture, err := io.ReadAll(pictureFile)
-		if err != nil {
-			toolkit.LogError(r, fmt.Errorf("appeal id: %v: error reading the picture file: %v", id, err))
-			// delete the appeal by id from the database
-			_, err = database.Exec("DELETE FROM appeals WHERE id = $1", id)
-			if err != nil {
-				toolkit.LogError(r, fmt.Errorf("appeal id: %v: error deleting the appeal from the database: %v", id, err))
-			}
-			response.Res(w, "error", http.StatusInternalServerError, "server error")
-			return
+	if picture != nil {
+		// store the picture in the media storage and reference it from the appeal
+		var mediaID int64
+		mediaID, err = media.SaveFile(r.Context(), picture, picture.Name())
+		if err == nil {
+			_, err = database.Exec("UPDATE appeals SET picture_id = $1 WHERE id = $2", mediaID, id)
 		}
-
-		// check picture is an image
-		// using http.DetectContentType
-		// if it is not an image, return an error
-		if http.DetectContentType(picture)[:5] != "image" {
-			// log the error
-			toolkit.LogError(r, fmt.Errorf("appeal id: %v: the 'picture' field must be an image", id))
-			// delete the appeal by id from the database
-			_, err = database.Exec("DELETE FROM appeals WHERE id = $1", id)
-			if err != nil {
-				toolkit.LogError(r, fmt.Errorf("appeal id: %v: error deleting the appeal from the database: %v", id, err))
-			}
-			response.Res(w, "error", http.StatusBadRequest, "The 'picture' field must be an image.")
-			return
-		}
-
-		// insert the picture into the database by id: picture column is of type bytea
-		_, err = database.Exec("UPDATE appeals SET picture = $1 WHERE id = $2", picture, id)
 		if err != nil {
 			toolkit.LogError(r, fmt.Errorf("appeal id: %v: error inserting the picture into the database: %v", id, err))
 			// delete the appeal by id from the database
@@ -126,51 +103,13 @@ func addAppeal(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// insert the video into the database
-	videoFile, _, err := r.FormFile("video")
-	if err != nil {
-		if err != http.ErrMissingFile {
-			toolkit.LogError(r, fmt.Errorf("appeal id: %v: error reading the video file: %v", id, err))
-			// delete the appeal by id from the database
-			_, err = database.Exec("DELETE FROM appeals WHERE id = $1", id)
-			if err != nil {
-				toolkit.LogError(r, fmt.Errorf("appeal id: %v: error deleting the appeal from the database: %v", id, err))
-			}
-			response.Res(w, "error", http.StatusInternalServerError, "server error")
-			return
-		}
-	} else {
-		defer videoFile.Close()
-
-		// read the video file
-		video, err := io.ReadAll(videoFile)
-		if err != nil {
-			toolkit.LogError(r, fmt.Errorf("appeal id: %v: error reading the video file: %v", id, err))
-			// delete the appeal by id from the database
-			_, err = database.Exec("DELETE FROM appeals WHERE id = $1", id)
-			if err != nil {
-				toolkit.LogError(r, fmt.Errorf("appeal id: %v: error deleting the appeal from the database: %v", id, err))
-			}
-			response.Res(w, "error", http.StatusInternalServerError, "server error")
-			return
-		}
-
-		// check video is a video
-		// using http.DetectContentType
-		// if it is not a video, return an error
-		if http.DetectContentType(video)[:5] != "video" {
-			// log the error
-			toolkit.LogError(r, fmt.Errorf("appeal id: %v: the 'video' field must be a video", id))
-			// delete the appeal by id from the database
-			_, err = database.Exec("DELETE FROM appeals WHERE id = $1", id)
-			if err != nil {
-				toolkit.LogError(r, fmt.Errorf("appeal id: %v: error deleting the appeal from the database: %v", id, err))
-			}
-			response.Res(w, "error", http.StatusBadRequest, "The 'video' field must be a video.")
-			return
+	if video != nil {
+		// store the video in the media storage and reference it from the appeal
+		var mediaID int64
+		mediaID, err = media.SaveFile(r.Context(), video, video.Name())
+		if err == nil {
+			_, err = database.Exec("UPDATE appeals SET video_id = $1 WHERE id = $2", mediaID, id)
 		}
-
-		// insert the video into the database by id: video column is of type bytea
-		_, err = database.Exec("UPDATE appeals SET video = $1 WHERE id = $2", video, id)
 		if err != nil {
 			toolkit.LogError(r, fmt.Errorf("appeal id: %v: error inserting the video into the database: %v", id, err))
 			// delete the appeal by id from the database
@@ -183,6 +122,7 @@ func addAppeal(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	metrics.AppealSubmissions.Inc()
 	response.Res(w, "success", http.StatusCreated, "The appeal form has been submitted successfully.")
 
 	go sendToTBot(r, id)
@@ -195,8 +135,6 @@ func sendToTBot(r *http.Request, id int) {
 		toolkit.LogError(r, fmt.Errorf("sendToTBot appeal id: %v: error creating a new database connection: %v", id, err))
 		return
 	}
-	// defer the close of the database connection
-	defer database.Close()
 
 	// get the appeal by id from the appeals table
 	var appeal Appeal
@@ -207,24 +145,14 @@ func sendToTBot(r *http.Request, id int) {
 	}
 
 	// Create a new Telegram bot
-	bot, err := telegramBot.TBot()
+	bot, err := telegramBot.TBot(telegram)
 	if err != nil {
 		toolkit.LogError(r, fmt.Errorf("sendToTBot appeal id: %v: error creating a new Telegram bot: %v", id, err))
-	}
-
-	// send message to the telegram bot
-	chatIDBoss, err := strconv.ParseInt(os.Getenv("TELEGRAM_CHAT_ID_BOSS"), 10, 64)
-	if err != nil {
-		toolkit.LogError(r, fmt.Errorf("sendToTBot appeal id: %v: error parsing Boss chat id: %v", id, err))
-	}
-
-	chatIDAdmin, err := strconv.ParseInt(os.Getenv("TELEGRAM_CHAT_ID_ADMIN"), 10, 64)
-	if err != nil {
-		toolkit.LogError(r, fmt.Errorf("sendToTBot appeal id: %v: error parsing admin chat id: %v", id, err))
+		return
 	}
 
 	// List of chat IDs to send the message to
-	chatIDs := []int64{chatIDBoss, chatIDAdmin}
+	chatIDs := []int64{telegram.ChatIDBoss, telegram.ChatIDAdmin}
 
 	// Send the message to the Telegram bot
 	for _, chatID := range chatIDs {
@@ -233,35 +161,47 @@ func sendToTBot(r *http.Request, id int) {
 		// Send the message
 		_, err = bot.Send(msg)
 		if err != nil {
+			metrics.TelegramSendFailures.Inc("message")
 			toolkit.LogError(r, fmt.Errorf("sendToTBot appeal id: %v: error sending the message to the Telegram bot: %v", id, err))
 		}
 	}
 
-	const fileSizeLimit int = 50 * 1024 * 1024 // 50MB
+	const fileSizeLimit int64 = 50 * 1024 * 1024 // 50MB
 
 	// get the picture of the appeal by id from the database
-	err = database.QueryRow("SELECT picture from appeals WHERE id = $1", id).Scan(&appeal.Picture)
+	err = database.QueryRow("SELECT picture_id from appeals WHERE id = $1", id).Scan(&appeal.Picture)
 	if err != nil {
 		toolkit.LogError(r, fmt.Errorf("sendToTBot appeal id: %v: error getting the picture from the database: %v", id, err))
 	}
 
-	if len(appeal.Picture) > 0 {
-		if len(appeal.Picture) > fileSizeLimit {
+	if appeal.Picture.Valid {
+		picture, err := media.Get(context.Background(), appeal.Picture.Int64)
+		if err != nil {
+			toolkit.LogError(r, fmt.Errorf("sendToTBot appeal id: %v: error getting the picture from the media storage: %v", id, err))
+		} else if picture.Size > fileSizeLimit {
 			message := fmt.Sprintf("[%s %s %s] дан мурожаатда телеграм бот 50МБ ҳажм чегарасидан ошган расм келди. Уни телеграм бот юклай олмайди. Илтимос уни вебсайт администратор панелида коʻринг.", appeal.Name, appeal.Surname, appeal.PhoneNumber)
 			for _, chatID := range chatIDs {
 				msg := tgbotapi.NewMessage(chatID, message)
 				_, err = bot.Send(msg)
 				if err != nil {
-					toolkit.LogError(r, fmt.Errorf("sendToTBot: chatID: %v: len(appeal.Picture) > fileSizeLimit appeal id: %v: error sending the message to the Telegram bot chat: %v", chatID, id, err))
+					metrics.TelegramSendFailures.Inc("message")
+					toolkit.LogError(r, fmt.Errorf("sendToTBot: chatID: %v: picture.Size > fileSizeLimit appeal id: %v: error sending the message to the Telegram bot chat: %v", chatID, id, err))
 				}
 			}
 		} else {
 			for _, chatID := range chatIDs {
-				// Send the picture
-				pic := tgbotapi.NewPhotoUpload(chatID, tgbotapi.FileBytes{Name: "picture", Bytes: appeal.Picture})
+				// Send the picture, read again from the media storage for every chat
+				_, content, err := media.Open(context.Background(), picture.ID)
+				if err != nil {
+					toolkit.LogError(r, fmt.Errorf("sendToTBot: chatID: %v: appeal id: %v: error reading the picture from the media storage: %v", chatID, id, err))
+					continue
+				}
+				pic := tgbotapi.NewPhotoUpload(chatID, tgbotapi.FileReader{Name: "picture", Reader: content, Size: picture.Size})
 				pic.Caption = fmt.Sprintf("Мурожаатчининг исми: %s\nФамилияси: %s\nТелефон рақами: %s", appeal.Name, appeal.Surname, appeal.PhoneNumber)
 				_, err = bot.Send(pic)
+				content.Close()
 				if err != nil {
+					metrics.TelegramSendFailures.Inc("photo")
 					toolkit.LogError(r, fmt.Errorf("sendToTBot: chatID: %v: appeal id: %v: error sending the picture to the Telegram bot chat: %v", chatID, id, err))
 				}
 			}
@@ -269,28 +209,39 @@ func sendToTBot(r *http.Request, id int) {
 	}
 
 	// get the video of the appeal by id from the database
-	err = database.QueryRow("SELECT video from appeals WHERE id = $1", id).Scan(&appeal.Video)
+	err = database.QueryRow("SELECT video_id from appeals WHERE id = $1", id).Scan(&appeal.Video)
 	if err != nil {
 		toolkit.LogError(r, fmt.Errorf("sendToTBot appeal id: %v: error getting the video from the database: %v", id, err))
 	}
 
-	if len(appeal.Video) > 0 {
-		if len(appeal.Video) > fileSizeLimit {
+	if appeal.Video.Valid {
+		video, err := media.Get(context.Background(), appeal.Video.Int64)
+		if err != nil {
+			toolkit.LogError(r, fmt.Errorf("sendToTBot appeal id: %v: error getting the video from the media storage: %v", id, err))
+		} else if video.Size > fileSizeLimit {
 			message := fmt.Sprintf("[%s %s %s] дан мурожаатда телеграм бот 50МБ ҳажм чегарасидан ошган видео келди. Уни телеграм бот юклай олмайди. Илтимос уни вебсайт администратор панелида коʻринг.", appeal.Name, appeal.Surname, appeal.PhoneNumber)
 			for _, chatID := range chatIDs {
 				msg := tgbotapi.NewMessage(chatID, message)
 				_, err = bot.Send(msg)
 				if err != nil {
-					toolkit.LogError(r, fmt.Errorf("sendToTBot: chatID: %v: len(appeal.Video) > fileSizeLimit appeal id: %v: error sending the message to the Telegram bot chat: %v", chatID, id, err))
+					metrics.TelegramSendFailures.Inc("message")
+					toolkit.LogError(r, fmt.Errorf("sendToTBot: chatID: %v: video.Size > fileSizeLimit appeal id: %v: error sending the message to the Telegram bot chat: %v", chatID, id, err))
 				}
 			}
 		} else {
 			for _, chatID := range chatIDs {
-				// Send the video
-				vid := tgbotapi.NewVideoUpload(chatID, tgbotapi.FileBytes{Name: "video", Bytes: appeal.Video})
+				// Send the video, read again from the media storage for every chat
+				_, content, err := media.Open(context.Background(), video.ID)
+				if err != nil {
+					toolkit.LogError(r, fmt.Errorf("sendToTBot: chatID: %v: appeal id: %v: error reading the video from the media storage: %v", chatID, id, err))
+					continue
+				}
+				vid := tgbotapi.NewVideoUpload(chatID, tgbotapi.FileReader{Name: "video", Reader: content, Size: video.Size})
 				vid.Caption = fmt.Sprintf("Мурожаатчининг исми: %s\nФамилияси: %s\nТелефон рақами: %s", appeal.Name, appeal.Surname, appeal.PhoneNumber)
 				_, err = bot.Send(vid)
+				content.Close()
 				if err != nil {
+					metrics.TelegramSendFailures.Inc("video")
 					toolkit.LogError(r, fmt.Errorf("sendToTBot: chatID: %v: appeal id: %v: error sending the video to the Telegram bot chat: %v", chatID, id, err))
 				}
 			}
@@ -299,12 +250,12 @@ func sendToTBot(r *http.Request, id int) {
 }
 
 type Appeal struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Surname     string `json:"surname"`
-	PhoneNumber string `json:"phone_number"`
-	Message     string `json:"message"`
-	CreatedAt   string `json:"created_at"`
-	Picture     []byte `json:"picture"`
-	Video       []byte `json:"video"`
+	ID          int           `json:"id"`
+	Name        string        `json:"name"`
+	Surname     string        `json:"surname"`
+	PhoneNumber string        `json:"phone_number"`
+	Message     string        `json:"message"`
+	CreatedAt   string        `json:"created_at"`
+	Picture     sql.NullInt64 `json:"-"`
+	Video       sql.NullInt64 `json:"-"`
 }
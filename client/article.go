@@ -2,12 +2,15 @@ package client
 
 import (
 	"database/sql"
-	"log"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/media"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/slug"
+	"Tahlilchi.uz/toolkit"
 	"github.com/gorilla/mux"
 	"github.com/lib/pq"
 )
@@ -25,15 +28,14 @@ type ArticleCategory struct {
 func getArticleCategoryList(w http.ResponseWriter, r *http.Request) {
 	database, err := db.DB()
 	if err != nil {
-		log.Println(err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	rows, err := database.Query("select * from article_category order by id")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -43,7 +45,7 @@ func getArticleCategoryList(w http.ResponseWriter, r *http.Request) {
 		var a ArticleCategory
 		err := rows.Scan(&a.ID, &a.TitleLatin, &a.DescriptionLatin, &a.TitleCyrillic, &a.DescriptionCyrillic)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -63,6 +65,7 @@ type Article struct {
 	Videos              pq.StringArray `json:"videos"`
 	Tags                pq.StringArray `json:"tags"`
 	CreatedAt           string         `json:"created_at"`
+	Slug                *string        `json:"slug"`
 }
 
 // getArticleListByCategory is a handler to get article list by category
@@ -89,15 +92,14 @@ func getArticleListByCategory(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, tags, created_at FROM articles WHERE category = $1 AND archived = false AND completed = true ORDER BY id DESC LIMIT $2 OFFSET $3", category, limit, start)
+	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, tags, created_at, slug FROM articles WHERE category = $1 AND archived = false AND completed = true AND "+inWindow+" ORDER BY id DESC LIMIT $2 OFFSET $3", category, limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -106,9 +108,9 @@ func getArticleListByCategory(w http.ResponseWriter, r *http.Request) {
 	var articles []Article
 	for rows.Next() {
 		var article Article
-		err := rows.Scan(&article.ID, &article.TitleLatin, &article.DescriptionLatin, &article.TitleCyrillic, &article.DescriptionCyrillic, &article.Videos, &article.Tags, &article.CreatedAt)
+		err := rows.Scan(&article.ID, &article.TitleLatin, &article.DescriptionLatin, &article.TitleCyrillic, &article.DescriptionCyrillic, &article.Videos, &article.Tags, &article.CreatedAt, &article.Slug)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -116,7 +118,7 @@ func getArticleListByCategory(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -148,15 +150,14 @@ func getArticleListByRelated(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, tags, created_at FROM articles WHERE related = $1 AND archived = false AND completed = true ORDER BY id DESC LIMIT $2 OFFSET $3", related, limit, start)
+	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, tags, created_at, slug FROM articles WHERE related = $1 AND archived = false AND completed = true AND "+inWindow+" ORDER BY id DESC LIMIT $2 OFFSET $3", related, limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -165,9 +166,9 @@ func getArticleListByRelated(w http.ResponseWriter, r *http.Request) {
 	var articles []Article
 	for rows.Next() {
 		var article Article
-		err := rows.Scan(&article.ID, &article.TitleLatin, &article.DescriptionLatin, &article.TitleCyrillic, &article.DescriptionCyrillic, &article.Videos, &article.Tags, &article.CreatedAt)
+		err := rows.Scan(&article.ID, &article.TitleLatin, &article.DescriptionLatin, &article.TitleCyrillic, &article.DescriptionCyrillic, &article.Videos, &article.Tags, &article.CreatedAt, &article.Slug)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -175,7 +176,7 @@ func getArticleListByRelated(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -204,16 +205,15 @@ func getAllArticles(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// Get the slice of posts
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, tags, created_at FROM articles WHERE archived = false AND completed = true ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
+	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, tags, created_at, slug FROM articles WHERE archived = false AND completed = true AND "+inWindow+" ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -222,9 +222,9 @@ func getAllArticles(w http.ResponseWriter, r *http.Request) {
 	var articles []Article
 	for rows.Next() {
 		var article Article
-		err := rows.Scan(&article.ID, &article.TitleLatin, &article.DescriptionLatin, &article.TitleCyrillic, &article.DescriptionCyrillic, &article.Videos, &article.Tags, &article.CreatedAt)
+		err := rows.Scan(&article.ID, &article.TitleLatin, &article.DescriptionLatin, &article.TitleCyrillic, &article.DescriptionCyrillic, &article.Videos, &article.Tags, &article.CreatedAt, &article.Slug)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -232,7 +232,7 @@ func getAllArticles(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -242,11 +242,11 @@ func getAllArticles(w http.ResponseWriter, r *http.Request) {
 
 // ArticlePhoto is a struct to represent article photo
 type ArticlePhoto struct {
-	ID        int    `json:"id"`
-	Article   int    `json:"article"`
-	FileName  string `json:"file_name"`
-	File      []byte `json:"file"`
-	CreatedAt string `json:"created_at"`
+	ID        int           `json:"id"`
+	Article   int           `json:"article"`
+	FileName  string        `json:"file_name"`
+	MediaID   sql.NullInt64 `json:"-"`
+	CreatedAt string        `json:"created_at"`
 }
 
 // getArticlePhotos is a handler to get article photos
@@ -256,17 +256,16 @@ func getArticlePhotos(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// check if the article exists, and archived is false, completed is true
 	var exists bool
-	err = database.QueryRow("SELECT EXISTS(SELECT 1 FROM articles WHERE id = $1 AND archived = false AND completed = true)", id).Scan(&exists)
+	err = database.QueryRow("SELECT EXISTS(SELECT 1 FROM articles WHERE id = $1 AND archived = false AND completed = true AND "+inWindow+")", id).Scan(&exists)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -283,7 +282,7 @@ func getArticlePhotos(w http.ResponseWriter, r *http.Request) {
 			response.Res(w, "error", http.StatusNotFound, "article photos not found")
 			return
 		}
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -294,7 +293,7 @@ func getArticlePhotos(w http.ResponseWriter, r *http.Request) {
 		var articlePhoto ArticlePhoto
 		err := rows.Scan(&articlePhoto.ID, &articlePhoto.Article, &articlePhoto.FileName)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -302,7 +301,7 @@ func getArticlePhotos(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -318,17 +317,16 @@ func getArticlePhoto(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// check if the article exists, and archived is false, completed is true
 	var exists bool
-	err = database.QueryRow("SELECT EXISTS(SELECT 1 FROM articles WHERE id = $1 AND archived = false AND completed = true)", id).Scan(&exists)
+	err = database.QueryRow("SELECT EXISTS(SELECT 1 FROM articles WHERE id = $1 AND archived = false AND completed = true AND "+inWindow+")", id).Scan(&exists)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -339,23 +337,20 @@ func getArticlePhoto(w http.ResponseWriter, r *http.Request) {
 
 	// get article photo from the database where article id is equal to the id and photo id is equal to the photoID
 	var articlePhoto ArticlePhoto
-	err = database.QueryRow("SELECT id, article, file_name, file, created_at FROM article_photos WHERE article = $1 AND id = $2", id, photoID).Scan(&articlePhoto.ID, &articlePhoto.Article, &articlePhoto.FileName, &articlePhoto.File, &articlePhoto.CreatedAt)
+	err = database.QueryRow("SELECT id, article, file_name, media_id, created_at FROM article_photos WHERE article = $1 AND id = $2", id, photoID).Scan(&articlePhoto.ID, &articlePhoto.Article, &articlePhoto.FileName, &articlePhoto.MediaID, &articlePhoto.CreatedAt)
 	if err != nil {
 		// check if the error is no rows in result set using sql.ErrNoRows
 		if err == sql.ErrNoRows {
 			response.Res(w, "error", http.StatusNotFound, "article photo not found")
 			return
 		}
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	// send the article photo as photo
-	contentType := http.DetectContentType(articlePhoto.File)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(articlePhoto.File)))
-	w.Write(articlePhoto.File)
+	media.Serve(w, r, articlePhoto.MediaID, "")
 }
 
 // getArticleCoverImage is a handler to get article cover image
@@ -365,17 +360,16 @@ func getArticleCoverImage(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// check if the article exists, and archived is false, completed is true
 	var exists bool
-	err = database.QueryRow("SELECT EXISTS(SELECT 1 FROM articles WHERE id = $1 AND archived = false AND completed = true)", id).Scan(&exists)
+	err = database.QueryRow("SELECT EXISTS(SELECT 1 FROM articles WHERE id = $1 AND archived = false AND completed = true AND "+inWindow+")", id).Scan(&exists)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -385,22 +379,82 @@ func getArticleCoverImage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// get article cover image from the database where article id is equal to the id
-	var coverImage []byte
-	err = database.QueryRow("SELECT cover_image FROM articles WHERE id = $1", id).Scan(&coverImage)
+	var coverImageID sql.NullInt64
+	err = database.QueryRow("SELECT cover_image_id FROM articles WHERE id = $1", id).Scan(&coverImageID)
 	if err != nil {
 		// check if the error is no rows in result set using sql.ErrNoRows
 		if err == sql.ErrNoRows {
 			response.Res(w, "error", http.StatusNotFound, "article cover image not found")
 			return
 		}
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	// send the article cover image as photo
-	contentType := http.DetectContentType(coverImage)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(coverImage)))
-	w.Write(coverImage)
+	media.Serve(w, r, coverImageID, "article-"+id+"-cover")
+}
+
+// ArticleDetail is an article with its category, media and related article
+type ArticleDetail struct {
+	Article
+	UpdatedAt     string        `json:"updated_at"`
+	Category      *Reference    `json:"category"`
+	CoverImageURL *string       `json:"cover_image_url"`
+	Photos        []DetailPhoto `json:"photos"`
+	Related       *Reference    `json:"related"`
+	CommentCount  int           `json:"comment_count"`
+}
+
+// getArticle is a handler for the /article/{key} route: the article by its id or slug
+func getArticle(w http.ResponseWriter, r *http.Request) {
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	id, ok := resolveKey(w, r, database, slug.Article, published)
+	if !ok {
+		return
+	}
+
+	var article ArticleDetail
+	var coverImageID sql.NullInt64
+	var category, related nullReference
+	dest := []any{&article.ID, &article.TitleLatin, &article.DescriptionLatin, &article.TitleCyrillic, &article.DescriptionCyrillic, &article.Videos, &article.Tags, &article.CreatedAt, &article.Slug,
+		&article.UpdatedAt, &coverImageID, &article.CommentCount}
+	dest = append(dest, category.dest(false)...)
+	dest = append(dest, related.dest(true)...)
+
+	// the related article only while it is published itself
+	err = database.QueryRow(`SELECT p.id, p.title_latin, p.description_latin, p.title_cyrillic, p.description_cyrillic, p.videos, p.tags, p.created_at, p.slug,
+			p.updated_at, p.cover_image_id, `+fmt.Sprintf(approvedComments, "article_comments", "article")+`,
+			c.id, c.title_latin, c.title_cyrillic, rel.id, rel.title_latin, rel.title_cyrillic, rel.slug
+		FROM (SELECT * FROM articles WHERE id = $1 AND `+published+`) p
+		LEFT JOIN article_category c ON c.id = p.category
+		LEFT JOIN (SELECT id, title_latin, title_cyrillic, slug FROM articles WHERE `+published+`) rel ON rel.id = p.related`, id).Scan(dest...)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	article.Category, article.Related = category.reference(), related.reference()
+	article.CoverImageURL = mediaURL(coverImageID, "/client/article/%d/cover_image", int64(article.ID))
+
+	article.Photos, err = detailPhotos(database, "SELECT id, file_name, created_at FROM article_photos WHERE article = $1 ORDER BY id", "/client/article/%d/photos/%d", int64(article.ID))
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, article)
 }
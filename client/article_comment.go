@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"Tahlilchi.uz/metrics"
 	"Tahlilchi.uz/model"
 	"Tahlilchi.uz/response"
 	"Tahlilchi.uz/toolkit"
@@ -48,6 +49,8 @@ func addArticleComment(w http.ResponseWriter, r *http.Request) {
 		// return
 		return
 	}
+	metrics.CommentSubmissions.Inc("article")
+
 	// respond with the article comment
 	response.Res(w, "success", http.StatusCreated, "article comment added successfully")
 }
@@ -1,12 +1,16 @@
 package client
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
+	"time"
 
 	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/media"
 	"Tahlilchi.uz/model"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/slug"
 	"Tahlilchi.uz/toolkit"
 	"github.com/gorilla/mux"
 	"github.com/lib/pq"
@@ -30,14 +34,13 @@ func getBusinessPromotionalPosts(w http.ResponseWriter, r *http.Request) {
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// get business promotional list response
 	var bppListResponse model.BusinessPromotionalPostListResponse
 
 	// get business promotional posts from the database: select id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, updated_at where archived is false and completed is true and expiration is greater than now order by id
 	// perform a database query: table is business_promotional_posts
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, updated_at FROM business_promotional_posts WHERE archived = false AND completed = true AND expiration > NOW() ORDER BY id LIMIT $1 OFFSET $2", limit, (page-1)*limit)
+	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, updated_at, slug FROM business_promotional_posts WHERE archived = false AND completed = true AND "+inWindow+" AND expiration > NOW() ORDER BY id LIMIT $1 OFFSET $2", limit, (page-1)*limit)
 	if err != nil {
 		err := fmt.Errorf("error querying the database: %v", err)
 		toolkit.LogError(r, err)
@@ -50,7 +53,7 @@ func getBusinessPromotionalPosts(w http.ResponseWriter, r *http.Request) {
 	var bppList []model.BusinessPromotionalPost
 	for rows.Next() {
 		var bpp model.BusinessPromotionalPost
-		err := rows.Scan(&bpp.ID, &bpp.TitleLatin, &bpp.DescriptionLatin, &bpp.TitleCyrillic, &bpp.DescriptionCyrillic, pq.Array(&bpp.Videos), &bpp.UpdatedAt)
+		err := rows.Scan(&bpp.ID, &bpp.TitleLatin, &bpp.DescriptionLatin, &bpp.TitleCyrillic, &bpp.DescriptionCyrillic, pq.Array(&bpp.Videos), &bpp.UpdatedAt, &bpp.Slug)
 		if err != nil {
 			err := fmt.Errorf("error scanning the database: %v", err)
 			toolkit.LogError(r, err)
@@ -66,7 +69,7 @@ func getBusinessPromotionalPosts(w http.ResponseWriter, r *http.Request) {
 
 	var count int
 	// count by id
-	err = database.QueryRow("SELECT COUNT(id) FROM business_promotional_posts WHERE archived = false AND completed = true").Scan(&count)
+	err = database.QueryRow("SELECT COUNT(id) FROM business_promotional_posts WHERE archived = false AND completed = true AND " + inWindow).Scan(&count)
 	if err != nil {
 		err := fmt.Errorf("error querying the database: %v", err)
 		toolkit.LogError(r, err)
@@ -94,11 +97,10 @@ func getBusinessPromotionalPostPhotoList(w http.ResponseWriter, r *http.Request)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// first check if the business promotional post where id is $1, archived is false and completed is true exists
 	var exists bool
-	err = database.QueryRow("SELECT EXISTS(SELECT 1 FROM business_promotional_posts WHERE id = $1 AND archived = false AND completed = true)", id).Scan(&exists)
+	err = database.QueryRow("SELECT EXISTS(SELECT 1 FROM business_promotional_posts WHERE id = $1 AND archived = false AND completed = true AND "+inWindow+")", id).Scan(&exists)
 	if err != nil {
 		err := fmt.Errorf("error querying the database: %v", err)
 		toolkit.LogError(r, err)
@@ -155,11 +157,10 @@ func getBusinessPromotionalPostPhoto(w http.ResponseWriter, r *http.Request) {
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// first check if the business promotional post where id is $1, archived is false and completed is true exists
 	var exists bool
-	err = database.QueryRow("SELECT EXISTS(SELECT 1 FROM business_promotional_posts WHERE id = $1 AND archived = false AND completed = true)", id).Scan(&exists)
+	err = database.QueryRow("SELECT EXISTS(SELECT 1 FROM business_promotional_posts WHERE id = $1 AND archived = false AND completed = true AND "+inWindow+")", id).Scan(&exists)
 	if err != nil {
 		err := fmt.Errorf("error querying the database: %v", err)
 		toolkit.LogError(r, err)
@@ -173,10 +174,14 @@ func getBusinessPromotionalPostPhoto(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// get business promotional post photo from the database: select id, bpp, file_name, file, created_at from bpp_photos where id = $1 and bpp = $2
+	// get business promotional post photo from the database: select id, bpp, file_name, media_id, created_at from bpp_photos where id = $1 and bpp = $2
 	// perform a database query: table is bpp_photos
 	var bppPhoto model.BusinessPromotionalPostPhoto
-	err = database.QueryRow("SELECT id, bpp, file_name, file, created_at FROM bpp_photos WHERE id = $1 AND bpp = $2", vars["photo_id"], id).Scan(&bppPhoto.ID, &bppPhoto.BPP, &bppPhoto.FileName, &bppPhoto.File, &bppPhoto.CreatedAt)
+	err = database.QueryRow("SELECT id, bpp, file_name, media_id, created_at FROM bpp_photos WHERE id = $1 AND bpp = $2", vars["photo_id"], id).Scan(&bppPhoto.ID, &bppPhoto.BPP, &bppPhoto.FileName, &bppPhoto.MediaID, &bppPhoto.CreatedAt)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "photo does not exist")
+		return
+	}
 	if err != nil {
 		err := fmt.Errorf("error querying the database: %v", err)
 		toolkit.LogError(r, err)
@@ -184,10 +189,7 @@ func getBusinessPromotionalPostPhoto(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	contentType := http.DetectContentType(bppPhoto.File)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(bppPhoto.File)))
-	w.Write(bppPhoto.File)
+	media.Serve(w, r, bppPhoto.MediaID, "")
 }
 
 // getBusinessPromotionalPostCoverImage is a handler to get business promotional post cover image
@@ -203,11 +205,10 @@ func getBusinessPromotionalPostCoverImage(w http.ResponseWriter, r *http.Request
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// first check if the business promotional post where id is $1, archived is false and completed is true exists
 	var exists bool
-	err = database.QueryRow("SELECT EXISTS(SELECT 1 FROM business_promotional_posts WHERE id = $1 AND archived = false AND completed = true)", id).Scan(&exists)
+	err = database.QueryRow("SELECT EXISTS(SELECT 1 FROM business_promotional_posts WHERE id = $1 AND archived = false AND completed = true AND "+inWindow+")", id).Scan(&exists)
 	if err != nil {
 		err := fmt.Errorf("error querying the database: %v", err)
 		toolkit.LogError(r, err)
@@ -221,10 +222,10 @@ func getBusinessPromotionalPostCoverImage(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// get business promotional post cover image from the database: select cover_image from business_promotional_posts where id = $1 and archived is false and completed is true
+	// get business promotional post cover image from the database: select cover_image_id from business_promotional_posts where id = $1 and archived is false and completed is true
 	// perform a database query: table is business_promotional_posts
-	var bppCoverImage []byte
-	err = database.QueryRow("SELECT cover_image FROM business_promotional_posts WHERE id = $1 AND archived = false AND completed = true", id).Scan(&bppCoverImage)
+	var bppCoverImageID sql.NullInt64
+	err = database.QueryRow("SELECT cover_image_id FROM business_promotional_posts WHERE id = $1 AND archived = false AND completed = true AND "+inWindow, id).Scan(&bppCoverImageID)
 	if err != nil {
 		err := fmt.Errorf("error querying the database: %v", err)
 		toolkit.LogError(r, err)
@@ -232,8 +233,64 @@ func getBusinessPromotionalPostCoverImage(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	contentType := http.DetectContentType(bppCoverImage)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(bppCoverImage)))
-	w.Write(bppCoverImage)
+	media.Serve(w, r, bppCoverImageID, "business-promotional-post-"+id+"-cover")
+}
+
+// BusinessPromotionalPostDetail is a business promotional post with its media
+type BusinessPromotionalPostDetail struct {
+	ID                  int           `json:"id"`
+	TitleLatin          string        `json:"title_latin"`
+	DescriptionLatin    string        `json:"description_latin"`
+	TitleCyrillic       string        `json:"title_cyrillic"`
+	DescriptionCyrillic string        `json:"description_cyrillic"`
+	Videos              []string      `json:"videos"`
+	Partner             string        `json:"partner"`
+	Expiration          time.Time     `json:"expiration"`
+	CreatedAt           string        `json:"created_at"`
+	UpdatedAt           string        `json:"updated_at"`
+	Slug                *string       `json:"slug"`
+	CoverImageURL       *string       `json:"cover_image_url"`
+	Photos              []DetailPhoto `json:"photos"`
+}
+
+// getBusinessPromotionalPost is a handler for the /business-promotional/post/{key} route: the business promotional post by its id or slug
+func getBusinessPromotionalPost(w http.ResponseWriter, r *http.Request) {
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	// the expired posts are not shown either
+	cond := published + " AND expiration > NOW()"
+	id, ok := resolveKey(w, r, database, slug.BPPost, cond)
+	if !ok {
+		return
+	}
+
+	var bpp BusinessPromotionalPostDetail
+	var coverImageID sql.NullInt64
+	err = database.QueryRow("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, partner, expiration, created_at, updated_at, slug, cover_image_id FROM business_promotional_posts WHERE id = $1 AND "+cond, id).
+		Scan(&bpp.ID, &bpp.TitleLatin, &bpp.DescriptionLatin, &bpp.TitleCyrillic, &bpp.DescriptionCyrillic, pq.Array(&bpp.Videos), &bpp.Partner, &bpp.Expiration, &bpp.CreatedAt, &bpp.UpdatedAt, &bpp.Slug, &coverImageID)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	bpp.CoverImageURL = mediaURL(coverImageID, "/client/business-promotional/post/%d/cover_image", int64(bpp.ID))
+
+	bpp.Photos, err = detailPhotos(database, "SELECT id, file_name, created_at FROM bpp_photos WHERE bpp = $1 ORDER BY id", "/client/business-promotional/post/%d/photo/%d", int64(bpp.ID))
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, bpp)
 }
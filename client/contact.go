@@ -1,12 +1,12 @@
 package client
 
 import (
-	"log"
 	"net/http"
 	"strings"
 
 	"Tahlilchi.uz/db"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/toolkit"
 )
 
 type AdminContactGET struct {
@@ -20,15 +20,14 @@ type AdminContactGET struct {
 func getAdminContact(w http.ResponseWriter, r *http.Request) {
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	result, err := database.Query("SELECT id, address, soc_med_acs, phone_number, email FROM admin_contact LIMIT 1")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -39,7 +38,7 @@ func getAdminContact(w http.ResponseWriter, r *http.Request) {
 		var socMedAcs []uint8
 		err := result.Scan(&contact.ID, &contact.Address, &socMedAcs, &contact.PhoneNumber, &contact.Email)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
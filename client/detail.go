@@ -0,0 +1,84 @@
+package client
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Reference is a category, subcategory, region or related item of a detail response with its titles
+type Reference struct {
+	ID            int64   `json:"id"`
+	TitleLatin    string  `json:"title_latin"`
+	TitleCyrillic string  `json:"title_cyrillic"`
+	Slug          *string `json:"slug,omitempty"`
+}
+
+// nullReference scans the columns of a LEFT JOINed Reference, all NULL without a joined row
+type nullReference struct {
+	ID            sql.NullInt64
+	TitleLatin    sql.NullString
+	TitleCyrillic sql.NullString
+	Slug          sql.NullString
+}
+
+// dest returns the scan destinations of id, title_latin and title_cyrillic, and of slug when withSlug
+func (n *nullReference) dest(withSlug bool) []any {
+	d := []any{&n.ID, &n.TitleLatin, &n.TitleCyrillic}
+	if withSlug {
+		d = append(d, &n.Slug)
+	}
+	return d
+}
+
+// reference returns the scanned Reference, nil without a joined row
+func (n nullReference) reference() *Reference {
+	if !n.ID.Valid {
+		return nil
+	}
+	ref := &Reference{ID: n.ID.Int64, TitleLatin: n.TitleLatin.String, TitleCyrillic: n.TitleCyrillic.String}
+	if n.Slug.Valid {
+		ref.Slug = &n.Slug.String
+	}
+	return ref
+}
+
+// mediaURL returns the path of the client route serving a media column, format with the id of the row, nil without media
+func mediaURL(mediaID sql.NullInt64, format string, id int64) *string {
+	if !mediaID.Valid {
+		return nil
+	}
+	url := fmt.Sprintf(format, id)
+	return &url
+}
+
+// DetailPhoto is a photo of a detail response with the path serving it
+type DetailPhoto struct {
+	ID        int64  `json:"id"`
+	FileName  string `json:"file_name"`
+	URL       string `json:"url"`
+	CreatedAt string `json:"created_at"`
+}
+
+// detailPhotos returns the photos of the row id from query, selecting id, file_name and created_at.
+// format is the path serving a photo with the id of the row and the id of the photo.
+func detailPhotos(database *sql.DB, query, format string, id int64) ([]DetailPhoto, error) {
+	rows, err := database.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	photos := []DetailPhoto{}
+	for rows.Next() {
+		var p DetailPhoto
+		if err := rows.Scan(&p.ID, &p.FileName, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		p.URL = fmt.Sprintf(format, id, p.ID)
+		photos = append(photos, p)
+	}
+	return photos, rows.Err()
+}
+
+// approvedComments is the subquery counting the approved comments of the row p of a detail query
+const approvedComments = "(SELECT COUNT(*) FROM %s WHERE %s = p.id AND approved = true)"
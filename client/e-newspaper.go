@@ -1,12 +1,16 @@
 package client
 
 import (
-	"log"
+	"database/sql"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/media"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/slug"
+	"Tahlilchi.uz/toolkit"
 	"github.com/gorilla/mux"
 )
 
@@ -21,15 +25,14 @@ type ENewspaperCategory struct {
 func getENewspaperCategoryList(w http.ResponseWriter, r *http.Request) {
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	rows, err := database.Query("SELECT id, title_latin, title_cyrillic FROM e_newspaper_category")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -40,7 +43,7 @@ func getENewspaperCategoryList(w http.ResponseWriter, r *http.Request) {
 		var eNewspaperCategory ENewspaperCategory
 		err := rows.Scan(&eNewspaperCategory.ID, &eNewspaperCategory.TitleLatin, &eNewspaperCategory.TitleCyrillic)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -58,7 +61,7 @@ func getENewspaperListByCategory(w http.ResponseWriter, r *http.Request) {
 	// Parse the page number from the query parameters
 	pageStr, ok := r.URL.Query()["page"]
 	if !ok || len(pageStr[0]) < 1 {
-		log.Printf("%v: Url Param 'page' is missing. Setting default value to 1.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'page' is missing. Setting default value to 1.")
 		pageStr = []string{"1"}
 	}
 	page, _ := strconv.Atoi(pageStr[0])
@@ -66,7 +69,7 @@ func getENewspaperListByCategory(w http.ResponseWriter, r *http.Request) {
 	// Parse the limit from the query parameters
 	limitStr, ok := r.URL.Query()["limit"]
 	if !ok || len(limitStr[0]) < 1 {
-		log.Printf("%v: Url Param 'limit' is missing. Setting default value to 10.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'limit' is missing. Setting default value to 10.")
 		limitStr = []string{"10"}
 	}
 	limit, _ := strconv.Atoi(limitStr[0])
@@ -76,15 +79,14 @@ func getENewspaperListByCategory(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, title_cyrillic FROM e_newspapers WHERE category = $1 AND archived = false AND completed = true ORDER BY id DESC LIMIT $2 OFFSET $3", category, limit, start)
+	rows, err := database.Query("SELECT id, title_latin, title_cyrillic, slug FROM e_newspapers WHERE category = $1 AND archived = false AND completed = true AND "+inWindow+" ORDER BY id DESC LIMIT $2 OFFSET $3", category, limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -93,9 +95,9 @@ func getENewspaperListByCategory(w http.ResponseWriter, r *http.Request) {
 	var eNewspapers []ENewspaper
 	for rows.Next() {
 		var eNewspaper ENewspaper
-		err := rows.Scan(&eNewspaper.ID, &eNewspaper.TitleLatin, &eNewspaper.TitleCyrillic)
+		err := rows.Scan(&eNewspaper.ID, &eNewspaper.TitleLatin, &eNewspaper.TitleCyrillic, &eNewspaper.Slug)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -110,7 +112,7 @@ func getENewspaperList(w http.ResponseWriter, r *http.Request) {
 	// Parse the page number from the query parameters
 	pageStr, ok := r.URL.Query()["page"]
 	if !ok || len(pageStr[0]) < 1 {
-		log.Printf("%v: Url Param 'page' is missing. Setting default value to 1.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'page' is missing. Setting default value to 1.")
 		pageStr = []string{"1"}
 	}
 	page, _ := strconv.Atoi(pageStr[0])
@@ -118,7 +120,7 @@ func getENewspaperList(w http.ResponseWriter, r *http.Request) {
 	// Parse the limit from the query parameters
 	limitStr, ok := r.URL.Query()["limit"]
 	if !ok || len(limitStr[0]) < 1 {
-		log.Printf("%v: Url Param 'limit' is missing. Setting default value to 10.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'limit' is missing. Setting default value to 10.")
 		limitStr = []string{"10"}
 	}
 	limit, _ := strconv.Atoi(limitStr[0])
@@ -128,15 +130,14 @@ func getENewspaperList(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, title_cyrillic FROM e_newspapers WHERE archived = false AND completed = true ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
+	rows, err := database.Query("SELECT id, title_latin, title_cyrillic, slug FROM e_newspapers WHERE archived = false AND completed = true AND "+inWindow+" ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -145,9 +146,9 @@ func getENewspaperList(w http.ResponseWriter, r *http.Request) {
 	var eNewspapers []ENewspaper
 	for rows.Next() {
 		var eNewspaper ENewspaper
-		err := rows.Scan(&eNewspaper.ID, &eNewspaper.TitleLatin, &eNewspaper.TitleCyrillic)
+		err := rows.Scan(&eNewspaper.ID, &eNewspaper.TitleLatin, &eNewspaper.TitleCyrillic, &eNewspaper.Slug)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -158,9 +159,10 @@ func getENewspaperList(w http.ResponseWriter, r *http.Request) {
 }
 
 type ENewspaper struct {
-	ID            int    `json:"id"`
-	TitleLatin    string `json:"title_latin"`
-	TitleCyrillic string `json:"title_cyrillic"`
+	ID            int     `json:"id"`
+	TitleLatin    string  `json:"title_latin"`
+	TitleCyrillic string  `json:"title_cyrillic"`
+	Slug          *string `json:"slug"`
 }
 
 func eNewspaperExists(id string) (*bool, error) {
@@ -169,7 +171,6 @@ func eNewspaperExists(id string) (*bool, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
 	var exists bool
 	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM e_newspapers WHERE id=$1)", id).Scan(&exists)
@@ -181,11 +182,11 @@ func eNewspaperExists(id string) (*bool, error) {
 }
 
 type ENewspaperByID struct {
-	ID            int    `json:"id"`
-	TitleLatin    string `json:"title_latin"`
-	TitleCyrillic string `json:"title_cyrillic"`
-	FileLatin     []byte `json:"file_latin"`
-	FileCyrillic  []byte `json:"file_cyrillic"`
+	ID            int           `json:"id"`
+	TitleLatin    string        `json:"title_latin"`
+	TitleCyrillic string        `json:"title_cyrillic"`
+	FileLatin     sql.NullInt64 `json:"-"`
+	FileCyrillic  sql.NullInt64 `json:"-"`
 }
 
 // getENewspaperCoverImage is a handler function that by id returns cover_image as image/jpeg
@@ -195,37 +196,33 @@ func getENewspaperCoverImage(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := eNewspaperExists(idStr)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: eNewspaperExists(idStr): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "eNewspaperExists(idStr): %v", *exists)
 		response.Res(w, "error", http.StatusNotFound, "e-newspaper not found")
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	var eNewspaperCoverImage []byte
-	err = database.QueryRow("SELECT cover_image FROM e_newspapers WHERE id = $1 AND archived = FALSE AND completed = TRUE", idStr).Scan(&eNewspaperCoverImage)
+	var eNewspaperCoverImage sql.NullInt64
+	err = database.QueryRow("SELECT cover_image_id FROM e_newspapers WHERE id = $1 AND archived = FALSE AND completed = TRUE AND "+inWindow, idStr).Scan(&eNewspaperCoverImage)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
-	contentType := http.DetectContentType(eNewspaperCoverImage)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(eNewspaperCoverImage)))
-	w.Write(eNewspaperCoverImage)
+	media.Serve(w, r, eNewspaperCoverImage, "e-newspaper-"+idStr+"-cover")
 }
 
 // getENewspaperFile is a handler function that by id and alphabet returns file_latin or file_cyrillic as pdf with the name of title_latin or tile_cyrillic
@@ -242,55 +239,108 @@ func getENewspaperFile(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := eNewspaperExists(idStr)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: eNewspaperExists(idStr): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "eNewspaperExists(idStr): %v", *exists)
 		response.Res(w, "error", http.StatusNotFound, "e-newspaper not found")
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	var eNewspaper ENewspaperByID
 	if alphabet == "latin" {
-		err = database.QueryRow("SELECT id, title_latin, file_latin FROM e_newspapers WHERE id = $1 AND archived = FALSE AND completed = TRUE", idStr).Scan(&eNewspaper.ID, &eNewspaper.TitleLatin, &eNewspaper.FileLatin)
+		err = database.QueryRow("SELECT id, title_latin, file_latin_id FROM e_newspapers WHERE id = $1 AND archived = FALSE AND completed = TRUE AND "+inWindow, idStr).Scan(&eNewspaper.ID, &eNewspaper.TitleLatin, &eNewspaper.FileLatin)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusBadRequest, err.Error())
 			return
 		}
 	}
 
 	if alphabet == "cyrillic" {
-		err = database.QueryRow("SELECT id, title_cyrillic, file_cyrillic FROM e_newspapers WHERE id = $1 AND archived = FALSE AND completed = TRUE", idStr).Scan(&eNewspaper.ID, &eNewspaper.TitleCyrillic, &eNewspaper.FileCyrillic)
+		err = database.QueryRow("SELECT id, title_cyrillic, file_cyrillic_id FROM e_newspapers WHERE id = $1 AND archived = FALSE AND completed = TRUE AND "+inWindow, idStr).Scan(&eNewspaper.ID, &eNewspaper.TitleCyrillic, &eNewspaper.FileCyrillic)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusBadRequest, err.Error())
 			return
 		}
 	}
 
-	var file []byte
+	var file sql.NullInt64
+	var title string
 	if alphabet == "latin" {
-		file = eNewspaper.FileLatin
+		file, title = eNewspaper.FileLatin, eNewspaper.TitleLatin
 	}
 
 	if alphabet == "cyrillic" {
-		file = eNewspaper.FileCyrillic
+		file, title = eNewspaper.FileCyrillic, eNewspaper.TitleCyrillic
 	}
 
-	contentType := http.DetectContentType(file)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(file)))
-	w.Write(file)
+	media.Serve(w, r, file, title)
+}
+
+// ENewspaperDetail is an e-newspaper with its category and files
+type ENewspaperDetail struct {
+	ENewspaper
+	CreatedAt       string     `json:"created_at"`
+	UpdatedAt       string     `json:"updated_at"`
+	Category        *Reference `json:"category"`
+	FileLatinURL    *string    `json:"file_latin_url"`
+	FileCyrillicURL *string    `json:"file_cyrillic_url"`
+	CoverImageURL   *string    `json:"cover_image_url"`
+	CommentCount    int        `json:"comment_count"`
+}
+
+// getENewspaper is a handler function for the /e-newspaper/{key} route: the e-newspaper by its id or slug
+func getENewspaper(w http.ResponseWriter, r *http.Request) {
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	id, ok := resolveKey(w, r, database, slug.ENewspaper, published)
+	if !ok {
+		return
+	}
+
+	var eNewspaper ENewspaperDetail
+	var fileLatinID, fileCyrillicID, coverImageID sql.NullInt64
+	var category nullReference
+	dest := []any{&eNewspaper.ID, &eNewspaper.TitleLatin, &eNewspaper.TitleCyrillic, &eNewspaper.Slug,
+		&eNewspaper.CreatedAt, &eNewspaper.UpdatedAt, &fileLatinID, &fileCyrillicID, &coverImageID, &eNewspaper.CommentCount}
+	dest = append(dest, category.dest(false)...)
+
+	err = database.QueryRow(`SELECT p.id, p.title_latin, p.title_cyrillic, p.slug,
+			p.created_at, p.updated_at, p.file_latin_id, p.file_cyrillic_id, p.cover_image_id, `+fmt.Sprintf(approvedComments, "e_newspaper_comments", "e_newspaper")+`,
+			c.id, c.title_latin, c.title_cyrillic
+		FROM (SELECT * FROM e_newspapers WHERE id = $1 AND `+published+`) p
+		LEFT JOIN e_newspaper_category c ON c.id = p.category`, id).Scan(dest...)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	eNewspaper.Category = category.reference()
+	eNewspaper.FileLatinURL = mediaURL(fileLatinID, "/client/e-newspaper/%d/file/latin", int64(eNewspaper.ID))
+	eNewspaper.FileCyrillicURL = mediaURL(fileCyrillicID, "/client/e-newspaper/%d/file/cyrillic", int64(eNewspaper.ID))
+	eNewspaper.CoverImageURL = mediaURL(coverImageID, "/client/e-newspaper/%d/cover_image", int64(eNewspaper.ID))
+
+	response.Res(w, "success", http.StatusOK, eNewspaper)
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"Tahlilchi.uz/metrics"
 	"Tahlilchi.uz/model"
 	"Tahlilchi.uz/response"
 	"Tahlilchi.uz/toolkit"
@@ -48,6 +49,8 @@ func addENewspaperComment(w http.ResponseWriter, r *http.Request) {
 		// return
 		return
 	}
+	metrics.CommentSubmissions.Inc("e_newspaper")
+
 	// respond with the e-newspaper comment
 	response.Res(w, "success", http.StatusCreated, "e-newspaper comment added successfully")
 }
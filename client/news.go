@@ -1,12 +1,16 @@
 package client
 
 import (
-	"log"
+	"database/sql"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/media"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/slug"
+	"Tahlilchi.uz/toolkit"
 	"github.com/gorilla/mux"
 	"github.com/lib/pq"
 )
@@ -20,6 +24,7 @@ type NewsPost struct {
 	Video               string         `json:"video"`
 	Tags                pq.StringArray `json:"tags"`
 	CreatedAt           string         `json:"created_at"`
+	Slug                *string        `json:"slug"`
 }
 
 func getAllNewsPosts(w http.ResponseWriter, r *http.Request) {
@@ -42,16 +47,15 @@ func getAllNewsPosts(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	// Get the slice of posts
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags, created_at FROM news_posts WHERE archived = false AND completed = true ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
+	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags, created_at, slug FROM news_posts WHERE archived = false AND completed = true AND "+inWindow+" ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -60,9 +64,9 @@ func getAllNewsPosts(w http.ResponseWriter, r *http.Request) {
 	var posts []NewsPost
 	for rows.Next() {
 		var post NewsPost
-		err := rows.Scan(&post.ID, &post.TitleLatin, &post.DescriptionLatin, &post.TitleCyrillic, &post.DescriptionCyrillic, &post.Video, &post.Tags, &post.CreatedAt)
+		err := rows.Scan(&post.ID, &post.TitleLatin, &post.DescriptionLatin, &post.TitleCyrillic, &post.DescriptionCyrillic, &post.Video, &post.Tags, &post.CreatedAt, &post.Slug)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -70,7 +74,7 @@ func getAllNewsPosts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -101,15 +105,14 @@ func getNewsByCategory(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags, created_at FROM news_posts WHERE category = $1 AND archived = false AND completed = true ORDER BY id DESC LIMIT $2 OFFSET $3", category, limit, start)
+	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags, created_at, slug FROM news_posts WHERE category = $1 AND archived = false AND completed = true AND "+inWindow+" ORDER BY id DESC LIMIT $2 OFFSET $3", category, limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -118,9 +121,9 @@ func getNewsByCategory(w http.ResponseWriter, r *http.Request) {
 	var posts []NewsPost
 	for rows.Next() {
 		var post NewsPost
-		err := rows.Scan(&post.ID, &post.TitleLatin, &post.DescriptionLatin, &post.TitleCyrillic, &post.DescriptionCyrillic, &post.Video, &post.Tags, &post.CreatedAt)
+		err := rows.Scan(&post.ID, &post.TitleLatin, &post.DescriptionLatin, &post.TitleCyrillic, &post.DescriptionCyrillic, &post.Video, &post.Tags, &post.CreatedAt, &post.Slug)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -128,7 +131,7 @@ func getNewsByCategory(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -159,15 +162,14 @@ func getNewsBySubCategory(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags, created_at FROM news_posts WHERE subcategory = $1 AND archived = false AND completed = true ORDER BY id DESC LIMIT $2 OFFSET $3", subcategory, limit, start)
+	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags, created_at, slug FROM news_posts WHERE subcategory = $1 AND archived = false AND completed = true AND "+inWindow+" ORDER BY id DESC LIMIT $2 OFFSET $3", subcategory, limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -176,9 +178,9 @@ func getNewsBySubCategory(w http.ResponseWriter, r *http.Request) {
 	var posts []NewsPost
 	for rows.Next() {
 		var post NewsPost
-		err := rows.Scan(&post.ID, &post.TitleLatin, &post.DescriptionLatin, &post.TitleCyrillic, &post.DescriptionCyrillic, &post.Video, &post.Tags, &post.CreatedAt)
+		err := rows.Scan(&post.ID, &post.TitleLatin, &post.DescriptionLatin, &post.TitleCyrillic, &post.DescriptionCyrillic, &post.Video, &post.Tags, &post.CreatedAt, &post.Slug)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -186,7 +188,7 @@ func getNewsBySubCategory(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -217,15 +219,14 @@ func getNewsByRegion(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags, created_at FROM news_posts WHERE region = $1 AND archived = false AND completed = true ORDER BY id DESC LIMIT $2 OFFSET $3", region, limit, start)
+	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags, created_at, slug FROM news_posts WHERE region = $1 AND archived = false AND completed = true AND "+inWindow+" ORDER BY id DESC LIMIT $2 OFFSET $3", region, limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -234,9 +235,9 @@ func getNewsByRegion(w http.ResponseWriter, r *http.Request) {
 	var posts []NewsPost
 	for rows.Next() {
 		var post NewsPost
-		err := rows.Scan(&post.ID, &post.TitleLatin, &post.DescriptionLatin, &post.TitleCyrillic, &post.DescriptionCyrillic, &post.Video, &post.Tags, &post.CreatedAt)
+		err := rows.Scan(&post.ID, &post.TitleLatin, &post.DescriptionLatin, &post.TitleCyrillic, &post.DescriptionCyrillic, &post.Video, &post.Tags, &post.CreatedAt, &post.Slug)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -244,7 +245,7 @@ func getNewsByRegion(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -272,15 +273,14 @@ func getTopNews(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags, created_at FROM news_posts WHERE archived = false AND top = true AND completed = true ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
+	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags, created_at, slug FROM news_posts WHERE archived = false AND top = true AND completed = true AND "+inWindow+" ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -289,9 +289,9 @@ func getTopNews(w http.ResponseWriter, r *http.Request) {
 	var posts []NewsPost
 	for rows.Next() {
 		var post NewsPost
-		err := rows.Scan(&post.ID, &post.TitleLatin, &post.DescriptionLatin, &post.TitleCyrillic, &post.DescriptionCyrillic, &post.Video, &post.Tags, &post.CreatedAt)
+		err := rows.Scan(&post.ID, &post.TitleLatin, &post.DescriptionLatin, &post.TitleCyrillic, &post.DescriptionCyrillic, &post.Video, &post.Tags, &post.CreatedAt, &post.Slug)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -299,7 +299,7 @@ func getTopNews(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -327,15 +327,14 @@ func getLatestNews(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags, created_at FROM news_posts WHERE archived = false AND latest = true AND completed = true ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
+	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags, created_at, slug FROM news_posts WHERE archived = false AND latest = true AND completed = true AND "+inWindow+" ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -344,9 +343,9 @@ func getLatestNews(w http.ResponseWriter, r *http.Request) {
 	var posts []NewsPost
 	for rows.Next() {
 		var post NewsPost
-		err := rows.Scan(&post.ID, &post.TitleLatin, &post.DescriptionLatin, &post.TitleCyrillic, &post.DescriptionCyrillic, &post.Video, &post.Tags, &post.CreatedAt)
+		err := rows.Scan(&post.ID, &post.TitleLatin, &post.DescriptionLatin, &post.TitleCyrillic, &post.DescriptionCyrillic, &post.Video, &post.Tags, &post.CreatedAt, &post.Slug)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -354,7 +353,7 @@ func getLatestNews(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -385,15 +384,14 @@ func getRelatedNewsPosts(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags, created_at FROM news_posts WHERE related = $1 AND archived = false AND completed = true ORDER BY id DESC LIMIT $2 OFFSET $3", id, limit, start)
+	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags, created_at, slug FROM news_posts WHERE related = $1 AND archived = false AND completed = true AND "+inWindow+" ORDER BY id DESC LIMIT $2 OFFSET $3", id, limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -402,9 +400,9 @@ func getRelatedNewsPosts(w http.ResponseWriter, r *http.Request) {
 	var posts []NewsPost
 	for rows.Next() {
 		var post NewsPost
-		err := rows.Scan(&post.ID, &post.TitleLatin, &post.DescriptionLatin, &post.TitleCyrillic, &post.DescriptionCyrillic, &post.Video, &post.Tags, &post.CreatedAt)
+		err := rows.Scan(&post.ID, &post.TitleLatin, &post.DescriptionLatin, &post.TitleCyrillic, &post.DescriptionCyrillic, &post.Video, &post.Tags, &post.CreatedAt, &post.Slug)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -412,7 +410,7 @@ func getRelatedNewsPosts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -423,15 +421,14 @@ func getRelatedNewsPosts(w http.ResponseWriter, r *http.Request) {
 func getCategoryList(w http.ResponseWriter, r *http.Request) {
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	rows, err := database.Query("SELECT * FROM news_category ORDER BY id")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -442,14 +439,14 @@ func getCategoryList(w http.ResponseWriter, r *http.Request) {
 		var c CategoryForGet
 		err := rows.Scan(&c.ID, &c.TitleLatin, &c.DescriptionLatin, &c.TitleCyrillic, &c.DescriptionCyrillic)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 
 		subRows, err := database.Query("SELECT * FROM news_subcategory WHERE category_id = $1", c.ID)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -459,7 +456,7 @@ func getCategoryList(w http.ResponseWriter, r *http.Request) {
 			var s Subcategory
 			err := subRows.Scan(&s.ID, &s.CategoryID, &s.TitleLatin, &s.DescriptionLatin, &s.TitleCyrillic, &s.DescriptionCyrillic)
 			if err != nil {
-				log.Printf("%v: error: %v", r.URL, err)
+				toolkit.LogError(r, err)
 				response.Res(w, "error", http.StatusInternalServerError, "server error")
 				return
 			}
@@ -496,24 +493,24 @@ func getNewsPostPhoto(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	var photo []byte
-	err = database.QueryRow("SELECT photo FROM news_posts WHERE id = $1 AND archived = false AND completed = true", id).Scan(&photo)
+	var photoID sql.NullInt64
+	err = database.QueryRow("SELECT photo_id FROM news_posts WHERE id = $1 AND archived = false AND completed = true AND "+inWindow, id).Scan(&photoID)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "not found")
+		return
+	}
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	contentType := http.DetectContentType(photo)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(photo)))
-	w.Write(photo)
+	media.Serve(w, r, photoID, "news-"+id+"-photo")
 }
 
 func getNewsPostAudio(w http.ResponseWriter, r *http.Request) {
@@ -522,24 +519,24 @@ func getNewsPostAudio(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	var audio []byte
-	err = database.QueryRow("SELECT audio FROM news_posts WHERE id = $1 AND archived = false AND completed = true", id).Scan(&audio)
+	var audioID sql.NullInt64
+	err = database.QueryRow("SELECT audio_id FROM news_posts WHERE id = $1 AND archived = false AND completed = true AND "+inWindow, id).Scan(&audioID)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "not found")
+		return
+	}
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	contentType := http.DetectContentType(audio)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(audio)))
-	w.Write(audio)
+	media.Serve(w, r, audioID, "news-"+id+"-audio")
 }
 
 func getNewsPostCoverImage(w http.ResponseWriter, r *http.Request) {
@@ -548,24 +545,24 @@ func getNewsPostCoverImage(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	var CoverImage []byte
-	err = database.QueryRow("SELECT cover_image FROM news_posts WHERE id = $1 AND archived = false AND completed = true", id).Scan(&CoverImage)
+	var coverImageID sql.NullInt64
+	err = database.QueryRow("SELECT cover_image_id FROM news_posts WHERE id = $1 AND archived = false AND completed = true AND "+inWindow, id).Scan(&coverImageID)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "not found")
+		return
+	}
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
-	contentType := http.DetectContentType(CoverImage)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(CoverImage)))
-	w.Write(CoverImage)
+	media.Serve(w, r, coverImageID, "news-"+id+"-cover")
 }
 
 // getNewsRegionList is a route handler function to get the news region list
@@ -577,20 +574,18 @@ func getNewsRegionList(w http.ResponseWriter, r *http.Request) {
 	// check if there is an error
 	if err != nil {
 		// log the error
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		// respond with the error
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		// return
 		return
 	}
-	// close the database connection
-	defer database.Close()
 	// get the news region list from the database: table news_regions
 	rows, err := database.Query("SELECT * FROM news_regions ORDER BY id")
 	// check if there is an error
 	if err != nil {
 		// log the error
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		// respond with the error
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		// return
@@ -609,7 +604,7 @@ func getNewsRegionList(w http.ResponseWriter, r *http.Request) {
 		// check if there is an error
 		if err != nil {
 			// log the error
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			// respond with the error
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			// return
@@ -630,3 +625,71 @@ type Region struct {
 	NameCyrillic        string `json:"name_cyrillic"`
 	DescriptionCyrillic string `json:"description_cyrillic"`
 }
+
+// NewsPostDetail is a news post with its category, subcategory, region, media and related news post
+type NewsPostDetail struct {
+	NewsPost
+	UpdatedAt     string     `json:"updated_at"`
+	Top           bool       `json:"top"`
+	Latest        bool       `json:"latest"`
+	Category      *Reference `json:"category"`
+	Subcategory   *Reference `json:"subcategory"`
+	Region        *Reference `json:"region"`
+	PhotoURL      *string    `json:"photo_url"`
+	AudioURL      *string    `json:"audio_url"`
+	CoverImageURL *string    `json:"cover_image_url"`
+	Related       *Reference `json:"related"`
+	CommentCount  int        `json:"comment_count"`
+}
+
+// getNewsPost is a handler for the /news/post/{key} route: the news post by its id or slug
+func getNewsPost(w http.ResponseWriter, r *http.Request) {
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	id, ok := resolveKey(w, r, database, slug.NewsPost, published)
+	if !ok {
+		return
+	}
+
+	var post NewsPostDetail
+	var photoID, audioID, coverImageID sql.NullInt64
+	var category, subcategory, region, related nullReference
+	dest := []any{&post.ID, &post.TitleLatin, &post.DescriptionLatin, &post.TitleCyrillic, &post.DescriptionCyrillic, &post.Video, &post.Tags, &post.CreatedAt, &post.Slug,
+		&post.UpdatedAt, &post.Top, &post.Latest, &photoID, &audioID, &coverImageID, &post.CommentCount}
+	dest = append(dest, category.dest(false)...)
+	dest = append(dest, subcategory.dest(false)...)
+	dest = append(dest, region.dest(false)...)
+	dest = append(dest, related.dest(true)...)
+
+	// the related news post only while it is published itself
+	err = database.QueryRow(`SELECT p.id, p.title_latin, p.description_latin, p.title_cyrillic, p.description_cyrillic, p.video, p.tags, p.created_at, p.slug,
+			p.updated_at, p.top, p.latest, p.photo_id, p.audio_id, p.cover_image_id, `+fmt.Sprintf(approvedComments, "news_post_comments", "news_post")+`,
+			c.id, c.title_latin, c.title_cyrillic, s.id, s.title_latin, s.title_cyrillic, rg.id, rg.name_latin, rg.name_cyrillic,
+			rel.id, rel.title_latin, rel.title_cyrillic, rel.slug
+		FROM (SELECT * FROM news_posts WHERE id = $1 AND `+published+`) p
+		LEFT JOIN news_category c ON c.id = p.category
+		LEFT JOIN news_subcategory s ON s.id = p.subcategory
+		LEFT JOIN news_regions rg ON rg.id = p.region
+		LEFT JOIN (SELECT id, title_latin, title_cyrillic, slug FROM news_posts WHERE `+published+`) rel ON rel.id = p.related`, id).Scan(dest...)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	post.Category, post.Subcategory, post.Region, post.Related = category.reference(), subcategory.reference(), region.reference(), related.reference()
+	post.PhotoURL = mediaURL(photoID, "/client/news/post/%d/photo", post.ID)
+	post.AudioURL = mediaURL(audioID, "/client/news/post/%d/audio", post.ID)
+	post.CoverImageURL = mediaURL(coverImageID, "/client/news/post/%d/cover_image", post.ID)
+
+	response.Res(w, "success", http.StatusOK, post)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"Tahlilchi.uz/metrics"
 	"Tahlilchi.uz/model"
 	"Tahlilchi.uz/response"
 	"Tahlilchi.uz/toolkit"
@@ -47,6 +48,8 @@ func addNewsPostComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	metrics.CommentSubmissions.Inc("news_post")
+
 	// send a response with the news post comment
 	response.Res(w, "success", http.StatusCreated, "news post comment added successfully")
 }
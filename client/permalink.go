@@ -0,0 +1,46 @@
+package client
+
+import (
+	"database/sql"
+	"net/http"
+	"path"
+	"strconv"
+
+	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/slug"
+	"Tahlilchi.uz/toolkit"
+	"github.com/gorilla/mux"
+)
+
+// published is the condition of the rows shown to the clients
+const published = "archived = false AND completed = true AND " + inWindow
+
+// resolveKey returns the id of the row of e named by the {key} of r, its id or its slug, among the rows of cond.
+// A key of digits only is an id, slug.Entity.Assign makes no such slug.
+// ok is false when a response was already written: the redirect of an old slug to the current one, or an error.
+func resolveKey(w http.ResponseWriter, r *http.Request, database *sql.DB, e slug.Entity, cond string) (id int64, ok bool) {
+	key := mux.Vars(r)["key"]
+	if id, err := strconv.ParseInt(key, 10, 64); err == nil {
+		return id, true
+	}
+
+	id, current, redirect, err := e.Resolve(database, key, cond)
+	if err == slug.ErrNotFound {
+		response.Res(w, "error", http.StatusNotFound, "not found")
+		return 0, false
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return 0, false
+	}
+
+	if redirect {
+		// the permalink of the old slug moves to the current one
+		u := *r.URL
+		u.Path, u.RawPath = path.Join(path.Dir(r.URL.Path), current), ""
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+		return 0, false
+	}
+	return id, true
+}
@@ -1,13 +1,16 @@
 package client
 
 import (
-	"log"
+	"database/sql"
 	"net/http"
 	"strconv"
 	"time"
 
 	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/media"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/slug"
+	"Tahlilchi.uz/toolkit"
 	"github.com/gorilla/mux"
 )
 
@@ -15,7 +18,7 @@ func getPhotoGalleryList(w http.ResponseWriter, r *http.Request) {
 	// Parse the page number from the query parameters
 	pageStr, ok := r.URL.Query()["page"]
 	if !ok || len(pageStr[0]) < 1 {
-		log.Printf("%v: Url Param 'page' is missing. Setting default value to 1.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'page' is missing. Setting default value to 1.")
 		pageStr = []string{"1"}
 	}
 	page, _ := strconv.Atoi(pageStr[0])
@@ -23,7 +26,7 @@ func getPhotoGalleryList(w http.ResponseWriter, r *http.Request) {
 	// Parse the limit from the query parameters
 	limitStr, ok := r.URL.Query()["limit"]
 	if !ok || len(limitStr[0]) < 1 {
-		log.Printf("%v: Url Param 'limit' is missing. Setting default value to 10.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'limit' is missing. Setting default value to 10.")
 		limitStr = []string{"10"}
 	}
 	limit, _ := strconv.Atoi(limitStr[0])
@@ -33,15 +36,14 @@ func getPhotoGalleryList(w http.ResponseWriter, r *http.Request) {
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, title_cyrillic FROM photo_gallery ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
+	rows, err := database.Query("SELECT id, title_latin, title_cyrillic, slug FROM photo_gallery ORDER BY id DESC LIMIT $1 OFFSET $2", limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -50,8 +52,8 @@ func getPhotoGalleryList(w http.ResponseWriter, r *http.Request) {
 	var photoGalleryList []PhotoGallery
 	for rows.Next() {
 		var photoGallery PhotoGallery
-		if err := rows.Scan(&photoGallery.ID, &photoGallery.TitleLatin, &photoGallery.TitleCyrillic); err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+		if err := rows.Scan(&photoGallery.ID, &photoGallery.TitleLatin, &photoGallery.TitleCyrillic, &photoGallery.Slug); err != nil {
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -60,7 +62,7 @@ func getPhotoGalleryList(w http.ResponseWriter, r *http.Request) {
 		var photoGalleryPhotos []PhotoGalleryPhoto
 		photoGalleryPhotosRows, err := database.Query("SELECT id, photo_gallery, file_name, created_at FROM photo_gallery_photos WHERE photo_gallery = $1 ORDER BY id", photoGallery.ID)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -69,7 +71,7 @@ func getPhotoGalleryList(w http.ResponseWriter, r *http.Request) {
 		for photoGalleryPhotosRows.Next() {
 			var photoGalleryPhoto PhotoGalleryPhoto
 			if err := photoGalleryPhotosRows.Scan(&photoGalleryPhoto.ID, &photoGalleryPhoto.PhotoGallery, &photoGalleryPhoto.FileName, &photoGalleryPhoto.CreatedAt); err != nil {
-				log.Printf("%v: error: %v", r.URL, err)
+				toolkit.LogError(r, err)
 				response.Res(w, "error", http.StatusInternalServerError, "server error")
 				return
 			}
@@ -88,6 +90,7 @@ type PhotoGallery struct {
 	ID            int                 `json:"id"`
 	TitleLatin    string              `json:"title_latin"`
 	TitleCyrillic string              `json:"title_cyrillic"`
+	Slug          *string             `json:"slug"`
 	Photos        []PhotoGalleryPhoto `json:"photos"`
 }
 
@@ -97,7 +100,6 @@ func photoGalleryExists(id string) (*bool, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
 	var exists bool
 	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM photo_gallery WHERE id=$1)", id).Scan(&exists)
@@ -112,7 +114,7 @@ func getPhotoGalleryPhotos(w http.ResponseWriter, r *http.Request) {
 	// Parse the page number from the query parameters
 	pageStr, ok := r.URL.Query()["page"]
 	if !ok || len(pageStr[0]) < 1 {
-		log.Printf("%v: Url Param 'page' is missing. Setting default value to 1.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'page' is missing. Setting default value to 1.")
 		pageStr = []string{"1"}
 	}
 	page, _ := strconv.Atoi(pageStr[0])
@@ -120,7 +122,7 @@ func getPhotoGalleryPhotos(w http.ResponseWriter, r *http.Request) {
 	// Parse the limit from the query parameters
 	limitStr, ok := r.URL.Query()["limit"]
 	if !ok || len(limitStr[0]) < 1 {
-		log.Printf("%v: Url Param 'limit' is missing. Setting default value to 10.", r.URL)
+		toolkit.LogInfo(r, "Url Param 'limit' is missing. Setting default value to 10.")
 		limitStr = []string{"10"}
 	}
 	limit, _ := strconv.Atoi(limitStr[0])
@@ -133,28 +135,27 @@ func getPhotoGalleryPhotos(w http.ResponseWriter, r *http.Request) {
 
 	exists, err := photoGalleryExists(id)
 	if err != nil {
-		log.Printf("%v: getPhotoGalleryPhotos photoGalleryExists(id) error: %v", r.URL, err)
+		toolkit.LogErrorf(r, "getPhotoGalleryPhotos photoGalleryExists(id) error: %v", err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	if !*exists {
-		log.Printf("%v: getPhotoGalleryPhotos photoGalleryExists(id): %v", r.URL, *exists)
+		toolkit.LogInfof(r, "getPhotoGalleryPhotos photoGalleryExists(id): %v", *exists)
 		response.Res(w, "error", http.StatusBadRequest, "photo gallery not found")
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	rows, err := database.Query("SELECT id, file_name FROM photo_gallery_photos WHERE photo_gallery = $1 ORDER BY id DESC LIMIT $2 OFFSET $3", id, limit, start)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -164,7 +165,7 @@ func getPhotoGalleryPhotos(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var photoGalleryPhoto PhotoGalleryPhoto
 		if err := rows.Scan(&photoGalleryPhoto.ID, &photoGalleryPhoto.FileName); err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -175,11 +176,11 @@ func getPhotoGalleryPhotos(w http.ResponseWriter, r *http.Request) {
 }
 
 type PhotoGalleryPhoto struct {
-	ID           int       `json:"id"`
-	PhotoGallery int       `json:"photo_gallery"`
-	FileName     string    `json:"file_name"`
-	CreatedAt    time.Time `json:"created_at"`
-	File         []byte    `json:"file"`
+	ID           int           `json:"id"`
+	PhotoGallery int           `json:"photo_gallery"`
+	FileName     string        `json:"file_name"`
+	CreatedAt    time.Time     `json:"created_at"`
+	MediaID      sql.NullInt64 `json:"-"`
 }
 
 // getPhotoGalleryPhoto is a handler to get photo gallery photo
@@ -191,23 +192,76 @@ func getPhotoGalleryPhoto(w http.ResponseWriter, r *http.Request) {
 	// open a database connection
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	var photoGalleryPhoto PhotoGalleryPhoto
-	err = database.QueryRow("SELECT id, photo_gallery, file_name, created_at, file FROM photo_gallery_photos WHERE photo_gallery = $1 AND id = $2", photo_gallery, id).Scan(&photoGalleryPhoto.ID, &photoGalleryPhoto.PhotoGallery, &photoGalleryPhoto.FileName, &photoGalleryPhoto.CreatedAt, &photoGalleryPhoto.File)
+	err = database.QueryRow("SELECT id, photo_gallery, file_name, created_at, media_id FROM photo_gallery_photos WHERE photo_gallery = $1 AND id = $2", photo_gallery, id).Scan(&photoGalleryPhoto.ID, &photoGalleryPhoto.PhotoGallery, &photoGalleryPhoto.FileName, &photoGalleryPhoto.CreatedAt, &photoGalleryPhoto.MediaID)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "photo not found")
+		return
+	}
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 
 	// send file
-	contentType := http.DetectContentType(photoGalleryPhoto.File)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(photoGalleryPhoto.File)))
-	w.Write(photoGalleryPhoto.File)
+	media.Serve(w, r, photoGalleryPhoto.MediaID, "")
+}
+
+// getPhotoGallery is a handler for the /photo-gallery/{key} route: the photo gallery with its photos by its id or slug
+func getPhotoGallery(w http.ResponseWriter, r *http.Request) {
+	database, err := db.DB()
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	id, ok := resolveKey(w, r, database, slug.PhotoGallery, "")
+	if !ok {
+		return
+	}
+
+	var photoGallery PhotoGallery
+	err = database.QueryRow("SELECT id, title_latin, title_cyrillic, slug FROM photo_gallery WHERE id = $1", id).
+		Scan(&photoGallery.ID, &photoGallery.TitleLatin, &photoGallery.TitleCyrillic, &photoGallery.Slug)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	rows, err := database.Query("SELECT id, photo_gallery, file_name, created_at FROM photo_gallery_photos WHERE photo_gallery = $1 ORDER BY id", photoGallery.ID)
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var photoGalleryPhoto PhotoGalleryPhoto
+		if err := rows.Scan(&photoGalleryPhoto.ID, &photoGalleryPhoto.PhotoGallery, &photoGalleryPhoto.FileName, &photoGalleryPhoto.CreatedAt); err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+		photoGallery.Photos = append(photoGallery.Photos, photoGalleryPhoto)
+	}
+	if err := rows.Err(); err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	response.Res(w, "success", http.StatusOK, photoGallery)
 }
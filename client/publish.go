@@ -0,0 +1,5 @@
+package client
+
+// inWindow is the condition of the rows inside their publishing window,
+// neither scheduled for later by publish_at nor past their unpublish_at
+const inWindow = "(publish_at IS NULL OR publish_at <= NOW()) AND (unpublish_at IS NULL OR unpublish_at > NOW())"
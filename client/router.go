@@ -1,10 +1,16 @@
 package client
 
 import (
+	"Tahlilchi.uz/config"
 	"github.com/gorilla/mux"
 )
 
-func ClientRouter(r *mux.Router) {
+// telegram is the bot the new appeals are sent through
+var telegram config.Telegram
+
+func ClientRouter(r *mux.Router, cfg *config.Config) {
+	telegram = cfg.Telegram
+
 	clientRouter := r.PathPrefix("/client").Subrouter()
 	clientRouter.HandleFunc("/appeal", addAppeal).Methods("POST")
 
@@ -24,6 +30,8 @@ func ClientRouter(r *mux.Router) {
 	newsPostRouter.HandleFunc("/{id}/photo", getNewsPostPhoto).Methods("GET")
 	newsPostRouter.HandleFunc("/{id}/audio", getNewsPostAudio).Methods("GET")
 	newsPostRouter.HandleFunc("/{id}/cover_image", getNewsPostCoverImage).Methods("GET")
+	// the news post by its id or slug, an old slug redirects to the current one
+	newsPostRouter.HandleFunc("/{key}", getNewsPost).Methods("GET")
 
 	// article router
 	articleRouter := clientRouter.PathPrefix("/article").Subrouter()
@@ -46,6 +54,8 @@ func ClientRouter(r *mux.Router) {
 
 	// route to get article cover image
 	articleRouter.HandleFunc("/{id}/cover_image", getArticleCoverImage).Methods("GET")
+	// route to get the article by its id or slug
+	articleRouter.HandleFunc("/{key}", getArticle).Methods("GET")
 
 	bpPostRouter := clientRouter.PathPrefix("/business-promotional/post").Subrouter()
 	bpPostRouter.HandleFunc("/list", getBusinessPromotionalPosts).Methods("GET")
@@ -57,6 +67,8 @@ func ClientRouter(r *mux.Router) {
 	bpPostPhotoRouter.HandleFunc("/{photo_id}", getBusinessPromotionalPostPhoto).Methods("GET")
 	// route to get business promotional post cover image
 	bpPostRouter.HandleFunc("/{id}/cover_image", getBusinessPromotionalPostCoverImage).Methods("GET")
+	// route to get business promotional post by its id or slug
+	bpPostRouter.HandleFunc("/{key}", getBusinessPromotionalPost).Methods("GET")
 
 	eNewspaperRouter := clientRouter.PathPrefix("/e-newspaper").Subrouter()
 	// route to get e-newspaper category list
@@ -69,12 +81,16 @@ func ClientRouter(r *mux.Router) {
 	eNewspaperRouter.HandleFunc("/{id}/cover_image", getENewspaperCoverImage).Methods("GET")
 	// route to get /e-newspaper/{id}/file/{alphabet} where file is pdf, alphabet is latin or cyrillic
 	eNewspaperRouter.HandleFunc("/{id}/file/{alphabet}", getENewspaperFile).Methods("GET")
+	// route to get /e-newspaper/{key} where key is the id or the slug
+	eNewspaperRouter.HandleFunc("/{key}", getENewspaper).Methods("GET")
 
 	photoGalleryRouter := clientRouter.PathPrefix("/photo-gallery").Subrouter()
 	photoGalleryRouter.HandleFunc("/list", getPhotoGalleryList).Methods("GET")
 	photoGalleryRouter.HandleFunc("/{id}/photos", getPhotoGalleryPhotos).Methods("GET")
 	// route to get photo gallery photo
 	photoGalleryRouter.HandleFunc("/{id}/photo/{photo_id}", getPhotoGalleryPhoto).Methods("GET")
+	// route to get photo gallery by its id or slug
+	photoGalleryRouter.HandleFunc("/{key}", getPhotoGallery).Methods("GET")
 
 	contactRouter := clientRouter.PathPrefix("/contact").Subrouter()
 	contactRouter.HandleFunc("", getAdminContact).Methods("GET")
@@ -97,6 +113,8 @@ func ClientRouter(r *mux.Router) {
 	videoNewsRouter := clientRouter.PathPrefix("/video-news").Subrouter()
 	// route to get video news list
 	videoNewsRouter.HandleFunc("/list", getVideoNewsList).Methods("GET") // Go file path: client/video_news.go
+	// route to get video news by id
+	videoNewsRouter.HandleFunc("/{id:[0-9]+}", getVideoNews).Methods("GET") // Go file path: client/video_news.go
 
 	// article comment router
 	articleCommentRouter := articleRouter.PathPrefix("/{id}/comment").Subrouter()
@@ -1,184 +1,201 @@
 package client
 
 import (
-	"log"
 	"net/http"
 
 	"Tahlilchi.uz/db"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/search"
+	"Tahlilchi.uz/toolkit"
 	"github.com/gorilla/mux"
 )
 
+// ArticleMatch is an article found by a search
+type ArticleMatch struct {
+	Article
+	search.Match
+}
+
 // searchArticle is a handler function for the /search/article route.
-// It is used to search for articles.
-// search columns: title_latin, description_latin, title_cyrillic, description_cyrillic, tags.
-// tags is text[]. use @> to search for a tag in the tags column.
+// It is used to search for articles in both alphabets: titles, tags and descriptions, the best matches first.
+// Query parameters: search, page and limit.
 func searchArticle(w http.ResponseWriter, r *http.Request) {
-	search := r.URL.Query().Get("search")
-	if search == "" {
-		log.Printf("%v: search query is empty", r.URL)
-		response.Res(w, "error", http.StatusBadRequest, "search query is empty")
+	q, err := search.Parse(r)
+	if err != nil {
+		toolkit.LogInfo(r, err.Error())
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, videos, tags, created_at FROM articles WHERE (title_latin ILIKE '%' || $1 || '%' OR description_latin ILIKE '%' || $1 || '%' OR title_cyrillic ILIKE '%' || $1 || '%' OR description_cyrillic ILIKE '%' || $1 || '%' OR tags @> ARRAY[$1]) AND archived = false AND completed = true", search)
+	rows, total, err := search.Article.Search(database, q, "t.id, t.title_latin, t.description_latin, t.title_cyrillic, t.description_cyrillic, t.videos, t.tags, t.created_at, t.slug", published)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 	defer rows.Close()
 
-	var articles []Article
+	articles := []ArticleMatch{}
 	for rows.Next() {
-		var article Article
-		err := rows.Scan(&article.ID, &article.TitleLatin, &article.TitleCyrillic, &article.DescriptionLatin, &article.DescriptionCyrillic, &article.Videos, &article.Tags, &article.CreatedAt)
+		var a ArticleMatch
+		err := rows.Scan(append([]any{&a.ID, &a.TitleLatin, &a.DescriptionLatin, &a.TitleCyrillic, &a.DescriptionCyrillic, &a.Videos, &a.Tags, &a.CreatedAt, &a.Slug}, a.Dest()...)...)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
-		articles = append(articles, article)
+		articles = append(articles, a)
 	}
 
-	response.Res(w, "success", http.StatusOK, articles)
+	response.Res(w, "success", http.StatusOK, search.NewPage(q, articles, total))
+}
+
+// ENewspaperMatch is an e-newspaper found by a search
+type ENewspaperMatch struct {
+	ENewspaper
+	search.Match
 }
 
 // searchENewspaper is a handler function for the /search/e-newspaper route.
-// It is used to search for e-newspapers.
-// search columns: title_latin, title_cyrillic.
-// where archived is false, completed is true.
+// It is used to search for e-newspapers by their titles in both alphabets, the best matches first.
+// Query parameters: search, page and limit.
 func searchENewspaper(w http.ResponseWriter, r *http.Request) {
-	search := r.URL.Query().Get("search")
-	if search == "" {
-		log.Printf("%v: search query is empty", r.URL)
-		response.Res(w, "error", http.StatusBadRequest, "search query is empty")
+	q, err := search.Parse(r)
+	if err != nil {
+		toolkit.LogInfo(r, err.Error())
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, title_cyrillic FROM e_newspapers WHERE (title_latin ILIKE '%' || $1 || '%' OR title_cyrillic ILIKE '%' || $1 || '%') AND archived = false AND completed = true", search)
+	rows, total, err := search.ENewspaper.Search(database, q, "t.id, t.title_latin, t.title_cyrillic, t.slug", published)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 	defer rows.Close()
 
-	var eNewspapers []ENewspaper
+	eNewspapers := []ENewspaperMatch{}
 	for rows.Next() {
-		var eNewspaper ENewspaper
-		err := rows.Scan(&eNewspaper.ID, &eNewspaper.TitleLatin, &eNewspaper.TitleCyrillic)
+		var e ENewspaperMatch
+		err := rows.Scan(append([]any{&e.ID, &e.TitleLatin, &e.TitleCyrillic, &e.Slug}, e.Dest()...)...)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
-		eNewspapers = append(eNewspapers, eNewspaper)
+		eNewspapers = append(eNewspapers, e)
 	}
 
-	response.Res(w, "success", http.StatusOK, eNewspapers)
+	response.Res(w, "success", http.StatusOK, search.NewPage(q, eNewspapers, total))
+}
+
+// NewsPostMatch is a news post found by a search
+type NewsPostMatch struct {
+	NewsPost
+	search.Match
 }
 
 // searchNews is a handler function for the /search/news route.
-// It is used to search for news.
-// search columns: title_latin, title_cyrillic, description_latin, description_cyrillic, tags.
-// tags is text[].
-// where archived is false, completed is true.
+// It is used to search for news in both alphabets: titles, tags and descriptions, the best matches first.
+// Query parameters: search, page and limit.
 func searchNews(w http.ResponseWriter, r *http.Request) {
-	search := r.URL.Query().Get("search")
-	if search == "" {
-		log.Printf("%v: search query is empty", r.URL)
-		response.Res(w, "error", http.StatusBadRequest, "search query is empty")
+	q, err := search.Parse(r)
+	if err != nil {
+		toolkit.LogInfo(r, err.Error())
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, description_latin, title_cyrillic, description_cyrillic, video, tags FROM news_posts WHERE (title_latin ILIKE '%' || $1 || '%' OR title_cyrillic ILIKE '%' || $1 || '%' OR description_latin ILIKE '%' || $1 || '%' OR description_cyrillic ILIKE '%' || $1 || '%' OR tags @> ARRAY[$1]) AND archived = false AND completed = true", search)
+	rows, total, err := search.NewsPost.Search(database, q, "t.id, t.title_latin, t.description_latin, t.title_cyrillic, t.description_cyrillic, t.video, t.tags, t.created_at, t.slug", published)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 	defer rows.Close()
 
-	var news []NewsPost
+	news := []NewsPostMatch{}
 	for rows.Next() {
-		var n NewsPost
-		err := rows.Scan(&n.ID, &n.TitleLatin, &n.DescriptionLatin, &n.TitleCyrillic, &n.DescriptionCyrillic, &n.Video, &n.Tags)
+		var n NewsPostMatch
+		err := rows.Scan(append([]any{&n.ID, &n.TitleLatin, &n.DescriptionLatin, &n.TitleCyrillic, &n.DescriptionCyrillic, &n.Video, &n.Tags, &n.CreatedAt, &n.Slug}, n.Dest()...)...)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 		news = append(news, n)
 	}
 
-	response.Res(w, "success", http.StatusOK, news)
+	response.Res(w, "success", http.StatusOK, search.NewPage(q, news, total))
+}
+
+// PhotoGalleryMatch is a photo gallery found by a search
+type PhotoGalleryMatch struct {
+	PhotoGallery
+	search.Match
 }
 
 // searchPhotoGallery is a handler function for the /search/photo-gallery route.
-// It is used to search for photo galleries.
-// search columns: title_latin, title_cyrillic.
+// It is used to search for photo galleries by their titles in both alphabets, the best matches first.
+// Query parameters: search, page and limit.
 func searchPhotoGallery(w http.ResponseWriter, r *http.Request) {
-	search := r.URL.Query().Get("search")
-	if search == "" {
-		log.Printf("%v: search query is empty", r.URL)
-		response.Res(w, "error", http.StatusBadRequest, "search query is empty")
+	q, err := search.Parse(r)
+	if err != nil {
+		toolkit.LogInfo(r, err.Error())
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
-	rows, err := database.Query("SELECT id, title_latin, title_cyrillic FROM photo_gallery WHERE title_latin ILIKE '%' || $1 || '%' OR title_cyrillic ILIKE '%' || $1 || '%'", search)
+	rows, total, err := search.PhotoGallery.Search(database, q, "t.id, t.title_latin, t.title_cyrillic, t.slug", "")
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
 	defer rows.Close()
 
-	var photoGalleries []PhotoGallery
+	photoGalleries := []PhotoGalleryMatch{}
 	for rows.Next() {
-		var p PhotoGallery
-		err := rows.Scan(&p.ID, &p.TitleLatin, &p.TitleCyrillic)
+		var p PhotoGalleryMatch
+		err := rows.Scan(append([]any{&p.ID, &p.TitleLatin, &p.TitleCyrillic, &p.Slug}, p.Dest()...)...)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
 		photoGalleries = append(photoGalleries, p)
 	}
 
-	response.Res(w, "success", http.StatusOK, photoGalleries)
+	response.Res(w, "success", http.StatusOK, search.NewPage(q, photoGalleries, total))
 }
 
 // searchPhotoGalleryPhotos is a handler function for the /search/photo-gallery/photos route.
@@ -190,22 +207,21 @@ func searchPhotoGalleryPhotos(w http.ResponseWriter, r *http.Request) {
 
 	search := r.URL.Query().Get("search")
 	if search == "" {
-		log.Printf("%v: search query is empty", r.URL)
+		toolkit.LogInfo(r, "search query is empty")
 		response.Res(w, "error", http.StatusBadRequest, "search query is empty")
 		return
 	}
 
 	database, err := db.DB()
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
-	defer database.Close()
 
 	rows, err := database.Query("SELECT id, file_name from photo_gallery_photos WHERE photo_gallery = $1 AND file_name ILIKE '%' || $2 || '%'", id, search)
 	if err != nil {
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "server error")
 		return
 	}
@@ -216,7 +232,7 @@ func searchPhotoGalleryPhotos(w http.ResponseWriter, r *http.Request) {
 		var p PhotoGalleryPhoto
 		err := rows.Scan(&p.ID, &p.FileName)
 		if err != nil {
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "server error")
 			return
 		}
@@ -1,12 +1,14 @@
 package client
 
 import (
-	"log"
+	"database/sql"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"Tahlilchi.uz/db"
 	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/toolkit"
 )
 
 // VideoNewsListResponse is a struct to represent a video news list response.
@@ -63,18 +65,16 @@ func getVideoNewsList(w http.ResponseWriter, r *http.Request) {
 	database, err := db.DB()
 	if err != nil {
 		// log error
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "Internal server error")
 		return
 	}
-	// defer closing the database
-	defer database.Close()
 
 	// get video news list from the database with limit and offset parameters querying the database
-	rows, err := database.Query("SELECT id, video, text_latin, text_cyrillic, created_at FROM video_news ORDER BY id DESC LIMIT $1 OFFSET $2", limit, offset)
+	rows, err := database.Query("SELECT id, video, text_latin, text_cyrillic, created_at FROM video_news WHERE archived = false AND completed = true AND "+inWindow+" ORDER BY id DESC LIMIT $1 OFFSET $2", limit, offset)
 	if err != nil {
 		// log error
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "Internal server error")
 		return
 	}
@@ -91,7 +91,7 @@ func getVideoNewsList(w http.ResponseWriter, r *http.Request) {
 		err := rows.Scan(&videoNews.ID, &videoNews.Video, &videoNews.TextLatin, &videoNews.TextCyrillic, &videoNews.CreatedAt)
 		if err != nil {
 			// log error
-			log.Printf("%v: error: %v", r.URL, err)
+			toolkit.LogError(r, err)
 			response.Res(w, "error", http.StatusInternalServerError, "Internal server error")
 			return
 		}
@@ -102,17 +102,17 @@ func getVideoNewsList(w http.ResponseWriter, r *http.Request) {
 	err = rows.Err()
 	if err != nil {
 		// log error
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
 	// get the total number of video news
 	var total int
-	err = database.QueryRow("SELECT COUNT(*) FROM video_news").Scan(&total)
+	err = database.QueryRow("SELECT COUNT(*) FROM video_news WHERE archived = false AND completed = true AND " + inWindow).Scan(&total)
 	if err != nil {
 		// log error
-		log.Printf("%v: error: %v", r.URL, err)
+		toolkit.LogError(r, err)
 		response.Res(w, "error", http.StatusInternalServerError, "Internal server error")
 		return
 	}
@@ -126,3 +126,47 @@ func getVideoNewsList(w http.ResponseWriter, r *http.Request) {
 	// send the response
 	response.Res(w, "success", http.StatusOK, videoNewsListResponse)
 }
+
+// VideoNewsDetail is a video news with its approved comment count
+type VideoNewsDetail struct {
+	VideoNews
+	UpdatedAt    string `json:"updated_at"`
+	CommentCount int    `json:"comment_count"`
+}
+
+// getVideoNews is a handler function for the /video-news/{id} route.
+// It is used to get a video news.
+func getVideoNews(w http.ResponseWriter, r *http.Request) {
+	id, err := toolkit.GetID(r)
+	if err != nil {
+		response.Res(w, "error", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// connect to the database
+	database, err := db.DB()
+	if err != nil {
+		// log error
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// get the video news if it is published
+	var videoNews VideoNewsDetail
+	err = database.QueryRow("SELECT p.id, p.video, p.text_latin, p.text_cyrillic, p.created_at, p.updated_at, "+fmt.Sprintf(approvedComments, "video_news_comments", "video_news")+" FROM video_news p WHERE p.id = $1 AND "+published, id).
+		Scan(&videoNews.ID, &videoNews.Video, &videoNews.TextLatin, &videoNews.TextCyrillic, &videoNews.CreatedAt, &videoNews.UpdatedAt, &videoNews.CommentCount)
+	if err == sql.ErrNoRows {
+		response.Res(w, "error", http.StatusNotFound, "video news not found")
+		return
+	}
+	if err != nil {
+		// log error
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// send the response
+	response.Res(w, "success", http.StatusOK, videoNews)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"Tahlilchi.uz/metrics"
 	"Tahlilchi.uz/model"
 	"Tahlilchi.uz/response"
 	"Tahlilchi.uz/toolkit"
@@ -48,6 +49,8 @@ func addVideoNewsComment(w http.ResponseWriter, r *http.Request) {
 		// return
 		return
 	}
+	metrics.CommentSubmissions.Inc("video_news")
+
 	// respond with the video news comment
 	response.Res(w, "success", http.StatusCreated, "video news comment added successfully")
 }
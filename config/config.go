@@ -0,0 +1,348 @@
+// Package config loads the settings of the application into one typed struct.
+//
+// The values are read in this order, a later source overriding an earlier one:
+// the defaults, the YAML file named by CONFIG_FILE (optional) and the environment.
+// A .env file in the working directory is loaded into the environment first,
+// variables which are already set are kept.
+//
+// The fields are described by their struct tags:
+//
+//	yaml:"name"       key in the YAML file
+//	env:"NAME"        environment variable
+//	default:"value"   value used when neither of them sets the field
+//	required:"true"   loading fails when the field is empty
+//	secret:"true"     the value is redacted by String
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds all the settings of the application
+type Config struct {
+	// Environment is "development" or "production"
+	Environment string `yaml:"environment" env:"ENVIRONMENT" default:"production"`
+	// LogLevel is debug, info, warn or error
+	LogLevel string `yaml:"log_level" env:"LOG_LEVEL" default:"info"`
+	// TrustProxyHeaders makes the client address be read from X-Forwarded-For and X-Real-IP,
+	// only to be set behind a reverse proxy which sets them
+	TrustProxyHeaders bool `yaml:"trust_proxy_headers" env:"TRUST_PROXY_HEADERS"`
+	// ProxyHops is the number of reverse proxies in front of the app appending to X-Forwarded-For,
+	// the entries on their left are sent by the client and are not trusted
+	ProxyHops int `yaml:"proxy_hops" env:"PROXY_HOPS" default:"1"`
+	// MigrateOnStart applies the pending migrations before the server starts
+	MigrateOnStart bool `yaml:"migrate_on_start" env:"MIGRATE_ON_START"`
+	// TOTPIssuer is shown by the authenticator apps of the admins
+	TOTPIssuer string `yaml:"totp_issuer" env:"TOTP_ISSUER" default:"Tahlilchi.uz"`
+	// MetricsToken is the bearer token required on /metrics, the metrics are public without it
+	MetricsToken string `yaml:"metrics_token" env:"METRICS_TOKEN" secret:"true"`
+
+	HTTP       HTTP       `yaml:"http"`
+	CORS       CORS       `yaml:"cors"`
+	DB         DB         `yaml:"db"`
+	Session    Session    `yaml:"session"`
+	Storage    Storage    `yaml:"storage"`
+	Mail       Mail       `yaml:"mail"`
+	Telegram   Telegram   `yaml:"telegram"`
+	SuperAdmin SuperAdmin `yaml:"superadmin"`
+}
+
+// HTTP holds the settings of the http server.
+// The read and write timeouts are short, the upload and media handlers give their own requests more time:
+// admins upload large video and audio files and the clients download them.
+type HTTP struct {
+	Addr              string        `yaml:"addr" env:"HTTP_ADDR" default:":8080"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" default:"10s"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT" default:"1m"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" default:"1m"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" default:"2m"`
+	// UploadTimeout is how long a multipart upload is given to be read and answered
+	UploadTimeout  time.Duration `yaml:"upload_timeout" env:"HTTP_UPLOAD_TIMEOUT" default:"15m"`
+	MaxHeaderBytes int           `yaml:"max_header_bytes" env:"HTTP_MAX_HEADER_BYTES" default:"1048576"`
+	// ShutdownTimeout is how long the requests in flight are waited for on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" default:"60s"`
+	// TLS is served when both files are set
+	TLSCertFile string `yaml:"tls_cert_file" env:"TLS_CERT_FILE"`
+	TLSKeyFile  string `yaml:"tls_key_file" env:"TLS_KEY_FILE"`
+}
+
+// CORS holds the origins of the frontends allowed to call the API
+type CORS struct {
+	AdminClient string `yaml:"admin_client" env:"ADMINCLIENT" required:"true"`
+	Client      string `yaml:"client" env:"CLIENT" required:"true"`
+}
+
+// DB holds the database connection and the settings of the shared connection pool
+type DB struct {
+	Host     string `yaml:"host" env:"DBHOST" required:"true"`
+	Port     int    `yaml:"port" env:"DBPORT" default:"5432"`
+	User     string `yaml:"user" env:"DBUSER" required:"true"`
+	Password string `yaml:"password" env:"DBPASSWORD" secret:"true"`
+	Name     string `yaml:"name" env:"DBNAME" required:"true"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" default:"25"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" default:"10"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" default:"30m"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" default:"5m"`
+}
+
+// Session holds the settings of the admin sessions.
+// The first key signs new cookies, all of them are accepted, so a key can be rotated by putting the new one in front.
+type Session struct {
+	Keys            []string      `yaml:"keys" env:"SESSION_KEYS" required:"true" secret:"true"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" env:"SESSION_IDLE_TIMEOUT" default:"2h"`
+	AbsoluteTimeout time.Duration `yaml:"absolute_timeout" env:"SESSION_ABSOLUTE_TIMEOUT" default:"24h"`
+}
+
+// Storage holds where the media files are kept: "local" for a directory or "s3" for an S3-compatible bucket
+type Storage struct {
+	Backend  string `yaml:"backend" env:"STORAGE_BACKEND" default:"local"`
+	LocalDir string `yaml:"local_dir" env:"STORAGE_LOCAL_DIR" default:"uploads"`
+	S3       S3     `yaml:"s3"`
+}
+
+// S3 holds the bucket of an S3-compatible object storage, e.g. https://s3.eu-central-1.amazonaws.com
+// or http://localhost:9000 for a local MinIO
+type S3 struct {
+	Endpoint  string `yaml:"endpoint" env:"S3_ENDPOINT"`
+	Region    string `yaml:"region" env:"S3_REGION" default:"us-east-1"`
+	Bucket    string `yaml:"bucket" env:"S3_BUCKET"`
+	AccessKey string `yaml:"access_key" env:"S3_ACCESS_KEY"`
+	SecretKey string `yaml:"secret_key" env:"S3_SECRET_KEY" secret:"true"`
+}
+
+// Mail holds the SMTP server the emails to the admins are sent through
+type Mail struct {
+	Server   string `yaml:"server" env:"SMTPSERVER" required:"true"`
+	Port     int    `yaml:"port" env:"SMTPPORT" required:"true"`
+	From     string `yaml:"from" env:"EMAILFROM" required:"true"`
+	Password string `yaml:"password" env:"EMAILFROMPASSWORD" required:"true" secret:"true"`
+}
+
+// Telegram holds the bot the new appeals are sent through and the chats they are sent to
+type Telegram struct {
+	Token       string `yaml:"token" env:"TELEGRAM_TOKEN" required:"true" secret:"true"`
+	ChatIDBoss  int64  `yaml:"chat_id_boss" env:"TELEGRAM_CHAT_ID_BOSS" required:"true"`
+	ChatIDAdmin int64  `yaml:"chat_id_admin" env:"TELEGRAM_CHAT_ID_ADMIN" required:"true"`
+}
+
+// SuperAdmin is the first superadmin created on a fresh deployment, nothing is created without an email and a password
+type SuperAdmin struct {
+	Name     string `yaml:"name" env:"SUPERADMIN_NAME" default:"Superadmin"`
+	Email    string `yaml:"email" env:"SUPERADMIN_EMAIL"`
+	Password string `yaml:"password" env:"SUPERADMIN_PASSWORD" secret:"true"`
+}
+
+// Load reads and validates the configuration.
+// All the invalid and missing values are reported at once.
+func Load() (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf(".env: %v", err)
+	}
+
+	var cfg Config
+	var errs []error
+
+	walk(reflect.ValueOf(&cfg).Elem(), "", func(f field) {
+		if def := f.tag.Get("default"); def != "" {
+			if err := set(f.value, def); err != nil {
+				errs = append(errs, fmt.Errorf("%s: default: %v", f.path, err))
+			}
+		}
+	})
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadYAML(path, &cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	walk(reflect.ValueOf(&cfg).Elem(), "", func(f field) {
+		name := f.tag.Get("env")
+		if name == "" {
+			return
+		}
+		if v := os.Getenv(name); v != "" {
+			if err := set(f.value, v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", name, err))
+			}
+		}
+	})
+
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("config: %w", errors.Join(errs...))
+	}
+	return &cfg, nil
+}
+
+// loadYAML reads the YAML file at path into cfg, unknown keys are an error
+func loadYAML(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config: %v", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("config: %s: %v", path, err)
+	}
+	return nil
+}
+
+// validate returns the errors of the required fields which are not set and of the values which do not make sense
+func (c *Config) validate() []error {
+	var errs []error
+
+	walk(reflect.ValueOf(c).Elem(), "", func(f field) {
+		if f.tag.Get("required") == "true" && f.value.IsZero() {
+			name := f.path
+			if env := f.tag.Get("env"); env != "" {
+				name = env
+			}
+			errs = append(errs, fmt.Errorf("%s is required", name))
+		}
+	})
+
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_LEVEL: unknown level %q", c.LogLevel))
+	}
+
+	switch c.Storage.Backend {
+	case "local":
+	case "s3":
+		if c.Storage.S3.Endpoint == "" || c.Storage.S3.Bucket == "" || c.Storage.S3.AccessKey == "" || c.Storage.S3.SecretKey == "" {
+			errs = append(errs, errors.New("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY are required for the s3 storage"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("STORAGE_BACKEND: unknown backend %q", c.Storage.Backend))
+	}
+
+	if (c.HTTP.TLSCertFile == "") != (c.HTTP.TLSKeyFile == "") {
+		errs = append(errs, errors.New("both TLS_CERT_FILE and TLS_KEY_FILE have to be set for TLS"))
+	}
+
+	timeouts := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"HTTP_READ_HEADER_TIMEOUT", c.HTTP.ReadHeaderTimeout},
+		{"HTTP_READ_TIMEOUT", c.HTTP.ReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.HTTP.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.HTTP.IdleTimeout},
+		{"HTTP_UPLOAD_TIMEOUT", c.HTTP.UploadTimeout},
+		{"SHUTDOWN_TIMEOUT", c.HTTP.ShutdownTimeout},
+		{"SESSION_IDLE_TIMEOUT", c.Session.IdleTimeout},
+		{"SESSION_ABSOLUTE_TIMEOUT", c.Session.AbsoluteTimeout},
+	}
+	for _, t := range timeouts {
+		if t.d <= 0 {
+			errs = append(errs, fmt.Errorf("%s has to be positive", t.name))
+		}
+	}
+	if c.ProxyHops <= 0 {
+		errs = append(errs, errors.New("PROXY_HOPS has to be positive"))
+	}
+	if c.HTTP.MaxHeaderBytes <= 0 {
+		errs = append(errs, errors.New("HTTP_MAX_HEADER_BYTES has to be positive"))
+	}
+	if c.DB.MaxOpenConns < 0 || c.DB.MaxIdleConns < 0 || c.DB.ConnMaxLifetime < 0 || c.DB.ConnMaxIdleTime < 0 {
+		errs = append(errs, errors.New("the database pool settings cannot be negative"))
+	}
+
+	return errs
+}
+
+// String returns the configuration one "key: value" line per field, the secrets redacted.
+// It is safe to print or log.
+func (c *Config) String() string {
+	var b strings.Builder
+	walk(reflect.ValueOf(c).Elem(), "", func(f field) {
+		v := f.value.Interface()
+		if s, ok := v.([]string); ok {
+			v = strings.Join(s, ",")
+		}
+		if f.tag.Get("secret") == "true" && !f.value.IsZero() {
+			v = "******"
+		}
+		fmt.Fprintf(&b, "%s: %v\n", f.path, v)
+	})
+	return b.String()
+}
+
+// field is a leaf field of Config
+type field struct {
+	path  string
+	tag   reflect.StructTag
+	value reflect.Value
+}
+
+// walk calls fn for every field of the struct v which is not a struct itself, the path is made of the yaml keys
+func walk(v reflect.Value, prefix string, fn func(field)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		path := prefix + key
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Duration(0)) {
+			walk(fv, path+".", fn)
+			continue
+		}
+		fn(field{path: path, tag: sf.Tag, value: fv})
+	}
+}
+
+// set parses s into the field v
+func set(v reflect.Value, s string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Slice:
+		var list []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// required are the environment variables of the required fields
+var required = map[string]string{
+	"ADMINCLIENT":            "https://admin.example.com",
+	"CLIENT":                 "https://example.com",
+	"DBHOST":                 "localhost",
+	"DBUSER":                 "tahlilchi",
+	"DBNAME":                 "tahlilchi",
+	"SESSION_KEYS":           "first-key,second-key",
+	"SMTPSERVER":             "smtp.example.com",
+	"SMTPPORT":               "587",
+	"EMAILFROM":              "noreply@example.com",
+	"EMAILFROMPASSWORD":      "mail-password",
+	"TELEGRAM_TOKEN":         "bot-token",
+	"TELEGRAM_CHAT_ID_BOSS":  "-1001",
+	"TELEGRAM_CHAT_ID_ADMIN": "-1002",
+}
+
+// setup clears every variable read by Load and runs the test in an empty directory, without a .env file,
+// then sets env
+func setup(t *testing.T, env map[string]string) {
+	t.Helper()
+
+	walk(reflect.ValueOf(&Config{}).Elem(), "", func(f field) {
+		if name := f.tag.Get("env"); name != "" {
+			t.Setenv(name, "")
+		}
+	})
+	t.Setenv("CONFIG_FILE", "")
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// with returns required with the extra variables
+func with(extra map[string]string) map[string]string {
+	env := make(map[string]string, len(required)+len(extra))
+	for k, v := range required {
+		env[k] = v
+	}
+	for k, v := range extra {
+		env[k] = v
+	}
+	return env
+}
+
+// writeYAML writes content to a file of the test directory and points CONFIG_FILE at it
+func writeYAML(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+}
+
+func TestLoadDefaults(t *testing.T) {
+	setup(t, required)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Environment != "production" || cfg.LogLevel != "info" || cfg.HTTP.Addr != ":8080" {
+		t.Errorf("defaults: environment %q, log level %q, addr %q", cfg.Environment, cfg.LogLevel, cfg.HTTP.Addr)
+	}
+	if cfg.HTTP.WriteTimeout != time.Minute || cfg.HTTP.UploadTimeout != 15*time.Minute || cfg.Session.IdleTimeout != 2*time.Hour {
+		t.Errorf("default durations: write timeout %v, upload timeout %v, session idle timeout %v", cfg.HTTP.WriteTimeout, cfg.HTTP.UploadTimeout, cfg.Session.IdleTimeout)
+	}
+	if cfg.DB.Port != 5432 || cfg.HTTP.MaxHeaderBytes != 1<<20 || cfg.ProxyHops != 1 {
+		t.Errorf("default ints: db port %d, max header bytes %d, proxy hops %d", cfg.DB.Port, cfg.HTTP.MaxHeaderBytes, cfg.ProxyHops)
+	}
+	if cfg.TrustProxyHeaders || cfg.MigrateOnStart {
+		t.Error("the bools without a default are not false")
+	}
+	if cfg.Telegram.ChatIDBoss != -1001 {
+		t.Errorf("chat id boss = %d", cfg.Telegram.ChatIDBoss)
+	}
+	if want := []string{"first-key", "second-key"}; !reflect.DeepEqual(cfg.Session.Keys, want) {
+		t.Errorf("session keys = %q, want %q", cfg.Session.Keys, want)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	setup(t, with(map[string]string{
+		"LOG_LEVEL":         "warn",
+		"HTTP_READ_TIMEOUT": "90s",
+	}))
+	writeYAML(t, `
+log_level: debug
+environment: development
+http:
+  addr: ":9090"
+  read_timeout: 5m
+  write_timeout: 3m
+db:
+  port: 6432
+`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{"env over yaml", cfg.LogLevel, "warn"},
+		{"env duration over yaml", cfg.HTTP.ReadTimeout, 90 * time.Second},
+		{"yaml over default", cfg.Environment, "development"},
+		{"yaml string", cfg.HTTP.Addr, ":9090"},
+		{"yaml duration", cfg.HTTP.WriteTimeout, 3 * time.Minute},
+		{"yaml int", cfg.DB.Port, 6432},
+		{"default", cfg.HTTP.IdleTimeout, 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown key", "log_levl: debug\n", "log_levl"},
+		{"bad duration", "http:\n  read_timeout: soon\n", "soon"},
+		{"bad int", "db:\n  port: many\n", "many"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setup(t, required)
+			writeYAML(t, tt.content)
+			_, err := Load()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		setup(t, required)
+		t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+		if _, err := Load(); err == nil {
+			t.Error("Load() with a missing CONFIG_FILE succeeded")
+		}
+	})
+}
+
+func TestLoadEnvErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{"duration", map[string]string{"HTTP_WRITE_TIMEOUT": "10"}, []string{"HTTP_WRITE_TIMEOUT"}},
+		{"negative duration", map[string]string{"SESSION_IDLE_TIMEOUT": "-1h"}, []string{"SESSION_IDLE_TIMEOUT has to be positive"}},
+		{"int", map[string]string{"DBPORT": "5432x"}, []string{"DBPORT"}},
+		{"int64", map[string]string{"TELEGRAM_CHAT_ID_BOSS": "boss"}, []string{"TELEGRAM_CHAT_ID_BOSS"}},
+		{"bool", map[string]string{"MIGRATE_ON_START": "sure"}, []string{"MIGRATE_ON_START"}},
+		{"proxy hops", map[string]string{"PROXY_HOPS": "0"}, []string{"PROXY_HOPS has to be positive"}},
+		{"log level", map[string]string{"LOG_LEVEL": "verbose"}, []string{`LOG_LEVEL: unknown level "verbose"`}},
+		{"storage backend", map[string]string{"STORAGE_BACKEND": "ftp"}, []string{`STORAGE_BACKEND: unknown backend "ftp"`}},
+		{"s3 without bucket", map[string]string{"STORAGE_BACKEND": "s3", "S3_ENDPOINT": "http://localhost:9000"}, []string{"S3_BUCKET"}},
+		{"tls cert without key", map[string]string{"TLS_CERT_FILE": "cert.pem"}, []string{"TLS_KEY_FILE"}},
+		// every error is reported at once
+		{"several", map[string]string{"DBPORT": "x", "LOG_LEVEL": "loud", "HTTP_IDLE_TIMEOUT": "0s"}, []string{"DBPORT", "LOG_LEVEL", "HTTP_IDLE_TIMEOUT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setup(t, with(tt.env))
+			_, err := Load()
+			if err == nil {
+				t.Fatal("Load() succeeded")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Load() error = %v, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadRequired(t *testing.T) {
+	setup(t, nil)
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load() without the required variables succeeded")
+	}
+	for name := range required {
+		if !strings.Contains(err.Error(), name+" is required") {
+			t.Errorf("the error does not report %s: %v", name, err)
+		}
+	}
+	if strings.Contains(err.Error(), "DBPORT") || strings.Contains(err.Error(), "DBPASSWORD") {
+		t.Errorf("an optional field is reported: %v", err)
+	}
+
+	// a required field set by the YAML file only
+	for name, v := range required {
+		if name != "DBHOST" {
+			t.Setenv(name, v)
+		}
+	}
+	writeYAML(t, "db:\n  host: db.internal\n")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DB.Host != "db.internal" {
+		t.Errorf("db host = %q", cfg.DB.Host)
+	}
+}
+
+func TestLoadDotEnv(t *testing.T) {
+	setup(t, required)
+	os.Unsetenv("DBNAME")
+
+	// .env does not override the variables which are set, even to an empty value
+	dotenv := "DBNAME=from_dotenv\nDBHOST=from_dotenv\nMETRICS_TOKEN=from_dotenv\n"
+	if err := os.WriteFile(".env", []byte(dotenv), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DB.Name != "from_dotenv" || cfg.DB.Host != "localhost" || cfg.MetricsToken != "" {
+		t.Errorf("db name %q, db host %q, metrics token %q", cfg.DB.Name, cfg.DB.Host, cfg.MetricsToken)
+	}
+}
+
+func TestString(t *testing.T) {
+	setup(t, with(map[string]string{
+		"DBPASSWORD":    "db-password",
+		"METRICS_TOKEN": "metrics-token",
+	}))
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := cfg.String()
+
+	for _, secret := range []string{"first-key", "second-key", "db-password", "metrics-token", "mail-password", "bot-token"} {
+		if strings.Contains(s, secret) {
+			t.Errorf("String() shows the secret %q:\n%s", secret, s)
+		}
+	}
+	for _, line := range []string{
+		"session.keys: ******\n",
+		"db.password: ******\n",
+		"telegram.token: ******\n",
+		"db.host: localhost\n",
+		"db.port: 5432\n",
+		"http.upload_timeout: 15m0s\n",
+		"cors.client: https://example.com\n",
+		"trust_proxy_headers: false\n",
+		// an empty secret is shown empty, so a missing one can be told apart
+		"superadmin.password: \n",
+		"storage.s3.secret_key: \n",
+	} {
+		if !strings.Contains(s, line) {
+			t.Errorf("String() has no line %q:\n%s", line, s)
+		}
+	}
+}
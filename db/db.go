@@ -1,24 +1,121 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"os"
-	"strconv"
+	"sync"
+
+	"Tahlilchi.uz/config"
+
+	_ "github.com/lib/pq"
 )
 
-func DB() (*sql.DB, error) {
-	port, _ := strconv.Atoi(os.Getenv("DBPORT"))
-	psqlconn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		os.Getenv("DBHOST"), port, os.Getenv("DBUSER"), os.Getenv("DBPASSWORD"), os.Getenv("DBNAME"))
-	db, err := sql.Open("postgres", psqlconn)
+var (
+	// pool is the process-wide connection pool shared by all handlers
+	pool *sql.DB
+	// mu guards pool
+	mu sync.Mutex
+)
+
+// Open creates the shared connection pool and pings the database.
+// It is meant to be called once at startup; calling it again after it succeeded is a no-op.
+func Open(ctx context.Context, cfg config.DB) error {
+	database, err := open(cfg)
+	if err != nil {
+		return err
+	}
+
+	// make sure the database is reachable before the server starts accepting requests
+	if err := database.PingContext(ctx); err != nil {
+		// a later Open creates the pool again instead of returning the unreachable one
+		mu.Lock()
+		if pool == database {
+			pool = nil
+		}
+		mu.Unlock()
+		database.Close()
+		return fmt.Errorf("db ping: %v", err)
+	}
+
+	return nil
+}
+
+// open creates the shared connection pool unless it exists
+func open(cfg config.DB) (*sql.DB, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if pool != nil {
+		return pool, nil
+	}
+
+	database, err := sql.Open("postgres", dataSourceName(cfg))
 	if err != nil {
 		return nil, err
 	}
-	return db, nil
+	database.SetMaxOpenConns(cfg.MaxOpenConns)
+	database.SetMaxIdleConns(cfg.MaxIdleConns)
+	database.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	database.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	pool = database
+	return pool, nil
+}
+
+// DB returns the shared connection pool opened by Open.
+// The returned *sql.DB must not be closed by the caller.
+func DB() (*sql.DB, error) {
+	return DBContext(context.Background())
+}
+
+// DBContext is the context-aware variant of DB.
+// It returns the context error if ctx is already done, e.g. the client went away.
+func DBContext(ctx context.Context) (*sql.DB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if pool == nil {
+		return nil, errors.New("db: the connection pool is not open")
+	}
+	return pool, nil
+}
+
+// Close closes the shared connection pool. It is called once on shutdown.
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if pool == nil {
+		return nil
+	}
+	err := pool.Close()
+	pool = nil
+	return err
+}
+
+// Stats returns the statistics of the shared connection pool
+func Stats() sql.DBStats {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if pool == nil {
+		return sql.DBStats{}
+	}
+	return pool.Stats()
+}
+
+func dataSourceName(cfg config.DB) string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name)
 }
 
-func ConnString() string {
-	connString := fmt.Sprintf("postgresql://%v:%v@%v:%v/%v?sslmode=disable", os.Getenv("DBUSER"), os.Getenv("DBPASSWORD"), os.Getenv("DBHOST"), os.Getenv("DBPORT"), os.Getenv("DBNAME"))
+func ConnString(cfg config.DB) string {
+	connString := fmt.Sprintf("postgresql://%v:%v@%v:%v/%v?sslmode=disable", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Name)
 	return connString
 }
@@ -0,0 +1,10 @@
+BEGIN;
+
+-- roles used to be free text typed in the developer console and were not enforced,
+-- so every existing admin keeps full access until a superadmin assigns a narrower role
+UPDATE admins SET role = 'superadmin' WHERE role NOT IN ('superadmin', 'editor', 'moderator', 'ads-manager');
+
+ALTER TABLE admins
+ADD CONSTRAINT admins_role_check CHECK (role IN ('superadmin', 'editor', 'moderator', 'ads-manager'));
+
+COMMIT;
@@ -0,0 +1,4 @@
+ALTER TABLE admins
+DROP COLUMN IF EXISTS disabled,
+DROP COLUMN IF EXISTS must_change_password,
+DROP COLUMN IF EXISTS updated_at;
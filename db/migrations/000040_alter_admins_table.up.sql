@@ -0,0 +1,4 @@
+ALTER TABLE admins
+ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN must_change_password BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
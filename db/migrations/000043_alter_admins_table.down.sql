@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS role_settings;
+
+DROP TABLE IF EXISTS admin_recovery_codes;
+
+ALTER TABLE admins
+DROP COLUMN IF EXISTS totp_secret,
+DROP COLUMN IF EXISTS totp_enabled,
+DROP COLUMN IF EXISTS totp_last_step;
@@ -0,0 +1,11 @@
+-- the files moved out by "main media migrate" are not moved back
+ALTER TABLE photo_gallery_photos DROP COLUMN IF EXISTS media_id;
+ALTER TABLE bpp_photos DROP COLUMN IF EXISTS media_id;
+ALTER TABLE article_photos DROP COLUMN IF EXISTS media_id;
+ALTER TABLE appeals DROP COLUMN IF EXISTS picture_id, DROP COLUMN IF EXISTS video_id;
+ALTER TABLE e_newspapers DROP COLUMN IF EXISTS file_latin_id, DROP COLUMN IF EXISTS file_cyrillic_id, DROP COLUMN IF EXISTS cover_image_id;
+ALTER TABLE business_promotional_posts DROP COLUMN IF EXISTS cover_image_id;
+ALTER TABLE articles DROP COLUMN IF EXISTS cover_image_id;
+ALTER TABLE news_posts DROP COLUMN IF EXISTS photo_id, DROP COLUMN IF EXISTS audio_id, DROP COLUMN IF EXISTS cover_image_id;
+
+DROP TABLE IF EXISTS media;
@@ -0,0 +1,43 @@
+-- media files kept in the storage backend, the rows of the other tables reference them by id
+CREATE TABLE IF NOT EXISTS media(
+    id BIGSERIAL PRIMARY KEY,
+    storage_key TEXT NOT NULL UNIQUE,
+    file_name TEXT NOT NULL DEFAULT '',
+    mime_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE news_posts
+    ADD COLUMN IF NOT EXISTS photo_id BIGINT REFERENCES media(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS audio_id BIGINT REFERENCES media(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS cover_image_id BIGINT REFERENCES media(id) ON DELETE SET NULL;
+
+ALTER TABLE articles
+    ADD COLUMN IF NOT EXISTS cover_image_id BIGINT REFERENCES media(id) ON DELETE SET NULL;
+
+ALTER TABLE business_promotional_posts
+    ADD COLUMN IF NOT EXISTS cover_image_id BIGINT REFERENCES media(id) ON DELETE SET NULL;
+
+ALTER TABLE e_newspapers
+    ADD COLUMN IF NOT EXISTS file_latin_id BIGINT REFERENCES media(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS file_cyrillic_id BIGINT REFERENCES media(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS cover_image_id BIGINT REFERENCES media(id) ON DELETE SET NULL;
+
+ALTER TABLE appeals
+    ADD COLUMN IF NOT EXISTS picture_id BIGINT REFERENCES media(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS video_id BIGINT REFERENCES media(id) ON DELETE SET NULL;
+
+-- the bytea columns are emptied by "main media migrate", new rows only reference the media
+ALTER TABLE article_photos
+    ADD COLUMN IF NOT EXISTS media_id BIGINT REFERENCES media(id) ON DELETE SET NULL,
+    ALTER COLUMN file DROP NOT NULL;
+
+ALTER TABLE bpp_photos
+    ADD COLUMN IF NOT EXISTS media_id BIGINT REFERENCES media(id) ON DELETE SET NULL,
+    ALTER COLUMN file DROP NOT NULL;
+
+ALTER TABLE photo_gallery_photos
+    ADD COLUMN IF NOT EXISTS media_id BIGINT REFERENCES media(id) ON DELETE SET NULL,
+    ALTER COLUMN file DROP NOT NULL;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS media_original_rendition_idx;
+
+ALTER TABLE media
+    DROP COLUMN IF EXISTS processed_at,
+    DROP COLUMN IF EXISTS height,
+    DROP COLUMN IF EXISTS width,
+    DROP COLUMN IF EXISTS rendition,
+    DROP COLUMN IF EXISTS original_id;
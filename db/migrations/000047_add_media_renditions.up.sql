@@ -0,0 +1,10 @@
+-- renditions of the uploaded images are media rows too, referencing the original image
+ALTER TABLE media
+    ADD COLUMN IF NOT EXISTS original_id BIGINT REFERENCES media(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS rendition TEXT,
+    ADD COLUMN IF NOT EXISTS width INTEGER,
+    ADD COLUMN IF NOT EXISTS height INTEGER,
+    -- set on the original images once their renditions are made, or they cannot be decoded
+    ADD COLUMN IF NOT EXISTS processed_at TIMESTAMP;
+
+CREATE UNIQUE INDEX IF NOT EXISTS media_original_rendition_idx ON media(original_id, rendition, mime_type) WHERE original_id IS NOT NULL;
@@ -0,0 +1,34 @@
+DROP INDEX IF EXISTS news_posts_unpublish_at_idx;
+DROP INDEX IF EXISTS news_posts_publish_at_idx;
+
+DROP INDEX IF EXISTS articles_unpublish_at_idx;
+DROP INDEX IF EXISTS articles_publish_at_idx;
+
+DROP INDEX IF EXISTS e_newspapers_unpublish_at_idx;
+DROP INDEX IF EXISTS e_newspapers_publish_at_idx;
+
+DROP INDEX IF EXISTS video_news_unpublish_at_idx;
+DROP INDEX IF EXISTS video_news_publish_at_idx;
+
+DROP INDEX IF EXISTS business_promotional_posts_unpublish_at_idx;
+DROP INDEX IF EXISTS business_promotional_posts_publish_at_idx;
+
+ALTER TABLE news_posts
+    DROP COLUMN IF EXISTS unpublish_at,
+    DROP COLUMN IF EXISTS publish_at;
+
+ALTER TABLE articles
+    DROP COLUMN IF EXISTS unpublish_at,
+    DROP COLUMN IF EXISTS publish_at;
+
+ALTER TABLE e_newspapers
+    DROP COLUMN IF EXISTS unpublish_at,
+    DROP COLUMN IF EXISTS publish_at;
+
+ALTER TABLE video_news
+    DROP COLUMN IF EXISTS unpublish_at,
+    DROP COLUMN IF EXISTS publish_at;
+
+ALTER TABLE business_promotional_posts
+    DROP COLUMN IF EXISTS unpublish_at,
+    DROP COLUMN IF EXISTS publish_at;
@@ -0,0 +1,41 @@
+-- publishing windows: PublishScheduled publishes the rows at publish_at and unpublishes them at unpublish_at,
+-- the clients see only the rows inside their window
+ALTER TABLE news_posts
+    ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMPTZ;
+
+ALTER TABLE articles
+    ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMPTZ;
+
+ALTER TABLE e_newspapers
+    ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMPTZ;
+
+ALTER TABLE video_news
+    ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMPTZ;
+
+ALTER TABLE business_promotional_posts
+    ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS news_posts_publish_at_idx ON news_posts(publish_at) WHERE publish_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS news_posts_unpublish_at_idx ON news_posts(unpublish_at) WHERE unpublish_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS articles_publish_at_idx ON articles(publish_at) WHERE publish_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS articles_unpublish_at_idx ON articles(unpublish_at) WHERE unpublish_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS e_newspapers_publish_at_idx ON e_newspapers(publish_at) WHERE publish_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS e_newspapers_unpublish_at_idx ON e_newspapers(unpublish_at) WHERE unpublish_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS video_news_publish_at_idx ON video_news(publish_at) WHERE publish_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS video_news_unpublish_at_idx ON video_news(unpublish_at) WHERE unpublish_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS business_promotional_posts_publish_at_idx ON business_promotional_posts(publish_at) WHERE publish_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS business_promotional_posts_unpublish_at_idx ON business_promotional_posts(unpublish_at) WHERE unpublish_at IS NOT NULL;
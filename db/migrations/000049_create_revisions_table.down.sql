@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS revisions;
+
+DROP FUNCTION IF EXISTS revisions_immutable();
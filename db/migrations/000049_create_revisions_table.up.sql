@@ -0,0 +1,48 @@
+-- every save of a news post or an article, with its full content as it was after the save
+CREATE TABLE IF NOT EXISTS revisions(
+    id BIGSERIAL PRIMARY KEY,
+    entity_type TEXT NOT NULL,
+    entity_id BIGINT NOT NULL,
+    number INTEGER NOT NULL,
+    author_email TEXT NOT NULL,
+    content JSONB NOT NULL,
+    -- the media referenced by content, kept by the media orphan cleanup
+    media_ids BIGINT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (entity_type, entity_id, number)
+);
+
+CREATE INDEX IF NOT EXISTS revisions_media_ids_idx ON revisions USING GIN (media_ids);
+
+-- revisions are immutable, they are only deleted with their news post or article
+CREATE OR REPLACE FUNCTION revisions_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'revisions are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS revisions_immutable ON revisions;
+CREATE TRIGGER revisions_immutable BEFORE UPDATE ON revisions
+    FOR EACH ROW EXECUTE FUNCTION revisions_immutable();
+
+-- the first revision of the existing rows is their current content
+INSERT INTO revisions (entity_type, entity_id, number, author_email, content, media_ids)
+SELECT 'news_post', t.id, 1, '',
+    jsonb_build_object('title_latin', t.title_latin, 'description_latin', t.description_latin,
+        'title_cyrillic', t.title_cyrillic, 'description_cyrillic', t.description_cyrillic,
+        'photo_id', t.photo_id, 'video', t.video, 'audio_id', t.audio_id, 'cover_image_id', t.cover_image_id,
+        'tags', t.tags, 'category', t.category, 'subcategory', t.subcategory, 'region', t.region,
+        'top', t.top, 'latest', t.latest, 'related', t.related),
+    array_remove(ARRAY[t.photo_id, t.audio_id, t.cover_image_id], NULL)
+FROM news_posts t
+ON CONFLICT DO NOTHING;
+
+INSERT INTO revisions (entity_type, entity_id, number, author_email, content, media_ids)
+SELECT 'article', t.id, 1, '',
+    jsonb_build_object('title_latin', t.title_latin, 'description_latin', t.description_latin,
+        'title_cyrillic', t.title_cyrillic, 'description_cyrillic', t.description_cyrillic,
+        'videos', t.videos, 'cover_image_id', t.cover_image_id, 'tags', t.tags,
+        'category', t.category, 'related', t.related),
+    array_remove(ARRAY[t.cover_image_id], NULL)
+FROM articles t
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,16 @@
+UPDATE admins SET role = 'editor' WHERE role = 'chief-editor';
+ALTER TABLE admins DROP CONSTRAINT IF EXISTS admins_role_check;
+ALTER TABLE admins
+ADD CONSTRAINT admins_role_check CHECK (role IN ('superadmin', 'editor', 'moderator', 'ads-manager'));
+
+DROP TABLE IF EXISTS workflow_events;
+
+DROP INDEX IF EXISTS video_news_in_review_idx;
+DROP INDEX IF EXISTS e_newspapers_in_review_idx;
+DROP INDEX IF EXISTS articles_in_review_idx;
+DROP INDEX IF EXISTS news_posts_in_review_idx;
+
+ALTER TABLE video_news DROP COLUMN IF EXISTS status;
+ALTER TABLE e_newspapers DROP COLUMN IF EXISTS status;
+ALTER TABLE articles DROP COLUMN IF EXISTS status;
+ALTER TABLE news_posts DROP COLUMN IF EXISTS status;
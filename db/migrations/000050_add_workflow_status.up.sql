@@ -0,0 +1,48 @@
+-- the editorial workflow: draft -> in_review -> approved -> published, and archived.
+-- completed and archived are kept in sync with status, completed is true only for published rows
+ALTER TABLE news_posts
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'in_review', 'approved', 'published', 'archived'));
+
+ALTER TABLE articles
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'in_review', 'approved', 'published', 'archived'));
+
+ALTER TABLE e_newspapers
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'in_review', 'approved', 'published', 'archived'));
+
+ALTER TABLE video_news
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'in_review', 'approved', 'published', 'archived'));
+
+UPDATE news_posts SET status = CASE WHEN archived THEN 'archived' WHEN completed THEN 'published' ELSE 'draft' END;
+UPDATE articles SET status = CASE WHEN archived THEN 'archived' WHEN completed THEN 'published' ELSE 'draft' END;
+UPDATE e_newspapers SET status = CASE WHEN archived THEN 'archived' WHEN completed THEN 'published' ELSE 'draft' END;
+-- the clients showed every video news, completed was never set
+UPDATE video_news SET status = CASE WHEN archived THEN 'archived' ELSE 'published' END, completed = NOT archived;
+
+CREATE INDEX IF NOT EXISTS news_posts_in_review_idx ON news_posts (updated_at) WHERE status = 'in_review';
+CREATE INDEX IF NOT EXISTS articles_in_review_idx ON articles (updated_at) WHERE status = 'in_review';
+CREATE INDEX IF NOT EXISTS e_newspapers_in_review_idx ON e_newspapers (updated_at) WHERE status = 'in_review';
+CREATE INDEX IF NOT EXISTS video_news_in_review_idx ON video_news (updated_at) WHERE status = 'in_review';
+
+-- every status transition, with the comment of a rejection or a change request
+CREATE TABLE IF NOT EXISTS workflow_events(
+    id BIGSERIAL PRIMARY KEY,
+    entity_type TEXT NOT NULL,
+    entity_id BIGINT NOT NULL,
+    action TEXT NOT NULL,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    admin_email TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS workflow_events_entity_idx ON workflow_events (entity_type, entity_id, created_at);
+
+-- the chief editor reviews and publishes the content of the editors
+ALTER TABLE admins DROP CONSTRAINT IF EXISTS admins_role_check;
+ALTER TABLE admins
+ADD CONSTRAINT admins_role_check CHECK (role IN ('superadmin', 'chief-editor', 'editor', 'moderator', 'ads-manager'));
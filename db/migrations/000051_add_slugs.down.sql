@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS slug_redirects;
+
+DROP INDEX IF EXISTS business_promotional_posts_slug_idx;
+DROP INDEX IF EXISTS photo_gallery_slug_idx;
+DROP INDEX IF EXISTS e_newspapers_slug_idx;
+DROP INDEX IF EXISTS articles_slug_idx;
+DROP INDEX IF EXISTS news_posts_slug_idx;
+
+ALTER TABLE business_promotional_posts DROP COLUMN IF EXISTS slug;
+ALTER TABLE photo_gallery DROP COLUMN IF EXISTS slug;
+ALTER TABLE e_newspapers DROP COLUMN IF EXISTS slug;
+ALTER TABLE articles DROP COLUMN IF EXISTS slug;
+ALTER TABLE news_posts DROP COLUMN IF EXISTS slug;
@@ -0,0 +1,23 @@
+-- URL slugs of the public content, assigned from title_latin by the application
+ALTER TABLE news_posts ADD COLUMN IF NOT EXISTS slug TEXT;
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS slug TEXT;
+ALTER TABLE e_newspapers ADD COLUMN IF NOT EXISTS slug TEXT;
+ALTER TABLE photo_gallery ADD COLUMN IF NOT EXISTS slug TEXT;
+ALTER TABLE business_promotional_posts ADD COLUMN IF NOT EXISTS slug TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS news_posts_slug_idx ON news_posts (slug);
+CREATE UNIQUE INDEX IF NOT EXISTS articles_slug_idx ON articles (slug);
+CREATE UNIQUE INDEX IF NOT EXISTS e_newspapers_slug_idx ON e_newspapers (slug);
+CREATE UNIQUE INDEX IF NOT EXISTS photo_gallery_slug_idx ON photo_gallery (slug);
+CREATE UNIQUE INDEX IF NOT EXISTS business_promotional_posts_slug_idx ON business_promotional_posts (slug);
+
+-- the old slugs of the rows whose title changed, redirected to their current slug
+CREATE TABLE IF NOT EXISTS slug_redirects(
+    entity_type TEXT NOT NULL,
+    slug TEXT NOT NULL,
+    entity_id BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (entity_type, slug)
+);
+
+CREATE INDEX IF NOT EXISTS slug_redirects_entity_idx ON slug_redirects (entity_type, entity_id);
@@ -0,0 +1,14 @@
+BEGIN;
+
+ALTER TABLE news_posts DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE articles DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE business_promotional_posts DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE e_newspapers DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE photo_gallery DROP COLUMN IF EXISTS search_vector;
+
+DROP FUNCTION IF EXISTS uz_search_tags(TEXT[]);
+DROP FUNCTION IF EXISTS uz_search_text(TEXT);
+
+DROP TEXT SEARCH CONFIGURATION IF EXISTS uzbek;
+
+COMMIT;
@@ -0,0 +1,57 @@
+BEGIN;
+
+-- uzbek is the text search configuration of the content in both alphabets.
+-- Uzbek has no stemmer, so the words are only lowercased, and the searches match them by prefix.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'uzbek') THEN
+        CREATE TEXT SEARCH CONFIGURATION uzbek (COPY = simple);
+    END IF;
+END
+$$;
+
+-- uz_search_text returns the text of a column for the search: without HTML tags and character references,
+-- and with the apostrophes typed for oʻ, gʻ and the tutuq belgisi all written as ʻ, so o'zbek and oʻzbek are the same word
+CREATE OR REPLACE FUNCTION uz_search_text(t TEXT) RETURNS TEXT
+    LANGUAGE SQL IMMUTABLE PARALLEL SAFE
+    AS $$ SELECT translate(regexp_replace(coalesce(t, ''), '<[^>]*>|&#?[a-z0-9]+;', ' ', 'gi'), '''`ʼ‘’', 'ʻʻʻʻʻ') $$;
+
+-- uz_search_tags returns the tags of a row for the search
+CREATE OR REPLACE FUNCTION uz_search_tags(tags TEXT[]) RETURNS TEXT
+    LANGUAGE SQL IMMUTABLE PARALLEL SAFE
+    AS $$ SELECT uz_search_text(array_to_string(tags, ' ')) $$;
+
+-- the titles weigh the most, then the tags and the partner, then the descriptions
+ALTER TABLE news_posts ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('uzbek', uz_search_text(title_latin) || ' ' || uz_search_text(title_cyrillic)), 'A') ||
+    setweight(to_tsvector('uzbek', uz_search_tags(tags)), 'B') ||
+    setweight(to_tsvector('uzbek', uz_search_text(description_latin) || ' ' || uz_search_text(description_cyrillic)), 'C')
+) STORED;
+
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('uzbek', uz_search_text(title_latin) || ' ' || uz_search_text(title_cyrillic)), 'A') ||
+    setweight(to_tsvector('uzbek', uz_search_tags(tags)), 'B') ||
+    setweight(to_tsvector('uzbek', uz_search_text(description_latin) || ' ' || uz_search_text(description_cyrillic)), 'C')
+) STORED;
+
+ALTER TABLE business_promotional_posts ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('uzbek', uz_search_text(title_latin) || ' ' || uz_search_text(title_cyrillic)), 'A') ||
+    setweight(to_tsvector('uzbek', uz_search_text(partner)), 'B') ||
+    setweight(to_tsvector('uzbek', uz_search_text(description_latin) || ' ' || uz_search_text(description_cyrillic)), 'C')
+) STORED;
+
+ALTER TABLE e_newspapers ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('uzbek', uz_search_text(title_latin) || ' ' || uz_search_text(title_cyrillic)), 'A')
+) STORED;
+
+ALTER TABLE photo_gallery ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('uzbek', uz_search_text(title_latin) || ' ' || uz_search_text(title_cyrillic)), 'A')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS news_posts_search_idx ON news_posts USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS articles_search_idx ON articles USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS business_promotional_posts_search_idx ON business_promotional_posts USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS e_newspapers_search_idx ON e_newspapers USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS photo_gallery_search_idx ON photo_gallery USING GIN (search_vector);
+
+COMMIT;
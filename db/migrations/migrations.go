@@ -0,0 +1,306 @@
+// Package migrations embeds the versioned SQL schema files of the application
+// and applies them to the database.
+//
+// Files are named <version>_<name>.up.sql and <version>_<name>.down.sql.
+// The applied version is kept in the schema_migrations table which has the
+// same layout as the one of the golang-migrate CLI, so databases migrated
+// with that tool are picked up as they are.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// lockID is the key of the postgres advisory lock held while migrating,
+// so two instances starting at the same time do not migrate concurrently
+const lockID int64 = 7206115321
+
+// ErrDirty is returned when a previous migration failed half way.
+// The schema has to be fixed by hand and the version forced before migrating again.
+var ErrDirty = errors.New("migrations: database schema is dirty, fix it and force the version")
+
+// ErrOutOfDate is returned by Check when there are migrations not applied yet
+var ErrOutOfDate = errors.New("migrations: database schema is out of date")
+
+// Migration is a single schema version with its up and down sql
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Load returns the embedded migrations sorted by version
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint]*Migration{}
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrations: %s: %v", e.Name(), err)
+		}
+		body, err := files.ReadFile(e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[uint(version)]
+		if !ok {
+			mig = &Migration{Version: uint(version), Name: m[2]}
+			byVersion[uint(version)] = mig
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		list = append(list, *mig)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+
+	return list, nil
+}
+
+// Latest returns the highest embedded version
+func Latest() (uint, error) {
+	list, err := Load()
+	if err != nil {
+		return 0, err
+	}
+	if len(list) == 0 {
+		return 0, nil
+	}
+	return list[len(list)-1].Version, nil
+}
+
+// Version returns the currently applied version and the dirty flag.
+// Version 0 means no migration has been applied yet.
+func Version(ctx context.Context, database *sql.DB) (uint, bool, error) {
+	if err := ensureTable(ctx, database); err != nil {
+		return 0, false, err
+	}
+	return version(ctx, database)
+}
+
+// Pending returns the migrations which are not applied yet
+func Pending(ctx context.Context, database *sql.DB) ([]Migration, error) {
+	current, _, err := Version(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	list, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, mig := range list {
+		if mig.Version > current {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// Check returns ErrDirty or ErrOutOfDate when the schema does not match the embedded migrations
+func Check(ctx context.Context, database *sql.DB) error {
+	_, dirty, err := Version(ctx, database)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return ErrDirty
+	}
+
+	pending, err := Pending(ctx, database)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending, next is %06d_%s", ErrOutOfDate, len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
+
+// Up applies all pending migrations and returns the applied ones
+func Up(ctx context.Context, database *sql.DB) ([]Migration, error) {
+	var applied []Migration
+
+	err := withLock(ctx, database, func(conn *sql.Conn) error {
+		current, dirty, err := version(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return ErrDirty
+		}
+
+		list, err := Load()
+		if err != nil {
+			return err
+		}
+		for _, mig := range list {
+			if mig.Version <= current {
+				continue
+			}
+			if err := run(ctx, conn, mig.Version, mig.Up); err != nil {
+				return fmt.Errorf("migrations: %06d_%s.up.sql: %v", mig.Version, mig.Name, err)
+			}
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the given number of applied migrations and returns the reverted ones
+func Down(ctx context.Context, database *sql.DB, steps int) ([]Migration, error) {
+	var reverted []Migration
+
+	err := withLock(ctx, database, func(conn *sql.Conn) error {
+		current, dirty, err := version(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return ErrDirty
+		}
+
+		list, err := Load()
+		if err != nil {
+			return err
+		}
+		for i := len(list) - 1; i >= 0 && len(reverted) < steps; i-- {
+			mig := list[i]
+			if mig.Version > current {
+				continue
+			}
+			// the version left after reverting this migration
+			var previous uint
+			if i > 0 {
+				previous = list[i-1].Version
+			}
+			if err := run(ctx, conn, previous, mig.Down); err != nil {
+				return fmt.Errorf("migrations: %06d_%s.down.sql: %v", mig.Version, mig.Name, err)
+			}
+			reverted = append(reverted, mig)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Force sets the version without running any migration and clears the dirty flag.
+// It is used after fixing a failed migration by hand.
+func Force(ctx context.Context, database *sql.DB, version uint) error {
+	return withLock(ctx, database, func(conn *sql.Conn) error {
+		return setVersion(ctx, conn, version, false)
+	})
+}
+
+func ensureTable(ctx context.Context, database *sql.DB) error {
+	_, err := database.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)")
+	return err
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func version(ctx context.Context, q queryer) (uint, bool, error) {
+	var v int64
+	var dirty bool
+	err := q.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&v, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return uint(v), dirty, nil
+}
+
+// withLock runs f on a single connection holding the migrations advisory lock
+func withLock(ctx context.Context, database *sql.DB, f func(conn *sql.Conn) error) error {
+	if err := ensureTable(ctx, database); err != nil {
+		return err
+	}
+
+	conn, err := database.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+
+	return f(conn)
+}
+
+// run marks the schema dirty, executes the sql and stores the resulting version.
+// The sql is sent as a single simple query, so files with their own BEGIN/COMMIT work as they are
+// and files without them run in the implicit transaction postgres opens for a multi-statement query.
+func run(ctx context.Context, conn *sql.Conn, resultVersion uint, query string) error {
+	if err := setVersion(ctx, conn, resultVersion, true); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(query) != "" {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	return setVersion(ctx, conn, resultVersion, false)
+}
+
+func setVersion(ctx context.Context, conn *sql.Conn, v uint, dirty bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+	// golang-migrate does not keep a row for version 0 unless it is dirty
+	if v > 0 || dirty {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)", int64(v), dirty); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
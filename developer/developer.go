@@ -4,17 +4,17 @@ import (
 	"fmt"
 	"log"
 
+	"Tahlilchi.uz/config"
 	"Tahlilchi.uz/telegramBot"
 )
 
-func Developer() bool {
+func Developer(cfg *config.Config) bool {
 	var exit bool
 
 	log.Println("Hello, developer.")
 	fmt.Println("1. Exit")
-	fmt.Println("2. Add admin")
-	fmt.Println("3. Get telegram bot chat id")
-	fmt.Println("What do you want to do? Please enter 1 or 2 or 3:")
+	fmt.Println("2. Get telegram bot chat id")
+	fmt.Println("What do you want to do? Please enter 1 or 2:")
 	var decision int
 	_, err := fmt.Scan(&decision)
 
@@ -26,10 +26,7 @@ func Developer() bool {
 		exit = true
 		return exit
 	} else if decision == 2 {
-		// AddAdmin
-		AddAdmin()
-	} else if decision == 3 {
-		telegramBot.ChatID()
+		telegramBot.ChatID(cfg.Telegram)
 	}
 
 	exit = true
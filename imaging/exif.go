@@ -0,0 +1,71 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// orientation returns the EXIF orientation of a JPEG, 1 (upright) if it has none.
+// Only the APP1 Exif segment is read, the image data is not.
+func orientation(b []byte) int {
+	if len(b) < 4 || b[0] != 0xFF || b[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(b); {
+		if b[i] != 0xFF {
+			return 1
+		}
+		marker := b[i+1]
+		// Start of scan: the metadata segments are all before it
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(b[i+2 : i+4]))
+		if length < 2 || i+2+length > len(b) {
+			return 1
+		}
+		segment := b[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation returns the Orientation tag (0x0112) of IFD0 of the TIFF structure of an Exif segment
+func tiffOrientation(t []byte) int {
+	if len(t) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(t[4:8]))
+	if ifd < 8 || ifd+2 > len(t) {
+		return 1
+	}
+	entries := int(order.Uint16(t[ifd : ifd+2]))
+	for e := 0; e < entries; e++ {
+		p := ifd + 2 + e*12
+		if p+12 > len(t) {
+			return 1
+		}
+		if order.Uint16(t[p:p+2]) != 0x0112 {
+			continue
+		}
+		// SHORT, the value is in the first 2 bytes of the value field
+		if v := int(order.Uint16(t[p+8 : p+10])); v >= 1 && v <= 8 {
+			return v
+		}
+		return 1
+	}
+	return 1
+}
@@ -0,0 +1,158 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// segment returns the JPEG segment of marker with the payload p
+func segment(marker byte, p []byte) []byte {
+	s := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(s[2:], uint16(len(p)+2))
+	return append(s, p...)
+}
+
+// byteOrder is binary.LittleEndian or binary.BigEndian
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// exifTIFF returns the TIFF structure of an Exif segment with IFD0 holding the entries, tag and SHORT value
+func exifTIFF(order byteOrder, entries ...[2]uint16) []byte {
+	t := make([]byte, 8, 8+2+12*len(entries)+4)
+	if order == binary.LittleEndian {
+		copy(t, "II")
+	} else {
+		copy(t, "MM")
+	}
+	order.PutUint16(t[2:], 42)
+	order.PutUint32(t[4:], 8)
+
+	t = order.AppendUint16(t, uint16(len(entries)))
+	for _, e := range entries {
+		t = order.AppendUint16(t, e[0])
+		t = order.AppendUint16(t, 3) // SHORT
+		t = order.AppendUint32(t, 1)
+		t = order.AppendUint16(t, e[1])
+		t = order.AppendUint16(t, 0)
+	}
+	// no next IFD
+	return order.AppendUint32(t, 0)
+}
+
+// jpegWith returns the start of a JPEG with the segments, the image data is not needed by orientation
+func jpegWith(segments ...[]byte) []byte {
+	b := []byte{0xFF, 0xD8}
+	for _, s := range segments {
+		b = append(b, s...)
+	}
+	return append(b, 0xFF, 0xD9)
+}
+
+func app1(t []byte) []byte {
+	return segment(0xE1, append([]byte("Exif\x00\x00"), t...))
+}
+
+func TestOrientation(t *testing.T) {
+	jfif := segment(0xE0, []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00"))
+	const tag = 0x0112
+
+	for o := uint16(1); o <= 8; o++ {
+		for _, order := range []byteOrder{binary.LittleEndian, binary.BigEndian} {
+			b := jpegWith(jfif, app1(exifTIFF(order, [2]uint16{0x010F, 7}, [2]uint16{tag, o})))
+			if got := orientation(b); got != int(o) {
+				t.Errorf("orientation %d %v = %d", o, order, got)
+			}
+		}
+	}
+
+	le := binary.LittleEndian
+	full := jpegWith(app1(exifTIFF(le, [2]uint16{tag, 6})))
+	tests := []struct {
+		name string
+		b    []byte
+		want int
+	}{
+		{"no exif", jpegWith(jfif), 1},
+		{"no orientation tag", jpegWith(app1(exifTIFF(le, [2]uint16{0x010F, 6}))), 1},
+		{"orientation 0", jpegWith(app1(exifTIFF(le, [2]uint16{tag, 0}))), 1},
+		{"orientation 9", jpegWith(app1(exifTIFF(le, [2]uint16{tag, 9}))), 1},
+		{"xmp app1", jpegWith(segment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00"))), 1},
+		{"exif after the start of scan", jpegWith(segment(0xDA, []byte{0}), app1(exifTIFF(le, [2]uint16{tag, 6}))), 1},
+		{"bad byte order", jpegWith(app1(append([]byte("XX"), exifTIFF(le, [2]uint16{tag, 6})[2:]...))), 1},
+		{"ifd before the header end", jpegWith(app1(append(exifTIFF(le)[:4], 4, 0, 0, 0))), 1},
+		{"ifd past the end", jpegWith(app1(append(exifTIFF(le)[:4], 0xFF, 0, 0, 0))), 1},
+		{"truncated", full[:len(full)-12], 1},
+		{"segment length past the end", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF, 'E', 'x'}, 1},
+		{"segment length too short", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0, 1, 0xFF, 0xD9}, 1},
+		{"garbage between segments", append([]byte{0xFF, 0xD8, 0}, full[2:]...), 1},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"), 1},
+		{"empty", nil, 1},
+		{"soi only", []byte{0xFF, 0xD8}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orientation(tt.b); got != tt.want {
+				t.Errorf("orientation = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// a 3x2 image with a red top-left pixel
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	red := color.RGBA{R: 255, A: 255}
+	img.Set(0, 0, red)
+
+	tests := []struct {
+		o    int
+		w, h int
+		// x and y are where the red pixel ends up
+		x, y int
+	}{
+		{1, 3, 2, 0, 0},
+		{2, 3, 2, 2, 0},
+		{3, 3, 2, 2, 1},
+		{4, 3, 2, 0, 1},
+		{5, 2, 3, 0, 0},
+		{6, 2, 3, 1, 0},
+		{7, 2, 3, 1, 2},
+		{8, 2, 3, 0, 2},
+		{9, 3, 2, 0, 0},
+	}
+	for _, tt := range tests {
+		got := orient(img, tt.o)
+		if b := got.Bounds(); b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("orient %d: %dx%d, want %dx%d", tt.o, b.Dx(), b.Dy(), tt.w, tt.h)
+			continue
+		}
+		if c := color.RGBAModel.Convert(got.At(tt.x, tt.y)); c != red {
+			t.Errorf("orient %d: pixel (%d, %d) = %v, want red", tt.o, tt.x, tt.y, c)
+		}
+	}
+}
+
+func TestDecodeOrients(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 16, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	// the Exif segment goes right after the SOI
+	b := []byte{0xFF, 0xD8}
+	b = append(b, app1(exifTIFF(binary.BigEndian, [2]uint16{0x0112, 6}))...)
+	b = append(b, buf.Bytes()[2:]...)
+
+	img, err := Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Bounds(); got.Dx() != 8 || got.Dy() != 16 {
+		t.Errorf("Decode = %dx%d, want 8x16", got.Dx(), got.Dy())
+	}
+}
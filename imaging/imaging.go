@@ -0,0 +1,151 @@
+// Package imaging makes the renditions of the uploaded images:
+// the EXIF orientation is applied, the images are scaled down to the sizes of the renditions
+// and encoded as JPEG and WebP. Encoding drops all the metadata, including the GPS position.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+
+	// The decoders of the formats accepted for the uploaded images
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+
+	"github.com/chai2010/webp"
+	"golang.org/x/image/draw"
+)
+
+// Size is a rendition of the images, scaled down to fit in MaxSide x MaxSide
+type Size struct {
+	Name    string
+	MaxSide int
+}
+
+// Sizes are the renditions made of every image, from the smallest
+var Sizes = []Size{
+	{Name: "thumbnail", MaxSide: 320},
+	{Name: "card", MaxSide: 800},
+	{Name: "full", MaxSide: 1920},
+}
+
+// SizeByName returns the size with the given name
+func SizeByName(name string) (Size, bool) {
+	for _, s := range Sizes {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Size{}, false
+}
+
+// Format is an encoding of the renditions
+type Format struct {
+	MimeType  string
+	Extension string
+	Encode    func(img image.Image) ([]byte, error)
+}
+
+// Formats are the encodings every rendition is made in
+var Formats = []Format{
+	{MimeType: "image/jpeg", Extension: ".jpg", Encode: encodeJPEG},
+	{MimeType: "image/webp", Extension: ".webp", Encode: encodeWebP},
+}
+
+// maxPixels limits the decoded size of an image, a small file can declare a huge image
+const maxPixels = 50_000_000
+
+// ErrTooLarge is returned for an image with more than maxPixels pixels
+var ErrTooLarge = errors.New("imaging: image is too large")
+
+// Decode decodes a JPEG, PNG, GIF or WebP image and turns it upright by its EXIF orientation
+func Decode(b []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	return orient(img, orientation(b)), nil
+}
+
+// Fit scales img down to fit in size, on a white background for the transparent images.
+// A smaller image is not scaled up.
+func Fit(img image.Image, size Size) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size.MaxSide || h > size.MaxSide {
+		if w >= h {
+			w, h = size.MaxSide, max(1, h*size.MaxSide/w)
+		} else {
+			w, h = max(1, w*size.MaxSide/h), size.MaxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	return dst
+}
+
+func encodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 82})
+	return buf.Bytes(), err
+}
+
+func encodeWebP(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := webp.Encode(&buf, img, &webp.Options{Quality: 80})
+	return buf.Bytes(), err
+}
+
+// orient returns img turned by the EXIF orientation o
+func orient(img image.Image, o int) image.Image {
+	if o <= 1 || o > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	// Orientations 5 to 8 swap the width and the height
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch o {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // mirrored along the top-left diagonal
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // mirrored along the top-right diagonal
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90 counterclockwise
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
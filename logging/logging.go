@@ -0,0 +1,176 @@
+// Package logging sets up the JSON logger of the application
+// and carries the fields of the current request through its context,
+// so every line logged while serving a request can be traced back to it.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Init makes a JSON logger writing to stdout the default one.
+// The level is debug, info (default), warn or error.
+// Lines written with the log package go through it as well, at info level.
+func Init(level string) {
+	var l slog.Level
+	switch strings.ToLower(level) {
+	case "debug":
+		l = slog.LevelDebug
+	case "warn":
+		l = slog.LevelWarn
+	case "error":
+		l = slog.LevelError
+	default:
+		l = slog.LevelInfo
+	}
+
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: l})))
+}
+
+// request holds the fields of a request added to its log lines.
+// It is shared by pointer, so the route and the admin found further down the chain show up in the access log.
+type request struct {
+	id         string
+	method     string
+	path       string
+	route      string
+	ip         string
+	adminEmail string
+}
+
+type ctxKey struct{}
+
+func fromContext(ctx context.Context) *request {
+	req, _ := ctx.Value(ctxKey{}).(*request)
+	return req
+}
+
+// Logger returns the default logger with the fields of the request of ctx, if there is one
+func Logger(ctx context.Context) *slog.Logger {
+	req := fromContext(ctx)
+	if req == nil {
+		return slog.Default()
+	}
+	return slog.Default().With(req.attrs()...)
+}
+
+func (req *request) attrs() []any {
+	attrs := []any{
+		slog.String("request_id", req.id),
+		slog.String("method", req.method),
+		slog.String("path", req.path),
+		slog.String("ip", req.ip),
+	}
+	if req.route != "" {
+		attrs = append(attrs, slog.String("route", req.route))
+	}
+	if req.adminEmail != "" {
+		attrs = append(attrs, slog.String("admin", req.adminEmail))
+	}
+	return attrs
+}
+
+// RequestID returns the id of the request of ctx, empty if there is none
+func RequestID(ctx context.Context) string {
+	if req := fromContext(ctx); req != nil {
+		return req.id
+	}
+	return ""
+}
+
+// SetAdmin records the email of the admin making the request of ctx
+func SetAdmin(ctx context.Context, email string) {
+	if req := fromContext(ctx); req != nil {
+		req.adminEmail = email
+	}
+}
+
+// Route is a mux middleware which records the route template of the matched route for the access log
+func Route(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if req := fromContext(r.Context()); req != nil {
+			if route := mux.CurrentRoute(r); route != nil {
+				req.route, _ = route.GetPathTemplate()
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validRequestID limits the X-Request-ID header taken from the client or the proxy
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// responseRecorder is a http.ResponseWriter which remembers the status code and the size of the body
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for http.ResponseController
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// AccessLog is a middleware which gives every request an id, sent back in the X-Request-ID header,
+// puts the request fields into its context and logs one line per request when it is served.
+// clientIP returns the address of the client, behind a proxy the forwarded one.
+func AccessLog(next http.Handler, clientIP func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+
+		req := &request{id: id, method: r.Method, path: r.URL.Path, ip: clientIP(r)}
+		r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, req))
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		level := slog.LevelInfo
+		switch {
+		case rec.status >= 500:
+			level = slog.LevelError
+		case rec.status >= 400:
+			level = slog.LevelWarn
+		}
+
+		attrs := append(req.attrs(),
+			slog.Int("status", rec.status),
+			slog.Int("bytes", rec.bytes),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("user_agent", r.UserAgent()),
+		)
+		slog.Default().Log(r.Context(), level, "request", attrs...)
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
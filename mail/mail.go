@@ -0,0 +1,31 @@
+// Package mail sends the emails of the application through an SMTP server
+package mail
+
+import (
+	"net/smtp"
+	"strconv"
+
+	"Tahlilchi.uz/config"
+)
+
+// Mailer sends emails through the SMTP server of its configuration
+type Mailer struct {
+	cfg config.Mail
+}
+
+// New returns a Mailer for the given SMTP server
+func New(cfg config.Mail) *Mailer {
+	return &Mailer{cfg: cfg}
+}
+
+// Send connects to the server, authenticates, sets the sender and the recipient,
+// and sends the email all in one step
+func (m *Mailer) Send(to, subject, body string) error {
+	msg := []byte("To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"\r\n" +
+		body + "\r\n")
+
+	auth := smtp.PlainAuth("", m.cfg.From, m.cfg.Password, m.cfg.Server)
+	return smtp.SendMail(m.cfg.Server+":"+strconv.Itoa(m.cfg.Port), auth, m.cfg.From, []string{to}, msg)
+}
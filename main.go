@@ -1,14 +1,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"Tahlilchi.uz/admin"
+	"Tahlilchi.uz/authPackage"
+	"Tahlilchi.uz/config"
+	"Tahlilchi.uz/db"
 	"Tahlilchi.uz/developer"
-	"github.com/go-co-op/gocron"
-	"github.com/joho/godotenv"
+	"Tahlilchi.uz/logging"
+	"Tahlilchi.uz/media"
+	"Tahlilchi.uz/metrics"
+	"Tahlilchi.uz/scheduler"
+	"Tahlilchi.uz/slug"
+	"Tahlilchi.uz/storage"
+	"Tahlilchi.uz/toolkit"
+	"Tahlilchi.uz/upload"
 )
 
 func main() {
@@ -16,36 +30,117 @@ func main() {
 		if r := recover(); r != nil {
 			fmt.Println("Recovered from", r)
 		}
+	}()
+
+	// Load the configuration from the environment, the .env file and the optional CONFIG_FILE, all the errors at once
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-		if os.Getenv("ENVIRONMENT") == "development" {
-			go func() {
-				for {
-					exit := developer.Developer()
+	// "main config" prints the configuration with the secrets redacted and exits
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		fmt.Print(cfg)
+		return
+	}
 
-					if exit {
-						break
-					}
+	// Log JSON lines through log/slog
+	logging.Init(cfg.LogLevel)
 
-					time.Sleep(1 * time.Second)
-				}
-			}()
+	// Open the shared database connection pool and make sure the database is reachable
+	if err := db.Open(context.Background(), cfg.DB); err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// "main migrate ..." only migrates the database schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
 		}
+		return
+	}
 
-		// Initialize a new scheduler
-		s := gocron.NewScheduler(time.UTC)
+	// Do not start the server on an out of date schema
+	checkSchema(cfg.MigrateOnStart)
 
-		// Schedule the function to run every hour
-		s.Every(1).Hour().Do(admin.CheckAndArchiveExpiredBPPosts)
+	// Keep the uploaded files in the local directory or the S3 bucket
+	store, err := storage.New(cfg.Storage)
+	if err != nil {
+		log.Fatal(err)
+	}
+	media.Configure(store)
 
-		// Start the scheduler without blocking
-		s.StartAsync()
+	// "main media migrate" moves the files still kept in the bytea columns to the storage and exits
+	if len(os.Args) > 2 && os.Args[1] == "media" && os.Args[2] == "migrate" {
+		if err := media.MigrateBytea(context.Background(), log.Printf); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-		Router()
-	}()
+	// Create the first superadmin of a fresh deployment
+	if err := admin.BootstrapSuperAdmin(cfg.SuperAdmin); err != nil {
+		log.Fatal(err)
+	}
 
-	// Load .env file
-	err := godotenv.Load()
-	if err != nil {
-		panic(err)
+	authPackage.Store.Configure(cfg.Session)
+	upload.SetTimeout(cfg.HTTP.UploadTimeout)
+	upload.OnRejection(metrics.UploadRejected)
+	metrics.DBStats(db.Stats)
+	if cfg.TrustProxyHeaders {
+		toolkit.TrustProxyHeaders(cfg.ProxyHops)
 	}
+
+	if cfg.Environment == "development" {
+		go func() {
+			for {
+				exit := developer.Developer(cfg)
+
+				if exit {
+					break
+				}
+
+				time.Sleep(1 * time.Second)
+			}
+		}()
+	}
+
+	// Initialize a new scheduler, the status of the jobs is served on /admin/scheduler
+	s := scheduler.New()
+
+	// Schedule the functions to run every hour or day
+	jobs := []struct {
+		interval time.Duration
+		name     string
+		fn       func() error
+	}{
+		{time.Hour, "CheckAndArchiveExpiredBPPosts", admin.CheckAndArchiveExpiredBPPosts},
+		{time.Minute, "PublishScheduled", admin.PublishScheduled},
+		{time.Hour, "DeleteExpiredSessions", authPackage.DeleteExpiredSessions},
+		{24 * time.Hour, "DeleteOldLoginAttempts", admin.DeleteOldLoginAttempts},
+		{10 * time.Minute, "ProcessImages", media.ProcessImages},
+		{24 * time.Hour, "DeleteOrphanMedia", media.DeleteOrphans},
+		{10 * time.Minute, "AssignSlugs", slug.AssignMissing},
+	}
+	for _, j := range jobs {
+		if err := s.Every(j.interval, j.name, j.fn); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Start the scheduler without blocking
+	s.Start()
+
+	// Serve until SIGINT or SIGTERM, e.g. when the container is restarted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := serve(ctx, Router(cfg, s), cfg.HTTP); err != nil {
+		slog.Error("server", "error", err)
+	}
+
+	// Wait for the running jobs and stop the scheduler, the database pool is closed after it
+	s.Stop()
+	slog.Info("stopped")
 }
@@ -0,0 +1,304 @@
+// Package media keeps the uploaded files in the storage backend
+// and their key, name, MIME type, size and SHA-256 checksum in the media table.
+// The other tables reference the files by the id of their media row.
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/storage"
+	"Tahlilchi.uz/upload"
+)
+
+// store is the storage backend set by Configure
+var store storage.Storage
+
+// Configure sets the storage backend of the media files
+func Configure(s storage.Storage) {
+	store = s
+}
+
+// ErrNotFound is returned for a media id without a row
+var ErrNotFound = errors.New("media: not found")
+
+// Info is a row of the media table
+type Info struct {
+	ID        int64
+	Key       string
+	FileName  string
+	MimeType  string
+	Size      int64
+	SHA256    string
+	CreatedAt time.Time
+}
+
+// newKey returns a new random storage key, grouped by month and keeping the extension of fileName
+func newKey(fileName string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	ext := strings.ToLower(path.Ext(fileName))
+	if len(ext) > 10 || strings.ContainsAny(ext, "/\\ ") {
+		ext = ""
+	}
+	return time.Now().UTC().Format("2006/01/") + hex.EncodeToString(b) + ext, nil
+}
+
+// Save stores size bytes read from r and returns the id of the new media row
+func Save(ctx context.Context, r io.Reader, size int64, fileName, mimeType string) (int64, error) {
+	key, err := newKey(fileName)
+	if err != nil {
+		return 0, err
+	}
+
+	h := sha256.New()
+	if err := store.Put(ctx, key, io.TeeReader(r, h), size, mimeType); err != nil {
+		return 0, err
+	}
+
+	database, err := db.DBContext(ctx)
+	if err != nil {
+		store.Delete(context.Background(), key)
+		return 0, err
+	}
+
+	var id int64
+	err = database.QueryRowContext(ctx, "INSERT INTO media (storage_key, file_name, mime_type, size, sha256, created_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		key, fileName, mimeType, size, hex.EncodeToString(h.Sum(nil)), time.Now().UTC()).Scan(&id)
+	if err != nil {
+		store.Delete(context.Background(), key)
+		return 0, err
+	}
+	return id, nil
+}
+
+// SaveBytes stores b with the MIME type detected from its content and returns the id of the new media row.
+// The renditions of an image are made in the background.
+func SaveBytes(ctx context.Context, b []byte, fileName string) (int64, error) {
+	mimeType := upload.DetectContentType(b)
+	id, err := Save(ctx, bytes.NewReader(b), int64(len(b)), fileName, mimeType)
+	if err != nil {
+		return 0, err
+	}
+
+	if isImage(mimeType) {
+		processLater(id)
+	}
+	return id, nil
+}
+
+// SaveFile streams the checked upload f to the storage under fileName and returns the id of the new media row.
+// The file is not read into memory. The renditions of an image are made in the background.
+func SaveFile(ctx context.Context, f *upload.File, fileName string) (int64, error) {
+	content, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer content.Close()
+
+	id, err := Save(ctx, content, f.Size(), fileName, f.ContentType)
+	if err != nil {
+		return 0, err
+	}
+
+	if isImage(f.ContentType) {
+		processLater(id)
+	}
+	return id, nil
+}
+
+// SaveFileNullable stores f like SaveFile. Nothing is stored for a nil f and the id is NULL.
+func SaveFileNullable(ctx context.Context, f *upload.File, fileName string) (sql.NullInt64, error) {
+	if f == nil {
+		return sql.NullInt64{}, nil
+	}
+	id, err := SaveFile(ctx, f, fileName)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	return sql.NullInt64{Int64: id, Valid: true}, nil
+}
+
+// Get returns the media row with the given id
+func Get(ctx context.Context, id int64) (Info, error) {
+	return getWhere(ctx, "id = $1", id)
+}
+
+// getWhere returns the media row matching the condition
+func getWhere(ctx context.Context, cond string, args ...any) (Info, error) {
+	database, err := db.DBContext(ctx)
+	if err != nil {
+		return Info{}, err
+	}
+
+	var m Info
+	err = database.QueryRowContext(ctx, "SELECT id, storage_key, file_name, mime_type, size, sha256, created_at FROM media WHERE "+cond, args...).
+		Scan(&m.ID, &m.Key, &m.FileName, &m.MimeType, &m.Size, &m.SHA256, &m.CreatedAt)
+	if err == sql.ErrNoRows {
+		return Info{}, ErrNotFound
+	}
+	return m, err
+}
+
+// Open returns the media row with the given id and its content, the caller closes it
+func Open(ctx context.Context, id int64) (Info, io.ReadCloser, error) {
+	m, err := Get(ctx, id)
+	if err != nil {
+		return Info{}, nil, err
+	}
+	content, err := store.Get(ctx, m.Key)
+	if err == storage.ErrNotExist {
+		return Info{}, nil, ErrNotFound
+	}
+	if err != nil {
+		return Info{}, nil, err
+	}
+	return m, content, nil
+}
+
+// Reference is a column referencing the media table
+type Reference struct {
+	Table  string
+	Column string
+	// Bytea is the column the files were kept in before the media table
+	Bytea string
+	// FileName is the column holding the name of the uploaded file, if there is one
+	FileName string
+}
+
+// References are all the columns referencing the media table
+var References = []Reference{
+	{Table: "news_posts", Column: "photo_id", Bytea: "photo"},
+	{Table: "news_posts", Column: "audio_id", Bytea: "audio"},
+	{Table: "news_posts", Column: "cover_image_id", Bytea: "cover_image"},
+	{Table: "articles", Column: "cover_image_id", Bytea: "cover_image"},
+	{Table: "article_photos", Column: "media_id", Bytea: "file", FileName: "file_name"},
+	{Table: "business_promotional_posts", Column: "cover_image_id", Bytea: "cover_image"},
+	{Table: "bpp_photos", Column: "media_id", Bytea: "file", FileName: "file_name"},
+	{Table: "e_newspapers", Column: "file_latin_id", Bytea: "file_latin"},
+	{Table: "e_newspapers", Column: "file_cyrillic_id", Bytea: "file_cyrillic"},
+	{Table: "e_newspapers", Column: "cover_image_id", Bytea: "cover_image"},
+	{Table: "photo_gallery_photos", Column: "media_id", Bytea: "file", FileName: "file_name"},
+	{Table: "appeals", Column: "picture_id", Bytea: "picture"},
+	{Table: "appeals", Column: "video_id", Bytea: "video"},
+}
+
+// orphanAge is how long an unreferenced media file is kept, so a file saved by a request still in flight is not deleted
+const orphanAge = 24 * time.Hour
+
+// DeleteOrphans removes the media files which are no longer referenced, e.g. replaced or of deleted rows.
+// It is run by the scheduler.
+func DeleteOrphans() error {
+	database, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	var conds []string
+	for _, ref := range References {
+		conds = append(conds, fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s WHERE %s = m.id)", ref.Table, ref.Column))
+	}
+	// The renditions are referenced by their original image
+	conds = append(conds, "m.original_id IS NULL")
+	// The files of the older revisions are kept, they can be restored
+	conds = append(conds, "NOT EXISTS (SELECT 1 FROM revisions WHERE media_ids @> ARRAY[m.id])")
+	rows, err := database.Query("SELECT m.id, m.storage_key FROM media m WHERE m.created_at < $1 AND "+strings.Join(conds, " AND "), time.Now().Add(-orphanAge).UTC())
+	if err != nil {
+		return err
+	}
+
+	type orphan struct {
+		id  int64
+		key string
+	}
+	var orphans []orphan
+	for rows.Next() {
+		var o orphan
+		if err := rows.Scan(&o.id, &o.key); err != nil {
+			rows.Close()
+			return err
+		}
+		orphans = append(orphans, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, o := range orphans {
+		if err := store.Delete(context.Background(), o.key); err != nil {
+			return err
+		}
+		if _, err := database.Exec("DELETE FROM media WHERE id = $1", o.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateBytea moves the files still kept in the bytea columns to the storage backend, one row at a time,
+// and empties the bytea columns. It can be run again after a failure, the moved files are skipped.
+func MigrateBytea(ctx context.Context, logf func(format string, args ...any)) error {
+	database, err := db.DBContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range References {
+		rows, err := database.QueryContext(ctx, fmt.Sprintf("SELECT id FROM %s WHERE %s IS NOT NULL AND %s IS NULL ORDER BY id", ref.Table, ref.Bytea, ref.Column))
+		if err != nil {
+			return err
+		}
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		fileName := "''"
+		if ref.FileName != "" {
+			fileName = ref.FileName
+		}
+		for _, id := range ids {
+			var data []byte
+			var name string
+			err := database.QueryRowContext(ctx, fmt.Sprintf("SELECT %s, %s FROM %s WHERE id = $1", ref.Bytea, fileName, ref.Table), id).Scan(&data, &name)
+			if err != nil {
+				return fmt.Errorf("%s.%s id %d: %v", ref.Table, ref.Bytea, id, err)
+			}
+
+			mediaID, err := SaveBytes(ctx, data, name)
+			if err != nil {
+				return fmt.Errorf("%s.%s id %d: %v", ref.Table, ref.Bytea, id, err)
+			}
+
+			_, err = database.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET %s = $1, %s = NULL WHERE id = $2", ref.Table, ref.Column, ref.Bytea), mediaID, id)
+			if err != nil {
+				return fmt.Errorf("%s.%s id %d: %v", ref.Table, ref.Bytea, id, err)
+			}
+		}
+		logf("media: moved %d files of %s.%s", len(ids), ref.Table, ref.Bytea)
+	}
+	return nil
+}
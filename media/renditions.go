@@ -0,0 +1,193 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+
+	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/imaging"
+	"github.com/lib/pq"
+)
+
+// imageTypes are the MIME types of the images the renditions are made of
+var imageTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+func isImage(mimeType string) bool {
+	for _, t := range imageTypes {
+		if t == mimeType {
+			return true
+		}
+	}
+	return false
+}
+
+// processImage makes the renditions of the original image id with content b, in every size and format,
+// and marks the original processed. An image which cannot be decoded is marked processed too,
+// it is not served by the public endpoints, only the renditions are stripped of the metadata.
+func processImage(ctx context.Context, id int64, b []byte, fileName string) error {
+	database, err := db.DBContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	img, err := imaging.Decode(b)
+	if err != nil {
+		if _, markErr := database.ExecContext(ctx, "UPDATE media SET processed_at = $1 WHERE id = $2", time.Now().UTC(), id); markErr != nil {
+			return markErr
+		}
+		return err
+	}
+
+	// The renditions of a failed run are left to DeleteOrphans
+	if _, err := database.ExecContext(ctx, "UPDATE media SET original_id = NULL WHERE original_id = $1", id); err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(path.Base(fileName), path.Ext(fileName))
+	if base == "." || base == "/" {
+		base = ""
+	}
+	for _, size := range imaging.Sizes {
+		resized := imaging.Fit(img, size)
+		for _, format := range imaging.Formats {
+			enc, err := format.Encode(resized)
+			if err != nil {
+				return fmt.Errorf("%s %s: %v", size.Name, format.MimeType, err)
+			}
+
+			renditionID, err := Save(ctx, bytes.NewReader(enc), int64(len(enc)), base+"-"+size.Name+format.Extension, format.MimeType)
+			if err != nil {
+				return fmt.Errorf("%s %s: %v", size.Name, format.MimeType, err)
+			}
+			_, err = database.ExecContext(ctx, "UPDATE media SET original_id = $1, rendition = $2, width = $3, height = $4 WHERE id = $5",
+				id, size.Name, resized.Bounds().Dx(), resized.Bounds().Dy(), renditionID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = database.ExecContext(ctx, "UPDATE media SET processed_at = $1 WHERE id = $2", time.Now().UTC(), id)
+	return err
+}
+
+// processed reports whether the original image id is processed, its renditions made or its decoding failed
+func processed(ctx context.Context, id int64) (bool, error) {
+	database, err := db.DBContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	var done bool
+	err = database.QueryRowContext(ctx, "SELECT processed_at IS NOT NULL FROM media WHERE id = $1", id).Scan(&done)
+	return done, err
+}
+
+// rendition returns the rendition of the original image id in the given size and MIME type
+func rendition(ctx context.Context, id int64, size, mimeType string) (Info, error) {
+	return getWhere(ctx, "original_id = $1 AND rendition = $2 AND mime_type = $3", id, size, mimeType)
+}
+
+// processBatch is how many images ProcessImages processes in one run
+const processBatch = 100
+
+// processDelay keeps ProcessImages off the images being processed by their upload
+const processDelay = 10 * time.Minute
+
+// ProcessImages makes the renditions of the images saved before the image processing
+// or whose processing failed on upload. It is run by the scheduler.
+func ProcessImages() error {
+	ctx := context.Background()
+	database, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	rows, err := database.Query(`SELECT id FROM media WHERE mime_type = ANY($1) AND original_id IS NULL AND rendition IS NULL
+		AND processed_at IS NULL AND created_at < $2 ORDER BY id LIMIT $3`,
+		pq.Array(imageTypes), time.Now().Add(-processDelay).UTC(), processBatch)
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, id := range ids {
+		m, b, err := readOriginal(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			// The file is gone, there is nothing to process
+			if _, err := database.Exec("UPDATE media SET processed_at = $1 WHERE id = $2", time.Now().UTC(), id); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("media %d: %v", id, err))
+			continue
+		}
+
+		if err := processImage(ctx, id, b, m.FileName); err != nil {
+			slog.Warn("media: making the renditions", "media", id, "error", err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readOriginal returns the media row of the original image id and its content read back from the storage
+func readOriginal(ctx context.Context, id int64) (Info, []byte, error) {
+	m, content, err := Open(ctx, id)
+	if err != nil {
+		return Info{}, nil, err
+	}
+	defer content.Close()
+	b, err := io.ReadAll(content)
+	if err != nil {
+		return Info{}, nil, err
+	}
+	return m, b, nil
+}
+
+// maxProcessing limits the images processed at once in the background, decoding a large image takes a lot of memory
+const maxProcessing = 2
+
+var processing = make(chan struct{}, maxProcessing)
+
+// processLater makes the renditions of the stored original image id in the background,
+// so the upload does not wait for them. ProcessImages retries a failure.
+func processLater(id int64) {
+	go func() {
+		processing <- struct{}{}
+		defer func() { <-processing }()
+
+		if err := processStored(context.Background(), id); err != nil {
+			slog.Warn("media: making the renditions", "media", id, "error", err)
+		}
+	}()
+}
+
+// processStored makes the renditions of the stored original image id
+func processStored(ctx context.Context, id int64) error {
+	m, b, err := readOriginal(ctx, id)
+	if err != nil {
+		return err
+	}
+	return processImage(ctx, id, b, m.FileName)
+}
@@ -0,0 +1,278 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"Tahlilchi.uz/imaging"
+	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/toolkit"
+)
+
+// Serve writes the media file with the given id as the response of a public endpoint, 404 if there is none.
+// Range, If-None-Match, If-Modified-Since and If-Range requests are answered by http.ServeContent.
+// name is the file name offered in Content-Disposition without the extension, the uploaded file name if empty.
+func Serve(w http.ResponseWriter, r *http.Request, id sql.NullInt64, name string) {
+	serve(w, r, id, name, false)
+}
+
+// ServePrivate is Serve for the endpoints of the admins, the response is not kept by shared caches
+func ServePrivate(w http.ResponseWriter, r *http.Request, id sql.NullInt64, name string) {
+	serve(w, r, id, name, true)
+}
+
+func serve(w http.ResponseWriter, r *http.Request, id sql.NullInt64, name string, private bool) {
+	if !id.Valid {
+		response.Res(w, "error", http.StatusNotFound, "file not found")
+		return
+	}
+
+	m, err := Get(r.Context(), id.Int64)
+	if err == ErrNotFound {
+		toolkit.LogErrorf(r, "media %d: %v", id.Int64, err)
+		response.Res(w, "error", http.StatusNotFound, "file not found")
+		return
+	}
+	if err != nil {
+		toolkit.LogError(r, err)
+		response.Res(w, "error", http.StatusInternalServerError, "server error")
+		return
+	}
+
+	if isImage(m.MimeType) {
+		m, err = pickRendition(r, m, private)
+		if errors.Is(err, errInvalidSize) {
+			response.Res(w, "error", http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, errProcessing) {
+			w.Header().Set("Retry-After", "10")
+			response.Res(w, "error", http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		if errors.Is(err, errNoRendition) {
+			toolkit.LogErrorf(r, "media %d: %v", id.Int64, err)
+			response.Res(w, "error", http.StatusNotFound, "file not found")
+			return
+		}
+		if err != nil {
+			toolkit.LogError(r, err)
+			response.Res(w, "error", http.StatusInternalServerError, "server error")
+			return
+		}
+		w.Header().Add("Vary", "Accept")
+	}
+
+	content := &reader{ctx: r.Context(), key: m.Key, size: m.Size}
+	defer content.Close()
+
+	h := w.Header()
+	h.Set("Content-Type", m.MimeType)
+	h.Set("ETag", `"`+m.SHA256+`"`)
+	h.Set("Cache-Control", cacheControl(m.MimeType, private))
+	if d := mime.FormatMediaType("inline", map[string]string{"filename": fileName(m, name)}); d != "" {
+		h.Set("Content-Disposition", d)
+	}
+	// the write timeout of the server is too short for a large file
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(serveTimeout(m.Size))); err != nil {
+		toolkit.LogErrorf(r, "media %d: setting the write deadline: %v", m.ID, err)
+	}
+	http.ServeContent(w, r, "", m.CreatedAt, content)
+
+	if content.err != nil && !errors.Is(content.err, context.Canceled) {
+		toolkit.LogErrorf(r, "media %d: %v", m.ID, content.err)
+	}
+}
+
+// minServeRate is the slowest download in bytes per second a file is given the time for
+const minServeRate = 32 << 10
+
+// serveTimeout is how long a file of size bytes is given to be written
+func serveTimeout(size int64) time.Duration {
+	return time.Minute + time.Duration(size/minServeRate)*time.Second
+}
+
+var (
+	errInvalidSize = errors.New("invalid size, expected thumbnail, card or full")
+	// errProcessing is returned for an image whose renditions are not made yet
+	errProcessing = errors.New("the image is being processed, try again later")
+	// errNoRendition is returned for a processed image without renditions, one which could not be decoded
+	errNoRendition = errors.New("no rendition of the image")
+)
+
+// pickRendition returns the rendition of the image m in the size of the size query parameter,
+// WebP if the client accepts it and JPEG otherwise. The public endpoints serve the full size by default
+// and never the original, which may carry its EXIF metadata and GPS position: without a rendition
+// the error is errProcessing or errNoRendition. The endpoints of the admins serve the original by default.
+func pickRendition(r *http.Request, m Info, private bool) (Info, error) {
+	size := r.URL.Query().Get("size")
+	if size == "" {
+		if private {
+			return m, nil
+		}
+		size = "full"
+	}
+	if _, ok := imaging.SizeByName(size); !ok {
+		return Info{}, errInvalidSize
+	}
+
+	mimeType := "image/jpeg"
+	if strings.Contains(r.Header.Get("Accept"), "image/webp") {
+		mimeType = "image/webp"
+	}
+	found, err := rendition(r.Context(), m.ID, size, mimeType)
+	if err == ErrNotFound && mimeType == "image/webp" {
+		found, err = rendition(r.Context(), m.ID, size, "image/jpeg")
+	}
+	if err != ErrNotFound {
+		return found, err
+	}
+
+	if private {
+		return m, nil
+	}
+	done, err := processed(r.Context(), m.ID)
+	if err != nil {
+		return Info{}, err
+	}
+	if !done {
+		return Info{}, errProcessing
+	}
+	return Info{}, errNoRendition
+}
+
+// cacheControl returns the Cache-Control policy of a file.
+// The URLs of the files stay the same when a file is replaced, so the files are revalidated with their ETag after max-age.
+func cacheControl(mimeType string, private bool) string {
+	if private {
+		return "private, no-cache"
+	}
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return "public, max-age=86400"
+	case strings.HasPrefix(mimeType, "audio/"), strings.HasPrefix(mimeType, "video/"):
+		// Large and rarely replaced
+		return "public, max-age=604800"
+	case mimeType == "application/pdf":
+		// An e-newspaper may be corrected after it is published
+		return "public, max-age=3600"
+	}
+	return "no-cache"
+}
+
+// extensions are the file extensions of the MIME types detected by upload.DetectContentType,
+// mime.ExtensionsByType returns several in no useful order for some of them
+var extensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/bmp":       ".bmp",
+	"audio/mpeg":      ".mp3",
+	"audio/wave":      ".wav",
+	"audio/ogg":       ".ogg",
+	"audio/aiff":      ".aiff",
+	"audio/aac":       ".aac",
+	"audio/mp4":       ".m4a",
+	"video/mp4":       ".mp4",
+	"video/webm":      ".webm",
+	"video/avi":       ".avi",
+	"video/quicktime": ".mov",
+	"application/pdf": ".pdf",
+}
+
+// fileName returns name, or the uploaded file name without its extension if name is empty,
+// with the extension of the uploaded file or of the MIME type
+func fileName(m Info, name string) string {
+	ext := strings.ToLower(path.Ext(m.FileName))
+	if name == "" {
+		name = strings.TrimSuffix(path.Base(m.FileName), path.Ext(m.FileName))
+	}
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || r == '/' || r == '\\' || r == '"' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" || name == "." {
+		name = "file"
+	}
+
+	if ext == "" {
+		mediaType, _, _ := mime.ParseMediaType(m.MimeType)
+		if e, ok := extensions[mediaType]; ok {
+			ext = e
+		} else if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	return name + ext
+}
+
+// reader reads a stored file as the io.ReadSeeker http.ServeContent needs.
+// Seeking only moves the offset, the file is requested from the offset on the next Read,
+// so a range of a file in S3 is downloaded without the rest of it.
+type reader struct {
+	ctx  context.Context
+	key  string
+	size int64
+	off  int64
+	body io.ReadCloser
+	// err is the last error of the storage, http.ServeContent does not return it
+	err error
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if r.off >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		body, err := store.GetRange(r.ctx, r.key, r.off, -1)
+		if err != nil {
+			r.err = err
+			return 0, err
+		}
+		r.body = body
+	}
+	n, err := r.body.Read(p)
+	r.off += int64(n)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+func (r *reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.off
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("media: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("media: negative position")
+	}
+	if offset != r.off && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.off = offset
+	return offset, nil
+}
+
+func (r *reader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	return r.body.Close()
+}
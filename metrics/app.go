@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"Tahlilchi.uz/upload"
+	"github.com/gorilla/mux"
+)
+
+// the metrics of the application
+var (
+	HTTPRequests = NewCounterVec("http_requests_total",
+		"Number of HTTP requests by route template, method and status.", "route", "method", "status")
+	HTTPRequestDuration = NewHistogramVec("http_request_duration_seconds",
+		"Latency of HTTP requests by route template and method.", DefaultBuckets, "route", "method")
+
+	UploadedFiles = NewCounterVec("uploaded_files_total",
+		"Number of files accepted by the upload routes by kind: photo, audio, pdf, video or other.", "kind")
+	UploadedBytes = NewCounterVec("uploaded_bytes_total",
+		"Size of the files accepted by the upload routes by kind.", "kind")
+	UploadRejections = NewCounterVec("upload_rejections_total",
+		"Number of files rejected by the upload checks by kind and response status, and of requests over their limit with the kind request.", "kind", "status")
+
+	CommentSubmissions = NewCounterVec("comment_submissions_total",
+		"Number of comments submitted by the readers by entity: news_post, article, e_newspaper or video_news.", "entity")
+	CommentApprovals = NewCounterVec("comment_approvals_total",
+		"Number of comments approved by the admins by entity.", "entity")
+	AppealSubmissions = NewCounterVec("appeal_submissions_total",
+		"Number of appeals submitted by the readers.")
+	TelegramSendFailures = NewCounterVec("telegram_send_failures_total",
+		"Number of messages which could not be sent to the Telegram chats by kind: message, photo or video.", "kind")
+
+	JobDuration = NewHistogramVec("scheduler_job_duration_seconds",
+		"Duration of the runs of the scheduled jobs.", DefaultBuckets, "job")
+	JobFailures = NewCounterVec("scheduler_job_failures_total",
+		"Number of failed runs of the scheduled jobs.", "job")
+	ArchivedBPPosts = NewCounterVec("archived_business_promotional_posts_total",
+		"Number of business promotional posts archived by the archive job on expiration.")
+	ScheduledPublications = NewCounterVec("scheduled_publications_total",
+		"Number of rows published or unpublished by the publish job at their publish_at or unpublish_at by table and action: publish or unpublish.", "table", "action")
+)
+
+// DBStats registers the statistics of the database connection pool returned by stats
+func DBStats(stats func() sql.DBStats) {
+	NewGaugeFunc("db_max_open_connections", "Maximum number of open connections to the database.",
+		func() float64 { return float64(stats().MaxOpenConnections) })
+	NewGaugeFunc("db_open_connections", "Number of open connections to the database.",
+		func() float64 { return float64(stats().OpenConnections) })
+	NewGaugeFunc("db_in_use_connections", "Number of connections in use.",
+		func() float64 { return float64(stats().InUse) })
+	NewGaugeFunc("db_idle_connections", "Number of idle connections.",
+		func() float64 { return float64(stats().Idle) })
+	NewCounterFunc("db_wait_count_total", "Number of connections waited for.",
+		func() float64 { return float64(stats().WaitCount) })
+	NewCounterFunc("db_wait_duration_seconds_total", "Time spent waiting for connections.",
+		func() float64 { return stats().WaitDuration.Seconds() })
+}
+
+// statusRecorder is a http.ResponseWriter which remembers the status code
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for http.ResponseController
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Instrument is a router middleware which counts the requests and observes their latency by route template,
+// and counts the files of the successful multipart requests by kind. The rejected files are counted by UploadRejected.
+func Instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if t, err := current.GetPathTemplate(); err == nil {
+				route = t
+			}
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		HTTPRequests.Inc(route, r.Method, strconv.Itoa(rec.status))
+		HTTPRequestDuration.Observe(time.Since(start).Seconds(), route, r.Method)
+
+		// the form is parsed by the handler on the same request
+		if r.MultipartForm == nil || rec.status >= 400 {
+			return
+		}
+		for _, files := range r.MultipartForm.File {
+			for _, fh := range files {
+				kind := fileKind(fh)
+				UploadedFiles.Inc(kind)
+				UploadedBytes.Add(float64(fh.Size), kind)
+			}
+		}
+	})
+}
+
+// UploadRejected counts a rejected upload, it is set with upload.OnRejection
+func UploadRejected(kind string, status int) {
+	UploadRejections.Inc(kind, strconv.Itoa(status))
+}
+
+// fileKind returns the kind of an uploaded file by its content
+func fileKind(fh *multipart.FileHeader) string {
+	f, err := fh.Open()
+	if err != nil {
+		return "other"
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	contentType := upload.DetectContentType(head[:n])
+
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		return "photo"
+	case strings.HasPrefix(contentType, "audio/"):
+		return "audio"
+	case contentType == "application/pdf":
+		return "pdf"
+	case strings.HasPrefix(contentType, "video/"):
+		return "video"
+	}
+	return "other"
+}
+
+// Protect requires the bearer token for the metrics handler, nothing is required for an empty token
+func Protect(h http.Handler, token string) http.Handler {
+	if token == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, bearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !bearer || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCounterVecWrite(t *testing.T) {
+	c := NewCounterVec("test_requests_total", "Number of test requests.", "route", "status")
+	c.Inc("/news/{id}", "200")
+	c.Add(2.5, "/news/{id}", "200")
+	c.Inc("/a", "404")
+	// the values are escaped
+	c.Inc(`C:\path "quoted"`+"\nline", "500")
+	// a negative value is ignored
+	c.Add(-1, "/a", "404")
+
+	var b strings.Builder
+	c.write(&b)
+	want := `# HELP test_requests_total Number of test requests.
+# TYPE test_requests_total counter
+test_requests_total{route="/a",status="404"} 1
+test_requests_total{route="/news/{id}",status="200"} 3.5
+test_requests_total{route="C:\\path \"quoted\"\nline",status="500"} 1
+`
+	if b.String() != want {
+		t.Errorf("write =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestCounterVecWithoutLabels(t *testing.T) {
+	c := NewCounterVec("test_appeals_total", "Number of test appeals.")
+	c.Inc()
+	c.Add(1e6)
+
+	var b strings.Builder
+	c.write(&b)
+	want := `# HELP test_appeals_total Number of test appeals.
+# TYPE test_appeals_total counter
+test_appeals_total 1.000001e+06
+`
+	if b.String() != want {
+		t.Errorf("write =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestHistogramVecWrite(t *testing.T) {
+	h := NewHistogramVec("test_duration_seconds", "Duration of the test jobs.", []float64{.1, 1, 10}, "job")
+	h.Observe(.05, "publish")
+	// a value on a bound falls into its bucket
+	h.Observe(1, "publish")
+	h.Observe(2, "publish")
+	// above the last bound, in +Inf only
+	h.Observe(30, "publish")
+	h.Observe(.5, `a"b`)
+
+	var b strings.Builder
+	h.write(&b)
+	want := `# HELP test_duration_seconds Duration of the test jobs.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{job="a\"b",le="0.1"} 0
+test_duration_seconds_bucket{job="a\"b",le="1"} 1
+test_duration_seconds_bucket{job="a\"b",le="10"} 1
+test_duration_seconds_bucket{job="a\"b",le="+Inf"} 1
+test_duration_seconds_sum{job="a\"b"} 0.5
+test_duration_seconds_count{job="a\"b"} 1
+test_duration_seconds_bucket{job="publish",le="0.1"} 1
+test_duration_seconds_bucket{job="publish",le="1"} 2
+test_duration_seconds_bucket{job="publish",le="10"} 3
+test_duration_seconds_bucket{job="publish",le="+Inf"} 4
+test_duration_seconds_sum{job="publish"} 33.05
+test_duration_seconds_count{job="publish"} 4
+`
+	if b.String() != want {
+		t.Errorf("write =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestCheckLabels(t *testing.T) {
+	c := NewCounterVec("test_labels_total", "Number of test labels.", "kind")
+	defer func() {
+		if recover() == nil {
+			t.Error("Inc with a missing label value did not panic")
+		}
+	}()
+	c.Inc()
+}
+
+func TestFormatFloat(t *testing.T) {
+	for v, want := range map[float64]string{0: "0", 0.25: "0.25", 300: "300", math.Inf(1): "+Inf", math.Inf(-1): "-Inf"} {
+		if got := formatFloat(v); got != want {
+			t.Errorf("formatFloat(%v) = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestProtect(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("metrics"))
+	})
+
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		status        int
+	}{
+		{"right token", "secret", "Bearer secret", http.StatusOK},
+		{"no header", "secret", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "Bearer secrex", http.StatusUnauthorized},
+		{"prefix of the token", "secret", "Bearer secre", http.StatusUnauthorized},
+		{"another scheme", "secret", "Basic secret", http.StatusUnauthorized},
+		{"empty bearer", "secret", "Bearer ", http.StatusUnauthorized},
+		{"token without the scheme", "secret", "secret", http.StatusUnauthorized},
+		{"no token required", "", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/metrics", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			Protect(ok, tt.token).ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Errorf("status %d, want %d", w.Code, tt.status)
+			}
+			if tt.status == http.StatusOK && w.Body.String() != "metrics" {
+				t.Errorf("body %q", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/db/migrations"
+)
+
+// migrate runs the "migrate" subcommand:
+//
+//	main migrate up            apply all pending migrations
+//	main migrate down [n]      revert the last n migrations (default 1)
+//	main migrate version       print the applied version
+//	main migrate force <v>     set the version after fixing a dirty schema by hand
+func migrate(args []string) error {
+	ctx := context.Background()
+
+	database, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [n]|version|force <version>")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrations.Up(ctx, database)
+		for _, m := range applied {
+			log.Printf("migrate: applied %06d_%s", m.Version, m.Name)
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("migrate down: invalid number of steps %q", args[1])
+			}
+		}
+		reverted, err := migrations.Down(ctx, database, steps)
+		for _, m := range reverted {
+			log.Printf("migrate: reverted %06d_%s", m.Version, m.Name)
+		}
+		return err
+	case "version":
+		version, dirty, err := migrations.Version(ctx, database)
+		if err != nil {
+			return err
+		}
+		latest, err := migrations.Latest()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("version: %d, dirty: %v, latest: %d\n", version, dirty, latest)
+		return nil
+	case "force":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: migrate force <version>")
+		}
+		version, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("migrate force: invalid version %q", args[1])
+		}
+		return migrations.Force(ctx, database, uint(version))
+	}
+
+	return fmt.Errorf("migrate: unknown command %q", args[0])
+}
+
+// checkSchema applies pending migrations when MIGRATE_ON_START is true
+// and refuses to go on when the schema is still not up to date
+func checkSchema(migrateOnStart bool) {
+	ctx := context.Background()
+
+	database, err := db.DB()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if migrateOnStart {
+		applied, err := migrations.Up(ctx, database)
+		for _, m := range applied {
+			log.Printf("migrate: applied %06d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := migrations.Check(ctx, database); err != nil {
+		log.Fatalf("%v: run \"migrate up\" or set MIGRATE_ON_START=true", err)
+	}
+}
@@ -30,8 +30,6 @@ func (ac *ArticleComment) AddArticleComment(article int) error {
 	if err != nil {
 		return err
 	}
-	// defer the close of the database connection
-	defer database.Close()
 
 	// create a new transaction
 	tx, err := database.Begin()
@@ -76,8 +74,6 @@ func (ac *ArticleComment) GetArticleCommentList(admin bool, id, page, limit int)
 	if err != nil {
 		return ArticleCommentListResponse{}, err
 	}
-	// defer the close of the database connection
-	defer database.Close()
 
 	// declare rows variable using *sql.Rows
 	var rows *sql.Rows
@@ -166,8 +162,6 @@ func (ac *ArticleComment) ApproveArticleComment(id, commentID int) error {
 	if err != nil {
 		return err
 	}
-	// defer the close of the database connection
-	defer database.Close()
 
 	// create a new transaction
 	tx, err := database.Begin()
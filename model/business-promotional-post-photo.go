@@ -1,9 +1,12 @@
 package model
 
+import "database/sql"
+
 type BusinessPromotionalPostPhoto struct {
-	ID        int    `json:"id"`
-	BPP       int    `json:"bpp"`
-	FileName  string `json:"file_name"`
-	File      []byte `json:"file"`
-	CreatedAt string `json:"created_at"`
+	ID        int           `json:"id"`
+	BPP       int           `json:"bpp"`
+	FileName  string        `json:"file_name"`
+	File      []byte        `json:"file"`
+	MediaID   sql.NullInt64 `json:"-"`
+	CreatedAt string        `json:"created_at"`
 }
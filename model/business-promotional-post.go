@@ -23,4 +23,7 @@ type BusinessPromotionalPost struct {
 	Archived            bool                           `json:"archived"`
 	Partner             string                         `json:"partner"`
 	Completed           bool                           `json:"completed"`
+	PublishAt           *time.Time                     `json:"publish_at"`
+	UnpublishAt         *time.Time                     `json:"unpublish_at"`
+	Slug                *string                        `json:"slug"`
 }
@@ -43,8 +43,6 @@ func (enc *ENewspaperComment) AddENewspaperComment(e_newspaper int) error {
 	if err != nil {
 		return err
 	}
-	// defer the close of the database connection
-	defer database.Close()
 
 	// create a new transaction
 	tx, err := database.Begin()
@@ -93,8 +91,6 @@ func (enc *ENewspaperComment) GetENewspaperCommentListResponse(admin bool, id, p
 	if err != nil {
 		return ENewspaperCommentListResponse{}, err
 	}
-	// defer the close of the database connection
-	defer database.Close()
 
 	// declare rows variable using *sql.Rows
 	var rows *sql.Rows
@@ -185,8 +181,6 @@ func (enc *ENewspaperComment) ApproveENewspaperComment(e_newspaper, commentID in
 	if err != nil {
 		return err
 	}
-	// defer the close of the database connection
-	defer database.Close()
 
 	// create a new transaction
 	tx, err := database.Begin()
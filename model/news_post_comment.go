@@ -26,7 +26,6 @@ func (npc *NewsPostComment) AddNewsPostComment(newsPost int) error {
 	if err != nil {
 		return err
 	}
-	defer database.Close()
 
 	tx, err := database.Begin()
 	if err != nil {
@@ -63,7 +62,6 @@ func (npc *NewsPostComment) GetNewsPostCommentListResponse(admin bool, newsPost
 	if err != nil {
 		return NewsPostCommentListResponse{}, err
 	}
-	defer database.Close()
 
 	var rows *sql.Rows
 	if admin {
@@ -125,7 +123,6 @@ func (npc *NewsPostComment) ApproveNewsPostComment(newsPost, commentID int) erro
 	if err != nil {
 		return err
 	}
-	defer database.Close()
 
 	tx, err := database.Begin()
 	if err != nil {
@@ -1,6 +1,11 @@
 package model
 
-import "Tahlilchi.uz/db"
+import (
+	"database/sql"
+	"time"
+
+	"Tahlilchi.uz/db"
+)
 
 // VideoNewsList is a struct to map the video news list data
 type VideoNewsListResponse struct {
@@ -19,6 +24,11 @@ type VideoNews struct {
 	UpdatedAt    string `json:"updated_at"`
 	Archived     bool   `json:"archived"`
 	Completed    bool   `json:"completed"`
+	// PublishAt and UnpublishAt are the publishing window, not written by UpdateVideoNews
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
+	// Status is set by the editorial workflow only
+	Status string `json:"status"`
 }
 
 // AddVideoNews is a method to add a video news to the database
@@ -28,8 +38,6 @@ func (vn *VideoNews) AddVideoNews() error {
 	if err != nil {
 		return err
 	}
-	// defer the close of the database connection
-	defer database.Close()
 
 	// create a new transaction
 	tx, err := database.Begin()
@@ -37,7 +45,7 @@ func (vn *VideoNews) AddVideoNews() error {
 		return err
 	}
 	// prepare the insert statement
-	stmt, err := tx.Prepare("INSERT INTO video_news (video, text_latin, text_cyrillic) VALUES ($1, $2, $3)")
+	stmt, err := tx.Prepare("INSERT INTO video_news (video, text_latin, text_cyrillic, publish_at, unpublish_at) VALUES ($1, $2, $3, $4, $5)")
 	if err != nil {
 		return err
 	}
@@ -45,7 +53,7 @@ func (vn *VideoNews) AddVideoNews() error {
 	defer stmt.Close()
 
 	// execute the insert statement
-	_, err = stmt.Exec(vn.Video, vn.TextLatin, vn.TextCyrillic)
+	_, err = stmt.Exec(vn.Video, vn.TextLatin, vn.TextCyrillic, vn.PublishAt, vn.UnpublishAt)
 	if err != nil {
 		return err
 	}
@@ -57,19 +65,12 @@ func (vn *VideoNews) AddVideoNews() error {
 	return nil
 }
 
-// UpdateVideoNews is a method to update a video news in the database
-func (vn *VideoNews) UpdateVideoNews(id string) error {
-	// create a new database connection
-	database, err := db.DB()
-	if err != nil {
-		return err
-	}
-	// defer the close of the database connection
-	defer database.Close()
-
+// UpdateVideoNews is a method to update a video news in the database, in the transaction tx.
+// The publishing window is written by the caller, which tells a cleared time from one left out.
+func (vn *VideoNews) UpdateVideoNews(tx *sql.Tx, id string) error {
 	// if VideoNews Video is not empty, update the video news video, set current date time to updated_at
 	if vn.Video != "" {
-		_, err := database.Exec("UPDATE video_news SET video = $1, updated_at = now() WHERE id = $2", vn.Video, id)
+		_, err := tx.Exec("UPDATE video_news SET video = $1, updated_at = now() WHERE id = $2", vn.Video, id)
 		if err != nil {
 			return err
 		}
@@ -77,14 +78,14 @@ func (vn *VideoNews) UpdateVideoNews(id string) error {
 
 	// if VideoNews TextLatin is not empty, update the video news text_latin
 	if vn.TextLatin != "" {
-		_, err := database.Exec("UPDATE video_news SET text_latin = $1, updated_at = now() WHERE id = $2", vn.TextLatin, id)
+		_, err := tx.Exec("UPDATE video_news SET text_latin = $1, updated_at = now() WHERE id = $2", vn.TextLatin, id)
 		if err != nil {
 			return err
 		}
 	}
 	// if VideoNews TextCyrillic is not empty, update the video news text_cyrillic
 	if vn.TextCyrillic != "" {
-		_, err := database.Exec("UPDATE video_news SET text_cyrillic = $1, updated_at = now() WHERE id = $2", vn.TextCyrillic, id)
+		_, err := tx.Exec("UPDATE video_news SET text_cyrillic = $1, updated_at = now() WHERE id = $2", vn.TextCyrillic, id)
 		if err != nil {
 			return err
 		}
@@ -100,8 +101,6 @@ func (vn *VideoNews) DeleteVideoNews() error {
 	if err != nil {
 		return err
 	}
-	// defer the close of the database connection
-	defer database.Close()
 
 	// execute the delete statement
 	_, err = database.Exec("DELETE FROM video_news WHERE id = $1", vn.ID)
@@ -119,14 +118,12 @@ func GetVideoNewsList(limit, offset int) (*VideoNewsListResponse, error) {
 	if err != nil {
 		return nil, err
 	}
-	// defer the close of the database connection
-	defer database.Close()
 
 	// create a new VideoNewsListResponse
 	vnList := VideoNewsListResponse{}
 
 	// execute the select statement to get the video news list
-	rows, err := database.Query("SELECT id, video, text_latin, text_cyrillic, created_at, updated_at, archived, completed FROM video_news ORDER BY id DESC LIMIT $1 OFFSET $2", limit, offset)
+	rows, err := database.Query("SELECT id, video, text_latin, text_cyrillic, created_at, updated_at, archived, completed, publish_at, unpublish_at, status FROM video_news ORDER BY id DESC LIMIT $1 OFFSET $2", limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -138,7 +135,7 @@ func GetVideoNewsList(limit, offset int) (*VideoNewsListResponse, error) {
 		// create a new VideoNews
 		vn := VideoNews{}
 		// scan the rows into the VideoNews
-		err := rows.Scan(&vn.ID, &vn.Video, &vn.TextLatin, &vn.TextCyrillic, &vn.CreatedAt, &vn.UpdatedAt, &vn.Archived, &vn.Completed)
+		err := rows.Scan(&vn.ID, &vn.Video, &vn.TextLatin, &vn.TextCyrillic, &vn.CreatedAt, &vn.UpdatedAt, &vn.Archived, &vn.Completed, &vn.PublishAt, &vn.UnpublishAt, &vn.Status)
 		if err != nil {
 			return nil, err
 		}
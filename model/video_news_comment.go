@@ -29,7 +29,6 @@ func (vnc *VideoNewsComment) AddVideoNewsComment(videoNews int) error {
 	if err != nil {
 		return err
 	}
-	defer database.Close()
 
 	tx, err := database.Begin()
 	if err != nil {
@@ -67,7 +66,6 @@ func (vnc *VideoNewsComment) GetVideoNewsCommentListResponse(admin bool, videoNe
 	if err != nil {
 		return VideoNewsCommentListResponse{}, err
 	}
-	defer database.Close()
 
 	var rows *sql.Rows
 	if admin {
@@ -130,7 +128,6 @@ func (vnc *VideoNewsComment) ApproveVideoNewsComment(videoNews, commentID int) e
 	if err != nil {
 		return err
 	}
-	defer database.Close()
 
 	tx, err := database.Begin()
 	if err != nil {
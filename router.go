@@ -2,27 +2,42 @@ package main
 
 import (
 	"net/http"
-	"os"
 
 	"Tahlilchi.uz/admin"
 	"Tahlilchi.uz/client"
+	"Tahlilchi.uz/config"
+	"Tahlilchi.uz/logging"
+	"Tahlilchi.uz/metrics"
 	"Tahlilchi.uz/routerFuncs"
+	"Tahlilchi.uz/scheduler"
+	"Tahlilchi.uz/toolkit"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 )
 
-func Router() {
+// Router returns the handler of all routes of the app
+func Router(cfg *config.Config, jobs *scheduler.Scheduler) http.Handler {
 	r := mux.NewRouter()
+	// record the route template of every request for the access log and count the requests by it
+	r.Use(logging.Route, metrics.Instrument)
 
 	r.HandleFunc("/", routerFuncs.Root).Methods("GET") // .Schemes(os.Getenv("SCHEMES"))  add .Host(os.Getenv("HOST")) in the end
+	// probes of the orchestrator and the build information: location: routerFuncs/health.go
+	r.HandleFunc("/healthz", routerFuncs.Health).Methods("GET")
+	r.HandleFunc("/readyz", routerFuncs.Ready(cfg)).Methods("GET")
+	r.HandleFunc("/version", routerFuncs.Version).Methods("GET")
+	// Prometheus metrics: location: metrics/app.go
+	r.Handle("/metrics", metrics.Protect(metrics.Handler(), cfg.MetricsToken)).Methods("GET")
 
-	admin.AdminRouter(r)
-	client.ClientRouter(r)
+	admin.AdminRouter(r, cfg, jobs)
+	client.ClientRouter(r, cfg)
 
-	headersOk := handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization"})
-	originsOk := handlers.AllowedOrigins([]string{os.Getenv("ADMINCLIENT"), os.Getenv("CLIENT")})
+	headersOk := handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization", "Range", "If-None-Match", "If-Modified-Since", "If-Range"})
+	// The headers of the media files the frontends read for seeking, caching and downloading
+	exposedOk := handlers.ExposedHeaders([]string{"Accept-Ranges", "Content-Range", "Content-Length", "Content-Disposition", "ETag", "Last-Modified"})
+	originsOk := handlers.AllowedOrigins([]string{cfg.CORS.AdminClient, cfg.CORS.Client})
 	methodsOk := handlers.AllowedMethods([]string{"GET", "HEAD", "POST", "PATCH", "DELETE", "OPTIONS"})
 	credentials := handlers.AllowCredentials()
 
-	http.ListenAndServe(":8080", handlers.CORS(originsOk, headersOk, methodsOk, credentials)(r))
+	return logging.AccessLog(handlers.CORS(originsOk, headersOk, exposedOk, methodsOk, credentials)(r), toolkit.ClientIP)
 }
@@ -0,0 +1,95 @@
+package routerFuncs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"Tahlilchi.uz/config"
+	"Tahlilchi.uz/db"
+	"Tahlilchi.uz/db/migrations"
+	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/version"
+)
+
+// readyTimeout limits the checks of Ready, so a hanging database fails the probe instead of blocking it
+const readyTimeout = 3 * time.Second
+
+// Health is the liveness probe: the process is up and serving requests
+func Health(w http.ResponseWriter, r *http.Request) {
+	response.Res(w, "success", http.StatusOK, "ok")
+}
+
+// Readiness is the result of the readiness checks, "ok" or the error of every check
+type Readiness struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Ready returns the readiness probe: the database is reachable, the schema is up to date
+// and the SMTP and Telegram settings are set. It responds with 503 when any check fails.
+func Ready(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+		defer cancel()
+
+		checks := map[string]error{
+			"database":   checkDatabase(ctx),
+			"migrations": checkMigrations(ctx),
+			"mail":       checkMail(cfg.Mail),
+			"telegram":   checkTelegram(cfg.Telegram),
+		}
+
+		res := Readiness{Ready: true, Checks: map[string]string{}}
+		for name, err := range checks {
+			if err != nil {
+				res.Ready = false
+				res.Checks[name] = err.Error()
+				continue
+			}
+			res.Checks[name] = "ok"
+		}
+
+		if !res.Ready {
+			response.Res(w, "error", http.StatusServiceUnavailable, res)
+			return
+		}
+		response.Res(w, "success", http.StatusOK, res)
+	}
+}
+
+func checkDatabase(ctx context.Context) error {
+	database, err := db.DBContext(ctx)
+	if err != nil {
+		return err
+	}
+	return database.PingContext(ctx)
+}
+
+func checkMigrations(ctx context.Context) error {
+	database, err := db.DBContext(ctx)
+	if err != nil {
+		return err
+	}
+	return migrations.Check(ctx, database)
+}
+
+func checkMail(cfg config.Mail) error {
+	if cfg.Server == "" || cfg.Port == 0 || cfg.From == "" || cfg.Password == "" {
+		return errors.New("SMTP server is not configured")
+	}
+	return nil
+}
+
+func checkTelegram(cfg config.Telegram) error {
+	if cfg.Token == "" || cfg.ChatIDBoss == 0 || cfg.ChatIDAdmin == 0 {
+		return errors.New("Telegram bot is not configured")
+	}
+	return nil
+}
+
+// Version returns the commit and the time the binary is built from and the Go version
+func Version(w http.ResponseWriter, r *http.Request) {
+	response.Res(w, "success", http.StatusOK, version.Get())
+}
@@ -0,0 +1,121 @@
+// Package scheduler runs the periodic jobs of the application
+// and keeps the status of their last runs.
+package scheduler
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"Tahlilchi.uz/metrics"
+	"github.com/go-co-op/gocron"
+)
+
+// Scheduler runs jobs at fixed intervals, a job never runs twice at the same time
+type Scheduler struct {
+	cron *gocron.Scheduler
+
+	mu   sync.Mutex
+	jobs []*job
+}
+
+type job struct {
+	name     string
+	interval time.Duration
+	cronJob  *gocron.Job
+	status   JobStatus
+}
+
+// JobStatus is the status of a job
+type JobStatus struct {
+	Name         string     `json:"name"`
+	Interval     string     `json:"interval"`
+	Running      bool       `json:"running"`
+	Runs         int        `json:"runs"`
+	Failures     int        `json:"failures"`
+	LastRun      *time.Time `json:"last_run"`
+	LastDuration string     `json:"last_duration"`
+	LastError    string     `json:"last_error"`
+	LastErrorAt  *time.Time `json:"last_error_at"`
+	NextRun      *time.Time `json:"next_run"`
+}
+
+// New returns a new Scheduler, the jobs are run in UTC
+func New() *Scheduler {
+	cron := gocron.NewScheduler(time.UTC)
+	cron.SingletonModeAll()
+	return &Scheduler{cron: cron}
+}
+
+// Every schedules fn to run every interval under the given name.
+// The errors of fn are logged and kept as the last error of the job.
+func (s *Scheduler) Every(interval time.Duration, name string, fn func() error) error {
+	j := &job{name: name, interval: interval}
+
+	cronJob, err := s.cron.Every(interval).Do(func() {
+		s.run(j, fn)
+	})
+	if err != nil {
+		return err
+	}
+	j.cronJob = cronJob
+
+	s.mu.Lock()
+	s.jobs = append(s.jobs, j)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Scheduler) run(j *job, fn func() error) {
+	start := time.Now().UTC()
+	s.mu.Lock()
+	j.status.Running = true
+	s.mu.Unlock()
+
+	err := fn()
+	duration := time.Since(start)
+	metrics.JobDuration.Observe(duration.Seconds(), j.name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.status.Running = false
+	j.status.Runs++
+	j.status.LastRun = &start
+	j.status.LastDuration = duration.String()
+	if err != nil {
+		metrics.JobFailures.Inc(j.name)
+		j.status.Failures++
+		j.status.LastError = err.Error()
+		j.status.LastErrorAt = &start
+		slog.Error("scheduled job failed", "job", j.name, "error", err)
+	}
+}
+
+// Start starts running the jobs without blocking
+func (s *Scheduler) Start() {
+	s.cron.StartAsync()
+}
+
+// Stop stops the scheduler after waiting for the running jobs
+func (s *Scheduler) Stop() {
+	s.cron.Stop()
+}
+
+// Status returns the status of all the jobs
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		status := j.status
+		status.Name = j.name
+		status.Interval = j.interval.String()
+		if next := j.cronJob.NextRun(); !next.IsZero() {
+			next = next.UTC()
+			status.NextRun = &next
+		}
+		list = append(list, status)
+	}
+	return list
+}
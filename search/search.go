@@ -0,0 +1,249 @@
+// Package search runs the full-text searches of the content over both alphabets.
+// The search_vector columns and the uzbek text search configuration are created by the migrations.
+package search
+
+import (
+	"database/sql"
+	"errors"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"Tahlilchi.uz/translit"
+)
+
+// config is the text search configuration of the content
+const config = "uzbek"
+
+// maxWords is the number of words of a query that are searched, the rest are ignored
+const maxWords = 8
+
+// MaxLimit is the largest page size of a search
+const MaxLimit = 100
+
+// startSel and stopSel wrap the matched words in the ts_headline output. They are not HTML,
+// so the content can be escaped before they are turned into <mark> by highlight.
+const (
+	startSel = "\x01"
+	stopSel  = "\x02"
+)
+
+// headlineOptions are the ts_headline options of the snippets
+const headlineOptions = "StartSel=" + startSel + ", StopSel=" + stopSel + ", MaxFragments=2, MaxWords=30, MinWords=10"
+
+// Errors of Parse, their messages are returned to the clients
+var (
+	ErrEmpty        = errors.New("search query is empty")
+	ErrInvalidPage  = errors.New("invalid page or limit")
+	ErrInvalidLimit = errors.New("limit must be between 1 and " + strconv.Itoa(MaxLimit))
+)
+
+// Query is a parsed search request
+type Query struct {
+	// Latin and Cyrillic are the tsquery of the words of the search in each alphabet
+	Latin    string
+	Cyrillic string
+	Page     int
+	Limit    int
+}
+
+// Parse returns the Query of the search, page and limit query parameters of r.
+// page defaults to 1 and limit to 10.
+func Parse(r *http.Request) (Query, error) {
+	q := Query{Page: 1, Limit: 10}
+	params := r.URL.Query()
+
+	var err error
+	if p := params.Get("page"); p != "" {
+		if q.Page, err = strconv.Atoi(p); err != nil || q.Page < 1 {
+			return q, ErrInvalidPage
+		}
+	}
+	if l := params.Get("limit"); l != "" {
+		if q.Limit, err = strconv.Atoi(l); err != nil {
+			return q, ErrInvalidPage
+		}
+		if q.Limit < 1 || q.Limit > MaxLimit {
+			return q, ErrInvalidLimit
+		}
+	}
+
+	search := params.Get("search")
+	// the words are searched in both alphabets, a Latin query finds the Cyrillic content and the other way around
+	q.Latin = terms(translit.ToLatin(search))
+	q.Cyrillic = terms(translit.ToCyrillic(search))
+	if q.Latin == "" || q.Cyrillic == "" {
+		return q, ErrEmpty
+	}
+	return q, nil
+}
+
+// terms returns the tsquery matching the rows with all the words of s, each as a prefix.
+// Only letters and digits are kept, so nothing of s is read as tsquery syntax.
+func terms(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !isApostrophe(r)
+	})
+
+	var ts []string
+	for _, w := range words {
+		// the apostrophes are written as ʻ, like uz_search_text does for the content
+		w = strings.Trim(strings.Map(func(r rune) rune {
+			if isApostrophe(r) {
+				return 'ʻ'
+			}
+			return r
+		}, w), "ʻ")
+		if w == "" {
+			continue
+		}
+		ts = append(ts, w+":*")
+		if len(ts) == maxWords {
+			break
+		}
+	}
+	return strings.Join(ts, " & ")
+}
+
+// isApostrophe reports whether r is one of the characters typed for oʻ, gʻ and the tutuq belgisi
+func isApostrophe(r rune) bool {
+	switch r {
+	case '\'', '`', 'ʻ', 'ʼ', '‘', '’':
+		return true
+	}
+	return false
+}
+
+// Entity is a table with a search_vector column
+type Entity struct {
+	Table string
+	// HeadlineLatin and HeadlineCyrillic are the columns the snippets are taken from
+	HeadlineLatin    string
+	HeadlineCyrillic string
+}
+
+// Entities are the searched tables
+var (
+	NewsPost     = Entity{Table: "news_posts", HeadlineLatin: "description_latin", HeadlineCyrillic: "description_cyrillic"}
+	Article      = Entity{Table: "articles", HeadlineLatin: "description_latin", HeadlineCyrillic: "description_cyrillic"}
+	BPPost       = Entity{Table: "business_promotional_posts", HeadlineLatin: "description_latin", HeadlineCyrillic: "description_cyrillic"}
+	ENewspaper   = Entity{Table: "e_newspapers", HeadlineLatin: "title_latin", HeadlineCyrillic: "title_cyrillic"}
+	PhotoGallery = Entity{Table: "photo_gallery", HeadlineLatin: "title_latin", HeadlineCyrillic: "title_cyrillic"}
+)
+
+// matching is the FROM and WHERE of the rows of e matching the Latin or the Cyrillic query, $1 and $2, as q
+func (e Entity) matching(cond string) string {
+	where := "search_vector @@ s.q"
+	if cond != "" {
+		where += " AND " + cond
+	}
+	return e.Table + ", (SELECT to_tsquery('" + config + "', $1) || to_tsquery('" + config + "', $2) AS q) s WHERE " + where
+}
+
+// Search returns the page of q of the rows of e matching it among the rows of cond, the best ranked first, and their total.
+// columns are the columns of the table t selected first, the rank and the Latin and Cyrillic headlines follow them:
+// scan them with Match.Dest.
+func (e Entity) Search(database *sql.DB, q Query, columns, cond string) (*sql.Rows, int, error) {
+	var total int
+	err := database.QueryRow("SELECT COUNT(*) FROM "+e.matching(cond), q.Latin, q.Cyrillic).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// the page is ranked and cut first, the headlines are only built for its rows
+	rows, err := database.Query(`SELECT `+columns+`, m.rank,
+			ts_headline('`+config+`', uz_search_text(t.`+e.HeadlineLatin+`), m.q, $5),
+			ts_headline('`+config+`', uz_search_text(t.`+e.HeadlineCyrillic+`), m.q, $5)
+		FROM (SELECT id, s.q, ts_rank(search_vector, s.q) AS rank FROM `+e.matching(cond)+`
+			ORDER BY rank DESC, id DESC LIMIT $3 OFFSET $4) m
+		JOIN `+e.Table+` t ON t.id = m.id
+		ORDER BY m.rank DESC, t.id DESC`, q.Latin, q.Cyrillic, q.Limit, (q.Page-1)*q.Limit, headlineOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	return rows, total, nil
+}
+
+// Headline is the snippet of a result in both alphabets, the matched words wrapped in <mark>.
+// The rest of the text is HTML-escaped, the snippets can be shown as HTML.
+type Headline struct {
+	Latin    string `json:"latin"`
+	Cyrillic string `json:"cyrillic"`
+}
+
+// Match is the rank and the headline of a result, embedded in the result structs
+type Match struct {
+	Rank     float64  `json:"rank"`
+	Headline Headline `json:"headline"`
+}
+
+// Dest returns the scan destinations of the rank and the headlines selected by Search
+func (m *Match) Dest() []any {
+	return []any{&m.Rank, highlighted{&m.Headline.Latin}, highlighted{&m.Headline.Cyrillic}}
+}
+
+// highlighted is the scan destination of a ts_headline output of Search, it stores its highlight
+type highlighted struct {
+	s *string
+}
+
+func (h highlighted) Scan(src any) error {
+	var headline sql.NullString
+	if err := headline.Scan(src); err != nil {
+		return err
+	}
+	*h.s = highlight(headline.String)
+	return nil
+}
+
+// highlight returns the ts_headline output s HTML-escaped, with the words between startSel and stopSel wrapped in <mark>.
+// The selectors are paired up, so a stray one of the content cannot leave a <mark> open or close one which is not.
+func highlight(s string) string {
+	var b strings.Builder
+	open := false
+	for s != "" {
+		i := strings.IndexAny(s, startSel+stopSel)
+		if i < 0 {
+			b.WriteString(html.EscapeString(s))
+			break
+		}
+		b.WriteString(html.EscapeString(s[:i]))
+		switch {
+		case s[i:i+1] == startSel && !open:
+			b.WriteString("<mark>")
+			open = true
+		case s[i:i+1] == stopSel && open:
+			b.WriteString("</mark>")
+			open = false
+		}
+		s = s[i+1:]
+	}
+	if open {
+		b.WriteString("</mark>")
+	}
+	return b.String()
+}
+
+// Page is a page of search results
+type Page struct {
+	Results  any  `json:"results"`
+	Total    int  `json:"total"`
+	Page     int  `json:"page"`
+	Limit    int  `json:"limit"`
+	Previous bool `json:"previous"`
+	Next     bool `json:"next"`
+}
+
+// NewPage returns the Page of q with results among total results
+func NewPage(q Query, results any, total int) Page {
+	return Page{
+		Results:  results,
+		Total:    total,
+		Page:     q.Page,
+		Limit:    q.Limit,
+		Previous: q.Page > 1,
+		Next:     q.Page*q.Limit < total,
+	}
+}
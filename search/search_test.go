@@ -0,0 +1,181 @@
+package search
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"one word", "Toshkent", "toshkent:*"},
+		{"words", "yangi  qonun", "yangi:* & qonun:*"},
+		{"turned comma", "Oʻzbekiston", "oʻzbekiston:*"},
+		{"ascii apostrophe", "O'zbekiston", "oʻzbekiston:*"},
+		{"backtick", "g`alaba", "gʻalaba:*"},
+		{"right quotation mark", "maʼnaviyat ta’lim", "maʻnaviyat:* & taʻlim:*"},
+		{"apostrophes around a word", "'salom'", "salom:*"},
+		{"apostrophes only", "' ` ʻ", ""},
+		{"cyrillic", "Ўзбекистон янгиликлари", "ўзбекистон:* & янгиликлари:*"},
+		{"digits", "2024 yil", "2024:* & yil:*"},
+		// nothing of the query is read as tsquery syntax
+		{"operators", "a & b | !c <-> (d)", "a:* & b:* & c:* & d:*"},
+		{"weights and prefixes", "soliq:AB narx:*", "soliq:* & ab:* & narx:*"},
+		{"quotes and backslashes", `"soliq" \narx\`, "soliq:* & narx:*"},
+		{"punctuation only", "&|!:*()<->", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := terms(tt.in); got != tt.want {
+				t.Errorf("terms(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTermsMaxWords(t *testing.T) {
+	var words []string
+	for i := 1; i <= maxWords+3; i++ {
+		words = append(words, "w"+strconv.Itoa(i))
+	}
+	got := terms(strings.Join(words, " "))
+
+	parts := strings.Split(got, " & ")
+	if len(parts) != maxWords {
+		t.Fatalf("terms of %d words has %d: %q", len(words), len(parts), got)
+	}
+	if last := parts[maxWords-1]; last != "w"+strconv.Itoa(maxWords)+":*" {
+		t.Errorf("the last word kept is %q", last)
+	}
+
+	// the words left empty by the apostrophes do not count
+	got = terms(strings.Repeat("' ", maxWords) + "salom")
+	if got != "salom:*" {
+		t.Errorf("terms = %q, want salom:*", got)
+	}
+}
+
+func parse(t *testing.T, params url.Values) (Query, error) {
+	t.Helper()
+	return Parse(httptest.NewRequest("GET", "/search?"+params.Encode(), nil))
+}
+
+func TestParse(t *testing.T) {
+	q, err := parse(t, url.Values{"search": {"Oʻzbekiston"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Page != 1 || q.Limit != 10 {
+		t.Errorf("page %d, limit %d, want the defaults 1 and 10", q.Page, q.Limit)
+	}
+	// a Latin query finds the Cyrillic content and the other way around
+	if q.Latin != "oʻzbekiston:*" || q.Cyrillic != "ўзбекистон:*" {
+		t.Errorf("latin %q, cyrillic %q", q.Latin, q.Cyrillic)
+	}
+
+	q, err = parse(t, url.Values{"search": {"Тошкент"}, "page": {"3"}, "limit": {"25"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Page != 3 || q.Limit != 25 || q.Latin != "toshkent:*" || q.Cyrillic != "тошкент:*" {
+		t.Errorf("got %+v", q)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		params url.Values
+		want   error
+	}{
+		{"no search", url.Values{}, ErrEmpty},
+		{"empty search", url.Values{"search": {"  "}}, ErrEmpty},
+		{"punctuation only", url.Values{"search": {"&|!"}}, ErrEmpty},
+		{"page 0", url.Values{"search": {"a"}, "page": {"0"}}, ErrInvalidPage},
+		{"negative page", url.Values{"search": {"a"}, "page": {"-1"}}, ErrInvalidPage},
+		{"page not a number", url.Values{"search": {"a"}, "page": {"two"}}, ErrInvalidPage},
+		{"limit not a number", url.Values{"search": {"a"}, "limit": {"ten"}}, ErrInvalidPage},
+		{"limit 0", url.Values{"search": {"a"}, "limit": {"0"}}, ErrInvalidLimit},
+		{"limit above the max", url.Values{"search": {"a"}, "limit": {strconv.Itoa(MaxLimit + 1)}}, ErrInvalidLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parse(t, tt.params); err != tt.want {
+				t.Errorf("Parse error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	for _, limit := range []int{1, MaxLimit} {
+		if _, err := parse(t, url.Values{"search": {"a"}, "limit": {strconv.Itoa(limit)}}); err != nil {
+			t.Errorf("limit %d: %v", limit, err)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"plain", "yangi qonun", "yangi qonun"},
+		{"match", "yangi " + startSel + "qonun" + stopSel + " qabul qilindi", "yangi <mark>qonun</mark> qabul qilindi"},
+		{"matches", startSel + "a" + stopSel + " b " + startSel + "c" + stopSel, "<mark>a</mark> b <mark>c</mark>"},
+		{"html of the content", `<img src=x onerror="alert(1)"> & ` + startSel + "soliq" + stopSel,
+			"&lt;img src=x onerror=&#34;alert(1)&#34;&gt; &amp; <mark>soliq</mark>"},
+		{"html inside a match", startSel + "<b>" + stopSel, "<mark>&lt;b&gt;</mark>"},
+		{"stray stop", stopSel + "a", "a"},
+		{"stray start", "a " + startSel + "b", "a <mark>b</mark>"},
+		{"nested start", startSel + "a" + startSel + "b" + stopSel + stopSel, "<mark>ab</mark>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlight(tt.in); got != tt.want {
+				t.Errorf("highlight(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightedScan(t *testing.T) {
+	var m Match
+	dest := m.Dest()
+	for i, src := range []any{0.5, []byte("a " + startSel + "<b>" + stopSel), nil} {
+		if s, ok := dest[i].(interface{ Scan(any) error }); ok {
+			if err := s.Scan(src); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if m.Headline.Latin != "a <mark>&lt;b&gt;</mark>" || m.Headline.Cyrillic != "" {
+		t.Errorf("headline %+v", m.Headline)
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	tests := []struct {
+		page, limit, total int
+		previous, next     bool
+	}{
+		{1, 10, 0, false, false},
+		{1, 10, 10, false, false},
+		{1, 10, 11, false, true},
+		{2, 10, 20, true, false},
+		{2, 10, 21, true, true},
+	}
+	for _, tt := range tests {
+		p := NewPage(Query{Page: tt.page, Limit: tt.limit}, nil, tt.total)
+		if p.Previous != tt.previous || p.Next != tt.next {
+			t.Errorf("page %d of %d by %d: previous %v, next %v", tt.page, tt.total, tt.limit, p.Previous, p.Next)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Package slug makes the URL slugs of the public content from its Latin title
+// and keeps them unique per table. The old slugs of a row keep redirecting to it after its title changes.
+package slug
+
+import (
+	"strings"
+	"unicode"
+
+	"Tahlilchi.uz/translit"
+)
+
+// maxLen is the length limit of a slug, longer slugs are cut at a hyphen
+const maxLen = 80
+
+// apostrophes are the signs of oʻ and gʻ and the tutuq belgisi, dropped from the slugs
+const apostrophes = "'`ʻʼ‘’"
+
+// folded are the ASCII letters of the Latin letters with diacritics, e.g. of the foreign names in the titles
+var folded = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'ı': "i", 'į': "i",
+	'ł': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ß': "ss", 'ś': "s", 'ş': "s", 'š': "s", 'ș': "s", 'ť': "t", 'ţ': "t", 'ț': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// Make returns the slug of title: lowercase ASCII letters and digits separated by single hyphens.
+// oʻ and gʻ become o and g, the letters with diacritics lose them and Cyrillic titles are transliterated.
+// It is empty for a title without letters or digits. The slug of a title of digits only is digits only too,
+// Entity.Assign does not use it as it is, as it would be read as an id.
+func Make(title string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(translit.ToLatin(title)) {
+		var s string
+		switch {
+		case strings.ContainsRune(apostrophes, r):
+			continue
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			s = string(r)
+		default:
+			s = folded[r]
+		}
+
+		if s == "" {
+			hyphen = b.Len() > 0
+			continue
+		}
+		if hyphen {
+			b.WriteByte('-')
+			hyphen = false
+		}
+		b.WriteString(s)
+	}
+
+	slug := b.String()
+	if len(slug) > maxLen {
+		slug = slug[:maxLen]
+		if i := strings.LastIndexByte(slug, '-'); i > 0 {
+			slug = slug[:i]
+		}
+	}
+	return slug
+}
+
+// numeric reports whether the slug s is digits only
+func numeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,114 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"lowercase", "Yangi Qonun", "yangi-qonun"},
+		{"oʻ and gʻ", "Oʻzbekiston gʻalabasi", "ozbekiston-galabasi"},
+		{"ascii apostrophes", "O'zbekiston g`alabasi", "ozbekiston-galabasi"},
+		{"tutuq belgisi", "Maʼnaviyat va taʼlim", "manaviyat-va-talim"},
+		{"cyrillic", "Ўзбекистон янгиликлари", "ozbekiston-yangiliklari"},
+		{"cyrillic sh ch ng", "Шаҳар чироқлари тонг", "shahar-chiroqlari-tong"},
+		{"cyrillic ye", "Европа ва поезд", "yevropa-va-poyezd"},
+		{"mixed alphabets", "Toshkent — Самарқанд", "toshkent-samarqand"},
+		{"diacritics", "Café Ñandú Zürich", "cafe-nandu-zurich"},
+		{"ligatures", "Straße Œuvre", "strasse-oeuvre"},
+		{"turkish", "Ağrı Dağı ve Şişli", "agri-dagi-ve-sisli"},
+		{"punctuation", "  «Kun.uz»: 10 ta savol?!  ", "kun-uz-10-ta-savol"},
+		{"no double hyphens", "a -- b __ c", "a-b-c"},
+		{"symbols only", "!!! — ???", ""},
+		{"emoji and cjk", "Hello 🌍 世界", "hello"},
+		{"digits", "2024", "2024"},
+		{"digits and letters", "2024-yil", "2024-yil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Make(tt.title); got != tt.want {
+				t.Errorf("Make(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeLength(t *testing.T) {
+	title := strings.Repeat("uzun ", 30)
+	got := Make(title)
+	if len(got) > maxLen {
+		t.Errorf("len(Make) = %d, want at most %d", len(got), maxLen)
+	}
+	if strings.HasSuffix(got, "-") || !strings.HasSuffix(got, "uzun") {
+		t.Errorf("Make is not cut at a hyphen: %q", got)
+	}
+
+	// a single word is cut at maxLen
+	if got := Make(strings.Repeat("a", 100)); got != strings.Repeat("a", maxLen) {
+		t.Errorf("Make(100 letters) = %q", got)
+	}
+}
+
+func TestBase(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Yangi yil", "yangi-yil"},
+		{"", "news-post"},
+		{"?!", "news-post"},
+		// a slug of digits only would be read as an id
+		{"2024", "news-post-2024"},
+		{"2024 — 2025", "2024-2025"},
+		{strings.Repeat("7", 100), "news-post-" + strings.Repeat("7", maxLen)},
+	}
+	for _, tt := range tests {
+		got := NewsPost.base(tt.title)
+		if got != tt.want {
+			t.Errorf("base(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+		if numeric(got) {
+			t.Errorf("base(%q) = %q is numeric", tt.title, got)
+		}
+	}
+
+	if got := BPPost.base("100"); got != "business-promotional-post-100" {
+		t.Errorf("BPPost.base(100) = %q", got)
+	}
+}
+
+func TestSuffixed(t *testing.T) {
+	tests := []struct {
+		base string
+		n    int
+		want string
+	}{
+		{"yangi-yil", 1, "yangi-yil"},
+		{"yangi-yil", 2, "yangi-yil-2"},
+		{"yangi-yil", 10, "yangi-yil-10"},
+		{"news-post-2024", 3, "news-post-2024-3"},
+	}
+	for _, tt := range tests {
+		got := suffixed(tt.base, tt.n)
+		if got != tt.want {
+			t.Errorf("suffixed(%q, %d) = %q, want %q", tt.base, tt.n, got, tt.want)
+		}
+		if numeric(got) {
+			t.Errorf("suffixed(%q, %d) = %q is numeric", tt.base, tt.n, got)
+		}
+	}
+}
+
+func TestNumeric(t *testing.T) {
+	for s, want := range map[string]bool{"": false, "0": true, "2024": true, "2024-2": false, "a1": false, "１２": false} {
+		if got := numeric(s); got != want {
+			t.Errorf("numeric(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
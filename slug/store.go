@@ -0,0 +1,201 @@
+package slug
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"Tahlilchi.uz/db"
+	"github.com/lib/pq"
+)
+
+// Entity is a table of public content with slugs
+type Entity struct {
+	// Type is the entity_type of its redirects
+	Type  string
+	Table string
+}
+
+var (
+	NewsPost     = Entity{Type: "news_post", Table: "news_posts"}
+	Article      = Entity{Type: "article", Table: "articles"}
+	ENewspaper   = Entity{Type: "e_newspaper", Table: "e_newspapers"}
+	PhotoGallery = Entity{Type: "photo_gallery", Table: "photo_gallery"}
+	BPPost       = Entity{Type: "business_promotional_post", Table: "business_promotional_posts"}
+)
+
+// Entities are all the tables with slugs
+var Entities = []Entity{NewsPost, Article, ENewspaper, PhotoGallery, BPPost}
+
+// ErrNotFound is returned by Resolve for a slug of no row
+var ErrNotFound = errors.New("slug: not found")
+
+// assignAttempts is how often Assign tries again when a concurrent request took the same slug
+const assignAttempts = 3
+
+// Assign sets the slug of the row id from its title_latin, the first of slug, slug-2, slug-3 and so on
+// which no other row uses, now or as an old slug. A replaced slug keeps redirecting to the row.
+func (e Entity) Assign(database *sql.DB, id any) error {
+	var err error
+	for i := 0; i < assignAttempts; i++ {
+		err = e.assign(database, id)
+		var pqErr *pq.Error
+		if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+			// anything but a unique_violation
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("assigning the slug of %s %v: %v", e.Type, id, err)
+	}
+	return nil
+}
+
+func (e Entity) assign(database *sql.DB, id any) error {
+	var title string
+	var current sql.NullString
+	err := database.QueryRow("SELECT title_latin, slug FROM "+e.Table+" WHERE id = $1", id).Scan(&title, &current)
+	if err != nil {
+		return err
+	}
+
+	base := e.base(title)
+	candidate := base
+	for n := 2; ; n++ {
+		var taken bool
+		err := database.QueryRow(`SELECT EXISTS(SELECT 1 FROM `+e.Table+` WHERE slug = $1 AND id <> $2)
+			OR EXISTS(SELECT 1 FROM slug_redirects WHERE entity_type = $3 AND slug = $1 AND entity_id <> $2)`, candidate, id, e.Type).Scan(&taken)
+		if err != nil {
+			return err
+		}
+		if !taken {
+			break
+		}
+		candidate = suffixed(base, n)
+	}
+	if current.Valid && current.String == candidate {
+		return nil
+	}
+
+	// Start a new transaction
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if current.Valid {
+		_, err = tx.Exec("INSERT INTO slug_redirects (entity_type, slug, entity_id) VALUES ($1, $2, $3) ON CONFLICT (entity_type, slug) DO UPDATE SET entity_id = EXCLUDED.entity_id",
+			e.Type, current.String, id)
+		if err != nil {
+			return err
+		}
+	}
+	// a slug the row had before is its own again
+	_, err = tx.Exec("DELETE FROM slug_redirects WHERE entity_type = $1 AND slug = $2", e.Type, candidate)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE "+e.Table+" SET slug = $1 WHERE id = $2", candidate, id)
+	if err != nil {
+		return err
+	}
+
+	// Commit the transaction
+	return tx.Commit()
+}
+
+// base returns the slug of title of a row of e. A title without letters or digits gets the type of e as its slug
+// and a title of digits only gets it in front, so that no slug is read as an id.
+func (e Entity) base(title string) string {
+	base := Make(title)
+	prefix := strings.ReplaceAll(e.Type, "_", "-")
+	switch {
+	case base == "":
+		return prefix
+	case numeric(base):
+		return prefix + "-" + base
+	}
+	return base
+}
+
+// suffixed returns the nth candidate slug of base, slug-2 for the second one.
+// It is never numeric, as base is not.
+func suffixed(base string, n int) string {
+	if n < 2 {
+		return base
+	}
+	return fmt.Sprintf("%s-%d", base, n)
+}
+
+// Resolve returns the id of the row with the slug and whether the slug is an old one, which should be redirected to the current slug.
+// cond limits the rows, e.g. to the published ones.
+func (e Entity) Resolve(database *sql.DB, slug, cond string) (id int64, current string, redirect bool, err error) {
+	if cond == "" {
+		cond = "true"
+	}
+	err = database.QueryRow("SELECT id, slug FROM "+e.Table+" WHERE slug = $1 AND "+cond, slug).Scan(&id, &current)
+	if err == nil {
+		return id, current, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, "", false, err
+	}
+
+	err = database.QueryRow("SELECT t.id, t.slug FROM slug_redirects sr JOIN "+e.Table+" t ON t.id = sr.entity_id WHERE sr.entity_type = $1 AND sr.slug = $2 AND t.slug IS NOT NULL AND "+cond,
+		e.Type, slug).Scan(&id, &current)
+	if err == sql.ErrNoRows {
+		return 0, "", false, ErrNotFound
+	}
+	if err != nil {
+		return 0, "", false, err
+	}
+	return id, current, true, nil
+}
+
+// DeleteRedirects removes the old slugs of the deleted row id
+func (e Entity) DeleteRedirects(database *sql.DB, id any) error {
+	_, err := database.Exec("DELETE FROM slug_redirects WHERE entity_type = $1 AND entity_id = $2", e.Type, id)
+	return err
+}
+
+// backfillBatch is how many rows of every table AssignMissing assigns a slug in one run
+const backfillBatch = 500
+
+// AssignMissing assigns the slugs of the rows without one, those added before the slugs,
+// and replaces the slugs of digits only, which the permalinks read as ids. It is run by the scheduler.
+func AssignMissing() error {
+	database, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, e := range Entities {
+		rows, err := database.Query("SELECT id FROM "+e.Table+" WHERE slug IS NULL OR slug ~ '^[0-9]+$' ORDER BY id LIMIT $1", backfillBatch)
+		if err != nil {
+			return err
+		}
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if err := e.Assign(database, id); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,39 @@
+// Package storage keeps the media files of the application
+// in a local directory or in a bucket of an S3-compatible object storage.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"Tahlilchi.uz/config"
+)
+
+// ErrNotExist is returned for a key which is not stored
+var ErrNotExist = errors.New("storage: object does not exist")
+
+// Storage stores objects by key. Keys are slash separated paths without a leading slash.
+type Storage interface {
+	// Put stores size bytes read from r under key
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get returns the content stored under key, the caller closes it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// GetRange returns length bytes of the content stored under key from offset,
+	// up to the end for a negative length. The caller closes it.
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Delete removes the object stored under key, a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// New returns the storage selected by the configuration
+func New(cfg config.Storage) (Storage, error) {
+	switch cfg.Backend {
+	case "local":
+		return NewLocal(cfg.LocalDir)
+	case "s3":
+		return NewS3(cfg.S3)
+	}
+	return nil, fmt.Errorf("storage: unknown backend %q", cfg.Backend)
+}
@@ -2,14 +2,14 @@ package telegramBot
 
 import (
 	"log"
-	"os"
 
+	"Tahlilchi.uz/config"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
 
-func TBot() (*tgbotapi.BotAPI, error) {
+func TBot(cfg config.Telegram) (*tgbotapi.BotAPI, error) {
 	// Create a new Telegram bot
-	bot, err := tgbotapi.NewBotAPI(os.Getenv("TELEGRAM_TOKEN"))
+	bot, err := tgbotapi.NewBotAPI(cfg.Token)
 	if err != nil {
 		return nil, err
 	}
@@ -17,8 +17,8 @@ func TBot() (*tgbotapi.BotAPI, error) {
 	return bot, nil
 }
 
-func ChatID() {
-	bot, err := tgbotapi.NewBotAPI(os.Getenv("TELEGRAM_TOKEN"))
+func ChatID(cfg config.Telegram) {
+	bot, err := tgbotapi.NewBotAPI(cfg.Token)
 	if err != nil {
 		log.Panic(err)
 	}
@@ -0,0 +1,61 @@
+package toolkit
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// proxyHops is set by TrustProxyHeaders, 0 when the proxy headers are not trusted
+var proxyHops int
+
+// TrustProxyHeaders makes ClientIP read the address of the client from X-Forwarded-For and X-Real-IP,
+// hops being the number of reverse proxies in front of the app which append to X-Forwarded-For.
+// It is only to be turned on when the app runs behind such proxies, otherwise any client could fake its address.
+// hops 0 turns it off.
+func TrustProxyHeaders(hops int) {
+	proxyHops = hops
+}
+
+// ClientIP returns the IP address of the client of the request,
+// behind trusted reverse proxies the forwarded one
+func ClientIP(r *http.Request) string {
+	if proxyHops > 0 {
+		if ip := forwardedFor(r); ip != "" {
+			return ip
+		}
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedFor returns the address X-Forwarded-For got from the outermost trusted proxy, "" without a valid one.
+// The entries on the left are sent by the client and can be anything, only the proxyHops entries
+// on the right were appended by the proxies: the leftmost of them is the address the outermost proxy saw.
+func forwardedFor(r *http.Request) string {
+	var entries []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, entry := range strings.Split(header, ",") {
+			entries = append(entries, strings.TrimSpace(entry))
+		}
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+
+	i := len(entries) - proxyHops
+	if i < 0 {
+		i = 0
+	}
+	if net.ParseIP(entries[i]) == nil {
+		return ""
+	}
+	return entries[i]
+}
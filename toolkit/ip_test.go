@@ -0,0 +1,45 @@
+package toolkit
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		hops      int
+		forwarded []string
+		realIP    string
+		want      string
+	}{
+		{"proxy headers not trusted", 0, []string{"203.0.113.7"}, "203.0.113.8", "192.0.2.1"},
+		{"one proxy", 1, []string{"203.0.113.7"}, "", "203.0.113.7"},
+		{"spoofed entry before the proxy one", 1, []string{"10.0.0.1, 203.0.113.7"}, "", "203.0.113.7"},
+		{"spoofed header line before the proxy one", 1, []string{"10.0.0.1", "203.0.113.7"}, "", "203.0.113.7"},
+		{"two proxies", 2, []string{"10.0.0.1, 203.0.113.7, 198.51.100.2"}, "", "203.0.113.7"},
+		{"fewer entries than proxies", 2, []string{"203.0.113.7"}, "", "203.0.113.7"},
+		{"garbage from the proxy", 1, []string{"10.0.0.1, not-an-ip"}, "", "192.0.2.1"},
+		{"ipv6", 1, []string{"2001:db8::1"}, "", "2001:db8::1"},
+		{"x-real-ip without x-forwarded-for", 1, nil, "203.0.113.9", "203.0.113.9"},
+		{"invalid x-real-ip", 1, nil, "not-an-ip", "192.0.2.1"},
+		{"no headers", 1, nil, "", "192.0.2.1"},
+	}
+	defer TrustProxyHeaders(0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			TrustProxyHeaders(tt.hops)
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = "192.0.2.1:54321"
+			for _, f := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", f)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,22 +1,34 @@
 package toolkit
 
 import (
-	"log"
+	"fmt"
 	"net/http"
+
+	"Tahlilchi.uz/logging"
 )
 
 // LogError is a function to log an error inside a route handler function
 // it takes *http.Request, error as its parameters
 // it returns nothing
 func LogError(r *http.Request, err error) {
-	// use log.Printf to log the error in format "%v: error: %v"
-	log.Printf("%v: error: %v", r.URL, err)
+	// log the error with the request id, method, route and admin of the request
+	logging.Logger(r.Context()).Error(err.Error())
 }
 
 // LogInfo is a function to log an info message inside a route handler function
 // it takes *http.Request, string as its parameters
 // it returns nothing
 func LogInfo(r *http.Request, message string) {
-	// use log.Printf to log the message in format "%v: info: %v"
-	log.Printf("%v: info: %v", r.URL, message)
+	// log the message with the request id, method, route and admin of the request
+	logging.Logger(r.Context()).Info(message)
+}
+
+// LogErrorf is like LogError with a message formatted as by fmt.Sprintf
+func LogErrorf(r *http.Request, format string, args ...any) {
+	logging.Logger(r.Context()).Error(fmt.Sprintf(format, args...))
+}
+
+// LogInfof is like LogInfo with a message formatted as by fmt.Sprintf
+func LogInfof(r *http.Request, format string, args ...any) {
+	logging.Logger(r.Context()).Info(fmt.Sprintf(format, args...))
 }
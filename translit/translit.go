@@ -0,0 +1,268 @@
+// Package translit converts Uzbek text between the Latin and the Cyrillic alphabets
+// following the official mapping of the two alphabets.
+package translit
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Alphabets are the names of the two alphabets in the forms and in the API
+const (
+	Latin    = "latin"
+	Cyrillic = "cyrillic"
+)
+
+// apostrophe is the tutuq belgisi written by ToLatin, oʻ and gʻ are written with the turned comma ʻ
+const apostrophe = 'ʼ'
+
+// isApostrophe reports whether r is one of the characters typed for oʻ, gʻ and the tutuq belgisi
+func isApostrophe(r rune) bool {
+	switch r {
+	case '\'', '`', 'ʻ', 'ʼ', '‘', '’':
+		return true
+	}
+	return false
+}
+
+// latinLetters are the Latin letters written with a single Cyrillic letter
+var latinLetters = map[rune]rune{
+	'a': 'а', 'b': 'б', 'c': 'ц', 'd': 'д', 'f': 'ф', 'g': 'г', 'h': 'ҳ', 'i': 'и', 'j': 'ж',
+	'k': 'к', 'l': 'л', 'm': 'м', 'n': 'н', 'o': 'о', 'p': 'п', 'q': 'қ', 'r': 'р', 's': 'с',
+	't': 'т', 'u': 'у', 'v': 'в', 'x': 'х', 'y': 'й', 'z': 'з',
+}
+
+// latinApostrophed are the Latin letters followed by an apostrophe, oʻ and gʻ
+var latinApostrophed = map[rune]rune{'o': 'ў', 'g': 'ғ'}
+
+// latinDigraphs are the pairs of Latin letters written with a single Cyrillic letter
+var latinDigraphs = map[string]rune{
+	"sh": 'ш', "ch": 'ч', "ye": 'е', "yo": 'ё', "yu": 'ю', "ya": 'я',
+}
+
+// cyrillicLetters are the Cyrillic letters but е and ц, whose Latin spelling depends on their position
+var cyrillicLetters = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ғ': "gʻ", 'д': "d", 'ё': "yo", 'ж': "j", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'қ': "q", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'ў': "oʻ",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "x", 'ҳ': "h", 'ч': "ch",
+	'ш': "sh", 'щ': "sh", 'ъ': string(apostrophe), 'ь': "", 'ы': "i", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// ToCyrillic converts the Latin text s to Cyrillic. The characters of neither alphabet are kept.
+func ToCyrillic(s string) string {
+	return toCyrillic(s, false)
+}
+
+// ToLatin converts the Cyrillic text s to Latin. The characters of neither alphabet are kept.
+func ToLatin(s string) string {
+	return toLatin(s, false)
+}
+
+// ToCyrillicHTML is ToCyrillic for HTML, the tags and the character references are kept as they are
+func ToCyrillicHTML(s string) string {
+	return toCyrillic(s, true)
+}
+
+// ToLatinHTML is ToLatin for HTML, the tags and the character references are kept as they are
+func ToLatinHTML(s string) string {
+	return toLatin(s, true)
+}
+
+// To converts s to the alphabet to, Latin or Cyrillic, and reports whether to is one of them
+func To(to, s string, html bool) (string, bool) {
+	switch to {
+	case Latin:
+		return toLatin(s, html), true
+	case Cyrillic:
+		return toCyrillic(s, html), true
+	}
+	return "", false
+}
+
+func toCyrillic(s string, html bool) string {
+	rs := []rune(s)
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i := 0; i < len(rs); {
+		if html {
+			if n := markup(rs[i:]); n > 0 {
+				b.WriteString(string(rs[i : i+n]))
+				i += n
+				continue
+			}
+		}
+
+		r := rs[i]
+		lower := unicode.ToLower(r)
+		upper := unicode.IsUpper(r)
+		next := at(rs, i+1)
+
+		switch {
+		// oʻ and gʻ with any apostrophe
+		case (lower == 'o' || lower == 'g') && isApostrophe(next):
+			b.WriteRune(withCase(latinApostrophed[lower], upper))
+			i += 2
+			continue
+
+		// the tutuq belgisi separating s and h, which are not sh: Isʼhoq
+		case lower == 's' && isApostrophe(next) && unicode.ToLower(at(rs, i+2)) == 'h':
+			b.WriteRune(withCase('с', upper))
+			b.WriteRune(withCase('ҳ', unicode.IsUpper(at(rs, i+2))))
+			i += 3
+			continue
+
+		// yo followed by an apostrophe is y and oʻ: yoʻl
+		case lower == 'y' && unicode.ToLower(next) == 'o' && isApostrophe(at(rs, i+2)):
+
+		default:
+			if c, ok := latinDigraphs[string([]rune{lower, unicode.ToLower(next)})]; ok {
+				b.WriteRune(withCase(c, upper))
+				i += 2
+				continue
+			}
+		}
+
+		switch {
+		case lower == 'e':
+			// э at the beginning of a word and after a vowel, е elsewhere
+			c := 'е'
+			if prev := unicode.ToLower(at(rs, i-1)); !unicode.IsLetter(prev) || strings.ContainsRune("aeiou", prev) {
+				c = 'э'
+			}
+			b.WriteRune(withCase(c, upper))
+		case isApostrophe(r) && unicode.IsLetter(at(rs, i-1)) && unicode.IsLetter(next):
+			// the tutuq belgisi inside a word, the other apostrophes are quotes
+			b.WriteRune('ъ')
+		default:
+			if c, ok := latinLetters[lower]; ok {
+				b.WriteRune(withCase(c, upper))
+			} else {
+				b.WriteRune(r)
+			}
+		}
+		i++
+	}
+	return b.String()
+}
+
+func toLatin(s string, html bool) string {
+	rs := []rune(s)
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i := 0; i < len(rs); {
+		if html {
+			if n := markup(rs[i:]); n > 0 {
+				b.WriteString(string(rs[i : i+n]))
+				i += n
+				continue
+			}
+		}
+
+		r := rs[i]
+		lower := unicode.ToLower(r)
+		prev := unicode.ToLower(at(rs, i-1))
+
+		var l string
+		switch lower {
+		case 'е':
+			// ye at the beginning of a word and after a vowel or a sign, e elsewhere
+			l = "e"
+			if !isCyrillic(prev) || strings.ContainsRune("аеёиоуўэюяъь", prev) {
+				l = "ye"
+			}
+		case 'ц':
+			// ts after a vowel, s at the beginning of a word and after a consonant
+			l = "s"
+			if strings.ContainsRune("аеёиоуўэюя", prev) {
+				l = "ts"
+			}
+		case 'с':
+			l = "s"
+			if unicode.ToLower(at(rs, i+1)) == 'ҳ' {
+				// keep s and h apart from sh
+				l = "s" + string(apostrophe)
+			}
+		default:
+			var ok bool
+			if l, ok = cyrillicLetters[lower]; !ok {
+				b.WriteRune(r)
+				i++
+				continue
+			}
+		}
+
+		if unicode.IsUpper(r) && l != "" {
+			l = upperLatin(l, rs, i)
+		}
+		b.WriteString(l)
+		i++
+	}
+	return b.String()
+}
+
+// upperLatin returns the Latin spelling l of the uppercase letter rs[i]:
+// all uppercase inside an uppercase word, SHAHAR, only the first letter uppercase otherwise, Shahar
+func upperLatin(l string, rs []rune, i int) string {
+	next, prev := at(rs, i+1), at(rs, i-1)
+	if unicode.IsUpper(next) || (!unicode.IsLetter(next) && unicode.IsUpper(prev)) {
+		return strings.ToUpper(l)
+	}
+	r, size := utf8.DecodeRuneInString(l)
+	return string(unicode.ToUpper(r)) + l[size:]
+}
+
+// withCase returns the Cyrillic letter c in uppercase when upper
+func withCase(c rune, upper bool) rune {
+	if upper {
+		return unicode.ToUpper(c)
+	}
+	return c
+}
+
+// isCyrillic reports whether r is a letter of the Cyrillic alphabet
+func isCyrillic(r rune) bool {
+	return unicode.Is(unicode.Cyrillic, r)
+}
+
+// at returns rs[i], 0 outside rs
+func at(rs []rune, i int) rune {
+	if i < 0 || i >= len(rs) {
+		return 0
+	}
+	return rs[i]
+}
+
+// maxReference is the length limit of a character reference, e.g. &laquo; or &#8212;
+const maxReference = 10
+
+// markup returns the length of the HTML tag or character reference at the start of rs, 0 for text
+func markup(rs []rune) int {
+	switch rs[0] {
+	case '<':
+		for j := 1; j < len(rs); j++ {
+			switch rs[j] {
+			case '>':
+				return j + 1
+			case '<':
+				// a less-than sign of the text
+				return 0
+			}
+		}
+	case '&':
+		for j := 1; j < len(rs) && j <= maxReference; j++ {
+			if rs[j] == ';' {
+				if j > 1 {
+					return j + 1
+				}
+				return 0
+			}
+			if !unicode.IsLetter(rs[j]) && !unicode.IsDigit(rs[j]) && rs[j] != '#' {
+				return 0
+			}
+		}
+	}
+	return 0
+}
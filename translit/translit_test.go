@@ -0,0 +1,199 @@
+package translit
+
+import "testing"
+
+func TestToCyrillic(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"single letters", "bola", "бола"},
+		{"q x h", "qaxramon hovli", "қахрамон ҳовли"},
+		{"j", "jahon", "жаҳон"},
+		{"oʻ turned comma", "Oʻzbekiston", "Ўзбекистон"},
+		{"gʻ turned comma", "gʻalaba", "ғалаба"},
+		{"oʻ ascii apostrophe", "o'zbek", "ўзбек"},
+		{"gʻ right quotation mark", "g’oya", "ғоя"},
+		{"oʻ backtick", "o`qituvchi", "ўқитувчи"},
+		{"oʻ modifier apostrophe", "oʼrta", "ўрта"},
+		{"oʻ uppercase", "OʻZBEKISTON", "ЎЗБЕКИСТОН"},
+		{"gʻ uppercase", "Gʻafur Gʻulom", "Ғафур Ғулом"},
+		{"sh", "shahar", "шаҳар"},
+		{"sh uppercase", "Shahar", "Шаҳар"},
+		{"sh all caps", "SHAHAR", "ШАҲАР"},
+		{"ch", "choy", "чой"},
+		{"ch inside", "kuchli", "кучли"},
+		{"ng", "tong", "тонг"},
+		{"ng before vowel", "yangi", "янги"},
+		{"ye beginning", "yer", "ер"},
+		{"ye inside", "poyezd", "поезд"},
+		{"ye uppercase", "Yevropa", "Европа"},
+		{"yo", "yosh", "ёш"},
+		{"yo uppercase", "Yoshlar", "Ёшлар"},
+		{"yu", "yulduz", "юлдуз"},
+		{"ya", "dunyo va daryo", "дунё ва дарё"},
+		{"ya inside", "dunya", "дуня"},
+		{"y and oʻ", "yoʻl", "йўл"},
+		{"y and oʻ uppercase", "Yoʻldosh", "Йўлдош"},
+		{"y before consonant", "bayroq", "байроқ"},
+		{"e beginning", "ekran", "экран"},
+		{"e uppercase beginning", "Ekran", "Экран"},
+		{"e after vowel", "poeziya", "поэзия"},
+		{"e after consonant", "kecha", "кеча"},
+		{"tutuq", "maʼno", "маъно"},
+		{"tutuq ascii apostrophe", "ta'lim", "таълим"},
+		{"tutuq after n", "sanʼat", "санъат"},
+		{"s and h apart", "Isʼhoq", "Исҳоқ"},
+		{"s and h apart ascii", "is'hoq", "исҳоқ"},
+		{"quotes kept", "'Toshkent'", "'Тошкент'"},
+		{"digits and punctuation kept", "2024-yil, 5-may!", "2024-йил, 5-май!"},
+		{"cyrillic kept", "Тошкент", "Тошкент"},
+		{"sentence", "Oʻzbekiston Respublikasi Prezidenti yangi qaror qabul qildi.", "Ўзбекистон Республикаси Президенти янги қарор қабул қилди."},
+		{"mirzo ulugʻbek", "Mirzo Ulugʻbek", "Мирзо Улуғбек"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToCyrillic(tt.in); got != tt.want {
+				t.Errorf("ToCyrillic(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToLatin(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"single letters", "бола", "bola"},
+		{"қ х ҳ", "қахрамон ҳовли", "qaxramon hovli"},
+		{"ж", "жаҳон", "jahon"},
+		{"ў", "Ўзбекистон", "Oʻzbekiston"},
+		{"ғ", "ғалаба", "gʻalaba"},
+		{"ў all caps", "ЎЗБЕКИСТОН", "OʻZBEKISTON"},
+		{"ш", "шаҳар", "shahar"},
+		{"ш uppercase", "Шаҳар", "Shahar"},
+		{"ш all caps", "ШАҲАР", "SHAHAR"},
+		{"ш single uppercase letter", "Ш", "Sh"},
+		{"ч", "чой", "choy"},
+		{"ч all caps", "ЧОЙ", "CHOY"},
+		{"щ", "щётка", "shyotka"},
+		{"нг", "тонг", "tong"},
+		{"е beginning", "ер", "yer"},
+		{"е uppercase beginning", "Европа", "Yevropa"},
+		{"е all caps beginning", "ЕР", "YER"},
+		{"е after vowel", "поезд", "poyezd"},
+		{"е after consonant", "кеча", "kecha"},
+		{"е after ъ", "объект", "obʼyekt"},
+		{"е after ь", "премьер", "premyer"},
+		{"ё", "ёш", "yosh"},
+		{"ё uppercase", "Ёшлар", "Yoshlar"},
+		{"ю", "юлдуз", "yulduz"},
+		{"я", "дунё ва дарё", "dunyo va daryo"},
+		{"я uppercase", "Янги", "Yangi"},
+		{"э", "экран", "ekran"},
+		{"э after vowel", "поэзия", "poeziya"},
+		{"ц beginning", "цирк", "sirk"},
+		{"ц after consonant", "концерт", "konsert"},
+		{"ц after vowel", "милиция", "militsiya"},
+		{"ц uppercase beginning", "Цемент", "Sement"},
+		{"ъ", "маъно", "maʼno"},
+		{"ъ after н", "санъат", "sanʼat"},
+		{"ь dropped", "мебель", "mebel"},
+		{"ь uppercase dropped", "МЕБЕЛЬ", "MEBEL"},
+		{"ы", "тыл", "til"},
+		{"с and ҳ apart", "Исҳоқ", "Isʼhoq"},
+		{"digits and punctuation kept", "2024 йил, 5 май!", "2024 yil, 5 may!"},
+		{"latin kept", "Toshkent", "Toshkent"},
+		{"sentence", "Ўзбекистон Республикаси Президенти янги қарор қабул қилди.", "Oʻzbekiston Respublikasi Prezidenti yangi qaror qabul qildi."},
+		{"мирзо улуғбек", "Мирзо Улуғбек", "Mirzo Ulugʻbek"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToLatin(tt.in); got != tt.want {
+				t.Errorf("ToLatin(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []string{
+		"Oʻzbekiston",
+		"gʻalaba",
+		"Toshkent shahri",
+		"yangiliklar",
+		"poyezd",
+		"Yevropa",
+		"ekran",
+		"poeziya",
+		"maʼno",
+		"Isʼhoq",
+		"yoʻl",
+		"SHAHAR",
+		"Mirzo Ulugʻbek",
+	}
+	for _, latin := range tests {
+		t.Run(latin, func(t *testing.T) {
+			if got := ToLatin(ToCyrillic(latin)); got != latin {
+				t.Errorf("ToLatin(ToCyrillic(%q)) = %q", latin, got)
+			}
+		})
+	}
+}
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(string) string
+		in   string
+		want string
+	}{
+		{"tags kept", ToCyrillicHTML, `<p class="lead">shahar</p>`, `<p class="lead">шаҳар</p>`},
+		{"link kept", ToCyrillicHTML, `<a href="https://tahlilchi.uz/news">yangilik</a>`, `<a href="https://tahlilchi.uz/news">янгилик</a>`},
+		{"entities kept", ToCyrillicHTML, "&laquo;Oʻzbekiston&raquo;&nbsp;&#8212; vatan", "&laquo;Ўзбекистон&raquo;&nbsp;&#8212; ватан"},
+		{"e after a tag begins a word", ToCyrillicHTML, "<b>ekran</b>", "<b>экран</b>"},
+		{"less-than sign of the text", ToCyrillicHTML, "a < b <i>va</i>", "а < б <i>ва</i>"},
+		{"ampersand of the text", ToCyrillicHTML, "Ali & Vali", "Али & Вали"},
+		{"cyrillic tags kept", ToLatinHTML, `<p class="lead">шаҳар</p>`, `<p class="lead">shahar</p>`},
+		{"cyrillic entities kept", ToLatinHTML, "&laquo;Ўзбекистон&raquo;", "&laquo;Oʻzbekiston&raquo;"},
+		{"е after a tag begins a word", ToLatinHTML, "<b>ер</b>", "<b>yer</b>"},
+		{"plain text converts tags", ToCyrillic, "<b>", "<б>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.fn(tt.in); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTo(t *testing.T) {
+	tests := []struct {
+		to   string
+		in   string
+		html bool
+		want string
+		ok   bool
+	}{
+		{Cyrillic, "shahar", false, "шаҳар", true},
+		{Latin, "шаҳар", false, "shahar", true},
+		{Cyrillic, "<p>shahar</p>", true, "<p>шаҳар</p>", true},
+		{Latin, "<p>шаҳар</p>", true, "<p>shahar</p>", true},
+		{"greek", "shahar", false, "", false},
+		{"", "shahar", false, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.to+" "+tt.in, func(t *testing.T) {
+			got, ok := To(tt.to, tt.in, tt.html)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("To(%q, %q, %v) = %q, %v, want %q, %v", tt.to, tt.in, tt.html, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+)
+
+// sniffLen is how many bytes of a file DetectContentType reads
+const sniffLen = 512
+
+// DetectContentType returns the MIME type of a file by its magic bytes.
+// It recognizes the audio and video formats http.DetectContentType does not,
+// MP3 without an ID3 tag, AAC and the MP4 brands, and falls back to http.DetectContentType.
+func DetectContentType(b []byte) string {
+	if len(b) > sniffLen {
+		b = b[:sniffLen]
+	}
+
+	switch {
+	case bytes.HasPrefix(b, []byte("%PDF-")):
+		return "application/pdf"
+	case bytes.HasPrefix(b, []byte("\xFF\xD8\xFF")):
+		return "image/jpeg"
+	case bytes.HasPrefix(b, []byte("\x89PNG\r\n\x1A\n")):
+		return "image/png"
+	case bytes.HasPrefix(b, []byte("GIF87a")), bytes.HasPrefix(b, []byte("GIF89a")):
+		return "image/gif"
+	case len(b) >= 12 && bytes.Equal(b[:4], []byte("RIFF")) && bytes.Equal(b[8:12], []byte("WEBP")):
+		return "image/webp"
+	case len(b) >= 12 && bytes.Equal(b[:4], []byte("RIFF")) && bytes.Equal(b[8:12], []byte("WAVE")):
+		return "audio/wave"
+	case bytes.HasPrefix(b, []byte("ID3")):
+		return "audio/mpeg"
+	case bytes.HasPrefix(b, []byte("OggS")):
+		return "audio/ogg"
+	case bytes.HasPrefix(b, []byte("\x1A\x45\xDF\xA3")):
+		// EBML, the DocType of a WebM file is in its header
+		if bytes.Contains(b, []byte("webm")) {
+			return "video/webm"
+		}
+		return "video/x-matroska"
+	case len(b) >= 12 && bytes.Equal(b[4:8], []byte("ftyp")):
+		return ftypContentType(b)
+	case len(b) >= 2 && b[0] == 0xFF && b[1]&0xF6 == 0xF0:
+		// ADTS frame sync with layer 0
+		return "audio/aac"
+	case len(b) >= 2 && b[0] == 0xFF && b[1]&0xE0 == 0xE0 && b[1]&0x06 != 0:
+		// MPEG audio frame sync with layer I, II or III
+		return "audio/mpeg"
+	}
+	return http.DetectContentType(b)
+}
+
+// ftypContentType returns the MIME type of an ISO base media file b by the major brand of its ftyp box,
+// or by the first compatible brand it knows. It is application/octet-stream without a brand it knows.
+func ftypContentType(b []byte) string {
+	end := int(binary.BigEndian.Uint32(b[:4]))
+	if end > len(b) || end < 12 {
+		end = len(b)
+	}
+	// the major brand, the minor version and the compatible brands
+	major := string(b[8:12])
+	if t := brandContentType(major); t != "" {
+		return t
+	}
+	for i := 16; i+4 <= end; i += 4 {
+		if t := brandContentType(string(b[i : i+4])); t != "" {
+			return t
+		}
+	}
+	return "application/octet-stream"
+}
+
+// brandContentType returns the MIME type of an ISO base media file brand, "" for a brand it does not know
+func brandContentType(brand string) string {
+	switch brand {
+	case "isom", "iso2", "iso3", "iso4", "iso5", "iso6", "mp41", "mp42", "mp71", "avc1", "dash", "M4V ", "M4VH", "M4VP", "MSNV", "f4v ", "mmp4":
+		return "video/mp4"
+	case "M4A ", "M4B ", "M4P ", "F4A ", "F4B ":
+		return "audio/mp4"
+	case "qt  ":
+		return "video/quicktime"
+	case "3gp4", "3gp5", "3gp6", "3gp7", "3ge6", "3ge7", "3gg6":
+		return "video/3gpp"
+	case "3g2a", "3g2b", "3g2c":
+		return "video/3gpp2"
+	case "heic", "heix", "heim", "heis", "hevc", "hevx", "mif1", "msf1":
+		return "image/heic"
+	case "avif", "avis":
+		return "image/avif"
+	}
+	return ""
+}
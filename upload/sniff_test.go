@@ -0,0 +1,99 @@
+package upload
+
+import (
+	"bytes"
+	"testing"
+)
+
+// ftyp returns the start of an ISO base media file with the major brand and the compatible brands
+func ftyp(major string, compatible ...string) []byte {
+	box := []byte(major + "\x00\x00\x02\x00")
+	for _, c := range compatible {
+		box = append(box, c...)
+	}
+	size := len(box) + 8
+	b := []byte{byte(size >> 24), byte(size >> 16), byte(size >> 8), byte(size)}
+	b = append(b, "ftyp"...)
+	b = append(b, box...)
+	// the next box
+	return append(b, "\x00\x00\x00\x08free"...)
+}
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{"pdf", []byte("%PDF-1.7\n%\xE2\xE3\xCF\xD3"), "application/pdf"},
+		{"jpeg", []byte("\xFF\xD8\xFF\xE0\x00\x10JFIF\x00"), "image/jpeg"},
+		{"png", []byte("\x89PNG\r\n\x1A\n\x00\x00\x00\rIHDR"), "image/png"},
+		{"gif87a", []byte("GIF87a\x01\x00\x01\x00"), "image/gif"},
+		{"gif89a", []byte("GIF89a\x01\x00\x01\x00"), "image/gif"},
+		{"webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), "image/webp"},
+		{"wave", []byte("RIFF\x24\x00\x00\x00WAVEfmt "), "audio/wave"},
+		{"mp3 with id3", []byte("ID3\x04\x00\x00\x00\x00\x00\x00"), "audio/mpeg"},
+		{"mp3 frame", []byte("\xFF\xFB\x90\x44\x00\x00"), "audio/mpeg"},
+		{"mp2 frame", []byte("\xFF\xFD\x90\x44\x00\x00"), "audio/mpeg"},
+		{"aac adts", []byte("\xFF\xF1\x50\x80\x00\x1F\xFC"), "audio/aac"},
+		{"aac adts mpeg-2", []byte("\xFF\xF9\x50\x80\x00\x1F\xFC"), "audio/aac"},
+		{"ogg", []byte("OggS\x00\x02\x00\x00"), "audio/ogg"},
+		{"webm", []byte("\x1A\x45\xDF\xA3\x9F\x42\x86\x81\x01\x42\x82\x84webm"), "video/webm"},
+		{"matroska", []byte("\x1A\x45\xDF\xA3\xA3\x42\x86\x81\x01\x42\x82\x88matroska"), "video/x-matroska"},
+		{"mp4 isom", ftyp("isom", "isom", "iso2", "avc1", "mp41"), "video/mp4"},
+		{"mp4 mp42", ftyp("mp42", "mp42", "isom"), "video/mp4"},
+		{"m4a", ftyp("M4A ", "M4A ", "mp42", "isom"), "audio/mp4"},
+		{"quicktime", ftyp("qt  ", "qt  "), "video/quicktime"},
+		{"3gp", ftyp("3gp5", "3gp5", "isom"), "video/3gpp"},
+		{"3g2", ftyp("3g2a", "3g2a"), "video/3gpp2"},
+		{"heic", ftyp("heic", "mif1", "heic"), "image/heic"},
+		{"avif", ftyp("avif", "avif", "mif1", "miaf"), "image/avif"},
+		{"unknown major brand, known compatible one", ftyp("xxxx", "yyyy", "isom"), "video/mp4"},
+		{"unknown brands", ftyp("xxxx", "yyyy"), "application/octet-stream"},
+		{"brand of the next box not read", append(ftyp("xxxx"), "isom"...), "application/octet-stream"},
+		{"html", []byte("<!DOCTYPE html><html>"), "text/html; charset=utf-8"},
+		{"text", []byte("plain text"), "text/plain; charset=utf-8"},
+		{"empty", nil, "text/plain; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectContentType(tt.in); got != tt.want {
+				t.Errorf("DetectContentType(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectContentTypeTruncated(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{"riff without its type", []byte("RIFF\x24\x00"), "application/octet-stream"},
+		{"ftyp without its brand", []byte("\x00\x00\x00\x18ftyp"), "application/octet-stream"},
+		{"ftyp with a part of its brand", []byte("\x00\x00\x00\x18ftypis"), "application/octet-stream"},
+		{"ftyp box larger than the input", []byte("\x00\x00\x01\x00ftypxxxx\x00\x00\x00\x00isom"), "video/mp4"},
+		{"ftyp box size too small", []byte("\x00\x00\x00\x04ftypxxxx\x00\x00\x00\x00isom"), "video/mp4"},
+		// the short inputs fall back to http.DetectContentType, which finds no binary bytes in them
+		{"one byte of a frame sync", []byte("\xFF"), "text/plain; charset=utf-8"},
+		{"jpeg magic only", []byte("\xFF\xD8\xFF"), "image/jpeg"},
+		{"part of the png magic", []byte("\x89PNG"), "text/plain; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectContentType(tt.in); got != tt.want {
+				t.Errorf("DetectContentType(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectContentTypeReadsSniffLen(t *testing.T) {
+	// the DocType after sniffLen bytes is not read
+	b := append([]byte("\x1A\x45\xDF\xA3"), bytes.Repeat([]byte{0}, sniffLen)...)
+	b = append(b, "webm"...)
+	if got := DetectContentType(b); got != "video/x-matroska" {
+		t.Errorf("DetectContentType() = %q, want video/x-matroska", got)
+	}
+}
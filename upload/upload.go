@@ -0,0 +1,190 @@
+// Package upload validates the uploaded files. The format of a file is detected by its content,
+// never by the Content-Type of its multipart header, and checked against the formats accepted by its field.
+// The size of the request body and of every file is limited.
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"time"
+
+	"Tahlilchi.uz/response"
+	"Tahlilchi.uz/toolkit"
+)
+
+// Kind is the formats accepted by an upload field and the size limit of its files
+type Kind struct {
+	// Name is the kind in the metrics: photo, audio, pdf or video
+	Name string
+	// Description is the accepted formats in the error messages
+	Description string
+	Types       []string
+	MaxSize     int64
+}
+
+var (
+	// PDF is the kind of the files of the e-newspapers
+	PDF = Kind{Name: "pdf", Description: "a PDF document", Types: []string{"application/pdf"}, MaxSize: 50 << 20}
+	// Image is the kind of the photos and the cover images
+	Image = Kind{Name: "photo", Description: "a JPEG, PNG or WebP image", Types: []string{"image/jpeg", "image/png", "image/webp"}, MaxSize: 20 << 20}
+	// Audio is the kind of the audio of the news posts, AAC either raw (ADTS) or in an M4A file
+	Audio = Kind{Name: "audio", Description: "an MP3, AAC or OGG audio", Types: []string{"audio/mpeg", "audio/aac", "audio/mp4", "audio/ogg"}, MaxSize: 100 << 20}
+	// Video is the kind of the videos of the appeals
+	Video = Kind{Name: "video", Description: "an MP4 or WebM video", Types: []string{"video/mp4", "video/webm"}, MaxSize: 100 << 20}
+)
+
+// Max returns the kind with the size limit n, for a field with a limit of its own
+func (k Kind) Max(n int64) Kind {
+	k.MaxSize = n
+	return k
+}
+
+// Error is a rejected upload, answered with Status and Message by Fail
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// maxMemory is how much of a multipart form is kept in memory, the rest of the files go to temporary files
+const maxMemory = 32 << 20
+
+// timeout is how long an upload request is given to be read and answered, see SetTimeout
+var timeout = 15 * time.Minute
+
+// rejected is called for every rejected upload, see OnRejection
+var rejected = func(kind string, status int) {}
+
+// OnRejection sets the function called for every rejected upload with the status of its response and the kind of the file,
+// or "request" for a request body over its limit
+func OnRejection(f func(kind string, status int)) {
+	rejected = f
+}
+
+// SetTimeout sets how long an upload request is given to be read and answered.
+// It replaces the read and write timeouts of the server, which are too short for a large file.
+func SetTimeout(d time.Duration) {
+	timeout = d
+}
+
+// ParseForm parses the multipart form of r with the request body limited to maxRequest bytes.
+// The request is given the upload timeout to be read and answered.
+func ParseForm(w http.ResponseWriter, r *http.Request, maxRequest int64) error {
+	// a ResponseWriter without deadlines, e.g. of the tests, is only given the timeouts of the server
+	if err := extendDeadlines(w, time.Now().Add(timeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequest)
+	err := r.ParseMultipartForm(maxMemory)
+
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		rejected("request", http.StatusRequestEntityTooLarge)
+		return &Error{Status: http.StatusRequestEntityTooLarge, Message: "request is larger than " + formatSize(maxRequest)}
+	case errors.Is(err, http.ErrNotMultipart), errors.Is(err, http.ErrMissingBoundary):
+		return &Error{Status: http.StatusBadRequest, Message: "request is not a multipart form"}
+	}
+	return err
+}
+
+// extendDeadlines sets the read and write deadlines of the connection of w
+func extendDeadlines(w http.ResponseWriter, deadline time.Time) error {
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(deadline); err != nil {
+		return fmt.Errorf("setting the read deadline: %w", err)
+	}
+	if err := rc.SetWriteDeadline(deadline); err != nil {
+		return fmt.Errorf("setting the write deadline: %w", err)
+	}
+	return nil
+}
+
+// File is an uploaded file of the parsed multipart form which passed the checks of its kind.
+// Its content is not read into memory, Open streams it from the form.
+type File struct {
+	Header *multipart.FileHeader
+	// ContentType is the MIME type detected from the content
+	ContentType string
+}
+
+// Open opens the content of the file
+func (f *File) Open() (multipart.File, error) {
+	return f.Header.Open()
+}
+
+// Size returns the size of the file in bytes
+func (f *File) Size() int64 {
+	return f.Header.Size
+}
+
+// Name returns the file name sent by the client
+func (f *File) Name() string {
+	return f.Header.Filename
+}
+
+// FormFile returns the file of field of the parsed form after checking its format and size.
+// A missing file is not an error, the file is nil.
+func FormFile(r *http.Request, field string, kind Kind) (*File, error) {
+	f, fh, err := r.FormFile(field)
+	if err == http.ErrMissingFile {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", field, err)
+	}
+	f.Close()
+
+	return Check(fh, field, kind)
+}
+
+// Check checks the format and the size of a file uploaded in field.
+// Only the first bytes of the file are read, to detect its format.
+func Check(fh *multipart.FileHeader, field string, kind Kind) (*File, error) {
+	if fh.Size > kind.MaxSize {
+		rejected(kind.Name, http.StatusRequestEntityTooLarge)
+		return nil, &Error{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("%s %q is larger than %s", field, fh.Filename, formatSize(kind.MaxSize))}
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", field, err)
+	}
+	defer f.Close()
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("%s: %v", field, err)
+	}
+
+	contentType := DetectContentType(head[:n])
+	if !slices.Contains(kind.Types, contentType) {
+		rejected(kind.Name, http.StatusUnsupportedMediaType)
+		return nil, &Error{Status: http.StatusUnsupportedMediaType, Message: fmt.Sprintf("%s %q must be %s", field, fh.Filename, kind.Description)}
+	}
+	return &File{Header: fh, ContentType: contentType}, nil
+}
+
+// Fail answers the request with the status and the message of an upload Error, 500 for any other error
+func Fail(w http.ResponseWriter, r *http.Request, err error) {
+	var e *Error
+	if errors.As(err, &e) {
+		toolkit.LogInfo(r, e.Message)
+		response.Res(w, "error", e.Status, e.Message)
+		return
+	}
+	toolkit.LogError(r, err)
+	response.Res(w, "error", http.StatusInternalServerError, "server error")
+}
+
+func formatSize(n int64) string {
+	return fmt.Sprintf("%d MB", n>>20)
+}
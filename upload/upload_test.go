@@ -0,0 +1,176 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// pngFile is the start of a PNG file
+var pngFile = []byte("\x89PNG\r\n\x1A\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x02\x00\x00\x00")
+
+// formRequest returns a multipart request with the files, field name to content
+func formRequest(t *testing.T, files map[string][]byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for field, content := range files {
+		fw, err := mw.CreateFormFile(field, field+".bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(content)
+	}
+	mw.Close()
+
+	r := httptest.NewRequest("POST", "/", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+// status returns the status of an upload Error, 0 for nil and 500 for another error
+func status(err error) int {
+	if err == nil {
+		return 0
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Status
+	}
+	return http.StatusInternalServerError
+}
+
+func TestFormFile(t *testing.T) {
+	large := append(append([]byte{}, pngFile...), make([]byte, 2048)...)
+
+	tests := []struct {
+		name   string
+		file   []byte
+		kind   Kind
+		status int
+	}{
+		{"accepted", pngFile, Image, 0},
+		{"larger than the kind", large, Image.Max(1024), http.StatusRequestEntityTooLarge},
+		{"exactly the size of the kind", large, Image.Max(int64(len(large))), 0},
+		{"format of another kind", []byte("%PDF-1.7\n"), Image, http.StatusUnsupportedMediaType},
+		{"unknown ftyp brand", ftyp("xxxx", "yyyy"), Video, http.StatusUnsupportedMediaType},
+		{"empty file", []byte{}, Image, http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := formRequest(t, map[string][]byte{"photo": tt.file})
+			if err := ParseForm(w, r, 1<<20); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := FormFile(r, "photo", tt.kind)
+			if got := status(err); got != tt.status {
+				t.Fatalf("FormFile() error %v, status %d, want %d", err, got, tt.status)
+			}
+			if err != nil {
+				return
+			}
+
+			if f.Size() != int64(len(tt.file)) || f.Name() != "photo.bin" || f.ContentType != "image/png" {
+				t.Errorf("FormFile() = size %d, name %q, type %q", f.Size(), f.Name(), f.ContentType)
+			}
+			// the whole content is streamed from the form, not only the sniffed bytes
+			content, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer content.Close()
+			b, err := io.ReadAll(content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b, tt.file) {
+				t.Errorf("content of %d bytes, want %d", len(b), len(tt.file))
+			}
+		})
+	}
+}
+
+func TestFormFileMissing(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := formRequest(t, map[string][]byte{"other": pngFile})
+	if err := ParseForm(w, r, 1<<20); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := FormFile(r, "photo", Image)
+	if f != nil || err != nil {
+		t.Errorf("FormFile() = %v, %v, want nil, nil", f, err)
+	}
+}
+
+func TestParseForm(t *testing.T) {
+	t.Run("request too large", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := formRequest(t, map[string][]byte{"photo": make([]byte, 4096)})
+		if got := status(ParseForm(w, r, 1024)); got != http.StatusRequestEntityTooLarge {
+			t.Errorf("status %d, want %d", got, http.StatusRequestEntityTooLarge)
+		}
+	})
+
+	t.Run("not multipart", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", bytes.NewReader([]byte("a=b")))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if got := status(ParseForm(w, r, 1024)); got != http.StatusBadRequest {
+			t.Errorf("status %d, want %d", got, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestOnRejection(t *testing.T) {
+	var got []string
+	OnRejection(func(kind string, status int) {
+		got = append(got, fmt.Sprintf("%s %d", kind, status))
+	})
+	t.Cleanup(func() { OnRejection(func(string, int) {}) })
+
+	r := formRequest(t, map[string][]byte{"photo": make([]byte, 4096)})
+	ParseForm(httptest.NewRecorder(), r, 1024)
+
+	r = formRequest(t, map[string][]byte{"photo": pngFile, "audio": []byte("%PDF-1.7\n")})
+	if err := ParseForm(httptest.NewRecorder(), r, 1<<20); err != nil {
+		t.Fatal(err)
+	}
+	FormFile(r, "photo", Image)
+	FormFile(r, "photo", Image.Max(8))
+	FormFile(r, "audio", Audio)
+
+	want := []string{"request 413", "photo 413", "audio 415"}
+	if !slices.Equal(got, want) {
+		t.Errorf("rejections %q, want %q", got, want)
+	}
+}
+
+func TestFail(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"too large", &Error{Status: http.StatusRequestEntityTooLarge, Message: "too large"}, http.StatusRequestEntityTooLarge},
+		{"unsupported", &Error{Status: http.StatusUnsupportedMediaType, Message: "unsupported"}, http.StatusUnsupportedMediaType},
+		{"other error", errors.New("disk full"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			Fail(w, httptest.NewRequest("POST", "/", nil), tt.err)
+			if w.Code != tt.status {
+				t.Errorf("status %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
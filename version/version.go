@@ -0,0 +1,50 @@
+// Package version holds the build information of the binary.
+//
+// Commit and BuildTime are set at build time:
+//
+//	go build -ldflags "-X Tahlilchi.uz/version.Commit=$(git rev-parse HEAD) -X Tahlilchi.uz/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	// Commit is the git commit the binary is built from
+	Commit = ""
+	// BuildTime is the time the binary is built at, in RFC 3339
+	BuildTime = ""
+)
+
+// Info is the build information of the binary
+type Info struct {
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information.
+// Without the ldflags the commit and the time recorded by the go command are used, if there are any.
+func Get() Info {
+	info := Info{Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = s.Value
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}